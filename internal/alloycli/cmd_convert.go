@@ -26,7 +26,7 @@ func convertCommand() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "convert [flags] [file]",
-		Short: "Convert a supported config file to Alloy",
+		Short: "Convert a supported config file to Alloy, or Alloy to a supported format",
 		Long: `The convert subcommand translates a supported config file to
 an Alloy configuration file.
 
@@ -41,6 +41,9 @@ provided, no report is generated.
 
 The -f flag can be used to specify the format we are converting from.
 
+The -t flag can be used to convert an Alloy configuration file to the
+specified format instead. The -f and -t flags can't be used together.
+
 The -b flag can be used to bypass errors. Errors are defined as 
 non-critical issues identified during the conversion where an
 output can still be generated.
@@ -76,6 +79,7 @@ by separating them with a space.`,
 	cmd.Flags().StringVarP(&f.output, "output", "o", f.output, "The filepath and filename where the output is written.")
 	cmd.Flags().StringVarP(&f.report, "report", "r", f.report, "The filepath and filename where the report is written.")
 	cmd.Flags().StringVarP(&f.sourceFormat, "source-format", "f", f.sourceFormat, fmt.Sprintf("The format of the source file. Supported formats: %s.", supportedFormatsList()))
+	cmd.Flags().StringVarP(&f.target, "target", "t", f.target, fmt.Sprintf("The format to convert an Alloy config to. Supported formats: %s.", supportedTargetsList()))
 	cmd.Flags().BoolVarP(&f.bypassErrors, "bypass-errors", "b", f.bypassErrors, "Enable bypassing errors when converting")
 	cmd.Flags().StringVarP(&f.extraArgs, "extra-args", "e", f.extraArgs, "Extra arguments from the original format used by the converter. Multiple arguments can be passed by separating them with a space.")
 	return cmd
@@ -85,13 +89,19 @@ type alloyConvert struct {
 	output       string
 	report       string
 	sourceFormat string
+	target       string
 	bypassErrors bool
 	extraArgs    string
 }
 
 func (fc *alloyConvert) Run(configFile string) error {
-	if fc.sourceFormat == "" {
-		return fmt.Errorf("source-format is a required flag")
+	switch {
+	case fc.sourceFormat != "" && fc.target != "":
+		return fmt.Errorf("source-format and target can't be used together")
+	case fc.sourceFormat == "" && fc.target == "":
+		return fmt.Errorf("either source-format or target is a required flag")
+	case fc.target != "" && fc.extraArgs != "":
+		return fmt.Errorf("extra-args can't be used with target")
 	}
 
 	if configFile == "-" {
@@ -125,7 +135,15 @@ func convert(r io.Reader, fc *alloyConvert) error {
 		return err
 	}
 
-	alloyBytes, diags := converter.Convert(inputBytes, converter.Input(fc.sourceFormat), ea)
+	var (
+		alloyBytes []byte
+		diags      convert_diag.Diagnostics
+	)
+	if fc.target != "" {
+		alloyBytes, diags = converter.ConvertFromAlloy(inputBytes, converter.Target(fc.target))
+	} else {
+		alloyBytes, diags = converter.Convert(inputBytes, converter.Input(fc.sourceFormat), ea)
+	}
 	err = generateConvertReport(diags, fc)
 	if err != nil {
 		return err
@@ -181,8 +199,16 @@ func hasErrorLevel(ds convert_diag.Diagnostics, sev convert_diag.Severity) bool
 }
 
 func supportedFormatsList() string {
-	var ret = make([]string, len(converter.SupportedFormats))
-	for i, f := range converter.SupportedFormats {
+	return quotedList(converter.SupportedFormats)
+}
+
+func supportedTargetsList() string {
+	return quotedList(converter.SupportedTargets)
+}
+
+func quotedList(formats []string) string {
+	var ret = make([]string, len(formats))
+	for i, f := range formats {
 		ret[i] = fmt.Sprintf("%q", f)
 	}
 	return strings.Join(ret, ", ")
@@ -0,0 +1,128 @@
+package alloycli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/pipelinetest"
+	"github.com/grafana/alloy/internal/runtime/logging"
+	"github.com/grafana/alloy/syntax/diag"
+)
+
+func testCommand() *cobra.Command {
+	t := &alloyTest{
+		minStability: featuregate.StabilityGenerallyAvailable,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "test [flags] config-file test-file...",
+		Short: "Test a configuration file against fixture data",
+		Long: `The test subcommand runs the pipelines of a configuration file
+against fixture data and compares their output to expected results.
+
+Each test of a test file replaces input components of the configuration
+with fixture logs, samples, or targets, and replaces the components the
+data flows to with sinks capturing the data they receive. The configuration
+then runs with the same controller used by the run subcommand.
+
+The test subcommand exits with a non-zero status if any test fails.`,
+		Args:         cobra.MinimumNArgs(2),
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return t.Run(cmd.Context(), cmd.OutOrStdout(), args[0], args[1:])
+		},
+	}
+
+	cmd.Flags().BoolVarP(&t.verbose, "verbose", "v", t.verbose, "Write the logs of the components under test to stderr.")
+	cmd.Flags().Var(&t.minStability, "stability.level", fmt.Sprintf("Minimum stability level of features to enable. Supported values: %s", strings.Join(featuregate.AllowedValues(), ", ")))
+	cmd.Flags().BoolVar(&t.enableCommunityComps, "feature.community-components.enabled", t.enableCommunityComps, "Enable community components.")
+	return cmd
+}
+
+type alloyTest struct {
+	verbose              bool
+	minStability         featuregate.Stability
+	enableCommunityComps bool
+}
+
+func (t *alloyTest) Run(ctx context.Context, w io.Writer, configFile string, testFiles []string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	config, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	logger := logging.NewNop()
+	if t.verbose {
+		logger, err = logging.New(os.Stderr, logging.DefaultOptions)
+		if err != nil {
+			return fmt.Errorf("building logger: %w", err)
+		}
+	}
+
+	opts := pipelinetest.Options{
+		Logger:               logger,
+		MinStability:         t.minStability,
+		EnableCommunityComps: t.enableCommunityComps,
+	}
+
+	var passed, failed int
+	for _, testFile := range testFiles {
+		bb, err := os.ReadFile(testFile)
+		if err != nil {
+			return err
+		}
+		file, err := pipelinetest.ParseFile(testFile, bb)
+		if err != nil {
+			printErr(w, "", err)
+			return fmt.Errorf("could not parse test file %s", testFile)
+		}
+
+		for _, result := range pipelinetest.Run(ctx, opts, configFile, config, file) {
+			if result.Passed() {
+				passed++
+				fmt.Fprintf(w, "--- PASS: %s (%s)\n", result.Test, testFile)
+				continue
+			}
+
+			failed++
+			fmt.Fprintf(w, "--- FAIL: %s (%s)\n", result.Test, testFile)
+			if result.Err != nil {
+				printErr(w, "    ", result.Err)
+			}
+			for _, failure := range result.Failures {
+				fmt.Fprintf(w, "    %s\n", failure)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d test(s) failed", failed)
+	}
+	return nil
+}
+
+// printErr writes err to w, with one line per diagnostic when err holds
+// diagnostics.
+func printErr(w io.Writer, indent string, err error) {
+	var diags diag.Diagnostics
+	if errors.As(err, &diags) {
+		for _, diag := range diags {
+			fmt.Fprintf(w, "%s%s\n", indent, diag)
+		}
+		return
+	}
+	fmt.Fprintf(w, "%s%s\n", indent, err)
+}
@@ -0,0 +1,152 @@
+package alloycli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	alloy_runtime "github.com/grafana/alloy/internal/runtime"
+	"github.com/grafana/alloy/internal/runtime/logging"
+	"github.com/grafana/alloy/internal/service/standalone"
+	"github.com/grafana/alloy/syntax/diag"
+)
+
+func validateCommand() *cobra.Command {
+	v := &alloyValidate{
+		minStability: featuregate.StabilityGenerallyAvailable,
+		configFormat: "alloy",
+	}
+
+	cmd := &cobra.Command{
+		Use:   "validate [flags] path",
+		Short: "Validate a configuration file or directory without running it",
+		Long: `The validate subcommand loads a configuration file or directory
+and reports every problem found without running any component.
+
+Beyond syntax errors, validate reports unknown components and references,
+dependency cycles, type errors, and invalid argument values, including the
+ones of modules loaded with import blocks. Exports of components are only
+known at runtime: errors of expressions using them are reported as warnings.
+
+The validate subcommand exits with a non-zero status if any error is found.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Run(cmd.ErrOrStderr(), args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&v.configFormat, "config.format", v.configFormat, fmt.Sprintf("The format of the source file. Supported formats: %s.", supportedFormatsList()))
+	cmd.Flags().BoolVar(&v.configBypassConversionErrors, "config.bypass-conversion-errors", v.configBypassConversionErrors, "Enable bypassing errors when converting")
+	cmd.Flags().StringVar(&v.configExtraArgs, "config.extra-args", v.configExtraArgs, "Extra arguments from the original format used by the converter. Multiple arguments can be passed by separating them with a space.")
+	cmd.Flags().Var(&v.minStability, "stability.level", fmt.Sprintf("Minimum stability level of features to enable. Supported values: %s", strings.Join(featuregate.AllowedValues(), ", ")))
+	cmd.Flags().BoolVar(&v.enableCommunityComps, "feature.community-components.enabled", v.enableCommunityComps, "Enable community components.")
+	return cmd
+}
+
+type alloyValidate struct {
+	configFormat                 string
+	configBypassConversionErrors bool
+	configExtraArgs              string
+	minStability                 featuregate.Stability
+	enableCommunityComps         bool
+}
+
+// Run validates the configuration at path and writes the diagnostics found
+// to w.
+func (v *alloyValidate) Run(w io.Writer, path string) error {
+	source, err := loadAlloySource(path, v.configFormat, v.configBypassConversionErrors, v.configExtraArgs)
+	if err != nil {
+		// Syntax errors are reported the same way as evaluation errors.
+		var diags diag.Diagnostics
+		if errors.As(err, &diags) {
+			return v.report(w, v.rawConfigs(path), diags)
+		}
+		return err
+	}
+
+	dataPath, err := os.MkdirTemp("", "alloy-validate-*")
+	if err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	defer os.RemoveAll(dataPath)
+
+	l := logging.NewNop()
+	services, err := standalone.Services(standalone.Options{
+		Logger:   l,
+		DataPath: dataPath,
+		NodeName: "alloy-validate",
+	})
+	if err != nil {
+		return err
+	}
+
+	// The controller is never run: components are not built, and services
+	// only validate their configuration.
+	f := alloy_runtime.New(alloy_runtime.Options{
+		Logger:               l,
+		DataPath:             dataPath,
+		Reg:                  prometheus.NewRegistry(),
+		MinStability:         v.minStability,
+		EnableCommunityComps: v.enableCommunityComps,
+		Services:             services,
+		DryRun:               true,
+	})
+
+	err = f.LoadSource(source, nil, path)
+	if err == nil {
+		return nil
+	}
+	var diags diag.Diagnostics
+	if !errors.As(err, &diags) {
+		return err
+	}
+	return v.report(w, source.RawConfigs(), diags)
+}
+
+// rawConfigs returns the content of the configuration at path, used to print
+// the context of diagnostics. Nothing is returned for directories or
+// configurations which must be converted.
+func (v *alloyValidate) rawConfigs(path string) map[string][]byte {
+	if v.configFormat != "alloy" {
+		return nil
+	}
+	bb, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return map[string][]byte{path: bb}
+}
+
+// report prints diags using files for context, and returns an error if any
+// of them is an error.
+func (v *alloyValidate) report(w io.Writer, files map[string][]byte, diags diag.Diagnostics) error {
+	p := diag.NewPrinter(diag.PrinterConfig{
+		Color:              !color.NoColor,
+		ContextLinesBefore: 1,
+		ContextLinesAfter:  1,
+	})
+	_ = p.Fprint(w, files, diags)
+
+	// Print newline after the diagnostics.
+	fmt.Fprintln(w)
+
+	var errCount int
+	for _, d := range diags {
+		if d.Severity == diag.SeverityLevelError {
+			errCount++
+		}
+	}
+	if errCount > 0 {
+		return fmt.Errorf("found %d error(s) and %d warning(s)", errCount, len(diags)-errCount)
+	}
+	return nil
+}
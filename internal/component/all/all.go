@@ -41,12 +41,14 @@ import (
 	_ "github.com/grafana/alloy/internal/component/loki/process"                             // Import loki.process
 	_ "github.com/grafana/alloy/internal/component/loki/relabel"                             // Import loki.relabel
 	_ "github.com/grafana/alloy/internal/component/loki/rules/kubernetes"                    // Import loki.rules.kubernetes
+	_ "github.com/grafana/alloy/internal/component/loki/rules/local"                         // Import loki.rules.local
 	_ "github.com/grafana/alloy/internal/component/loki/secretfilter"                        // Import loki.secretfilter
 	_ "github.com/grafana/alloy/internal/component/loki/source/api"                          // Import loki.source.api
 	_ "github.com/grafana/alloy/internal/component/loki/source/aws_firehose"                 // Import loki.source.awsfirehose
 	_ "github.com/grafana/alloy/internal/component/loki/source/azure_event_hubs"             // Import loki.source.azure_event_hubs
 	_ "github.com/grafana/alloy/internal/component/loki/source/cloudflare"                   // Import loki.source.cloudflare
 	_ "github.com/grafana/alloy/internal/component/loki/source/docker"                       // Import loki.source.docker
+	_ "github.com/grafana/alloy/internal/component/loki/source/exec"                         // Import loki.source.exec
 	_ "github.com/grafana/alloy/internal/component/loki/source/file"                         // Import loki.source.file
 	_ "github.com/grafana/alloy/internal/component/loki/source/gcplog"                       // Import loki.source.gcplog
 	_ "github.com/grafana/alloy/internal/component/loki/source/gelf"                         // Import loki.source.gelf
@@ -55,10 +57,13 @@ import (
 	_ "github.com/grafana/alloy/internal/component/loki/source/kafka"                        // Import loki.source.kafka
 	_ "github.com/grafana/alloy/internal/component/loki/source/kubernetes"                   // Import loki.source.kubernetes
 	_ "github.com/grafana/alloy/internal/component/loki/source/kubernetes_events"            // Import loki.source.kubernetes_events
+	_ "github.com/grafana/alloy/internal/component/loki/source/mqtt"                         // Import loki.source.mqtt
+	_ "github.com/grafana/alloy/internal/component/loki/source/nats"                         // Import loki.source.nats
 	_ "github.com/grafana/alloy/internal/component/loki/source/podlogs"                      // Import loki.source.podlogs
 	_ "github.com/grafana/alloy/internal/component/loki/source/syslog"                       // Import loki.source.syslog
 	_ "github.com/grafana/alloy/internal/component/loki/source/windowsevent"                 // Import loki.source.windowsevent
 	_ "github.com/grafana/alloy/internal/component/loki/write"                               // Import loki.write
+	_ "github.com/grafana/alloy/internal/component/loki/write/file"                          // Import loki.write.file
 	_ "github.com/grafana/alloy/internal/component/mimir/rules/kubernetes"                   // Import mimir.rules.kubernetes
 	_ "github.com/grafana/alloy/internal/component/otelcol/auth/basic"                       // Import otelcol.auth.basic
 	_ "github.com/grafana/alloy/internal/component/otelcol/auth/bearer"                      // Import otelcol.auth.bearer
@@ -112,6 +117,8 @@ import (
 	_ "github.com/grafana/alloy/internal/component/otelcol/receiver/tcplog"                  // Import otelcol.receiver.tcplog
 	_ "github.com/grafana/alloy/internal/component/otelcol/receiver/vcenter"                 // Import otelcol.receiver.vcenter
 	_ "github.com/grafana/alloy/internal/component/otelcol/receiver/zipkin"                  // Import otelcol.receiver.zipkin
+	_ "github.com/grafana/alloy/internal/component/prometheus/aggregate"                     // Import prometheus.aggregate
+	_ "github.com/grafana/alloy/internal/component/prometheus/cardinality_limit"             // Import prometheus.cardinality_limit
 	_ "github.com/grafana/alloy/internal/component/prometheus/exporter/apache"               // Import prometheus.exporter.apache
 	_ "github.com/grafana/alloy/internal/component/prometheus/exporter/azure"                // Import prometheus.exporter.azure
 	_ "github.com/grafana/alloy/internal/component/prometheus/exporter/blackbox"             // Import prometheus.exporter.blackbox
@@ -143,8 +150,11 @@ import (
 	_ "github.com/grafana/alloy/internal/component/prometheus/operator/probes"               // Import prometheus.operator.probes
 	_ "github.com/grafana/alloy/internal/component/prometheus/operator/servicemonitors"      // Import prometheus.operator.servicemonitors
 	_ "github.com/grafana/alloy/internal/component/prometheus/receive_http"                  // Import prometheus.receive_http
+	_ "github.com/grafana/alloy/internal/component/prometheus/receive_pushgateway"           // Import prometheus.receive_pushgateway
+	_ "github.com/grafana/alloy/internal/component/prometheus/receive_statsd"                // Import prometheus.receive_statsd
 	_ "github.com/grafana/alloy/internal/component/prometheus/relabel"                       // Import prometheus.relabel
 	_ "github.com/grafana/alloy/internal/component/prometheus/remotewrite"                   // Import prometheus.remote_write
+	_ "github.com/grafana/alloy/internal/component/prometheus/rules/local"                   // Import prometheus.rules.local
 	_ "github.com/grafana/alloy/internal/component/prometheus/scrape"                        // Import prometheus.scrape
 	_ "github.com/grafana/alloy/internal/component/prometheus/write/queue"                   // Import prometheus.write.queue
 	_ "github.com/grafana/alloy/internal/component/pyroscope/ebpf"                           // Import pyroscope.ebpf
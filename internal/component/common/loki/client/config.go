@@ -45,8 +45,15 @@ type Config struct {
 	// prevent HOL blocking in multitenant deployments.
 	DropRateLimitedBatches bool `yaml:"drop_rate_limited_batches"`
 
+	// Selector is a stream selector, such as {cluster="eu"}, which restricts the entries sent by the client to the
+	// ones whose labels match it. An empty selector matches every entry.
+	Selector string `yaml:"selector,omitempty"`
+
 	// Queue controls configuration parameters specific to the queue client
 	Queue QueueConfig
+
+	// PersistentQueue controls whether batches are stored on disk until they're sent
+	PersistentQueue PersistentQueueConfig
 }
 
 // QueueConfig holds configurations for the queue-based remote-write client.
@@ -63,6 +70,20 @@ type QueueConfig struct {
 	DrainTimeout time.Duration
 }
 
+// PersistentQueueConfig holds configurations for the client which stores batches in an on-disk queue until they're
+// sent, so they survive endpoint outages longer than the retries and restarts.
+type PersistentQueueConfig struct {
+	Enabled bool
+
+	// Dir is the folder where the queues are stored. The queue of each client is stored in a subfolder named after the
+	// name of the client, or a hash of its URL. Subfolders which don't belong to any client are removed.
+	Dir string
+
+	// MaxSize is the maximum size in bytes of the batches stored in the queue. When the queue is full, the client stops
+	// accepting log entries until batches are sent.
+	MaxSize int
+}
+
 // RegisterFlags with prefix registers flags where every name is prefixed by
 // prefix. If prefix is a non-empty string, prefix should end with a period.
 func (c *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/natefinch/atomic"
+)
+
+const (
+	diskQueueFileSuffix = ".batch"
+	diskQueueVersion    = 1
+)
+
+// DiskRecord is a batch stored in a DiskQueue.
+type DiskRecord struct {
+	ID       uint64
+	TenantID string
+	// Entries is the number of log entries in the batch.
+	Entries int
+	// Data is the encoded push request of the batch.
+	Data []byte
+}
+
+type diskRecordFile struct {
+	id   uint64
+	size int64
+}
+
+// DiskQueue is a FIFO queue of batches, which are stored as one file per batch in a directory. The batches which are
+// still in the directory when the queue is created, for example after a restart, are the first ones returned by Next.
+//
+// A DiskQueue supports a single writer and a single reader.
+type DiskQueue struct {
+	dir     string
+	maxSize int64
+	logger  log.Logger
+	metrics *DiskQueueMetrics
+
+	mut     sync.Mutex
+	records []diskRecordFile
+	size    int64
+	nextID  uint64
+
+	// written and removed wake up a reader waiting for a batch, and a writer waiting for space in the queue.
+	written chan struct{}
+	removed chan struct{}
+}
+
+// NewDiskQueue creates a DiskQueue storing batches in dir, which is created if it doesn't exist. maxSize is the maximum
+// size in bytes of the stored batches.
+func NewDiskQueue(dir string, maxSize int64, logger log.Logger, metrics *DiskQueueMetrics) (*DiskQueue, error) {
+	if err := os.MkdirAll(dir, MarkerFolderMode); err != nil {
+		return nil, fmt.Errorf("error creating persistent queue folder %q: %w", dir, err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading persistent queue folder %q: %w", dir, err)
+	}
+
+	q := &DiskQueue{
+		dir:     dir,
+		maxSize: maxSize,
+		logger:  logger,
+		metrics: metrics,
+		written: make(chan struct{}, 1),
+		removed: make(chan struct{}, 1),
+	}
+	for _, f := range files {
+		id, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), diskQueueFileSuffix), 10, 64)
+		if f.IsDir() || !strings.HasSuffix(f.Name(), diskQueueFileSuffix) || err != nil {
+			// Interrupted writes may leave temporary files behind.
+			level.Debug(logger).Log("msg", "ignoring unknown file in persistent queue folder", "file", f.Name())
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error reading persistent queue file %q: %w", f.Name(), err)
+		}
+		q.records = append(q.records, diskRecordFile{id: id, size: info.Size()})
+		q.size += info.Size()
+		q.nextID = max(q.nextID, id+1)
+	}
+	slices.SortFunc(q.records, func(a, b diskRecordFile) int { return cmp.Compare(a.id, b.id) })
+
+	if len(q.records) > 0 {
+		level.Info(logger).Log("msg", "replaying batches from persistent queue", "batches", len(q.records), "bytes", q.size)
+	}
+	q.metrics.capacityBytes.WithLabelValues().Set(float64(maxSize))
+	q.metrics.replayed.WithLabelValues().Add(float64(len(q.records)))
+	q.updateMetrics()
+	return q, nil
+}
+
+// Put stores a batch at the end of the queue. If the queue is full, Put blocks until the reader removes enough batches,
+// or until ctx is done. A batch larger than the maximum size of the queue is stored when the queue is empty.
+func (q *DiskQueue) Put(ctx context.Context, tenantID string, entries int, data []byte) error {
+	buf := make([]byte, 0, len(data)+len(tenantID)+2*binary.MaxVarintLen64+1)
+	buf = append(buf, diskQueueVersion)
+	buf = binary.AppendUvarint(buf, uint64(len(tenantID)))
+	buf = append(buf, tenantID...)
+	buf = binary.AppendUvarint(buf, uint64(entries))
+	buf = append(buf, data...)
+	size := int64(len(buf))
+
+	var blockedSince time.Time
+	for {
+		q.mut.Lock()
+		if len(q.records) == 0 || q.size+size <= q.maxSize {
+			break
+		}
+		q.mut.Unlock()
+
+		if blockedSince.IsZero() {
+			blockedSince = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			q.metrics.blockedSeconds.WithLabelValues().Add(time.Since(blockedSince).Seconds())
+			return ctx.Err()
+		case <-q.removed:
+		}
+	}
+	defer q.mut.Unlock()
+	if !blockedSince.IsZero() {
+		q.metrics.blockedSeconds.WithLabelValues().Add(time.Since(blockedSince).Seconds())
+	}
+
+	id := q.nextID
+	if err := atomic.WriteFile(q.path(id), bytes.NewReader(buf)); err != nil {
+		return fmt.Errorf("error writing batch to persistent queue: %w", err)
+	}
+	q.nextID++
+	q.records = append(q.records, diskRecordFile{id: id, size: size})
+	q.size += size
+	q.updateMetrics()
+	notify(q.written)
+	return nil
+}
+
+// Next returns the oldest batch of the queue, without removing it. If the queue is empty, Next blocks until a batch is
+// stored or until ctx is done. Batches which can't be read are removed from the queue.
+func (q *DiskQueue) Next(ctx context.Context) (DiskRecord, error) {
+	for {
+		q.mut.Lock()
+		if len(q.records) == 0 {
+			q.mut.Unlock()
+			select {
+			case <-ctx.Done():
+				return DiskRecord{}, ctx.Err()
+			case <-q.written:
+			}
+			continue
+		}
+		id := q.records[0].id
+		q.mut.Unlock()
+
+		record, err := q.read(id)
+		if err == nil {
+			return record, nil
+		}
+		level.Error(q.logger).Log("msg", "dropping unreadable batch from persistent queue", "file", q.path(id), "err", err)
+		if err := q.Remove(id); err != nil {
+			return DiskRecord{}, err
+		}
+	}
+}
+
+// Remove removes the batch with the given ID from the queue.
+func (q *DiskQueue) Remove(id uint64) error {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	i := slices.IndexFunc(q.records, func(r diskRecordFile) bool { return r.id == id })
+	if i < 0 {
+		return nil
+	}
+	if err := os.Remove(q.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing batch from persistent queue: %w", err)
+	}
+	q.size -= q.records[i].size
+	q.records = slices.Delete(q.records, i, i+1)
+	q.updateMetrics()
+	notify(q.removed)
+	return nil
+}
+
+func (q *DiskQueue) read(id uint64) (DiskRecord, error) {
+	buf, err := os.ReadFile(q.path(id))
+	if err != nil {
+		return DiskRecord{}, err
+	}
+	if len(buf) == 0 || buf[0] != diskQueueVersion {
+		return DiskRecord{}, fmt.Errorf("unsupported batch file version")
+	}
+	buf = buf[1:]
+
+	tenantLen, n := binary.Uvarint(buf)
+	if n <= 0 || uint64(len(buf)-n) < tenantLen {
+		return DiskRecord{}, fmt.Errorf("invalid batch file header")
+	}
+	tenantID := string(buf[n : n+int(tenantLen)])
+	buf = buf[n+int(tenantLen):]
+
+	entries, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return DiskRecord{}, fmt.Errorf("invalid batch file header")
+	}
+	return DiskRecord{ID: id, TenantID: tenantID, Entries: int(entries), Data: buf[n:]}, nil
+}
+
+func (q *DiskQueue) path(id uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", id, diskQueueFileSuffix))
+}
+
+func (q *DiskQueue) updateMetrics() {
+	q.metrics.sizeBytes.WithLabelValues().Set(float64(q.size))
+	q.metrics.batches.WithLabelValues().Set(float64(len(q.records)))
+}
+
+// notify signals ch without blocking, since a pending signal is enough to wake up the waiting goroutine.
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
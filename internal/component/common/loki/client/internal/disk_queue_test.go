@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskQueue(t *testing.T, dir string, maxSize int64) *DiskQueue {
+	q, err := NewDiskQueue(dir, maxSize, log.NewNopLogger(), NewDiskQueueMetrics(prometheus.NewRegistry()).WithCurriedId("test"))
+	require.NoError(t, err)
+	return q
+}
+
+func TestDiskQueue(t *testing.T) {
+	t.Run("returns batches in order", func(t *testing.T) {
+		q := newTestDiskQueue(t, t.TempDir(), 1024)
+		ctx := context.Background()
+
+		require.NoError(t, q.Put(ctx, "tenant-a", 1, []byte("first")))
+		require.NoError(t, q.Put(ctx, "", 2, []byte("second")))
+
+		r, err := q.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, DiskRecord{ID: 0, TenantID: "tenant-a", Entries: 1, Data: []byte("first")}, r)
+
+		// Next returns the same batch until it's removed.
+		r, err = q.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), r.ID)
+		require.NoError(t, q.Remove(r.ID))
+
+		r, err = q.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, DiskRecord{ID: 1, TenantID: "", Entries: 2, Data: []byte("second")}, r)
+	})
+
+	t.Run("replays batches after a restart", func(t *testing.T) {
+		dir := t.TempDir()
+		q := newTestDiskQueue(t, dir, 1024)
+		require.NoError(t, q.Put(context.Background(), "", 1, []byte("first")))
+		require.NoError(t, q.Put(context.Background(), "", 1, []byte("second")))
+		// Leftovers of interrupted writes are ignored.
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000002.batch123"), []byte("partial"), 0o600))
+
+		q = newTestDiskQueue(t, dir, 1024)
+		r, err := q.Next(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []byte("first"), r.Data)
+		require.NoError(t, q.Remove(r.ID))
+
+		require.NoError(t, q.Put(context.Background(), "", 1, []byte("third")))
+		for _, expected := range []string{"second", "third"} {
+			r, err := q.Next(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, expected, string(r.Data))
+			require.NoError(t, q.Remove(r.ID))
+		}
+	})
+
+	t.Run("blocks writes when full", func(t *testing.T) {
+		q := newTestDiskQueue(t, t.TempDir(), 20)
+		require.NoError(t, q.Put(context.Background(), "", 1, []byte("0123456789")))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, q.Put(ctx, "", 1, []byte("0123456789")), context.DeadlineExceeded)
+
+		done := make(chan error)
+		go func() {
+			done <- q.Put(context.Background(), "", 1, []byte("0123456789"))
+		}()
+		r, err := q.Next(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, q.Remove(r.ID))
+		require.NoError(t, <-done)
+	})
+
+	t.Run("blocks reads when empty", func(t *testing.T) {
+		q := newTestDiskQueue(t, t.TempDir(), 1024)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := q.Next(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("drops unreadable batches", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000000.batch"), []byte("corrupted"), 0o600))
+		q := newTestDiskQueue(t, dir, 1024)
+		require.NoError(t, q.Put(context.Background(), "", 1, []byte("valid")))
+
+		r, err := q.Next(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "valid", string(r.Data))
+		_, err = os.Stat(filepath.Join(dir, "00000000000000000000.batch"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
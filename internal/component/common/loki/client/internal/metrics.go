@@ -36,3 +36,83 @@ func (m *MarkerMetrics) WithCurriedId(id string) *MarkerMetrics {
 		}),
 	}
 }
+
+// DiskQueueMetrics are the metrics of a DiskQueue, which track how much data is waiting to be sent to an endpoint and
+// how long the writers were blocked because the queue was full.
+type DiskQueueMetrics struct {
+	sizeBytes      *prometheus.GaugeVec
+	batches        *prometheus.GaugeVec
+	capacityBytes  *prometheus.GaugeVec
+	blockedSeconds *prometheus.CounterVec
+	replayed       *prometheus.CounterVec
+}
+
+func NewDiskQueueMetrics(reg prometheus.Registerer) *DiskQueueMetrics {
+	m := &DiskQueueMetrics{
+		sizeBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "loki_write",
+				Subsystem: "persistent_queue",
+				Name:      "size_bytes",
+				Help:      "Size of the batches stored in the persistent queue.",
+			},
+			[]string{"id"},
+		),
+		batches: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "loki_write",
+				Subsystem: "persistent_queue",
+				Name:      "batches",
+				Help:      "Number of batches stored in the persistent queue.",
+			},
+			[]string{"id"},
+		),
+		capacityBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "loki_write",
+				Subsystem: "persistent_queue",
+				Name:      "capacity_bytes",
+				Help:      "Maximum size of the batches stored in the persistent queue.",
+			},
+			[]string{"id"},
+		),
+		blockedSeconds: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "loki_write",
+				Subsystem: "persistent_queue",
+				Name:      "blocked_seconds_total",
+				Help:      "Time spent waiting for space in the full persistent queue, during which no log entry is accepted.",
+			},
+			[]string{"id"},
+		),
+		replayed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "loki_write",
+				Subsystem: "persistent_queue",
+				Name:      "replayed_batches_total",
+				Help:      "Number of batches found in the persistent queue on startup, which are sent again.",
+			},
+			[]string{"id"},
+		),
+	}
+	if reg != nil {
+		m.sizeBytes = util.MustRegisterOrGet(reg, m.sizeBytes).(*prometheus.GaugeVec)
+		m.batches = util.MustRegisterOrGet(reg, m.batches).(*prometheus.GaugeVec)
+		m.capacityBytes = util.MustRegisterOrGet(reg, m.capacityBytes).(*prometheus.GaugeVec)
+		m.blockedSeconds = util.MustRegisterOrGet(reg, m.blockedSeconds).(*prometheus.CounterVec)
+		m.replayed = util.MustRegisterOrGet(reg, m.replayed).(*prometheus.CounterVec)
+	}
+	return m
+}
+
+// WithCurriedId returns a curried version of DiskQueueMetrics, with the id label pre-filled.
+func (m *DiskQueueMetrics) WithCurriedId(id string) *DiskQueueMetrics {
+	labels := map[string]string{"id": id}
+	return &DiskQueueMetrics{
+		sizeBytes:      m.sizeBytes.MustCurryWith(labels),
+		batches:        m.batches.MustCurryWith(labels),
+		capacityBytes:  m.capacityBytes.MustCurryWith(labels),
+		blockedSeconds: m.blockedSeconds.MustCurryWith(labels),
+		replayed:       m.replayed.MustCurryWith(labels),
+	}
+}
@@ -3,13 +3,18 @@ package client
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/alloy/internal/component/common/loki/client/internal"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/loki/v3/clients/pkg/logentry/logql"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/grafana/alloy/internal/component/common/loki"
 	"github.com/grafana/alloy/internal/component/common/loki/limit"
@@ -66,6 +71,25 @@ func (p watcherClientPair) Stop(drain bool) {
 	p.client.Stop()
 }
 
+// route is a client, and the matchers of the selector of its config. A route without matchers receives every entry.
+type route struct {
+	client   Client
+	matchers []*labels.Matcher
+
+	routedEntries   prometheus.Counter
+	filteredEntries prometheus.Counter
+}
+
+// matches returns true if the labels of the entry match the selector of the route.
+func (r route) matches(lbs model.LabelSet) bool {
+	for _, m := range r.matchers {
+		if !m.Matches(string(lbs[model.LabelName(m.Name)])) {
+			return false
+		}
+	}
+	return true
+}
+
 // Manager manages remote write client instantiation, and connects the related
 // components to orchestrate the flow of loki.Entry from the scrape targets, to
 // the remote write clients themselves.
@@ -77,8 +101,9 @@ func (p watcherClientPair) Stop(drain bool) {
 type Manager struct {
 	name string
 
-	clients []Client
+	routes  []route
 	pairs   []watcherClientPair
+	metrics *RouteMetrics
 
 	entries chan loki.Entry
 	once    sync.Once
@@ -93,13 +118,17 @@ func NewManager(metrics *Metrics, logger log.Logger, limits limit.Config, reg pr
 	walWatcherMetrics := wal.NewWatcherMetrics(reg)
 	walMarkerMetrics := internal.NewMarkerMetrics(reg)
 	queueClientMetrics := NewQueueClientMetrics(reg)
+	diskQueueMetrics := internal.NewDiskQueueMetrics(reg)
+	routeMetrics := NewRouteMetrics(reg)
 
 	if len(clientCfgs) == 0 {
 		return nil, fmt.Errorf("at least one client config must be provided")
 	}
 
 	clientsCheck := make(map[string]struct{})
-	clients := make([]Client, 0, len(clientCfgs))
+	// persistentQueues holds the subfolder and the client name of every persistent queue, by parent folder.
+	persistentQueues := make(map[string]map[string]string)
+	routes := make([]route, 0, len(clientCfgs))
 	pairs := make([]watcherClientPair, 0, len(clientCfgs))
 	for _, cfg := range clientCfgs {
 		// Don't allow duplicate clients, we have client specific metrics that need at least one unique label value (name).
@@ -110,6 +139,27 @@ func NewManager(metrics *Metrics, logger log.Logger, limits limit.Config, reg pr
 
 		clientsCheck[clientName] = fake
 
+		var matchers []*labels.Matcher
+		if cfg.Selector != "" {
+			// Entries are read from the WAL by every client, so they can't be routed.
+			if walCfg.Enabled {
+				return nil, fmt.Errorf("selector of client %s is not supported when the WAL is enabled", clientName)
+			}
+			var err error
+			matchers, err = logql.ParseMatchers(cfg.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector of client %s: %w", clientName, err)
+			}
+		}
+		newRoute := func(client Client) route {
+			return route{
+				client:          client,
+				matchers:        matchers,
+				routedEntries:   routeMetrics.routedEntries.WithLabelValues(clientName),
+				filteredEntries: routeMetrics.filteredEntries.WithLabelValues(clientName),
+			}
+		}
+
 		if walCfg.Enabled {
 			// add some context information for the logger the watcher uses
 			wlog := log.With(logger, "client", clientName)
@@ -140,22 +190,48 @@ func NewManager(metrics *Metrics, logger log.Logger, limits limit.Config, reg pr
 				watcher: watcher,
 				client:  queue,
 			})
+		} else if cfg.PersistentQueue.Enabled {
+			dirName := persistentQueueDirName(cfg)
+			if persistentQueues[cfg.PersistentQueue.Dir] == nil {
+				persistentQueues[cfg.PersistentQueue.Dir] = make(map[string]string)
+			}
+			if other, ok := persistentQueues[cfg.PersistentQueue.Dir][dirName]; ok {
+				return nil, fmt.Errorf("clients %s and %s can't share the same persistent queue, set a different name to each of them", other, clientName)
+			}
+			persistentQueues[cfg.PersistentQueue.Dir][dirName] = clientName
+
+			dir := filepath.Join(cfg.PersistentQueue.Dir, dirName)
+			client, err := NewPersistent(metrics, diskQueueMetrics.WithCurriedId(clientName), cfg, dir, limits.MaxStreams, limits.MaxLineSize.Val(), limits.MaxLineSizeTruncate, logger)
+			if err != nil {
+				return nil, fmt.Errorf("error starting persistent queue client: %w", err)
+			}
+
+			routes = append(routes, newRoute(client))
+
+			pairs = append(pairs, watcherClientPair{
+				client: client,
+			})
 		} else {
 			client, err := New(metrics, cfg, limits.MaxStreams, limits.MaxLineSize.Val(), limits.MaxLineSizeTruncate, logger)
 			if err != nil {
 				return nil, fmt.Errorf("error starting client: %w", err)
 			}
 
-			clients = append(clients, client)
+			routes = append(routes, newRoute(client))
 
 			pairs = append(pairs, watcherClientPair{
 				client: client,
 			})
 		}
 	}
+	for dir, inUse := range persistentQueues {
+		removeOrphanedPersistentQueues(dir, inUse, logger)
+	}
+
 	manager := &Manager{
-		clients: clients,
+		routes:  routes,
 		pairs:   pairs,
+		metrics: routeMetrics,
 		entries: make(chan loki.Entry),
 	}
 	if walCfg.Enabled {
@@ -185,14 +261,24 @@ func (m *Manager) startWithConsume() {
 }
 
 // startWithForward starts the main manager routine, which reads entries from the exposed channel, and forwards them
-// doing a fan-out across all inner clients.
+// doing a fan-out across all inner clients whose selector matches the labels of the entry.
 func (m *Manager) startWithForward() {
 	m.wg.Add(1)
 	go func() {
 		defer m.wg.Done()
 		for e := range m.entries {
-			for _, c := range m.clients {
-				c.Chan() <- e
+			routed := false
+			for _, r := range m.routes {
+				if !r.matches(e.Labels) {
+					r.filteredEntries.Inc()
+					continue
+				}
+				r.client.Chan() <- e
+				r.routedEntries.Inc()
+				routed = true
+			}
+			if !routed {
+				m.metrics.unroutedEntries.Inc()
 			}
 		}
 	}()
@@ -252,6 +338,36 @@ func GetClientName(cfg Config) string {
 	return asSha256(cfg)
 }
 
+// persistentQueueDirName returns the name of the subfolder where the persistent queue of a client is stored. Unlike
+// GetClientName, it only depends on the name or the URL of the client, so the queued batches are kept when any other
+// setting of the client changes.
+func persistentQueueDirName(cfg Config) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return asSha256(cfg.URL.String())
+}
+
+// removeOrphanedPersistentQueues removes the subfolders of dir which aren't in inUse. They hold the queues of clients
+// which were removed or renamed, whose batches are never sent.
+func removeOrphanedPersistentQueues(dir string, inUse map[string]string, logger log.Logger) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error reading persistent queue folder", "dir", dir, "err", err)
+		return
+	}
+	for _, e := range entries {
+		if _, ok := inUse[e.Name()]; ok || !e.IsDir() {
+			continue
+		}
+		orphan := filepath.Join(dir, e.Name())
+		level.Warn(logger).Log("msg", "removing persistent queue of a client which doesn't exist anymore", "dir", orphan)
+		if err := os.RemoveAll(orphan); err != nil {
+			level.Warn(logger).Log("msg", "error removing persistent queue", "dir", orphan, "err", err)
+		}
+	}
+}
+
 func asSha256(o interface{}) string {
 	h := sha256.New()
 	h.Write([]byte(fmt.Sprintf("%v", o)))
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/flagext"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 
@@ -329,3 +331,88 @@ func TestManager_WALDisabled_MultipleConfigs(t *testing.T) {
 	}
 	require.Len(t, seenEntries, expectedTotalLines)
 }
+
+func TestManager_WALDisabled_Selectors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	logger := log.NewLogfmtLogger(os.Stdout)
+	euClientConfig, euReceivedReqs, closeEUServer := newServerAndClientConfig(t)
+	euClientConfig.Name = "eu"
+	euClientConfig.Selector = `{cluster="eu"}`
+	usClientConfig, usReceivedReqs, closeUSServer := newServerAndClientConfig(t)
+	usClientConfig.Name = "us"
+	usClientConfig.Selector = `{cluster=~"us-.*"}`
+
+	manager, err := NewManager(NewMetrics(reg), logger, testLimitsConfig, reg, wal.Config{}, NilNotifier, euClientConfig, usClientConfig)
+	require.NoError(t, err)
+
+	euRequests := utils.NewSyncSlice[utils.RemoteWriteRequest]()
+	usRequests := utils.NewSyncSlice[utils.RemoteWriteRequest]()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func(ctx context.Context) {
+		for {
+			select {
+			case req := <-euReceivedReqs:
+				euRequests.Append(req)
+			case req := <-usReceivedReqs:
+				usRequests.Append(req)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}(ctx)
+
+	defer func() {
+		manager.Stop()
+		closeEUServer.Close()
+		closeUSServer.Close()
+		cancel()
+	}()
+
+	for i, cluster := range []string{"eu", "us-east", "us-west", "ap", "eu"} {
+		manager.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"cluster": model.LabelValue(cluster)},
+			Entry: logproto.Entry{
+				Timestamp: time.Now(),
+				Line:      fmt.Sprintf("line%d", i),
+			},
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		return euRequests.Length() == 2 && usRequests.Length() == 2
+	}, 5*time.Second, 100*time.Millisecond, "timed out waiting for requests to be received")
+
+	for _, req := range euRequests.StartIterate() {
+		require.Equal(t, `{cluster="eu"}`, req.Request.Streams[0].Labels)
+	}
+	euRequests.DoneIterate()
+	for _, req := range usRequests.StartIterate() {
+		require.Contains(t, []string{`{cluster="us-east"}`, `{cluster="us-west"}`}, req.Request.Streams[0].Labels)
+	}
+	usRequests.DoneIterate()
+
+	expectedMetrics := `
+# HELP loki_write_filtered_entries_total Number of log entries not sent to an endpoint because they don't match its selector.
+# TYPE loki_write_filtered_entries_total counter
+loki_write_filtered_entries_total{id="eu"} 3
+loki_write_filtered_entries_total{id="us"} 3
+# HELP loki_write_routed_entries_total Number of log entries routed to an endpoint because they match its selector.
+# TYPE loki_write_routed_entries_total counter
+loki_write_routed_entries_total{id="eu"} 2
+loki_write_routed_entries_total{id="us"} 2
+# HELP loki_write_unrouted_entries_total Number of log entries dropped because they don't match the selector of any endpoint.
+# TYPE loki_write_unrouted_entries_total counter
+loki_write_unrouted_entries_total 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expectedMetrics),
+		"loki_write_filtered_entries_total", "loki_write_routed_entries_total", "loki_write_unrouted_entries_total"))
+}
+
+func TestManager_SelectorWithWAL(t *testing.T) {
+	cfg, _, closeServer := newServerAndClientConfig(t)
+	defer closeServer.Close()
+	cfg.Selector = `{cluster="eu"}`
+
+	_, err := NewManager(nilMetrics, log.NewNopLogger(), testLimitsConfig, prometheus.NewRegistry(), wal.Config{Enabled: true, Dir: t.TempDir()}, NilNotifier, cfg)
+	require.ErrorContains(t, err, "selector of client test-client is not supported when the WAL is enabled")
+}
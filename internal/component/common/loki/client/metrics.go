@@ -35,3 +35,46 @@ func (m *QueueClientMetrics) CurryWithId(id string) *QueueClientMetrics {
 		}),
 	}
 }
+
+// RouteMetrics tracks how the entries received by a Manager are routed to its clients, based on their selectors.
+type RouteMetrics struct {
+	routedEntries   *prometheus.CounterVec
+	filteredEntries *prometheus.CounterVec
+	unroutedEntries prometheus.Counter
+}
+
+func NewRouteMetrics(reg prometheus.Registerer) *RouteMetrics {
+	m := &RouteMetrics{
+		routedEntries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "loki_write",
+				Name:      "routed_entries_total",
+				Help:      "Number of log entries routed to an endpoint because they match its selector.",
+			},
+			[]string{"id"},
+		),
+		filteredEntries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "loki_write",
+				Name:      "filtered_entries_total",
+				Help:      "Number of log entries not sent to an endpoint because they don't match its selector.",
+			},
+			[]string{"id"},
+		),
+		unroutedEntries: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: "loki_write",
+				Name:      "unrouted_entries_total",
+				Help:      "Number of log entries dropped because they don't match the selector of any endpoint.",
+			},
+		),
+	}
+
+	if reg != nil {
+		m.routedEntries = util.MustRegisterOrGet(reg, m.routedEntries).(*prometheus.CounterVec)
+		m.filteredEntries = util.MustRegisterOrGet(reg, m.filteredEntries).(*prometheus.CounterVec)
+		m.unroutedEntries = util.MustRegisterOrGet(reg, m.unroutedEntries).(prometheus.Counter)
+	}
+
+	return m
+}
@@ -0,0 +1,400 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/backoff"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+
+	lokiutil "github.com/grafana/loki/v3/pkg/util"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/client/internal"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/useragent"
+)
+
+// persistentClient is a remote write client which stores batches in an on-disk queue before sending them. Batches are
+// retried until they're delivered or rejected by Loki, so no log entry is lost when the endpoint is unavailable for a long
+// time, and the batches still in the queue when the client stops are sent once it starts again.
+//
+// When the queue is full, the client stops accepting entries, which applies backpressure to the components sending
+// logs to it.
+type persistentClient struct {
+	name    string
+	metrics *Metrics
+	logger  log.Logger
+	cfg     Config
+	client  *http.Client
+	entries chan loki.Entry
+	queue   *internal.DiskQueue
+
+	once sync.Once
+	wg   sync.WaitGroup
+
+	externalLabels model.LabelSet
+
+	// ctx is used in any upstream calls from the `client`, and to stop waiting for space in the queue.
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	maxStreams          int
+	maxLineSize         int
+	maxLineSizeTruncate bool
+}
+
+// NewPersistent makes a new Client which stores batches in an on-disk queue in dir until they're sent.
+func NewPersistent(metrics *Metrics, queueMetrics *internal.DiskQueueMetrics, cfg Config, dir string, maxStreams, maxLineSize int, maxLineSizeTruncate bool, logger log.Logger) (Client, error) {
+	return newPersistentClient(metrics, queueMetrics, cfg, dir, maxStreams, maxLineSize, maxLineSizeTruncate, logger)
+}
+
+func newPersistentClient(metrics *Metrics, queueMetrics *internal.DiskQueueMetrics, cfg Config, dir string, maxStreams, maxLineSize int, maxLineSizeTruncate bool, logger log.Logger) (*persistentClient, error) {
+	if cfg.URL.URL == nil {
+		return nil, errors.New("client needs target URL")
+	}
+	if metrics == nil {
+		return nil, errors.New("metrics must be instantiated")
+	}
+
+	c := &persistentClient{
+		logger:  log.With(logger, "component", "client", "host", cfg.URL.Host),
+		cfg:     cfg,
+		entries: make(chan loki.Entry),
+		metrics: metrics,
+		name:    GetClientName(cfg),
+
+		externalLabels:      cfg.ExternalLabels.LabelSet,
+		maxStreams:          maxStreams,
+		maxLineSize:         maxLineSize,
+		maxLineSizeTruncate: maxLineSizeTruncate,
+	}
+
+	err := cfg.Client.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	c.client, err = config.NewClientFromConfig(cfg.Client, useragent.ProductName, config.WithHTTP2Disabled())
+	if err != nil {
+		return nil, err
+	}
+
+	c.client.Timeout = cfg.Timeout
+
+	c.queue, err = internal.NewDiskQueue(dir, int64(cfg.PersistentQueue.MaxSize), c.logger, queueMetrics)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize counters to 0 so the metrics are exported before the first
+	// occurrence of incrementing to avoid missing metrics.
+	for _, counter := range c.metrics.countersWithHost {
+		counter.WithLabelValues(c.cfg.URL.Host).Add(0)
+	}
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.wg.Add(2)
+	go c.run()
+	go c.runSend()
+	return c, nil
+}
+
+func (c *persistentClient) initBatchMetrics(tenantID string) {
+	// Initialize counters to 0 so the metrics are exported before the first
+	// occurrence of incrementing to avoid missing metrics.
+	for _, counter := range c.metrics.countersWithHostTenantReason {
+		for _, reason := range Reasons {
+			counter.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(0)
+		}
+	}
+
+	for _, counter := range c.metrics.countersWithHostTenant {
+		counter.WithLabelValues(c.cfg.URL.Host, tenantID).Add(0)
+	}
+}
+
+// run batches the received entries, and stores the batches in the queue once they're full or old enough.
+func (c *persistentClient) run() {
+	batches := map[string]*batch{}
+
+	// Batches whose max wait time has been reached are checked 10 times per
+	// BatchWait, with a cap of 10ms, like in the client without queue.
+	minWaitCheckFrequency := 10 * time.Millisecond
+	maxWaitCheckFrequency := c.cfg.BatchWait / 10
+	if maxWaitCheckFrequency < minWaitCheckFrequency {
+		maxWaitCheckFrequency = minWaitCheckFrequency
+	}
+
+	maxWaitCheck := time.NewTicker(maxWaitCheckFrequency)
+
+	defer func() {
+		maxWaitCheck.Stop()
+		// Store all pending batches, which are sent after a restart
+		for tenantID, batch := range batches {
+			c.enqueue(tenantID, batch)
+		}
+
+		c.wg.Done()
+	}()
+
+	for {
+		select {
+		case e, ok := <-c.entries:
+			if !ok {
+				return
+			}
+
+			e, tenantID := c.processEntry(e)
+
+			// Either drop or mutate the log entry because its length is greater than maxLineSize. maxLineSize == 0 means disabled.
+			if c.maxLineSize != 0 && len(e.Line) > c.maxLineSize {
+				if !c.maxLineSizeTruncate {
+					c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Inc()
+					c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Add(float64(len(e.Line)))
+					break
+				}
+
+				c.metrics.mutatedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Inc()
+				c.metrics.mutatedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Add(float64(len(e.Line) - c.maxLineSize))
+				e.Line = e.Line[:c.maxLineSize]
+			}
+
+			batch, ok := batches[tenantID]
+
+			// If the batch doesn't exist yet, we create a new one with the entry
+			if !ok {
+				batches[tenantID] = newBatch(c.maxStreams, e)
+				c.initBatchMetrics(tenantID)
+				break
+			}
+
+			// If adding the entry to the batch will increase the size over the max
+			// size allowed, we do store the current batch and then create a new one
+			if batch.sizeBytesAfter(e.Entry) > c.cfg.BatchSize {
+				c.enqueue(tenantID, batch)
+
+				batches[tenantID] = newBatch(c.maxStreams, e)
+				break
+			}
+
+			// The max size of the batch isn't reached, so we can add the entry
+			err := batch.add(e)
+			if err != nil {
+				level.Error(c.logger).Log("msg", "batch add err", "tenant", tenantID, "error", err)
+				reason := ReasonGeneric
+				if err.Error() == errMaxStreamsLimitExceeded {
+					reason = ReasonStreamLimited
+				}
+				c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(float64(len(e.Line)))
+				c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Inc()
+			}
+		case <-maxWaitCheck.C:
+			// Store all batches whose max wait time has been reached
+			for tenantID, batch := range batches {
+				if batch.age() < c.cfg.BatchWait {
+					continue
+				}
+
+				c.enqueue(tenantID, batch)
+				delete(batches, tenantID)
+			}
+		}
+	}
+}
+
+// enqueue encodes a batch and stores it in the queue, waiting for space if the queue is full. The batch is dropped if
+// the client is stopped while waiting.
+func (c *persistentClient) enqueue(tenantID string, batch *batch) {
+	buf, entriesCount, err := batch.encode()
+	if err != nil {
+		level.Error(c.logger).Log("msg", "error encoding batch", "error", err)
+		return
+	}
+	c.metrics.encodedBytes.WithLabelValues(c.cfg.URL.Host).Add(float64(len(buf)))
+
+	if err := c.queue.Put(c.ctx, tenantID, entriesCount, buf); err != nil {
+		level.Error(c.logger).Log("msg", "dropping batch which can't be stored in the persistent queue", "tenant", tenantID, "error", err)
+		c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonGeneric).Add(float64(len(buf)))
+		c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonGeneric).Add(float64(entriesCount))
+	}
+}
+
+// runSend sends the batches of the queue in order, until the client is stopped.
+func (c *persistentClient) runSend() {
+	defer c.wg.Done()
+
+	for {
+		record, err := c.queue.Next(c.ctx)
+		if err != nil {
+			if c.ctx.Err() == nil {
+				level.Error(c.logger).Log("msg", "error reading persistent queue", "error", err)
+			}
+			return
+		}
+		if !c.sendRecord(record) {
+			// The client was stopped, the batch is sent again after a restart.
+			return
+		}
+		if err := c.queue.Remove(record.ID); err != nil {
+			level.Error(c.logger).Log("msg", "error removing sent batch from persistent queue", "error", err)
+		}
+	}
+}
+
+// sendRecord sends a batch of the queue. Unlike the client without queue, retryable errors are retried until the
+// batch is sent, since the batch is safely stored. It returns false if the client was stopped before the batch was
+// sent or dropped.
+func (c *persistentClient) sendRecord(record internal.DiskRecord) bool {
+	tenantID := record.TenantID
+	bufBytes := float64(len(record.Data))
+
+	backoffCfg := c.cfg.BackoffConfig
+	backoffCfg.MaxRetries = 0
+	backoff := backoff.New(c.ctx, backoffCfg)
+	var (
+		status int
+		err    error
+	)
+	for {
+		start := time.Now()
+		status, err = c.send(c.ctx, tenantID, record.Data)
+
+		c.metrics.requestDuration.WithLabelValues(strconv.Itoa(status), c.cfg.URL.Host).Observe(time.Since(start).Seconds())
+
+		// Immediately drop rate limited batches to avoid HOL blocking for other tenants not experiencing throttling
+		if c.cfg.DropRateLimitedBatches && batchIsRateLimited(status) {
+			level.Warn(c.logger).Log("msg", "dropping batch due to rate limiting applied at ingester")
+			c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(bufBytes)
+			c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(float64(record.Entries))
+			return true
+		}
+
+		if err == nil {
+			c.metrics.sentBytes.WithLabelValues(c.cfg.URL.Host).Add(bufBytes)
+			c.metrics.sentEntries.WithLabelValues(c.cfg.URL.Host).Add(float64(record.Entries))
+			return true
+		}
+
+		// Only retry 429s, 500s and connection-level errors.
+		if status > 0 && !batchIsRateLimited(status) && status/100 != 5 {
+			break
+		}
+
+		if c.ctx.Err() != nil {
+			return false
+		}
+		level.Warn(c.logger).Log("msg", "error sending batch, will retry", "status", status, "tenant", tenantID, "error", err)
+		c.metrics.batchRetries.WithLabelValues(c.cfg.URL.Host, tenantID).Inc()
+		backoff.Wait()
+
+		if !backoff.Ongoing() {
+			return false
+		}
+	}
+
+	level.Error(c.logger).Log("msg", "final error sending batch", "status", status, "tenant", tenantID, "error", err)
+	c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonGeneric).Add(bufBytes)
+	c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonGeneric).Add(float64(record.Entries))
+	return true
+}
+
+func (c *persistentClient) send(ctx context.Context, tenantID string, buf []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", c.cfg.URL.String(), bytes.NewReader(buf))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", userAgent)
+
+	// If the tenant ID is not empty promtail is running in multi-tenant mode, so
+	// we should send it to Loki
+	if tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", tenantID)
+	}
+
+	// Add custom headers on request
+	if len(c.cfg.Headers) > 0 {
+		for k, v := range c.cfg.Headers {
+			if req.Header.Get(k) == "" {
+				req.Header.Add(k, v)
+			} else {
+				level.Warn(c.logger).Log("msg", "custom header key already exists, skipping", "key", k)
+			}
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return -1, err
+	}
+	defer lokiutil.LogError("closing response body", resp.Body.Close)
+
+	if resp.StatusCode/100 != 2 {
+		scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxErrMsgLen))
+		line := ""
+		if scanner.Scan() {
+			line = scanner.Text()
+		}
+		err = fmt.Errorf("server returned HTTP status %s (%d): %s", resp.Status, resp.StatusCode, line)
+	}
+	return resp.StatusCode, err
+}
+
+func (c *persistentClient) getTenantID(labels model.LabelSet) string {
+	// Check if it has been overridden while processing the pipeline stages
+	if value, ok := labels[ReservedLabelTenantID]; ok {
+		return string(value)
+	}
+
+	// Check if has been specified in the config
+	if c.cfg.TenantID != "" {
+		return c.cfg.TenantID
+	}
+
+	// Defaults to an empty string, which means the X-Scope-OrgID header
+	// will not be sent
+	return ""
+}
+
+func (c *persistentClient) processEntry(e loki.Entry) (loki.Entry, string) {
+	if len(c.externalLabels) > 0 {
+		e.Labels = c.externalLabels.Merge(e.Labels)
+	}
+	tenantID := c.getTenantID(e.Labels)
+	return e, tenantID
+}
+
+func (c *persistentClient) Chan() chan<- loki.Entry {
+	return c.entries
+}
+
+// Stop the client. The pending batches are stored in the queue if it has space for them, and the batch being sent is
+// kept in the queue, so they're all sent once the client starts again.
+func (c *persistentClient) Stop() {
+	c.once.Do(func() {
+		c.cancel()
+		close(c.entries)
+	})
+	c.wg.Wait()
+}
+
+// StopNow stops the client. Since batches aren't retried after the client is stopped, it's the same as Stop.
+func (c *persistentClient) StopNow() {
+	c.Stop()
+}
+
+func (c *persistentClient) Name() string {
+	return c.name
+}
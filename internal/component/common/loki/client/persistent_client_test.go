@@ -0,0 +1,230 @@
+package client
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	lokiutil "github.com/grafana/loki/v3/pkg/util"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/client/internal"
+	"github.com/grafana/alloy/internal/component/common/loki/wal"
+)
+
+// flakyServer is a Loki server which responds with 503 until it's marked as available.
+type flakyServer struct {
+	*httptest.Server
+	available atomic.Bool
+	requests  atomic.Int64
+	entries   atomic.Int64
+}
+
+func newFlakyServer(t *testing.T) *flakyServer {
+	s := &flakyServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		s.requests.Inc()
+		if !s.available.Load() {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var pushReq logproto.PushRequest
+		if err := lokiutil.ParseProtoReader(req.Context(), req.Body, int(req.ContentLength), math.MaxInt32, &pushReq, lokiutil.RawSnappy); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, stream := range pushReq.Streams {
+			s.entries.Add(int64(len(stream.Entries)))
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestPersistentClient(t *testing.T, serverURL, dir string, maxSize int) *persistentClient {
+	var url flagext.URLValue
+	require.NoError(t, url.Set(serverURL))
+
+	cfg := Config{
+		URL:       url,
+		BatchWait: 10 * time.Millisecond,
+		BatchSize: 100,
+		// The persistent client keeps retrying after MaxRetries.
+		BackoffConfig:   backoff.Config{MinBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, MaxRetries: 1},
+		Timeout:         time.Second,
+		PersistentQueue: PersistentQueueConfig{Enabled: true, MaxSize: maxSize},
+	}
+	reg := prometheus.NewRegistry()
+	c, err := newPersistentClient(NewMetrics(reg), internal.NewDiskQueueMetrics(reg).WithCurriedId("test"), cfg, dir, 0, 0, false, log.NewLogfmtLogger(os.Stdout))
+	require.NoError(t, err)
+	return c
+}
+
+func sendTestEntries(c Client, n int) {
+	for i := 0; i < n; i++ {
+		c.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"app": "test"},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: "line"},
+		}
+	}
+}
+
+func TestPersistentClient_RetriesUntilAvailable(t *testing.T) {
+	server := newFlakyServer(t)
+	c := newTestPersistentClient(t, server.URL, t.TempDir(), 1024*1024)
+	defer c.Stop()
+
+	sendTestEntries(c, 20)
+
+	// The batches are kept in the queue after more failures than MaxRetries.
+	require.Eventually(t, func() bool { return server.requests.Load() > 5 }, 5*time.Second, 10*time.Millisecond)
+	require.Zero(t, server.entries.Load())
+
+	server.available.Store(true)
+	require.Eventually(t, func() bool { return server.entries.Load() == 20 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPersistentClient_ReplaysAfterRestart(t *testing.T) {
+	var (
+		server = newFlakyServer(t)
+		dir    = t.TempDir()
+	)
+
+	c := newTestPersistentClient(t, server.URL, dir, 1024*1024)
+	sendTestEntries(c, 20)
+	require.Eventually(t, func() bool { return server.requests.Load() > 0 }, 5*time.Second, 10*time.Millisecond)
+	// Stopping the client stores the pending batches.
+	c.Stop()
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	server.available.Store(true)
+	c = newTestPersistentClient(t, server.URL, dir, 1024*1024)
+	defer c.Stop()
+	require.Eventually(t, func() bool { return server.entries.Load() == 20 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		files, err := os.ReadDir(dir)
+		return err == nil && len(files) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPersistentClient_BlocksWhenFull(t *testing.T) {
+	server := newFlakyServer(t)
+	// The queue only has space for a single batch.
+	c := newTestPersistentClient(t, server.URL, t.TempDir(), 1)
+	defer c.Stop()
+
+	sent := make(chan struct{})
+	go func() {
+		sendTestEntries(c, 500)
+		close(sent)
+	}()
+
+	select {
+	case <-sent:
+		t.Fatal("the client accepted all the entries while the queue is full")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	server.available.Store(true)
+	require.Eventually(t, func() bool {
+		select {
+		case <-sent:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return server.entries.Load() == 500 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func newTestPersistentManager(t *testing.T, cfgs ...Config) *Manager {
+	reg := prometheus.NewRegistry()
+	m, err := NewManager(NewMetrics(reg), log.NewLogfmtLogger(os.Stdout), testLimitsConfig, reg, wal.Config{}, NilNotifier, cfgs...)
+	require.NoError(t, err)
+	return m
+}
+
+func newTestPersistentConfig(t *testing.T, serverURL, dir string) Config {
+	var url flagext.URLValue
+	require.NoError(t, url.Set(serverURL))
+
+	return Config{
+		URL:             url,
+		BatchWait:       10 * time.Millisecond,
+		BatchSize:       100,
+		BackoffConfig:   backoff.Config{MinBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, MaxRetries: 1},
+		Timeout:         time.Second,
+		PersistentQueue: PersistentQueueConfig{Enabled: true, Dir: dir, MaxSize: 1024 * 1024},
+	}
+}
+
+func TestManager_PersistentQueueKeptOnConfigChange(t *testing.T) {
+	var (
+		server = newFlakyServer(t)
+		dir    = t.TempDir()
+		cfg    = newTestPersistentConfig(t, server.URL, dir)
+	)
+
+	m := newTestPersistentManager(t, cfg)
+	sendTestEntries(m, 20)
+	require.Eventually(t, func() bool { return server.requests.Load() > 0 }, 5*time.Second, 10*time.Millisecond)
+	m.Stop()
+
+	// Settings which don't identify the endpoint don't change where its queue is stored.
+	cfg.BatchSize = 200
+	cfg.Timeout = 2 * time.Second
+	server.available.Store(true)
+	m = newTestPersistentManager(t, cfg)
+	defer m.Stop()
+	require.Eventually(t, func() bool { return server.entries.Load() == 20 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestManager_PersistentQueueRemovesOrphans(t *testing.T) {
+	var (
+		server = newFlakyServer(t)
+		dir    = t.TempDir()
+		cfg    = newTestPersistentConfig(t, server.URL, dir)
+	)
+	cfg.Name = "current"
+
+	orphan := filepath.Join(dir, "removed")
+	require.NoError(t, os.MkdirAll(orphan, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(orphan, "1.batch"), []byte("batch"), 0o600))
+
+	m := newTestPersistentManager(t, cfg)
+	defer m.Stop()
+
+	require.NoDirExists(t, orphan)
+	require.DirExists(t, filepath.Join(dir, "current"))
+}
+
+func TestManager_PersistentQueueSharedByClients(t *testing.T) {
+	var (
+		server = newFlakyServer(t)
+		dir    = t.TempDir()
+		cfg1   = newTestPersistentConfig(t, server.URL, dir)
+		cfg2   = newTestPersistentConfig(t, server.URL, dir)
+	)
+	cfg2.TenantID = "other"
+
+	reg := prometheus.NewRegistry()
+	_, err := NewManager(NewMetrics(reg), log.NewLogfmtLogger(os.Stdout), testLimitsConfig, reg, wal.Config{}, NilNotifier, cfg1, cfg2)
+	require.ErrorContains(t, err, "can't share the same persistent queue")
+}
@@ -52,8 +52,13 @@ type positions struct {
 	cfg       Config
 	mtx       sync.Mutex
 	positions map[Entry]string
+	files     map[FileEntry]FilePosition
 	quit      chan struct{}
 	done      chan struct{}
+
+	// tailed holds the files put since the positions were loaded, and not
+	// removed since. Their entries are kept even when their path is missing.
+	tailed map[FileEntry]struct{}
 }
 
 // Entry describes a positions file entry consisting of an absolute file path and
@@ -68,9 +73,36 @@ type Entry struct {
 	Labels string `yaml:"labels"`
 }
 
+// FileID identifies a file by its device and inode numbers, which don't
+// change when the file is renamed.
+type FileID struct {
+	Device uint64 `yaml:"device"`
+	Inode  uint64 `yaml:"inode"`
+}
+
+// FileEntry describes a positions file entry for a file identified by its
+// FileID instead of its path, and the matching label set.
+type FileEntry struct {
+	ID     FileID `yaml:"id"`
+	Labels string `yaml:"labels"`
+}
+
+// FilePosition describes how far a file identified by a FileEntry was read.
+type FilePosition struct {
+	// Path is the path the file was last read from. It's used to clean up
+	// the entries of files which don't exist anymore.
+	Path   string `yaml:"path"`
+	Offset int64  `yaml:"offset"`
+	// Fingerprint identifies the content of the file, for callers which
+	// need to find it after its FileID changed, for example once it's
+	// compressed. Its format is up to the caller.
+	Fingerprint string `yaml:"fingerprint,omitempty"`
+}
+
 // File format for the positions data.
 type File struct {
-	Positions map[Entry]string `yaml:"positions"`
+	Positions map[Entry]string           `yaml:"positions"`
+	Files     map[FileEntry]FilePosition `yaml:"files,omitempty"`
 }
 
 type Positions interface {
@@ -90,6 +122,17 @@ type Positions interface {
 	Put(path, labels string, pos int64)
 	// Remove removes the position tracking for a filepath
 	Remove(path, labels string)
+	// GetFile returns how far we've read through the file with the given
+	// ID, and whether a position was recorded for it.
+	GetFile(id FileID, labels string) (FilePosition, bool)
+	// PutFile records (asynchronously) how far we've read through the file
+	// with the given ID.
+	PutFile(id FileID, labels string, pos FilePosition)
+	// RemoveFile removes the position tracking for the file with the given ID.
+	RemoveFile(id FileID, labels string)
+	// FilesAt returns the positions of the files which were last read from
+	// the given path, by file ID.
+	FilesAt(path, labels string) map[FileID]FilePosition
 	// SyncPeriod returns how often the positions file gets resynced
 	SyncPeriod() time.Duration
 	// Stop the Position tracker.
@@ -157,7 +200,7 @@ func readLegacyFile(legacyPath string, l log.Logger) *LegacyFile {
 
 // New makes a new Positions.
 func New(logger log.Logger, cfg Config) (Positions, error) {
-	positionData, err := readPositionsFile(cfg, logger)
+	positionData, err := readFile(cfg, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +208,9 @@ func New(logger log.Logger, cfg Config) (Positions, error) {
 	p := &positions{
 		logger:    logger,
 		cfg:       cfg,
-		positions: positionData,
+		positions: positionData.Positions,
+		files:     positionData.Files,
+		tailed:    make(map[FileEntry]struct{}),
 		quit:      make(chan struct{}),
 		done:      make(chan struct{}),
 	}
@@ -215,6 +260,39 @@ func (p *positions) remove(path, labels string) {
 	delete(p.positions, Entry{path, labels})
 }
 
+func (p *positions) GetFile(id FileID, labels string) (FilePosition, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	pos, ok := p.files[FileEntry{id, labels}]
+	return pos, ok
+}
+
+func (p *positions) PutFile(id FileID, labels string, pos FilePosition) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.files[FileEntry{id, labels}] = pos
+	p.tailed[FileEntry{id, labels}] = struct{}{}
+}
+
+func (p *positions) RemoveFile(id FileID, labels string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	delete(p.files, FileEntry{id, labels})
+	delete(p.tailed, FileEntry{id, labels})
+}
+
+func (p *positions) FilesAt(path, labels string) map[FileID]FilePosition {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	res := make(map[FileID]FilePosition)
+	for k, v := range p.files {
+		if k.Labels == labels && v.Path == path {
+			res[k.ID] = v
+		}
+	}
+	return res
+}
+
 func (p *positions) SyncPeriod() time.Duration {
 	return p.cfg.SyncPeriod
 }
@@ -247,9 +325,13 @@ func (p *positions) save() {
 	for k, v := range p.positions {
 		positions[k] = v
 	}
+	files := make(map[FileEntry]FilePosition, len(p.files))
+	for k, v := range p.files {
+		files[k] = v
+	}
 	p.mtx.Unlock()
 
-	if err := writePositionFile(p.cfg.PositionsFile, positions); err != nil {
+	if err := writeFile(p.cfg.PositionsFile, File{Positions: positions, Files: files}); err != nil {
 		level.Error(p.logger).Log("msg", "error writing positions file", "error", err)
 	}
 }
@@ -285,16 +367,43 @@ func (p *positions) cleanup() {
 	for _, tr := range toRemove {
 		p.remove(tr.Path, tr.Labels)
 	}
+
+	// Files identified by their ID are kept as long as the path they were
+	// last read from exists, as they may have been renamed since. The files
+	// which are still tailed are kept too, since their path is missing
+	// between their rotation and the creation of the new file.
+	for k, v := range p.files {
+		if _, ok := p.tailed[k]; ok {
+			continue
+		}
+		if _, err := os.Stat(v.Path); err != nil && os.IsNotExist(err) {
+			delete(p.files, k)
+		}
+	}
+}
+
+func writePositionFile(filename string, positions map[Entry]string) error {
+	return writeFile(filename, File{Positions: positions})
 }
 
 func readPositionsFile(cfg Config, logger log.Logger) (map[Entry]string, error) {
+	f, err := readFile(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	return f.Positions, nil
+}
+
+func readFile(cfg Config, logger log.Logger) (File, error) {
+	empty := File{Positions: map[Entry]string{}, Files: map[FileEntry]FilePosition{}}
+
 	cleanfn := filepath.Clean(cfg.PositionsFile)
 	buf, err := os.ReadFile(cleanfn)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return map[Entry]string{}, nil
+			return empty, nil
 		}
-		return nil, err
+		return File{}, err
 	}
 
 	var p File
@@ -303,16 +412,19 @@ func readPositionsFile(cfg Config, logger log.Logger) (map[Entry]string, error)
 		// return empty if cfg option enabled
 		if cfg.IgnoreInvalidYaml {
 			level.Debug(logger).Log("msg", "ignoring invalid positions file", "file", cleanfn, "error", err)
-			return map[Entry]string{}, nil
+			return empty, nil
 		}
 
-		return nil, fmt.Errorf("invalid yaml positions file [%s]: %v", cleanfn, err)
+		return File{}, fmt.Errorf("invalid yaml positions file [%s]: %v", cleanfn, err)
 	}
 
-	// p.Positions will be nil if the file exists but is empty
+	// p.Positions and p.Files will be nil if the file exists but is empty
 	if p.Positions == nil {
 		p.Positions = map[Entry]string{}
 	}
+	if p.Files == nil {
+		p.Files = map[FileEntry]FilePosition{}
+	}
 
-	return p.Positions, nil
+	return p, nil
 }
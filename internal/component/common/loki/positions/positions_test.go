@@ -365,3 +365,55 @@ positions:
 		Labels: ``,
 	}])
 }
+
+func TestFilePositions(t *testing.T) {
+	dir := t.TempDir()
+	temp := filepath.Join(dir, "positions.yml")
+	logPath := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("line\n"), 0644))
+
+	cfg := Config{
+		SyncPeriod:    20 * time.Second,
+		PositionsFile: temp,
+	}
+	p, err := New(util_log.Logger, cfg)
+	require.NoError(t, err)
+
+	rotated := FileID{Device: 1, Inode: 10}
+	current := FileID{Device: 1, Inode: 11}
+	p.PutFile(rotated, `{job="app"}`, FilePosition{Path: logPath, Offset: 100, Fingerprint: "a"})
+	p.PutFile(current, `{job="app"}`, FilePosition{Path: logPath, Offset: 5})
+	p.PutFile(current, `{job="other"}`, FilePosition{Path: logPath, Offset: 1})
+	p.PutFile(FileID{Device: 1, Inode: 12}, `{job="app"}`, FilePosition{Path: filepath.Join(dir, "gone.log"), Offset: 1})
+	p.Stop()
+
+	// The positions of the files are kept in the positions file.
+	p, err = New(util_log.Logger, cfg)
+	require.NoError(t, err)
+	defer p.Stop()
+
+	pos, ok := p.GetFile(rotated, `{job="app"}`)
+	require.True(t, ok)
+	require.Equal(t, FilePosition{Path: logPath, Offset: 100, Fingerprint: "a"}, pos)
+	require.Equal(t, map[FileID]FilePosition{
+		rotated: {Path: logPath, Offset: 100, Fingerprint: "a"},
+		current: {Path: logPath, Offset: 5},
+	}, p.FilesAt(logPath, `{job="app"}`))
+
+	p.RemoveFile(rotated, `{job="app"}`)
+	_, ok = p.GetFile(rotated, `{job="app"}`)
+	require.False(t, ok)
+
+	// The files which were last read from a path which doesn't exist anymore
+	// are cleaned up.
+	p.(*positions).cleanup()
+	require.Empty(t, p.FilesAt(filepath.Join(dir, "gone.log"), `{job="app"}`))
+	require.Len(t, p.FilesAt(logPath, `{job="app"}`), 1)
+
+	// The files which are tailed are kept while their path is missing, for
+	// example between the rotation of a file and the creation of the new one.
+	rotatedPath := filepath.Join(dir, "rotating.log")
+	p.PutFile(rotated, `{job="app"}`, FilePosition{Path: rotatedPath, Offset: 100})
+	p.(*positions).cleanup()
+	require.Len(t, p.FilesAt(rotatedPath, `{job="app"}`), 1)
+}
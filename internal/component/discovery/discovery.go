@@ -8,6 +8,7 @@ import (
 
 	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
@@ -183,7 +184,12 @@ func (c *Component) runDiscovery(ctx context.Context, d DiscovererWithMetrics) {
 		allTargets := toAlloyTargets(cache)
 		componentID := livedebugging.ComponentID(c.opts.ID)
 		if c.debugDataPublisher.IsActive(componentID) {
-			c.debugDataPublisher.Publish(componentID, fmt.Sprintf("%s", allTargets))
+			c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+				livedebugging.Target,
+				uint64(len(allTargets)),
+				labels.EmptyLabels(),
+				func() string { return fmt.Sprintf("%s", allTargets) },
+			))
 		}
 		c.opts.OnStateChange(Exports{Targets: allTargets})
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/alloy/internal/component/discovery"
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/prometheus/prometheus/model/labels"
 )
 
 func init() {
@@ -70,7 +71,13 @@ func (c *Component) Run(ctx context.Context) error {
 
 		componentID := livedebugging.ComponentID(c.opts.ID)
 		if c.debugDataPublisher.IsActive(componentID) {
-			c.debugDataPublisher.Publish(componentID, fmt.Sprintf("%s", c.processes))
+			processes := c.processes
+			c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+				livedebugging.Target,
+				uint64(len(processes)),
+				labels.EmptyLabels(),
+				func() string { return fmt.Sprintf("%s", processes) },
+			))
 		}
 
 		return nil
@@ -95,7 +95,12 @@ func (c *Component) Update(args component.Arguments) error {
 		}
 		componentID := livedebugging.ComponentID(c.opts.ID)
 		if c.debugDataPublisher.IsActive(componentID) {
-			c.debugDataPublisher.Publish(componentID, fmt.Sprintf("%s => %s", t, relabelled))
+			c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+				livedebugging.Target,
+				1,
+				t.PromLabels(),
+				func() string { return fmt.Sprintf("%s => %s", t, relabelled) },
+			))
 		}
 	}
 
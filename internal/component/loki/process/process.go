@@ -164,7 +164,16 @@ func (c *Component) handleIn(ctx context.Context, wg *sync.WaitGroup) {
 		case entry := <-c.receiver.Chan():
 			c.mut.RLock()
 			if c.debugDataPublisher.IsActive(componentID) {
-				c.debugDataPublisher.Publish(componentID, fmt.Sprintf("[IN]: timestamp: %s, entry: %s, labels: %s", entry.Timestamp.Format(time.RFC3339Nano), entry.Line, entry.Labels.String()))
+				lbls := livedebugging.LabelsFromLabelSet(entry.Labels)
+				ts, line := entry.Timestamp, entry.Line
+				c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.LokiLog,
+					1,
+					lbls,
+					func() string {
+						return fmt.Sprintf("[IN]: timestamp: %s, entry: %s, labels: %s", ts.Format(time.RFC3339Nano), line, lbls.String())
+					},
+				))
 			}
 			select {
 			case <-ctx.Done():
@@ -195,7 +204,16 @@ func (c *Component) handleOut(shutdownCh chan struct{}, wg *sync.WaitGroup) {
 			// The log entry is the same for every fanout,
 			// so we can publish it only once.
 			if c.debugDataPublisher.IsActive(componentID) {
-				c.debugDataPublisher.Publish(componentID, fmt.Sprintf("[OUT]: timestamp: %s, entry: %s, labels: %s", entry.Timestamp.Format(time.RFC3339Nano), entry.Line, entry.Labels.String()))
+				lbls := livedebugging.LabelsFromLabelSet(entry.Labels)
+				ts, line := entry.Timestamp, entry.Line
+				c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.LokiLog,
+					1,
+					lbls,
+					func() string {
+						return fmt.Sprintf("[OUT]: timestamp: %s, entry: %s, labels: %s", ts.Format(time.RFC3339Nano), line, lbls.String())
+					},
+				))
 			}
 
 			for _, f := range fanout {
@@ -0,0 +1,187 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Configuration errors.
+var (
+	ErrDeduplicateStageInvalidRegex = errors.New("deduplicate stage normalize regex compilation error")
+)
+
+// minDeduplicateFlushInterval is the minimum interval between checks for expired windows.
+const minDeduplicateFlushInterval = 10 * time.Millisecond
+
+// DeduplicateConfig contains the configuration for a deduplicate stage.
+type DeduplicateConfig struct {
+	Window    time.Duration `alloy:"window,attr,optional"`
+	Normalize []string      `alloy:"normalize,attr,optional"`
+	CountKey  string        `alloy:"count_key,attr,optional"`
+}
+
+// DefaultDeduplicateConfig sets the defaults.
+var DefaultDeduplicateConfig = DeduplicateConfig{
+	Window:   10 * time.Second,
+	CountKey: "repeat_count",
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *DeduplicateConfig) SetToDefault() {
+	*args = DefaultDeduplicateConfig
+}
+
+// Validate implements syntax.Validator.
+func (args *DeduplicateConfig) Validate() error {
+	if args.Window <= 0 {
+		return fmt.Errorf("window must be greater than 0")
+	}
+	if args.CountKey == "" {
+		return fmt.Errorf("count_key must not be empty")
+	}
+	return nil
+}
+
+func validateDeduplicateConfig(cfg DeduplicateConfig) ([]*regexp.Regexp, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	res := make([]*regexp.Regexp, 0, len(cfg.Normalize))
+	for _, expr := range cfg.Normalize {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDeduplicateStageInvalidRegex, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// deduplicateStage collapses identical lines of a stream received within a window into a single entry.
+type deduplicateStage struct {
+	logger    log.Logger
+	cfg       DeduplicateConfig
+	normalize []*regexp.Regexp
+
+	deduplicatedLines *prometheus.CounterVec
+	deduplicatedBytes *prometheus.CounterVec
+}
+
+// deduplicateState is the entry of a stream which is held while its duplicates are counted.
+type deduplicateState struct {
+	entry Entry
+	key   string
+	count int
+	since time.Time
+}
+
+// newDeduplicateStage creates a deduplicateStage from config
+func newDeduplicateStage(logger log.Logger, config DeduplicateConfig, registerer prometheus.Registerer) (Stage, error) {
+	normalize, err := validateDeduplicateConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deduplicateStage{
+		logger:    log.With(logger, "component", "stage", "type", "deduplicate"),
+		cfg:       config,
+		normalize: normalize,
+		deduplicatedLines: registerCounterVec(registerer, "loki_process", "deduplicated_lines_total",
+			"A count of all log lines suppressed by a deduplicate stage because they repeat a previous line", nil),
+		deduplicatedBytes: registerCounterVec(registerer, "loki_process", "deduplicated_bytes_total",
+			"A count of the bytes of all log lines suppressed by a deduplicate stage because they repeat a previous line", nil),
+	}, nil
+}
+
+func (m *deduplicateStage) Run(in chan Entry) chan Entry {
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+
+		streams := make(map[model.Fingerprint]*deduplicateState)
+		ticker := time.NewTicker(max(m.cfg.Window/4, minDeduplicateFlushInterval))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case e, ok := <-in:
+				if !ok {
+					for _, s := range streams {
+						out <- m.collapse(s)
+					}
+					return
+				}
+				m.process(streams, e, out)
+
+			case now := <-ticker.C:
+				for fp, s := range streams {
+					if now.Sub(s.since) >= m.cfg.Window {
+						out <- m.collapse(s)
+						delete(streams, fp)
+					}
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func (m *deduplicateStage) process(streams map[model.Fingerprint]*deduplicateState, e Entry, out chan Entry) {
+	var (
+		fp  = e.Labels.FastFingerprint()
+		key = m.key(e.Line)
+		now = time.Now()
+	)
+
+	s, ok := streams[fp]
+	if ok && s.key == key && now.Sub(s.since) < m.cfg.Window {
+		s.count++
+		m.deduplicatedLines.WithLabelValues().Inc()
+		m.deduplicatedBytes.WithLabelValues().Add(float64(len(e.Line)))
+		return
+	}
+	if ok {
+		// A different line, or the end of the window, flushes the held entry.
+		out <- m.collapse(s)
+	}
+	streams[fp] = &deduplicateState{entry: e, key: key, count: 1, since: now}
+}
+
+// key returns the line used to compare entries, after removing the parts which match the normalize expressions.
+func (m *deduplicateStage) key(line string) string {
+	for _, re := range m.normalize {
+		line = re.ReplaceAllString(line, "")
+	}
+	return line
+}
+
+// collapse returns the held entry of a stream, with the number of times it was received if it was repeated.
+func (m *deduplicateStage) collapse(s *deduplicateState) Entry {
+	e := s.entry
+	if s.count > 1 {
+		level.Debug(m.logger).Log("msg", "collapsing repeated lines", "count", s.count, "stream", e.Labels.String())
+		e.StructuredMetadata = append(e.StructuredMetadata, logproto.LabelAdapter{Name: m.cfg.CountKey, Value: strconv.Itoa(s.count)})
+	}
+	return e
+}
+
+// Name implements Stage
+func (m *deduplicateStage) Name() string {
+	return StageTypeDeduplicate
+}
+
+// Cleanup implements Stage.
+func (*deduplicateStage) Cleanup() {
+	// no-op
+}
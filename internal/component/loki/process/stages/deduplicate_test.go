@@ -0,0 +1,129 @@
+package stages
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+var testDeduplicateAlloy = `
+stage.deduplicate {
+    window    = "1m"
+    normalize = ["^\\S+ ", "id=\\w+"]
+}`
+
+func TestPipeline_Deduplicate(t *testing.T) {
+	t.Parallel()
+	logger := util.TestAlloyLogger(t)
+
+	pl, err := NewPipeline(logger, loadConfig(testDeduplicateAlloy), nil, prometheus.NewRegistry(), featuregate.StabilityExperimental)
+	require.NoError(t, err)
+
+	var (
+		app = model.LabelSet{"app": "api"}
+		db  = model.LabelSet{"app": "db"}
+		ts  = time.Now()
+	)
+	out := processEntries(pl,
+		newEntry(nil, app, "10:00:01 connection refused id=a1", ts),
+		newEntry(nil, app, "10:00:02 connection refused id=b2", ts.Add(time.Second)),
+		newEntry(nil, db, "10:00:02 connection refused id=c3", ts.Add(time.Second)),
+		newEntry(nil, app, "10:00:03 connection refused id=d4", ts.Add(2*time.Second)),
+		newEntry(nil, app, "10:00:04 connected", ts.Add(3*time.Second)),
+	)
+	require.Len(t, out, 3)
+
+	// The repeated lines of a stream are flushed as the first one, when a different line is received.
+	assert.Equal(t, "10:00:01 connection refused id=a1", out[0].Line)
+	assert.Equal(t, ts, out[0].Timestamp)
+	assert.Equal(t, push.LabelsAdapter{{Name: "repeat_count", Value: "3"}}, out[0].StructuredMetadata)
+
+	// The remaining entries are flushed when the input is closed, in any order.
+	lines := map[string]push.LabelsAdapter{out[1].Line: out[1].StructuredMetadata, out[2].Line: out[2].StructuredMetadata}
+	assert.Equal(t, map[string]push.LabelsAdapter{
+		"10:00:02 connection refused id=c3": nil,
+		"10:00:04 connected":                nil,
+	}, lines)
+}
+
+func TestDeduplicateConfig_Alloy(t *testing.T) {
+	t.Parallel()
+
+	var got DeduplicateConfig
+	require.NoError(t, syntax.Unmarshal([]byte(``), &got))
+	require.Equal(t, DefaultDeduplicateConfig, got)
+
+	require.ErrorContains(t, syntax.Unmarshal([]byte(`window = "0s"`), &got), "window must be greater than 0")
+	require.ErrorContains(t, syntax.Unmarshal([]byte(`count_key = ""`), &got), "count_key must not be empty")
+}
+
+func TestDeduplicateConfig_validate(t *testing.T) {
+	t.Parallel()
+
+	_, err := validateDeduplicateConfig(DeduplicateConfig{Window: time.Second, CountKey: "repeat_count", Normalize: []string{"("}})
+	require.ErrorIs(t, err, ErrDeduplicateStageInvalidRegex)
+
+	res, err := validateDeduplicateConfig(DeduplicateConfig{Window: time.Second, CountKey: "repeat_count", Normalize: []string{`\d+`}})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+}
+
+func TestDeduplicateStage_Window(t *testing.T) {
+	t.Parallel()
+	registry := prometheus.NewRegistry()
+
+	s, err := newDeduplicateStage(util.TestAlloyLogger(t), DeduplicateConfig{Window: 50 * time.Millisecond, CountKey: "repeats"}, registry)
+	require.NoError(t, err)
+
+	in := make(chan Entry)
+	out := s.Run(in)
+
+	labels := model.LabelSet{"app": "api"}
+	for i := 0; i < 3; i++ {
+		in <- newEntry(nil, labels, "retrying", time.Now())
+	}
+
+	// The held entry is flushed when the window expires, even if no other line is received.
+	select {
+	case e := <-out:
+		assert.Equal(t, "retrying", e.Line)
+		assert.Equal(t, push.LabelsAdapter{{Name: "repeats", Value: "3"}}, e.StructuredMetadata)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the repeated lines to be flushed when the window expires")
+	}
+
+	// The same line after the window starts a new group.
+	in <- newEntry(nil, labels, "retrying", time.Now())
+	close(in)
+	e, ok := <-out
+	require.True(t, ok)
+	assert.Equal(t, "retrying", e.Line)
+	assert.Empty(t, e.StructuredMetadata)
+	_, ok = <-out
+	require.False(t, ok)
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP loki_process_deduplicated_bytes_total A count of the bytes of all log lines suppressed by a deduplicate stage because they repeat a previous line
+# TYPE loki_process_deduplicated_bytes_total counter
+loki_process_deduplicated_bytes_total 16
+# HELP loki_process_deduplicated_lines_total A count of all log lines suppressed by a deduplicate stage because they repeat a previous line
+# TYPE loki_process_deduplicated_lines_total counter
+loki_process_deduplicated_lines_total 2
+`)))
+}
+
+func TestDeduplicateStabilityLevel(t *testing.T) {
+	_, err := NewPipeline(util.TestAlloyLogger(t), loadConfig(testDeduplicateAlloy), nil, prometheus.NewRegistry(), featuregate.StabilityPublicPreview)
+	require.ErrorContains(t, err, `stage "deduplicate" is at stability level "experimental"`)
+}
@@ -0,0 +1,241 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-kit/log"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Config Errors
+var (
+	ErrEmptyDelimitedStageConfig = errors.New("empty delimited stage configuration")
+	ErrColumnsRequired           = errors.New("delimited columns are required")
+	ErrEmptyDelimitedStageSource = errors.New("empty source")
+	ErrInvalidSeparator          = errors.New("the separator must be a single character")
+	ErrInvalidQuote              = errors.New("the quote must be empty or a single character different from the separator")
+	ErrMalformedDelimited        = errors.New("malformed delimited line")
+)
+
+// DelimitedConfig represents a delimited Stage configuration
+type DelimitedConfig struct {
+	Columns       []string `alloy:"columns,attr"`
+	Separator     string   `alloy:"separator,attr,optional"`
+	Quote         string   `alloy:"quote,attr,optional"`
+	TrimSpace     bool     `alloy:"trim_space,attr,optional"`
+	Source        *string  `alloy:"source,attr,optional"`
+	DropMalformed bool     `alloy:"drop_malformed,attr,optional"`
+}
+
+// DefaultDelimitedConfig sets the defaults.
+var DefaultDelimitedConfig = DelimitedConfig{
+	Separator: ",",
+	Quote:     `"`,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *DelimitedConfig) SetToDefault() {
+	*c = DefaultDelimitedConfig
+}
+
+// validateDelimitedConfig validates a delimited stage config and returns its separator and quote characters.
+func validateDelimitedConfig(c *DelimitedConfig) (separator rune, quote rune, err error) {
+	if c == nil {
+		return 0, 0, ErrEmptyDelimitedStageConfig
+	}
+
+	if len(c.Columns) == 0 {
+		return 0, 0, ErrColumnsRequired
+	}
+
+	seen := make(map[string]struct{}, len(c.Columns))
+	for _, col := range c.Columns {
+		// Columns without name are skipped.
+		if col == "" {
+			continue
+		}
+		if _, ok := seen[col]; ok {
+			return 0, 0, fmt.Errorf("duplicate column %q", col)
+		}
+		seen[col] = struct{}{}
+	}
+
+	if c.Source != nil && *c.Source == "" {
+		return 0, 0, ErrEmptyDelimitedStageSource
+	}
+
+	if utf8.RuneCountInString(c.Separator) != 1 {
+		return 0, 0, ErrInvalidSeparator
+	}
+	separator, _ = utf8.DecodeRuneInString(c.Separator)
+
+	switch utf8.RuneCountInString(c.Quote) {
+	case 0:
+	case 1:
+		quote, _ = utf8.DecodeRuneInString(c.Quote)
+		if quote == separator {
+			return 0, 0, ErrInvalidQuote
+		}
+	default:
+		return 0, 0, ErrInvalidQuote
+	}
+	return separator, quote, nil
+}
+
+// delimitedStage sets extracted data from the fields of a line separated by a delimiter, such as CSV or TSV lines.
+type delimitedStage struct {
+	cfg       *DelimitedConfig
+	separator rune
+	quote     rune
+	logger    log.Logger
+}
+
+// newDelimitedStage creates a new delimited pipeline stage from a config.
+func newDelimitedStage(logger log.Logger, cfg DelimitedConfig) (Stage, error) {
+	separator, quote, err := validateDelimitedConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &delimitedStage{
+		cfg:       &cfg,
+		separator: separator,
+		quote:     quote,
+		logger:    log.With(logger, "component", "stage", "type", "delimited"),
+	}, nil
+}
+
+func (d *delimitedStage) Run(in chan Entry) chan Entry {
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+		for e := range in {
+			err := d.processEntry(e.Extracted, &e.Line)
+			if err != nil && d.cfg.DropMalformed {
+				continue
+			}
+			out <- e
+		}
+	}()
+	return out
+}
+
+func (d *delimitedStage) processEntry(extracted map[string]interface{}, entry *string) error {
+	// If a source key is provided, the delimited stage should process it
+	// from the extracted map, otherwise should fall back to the entry
+	input := entry
+
+	if d.cfg.Source != nil {
+		if _, ok := extracted[*d.cfg.Source]; !ok {
+			if Debug {
+				level.Debug(d.logger).Log("msg", "source does not exist in the set of extracted values", "source", *d.cfg.Source)
+			}
+			return nil
+		}
+
+		value, err := getString(extracted[*d.cfg.Source])
+		if err != nil {
+			if Debug {
+				level.Debug(d.logger).Log("msg", "failed to convert source value to string", "source", *d.cfg.Source, "err", err, "type", reflect.TypeOf(extracted[*d.cfg.Source]))
+			}
+			return nil
+		}
+
+		input = &value
+	}
+
+	if input == nil {
+		if Debug {
+			level.Debug(d.logger).Log("msg", "cannot parse a nil entry")
+		}
+		return nil
+	}
+
+	fields, err := splitDelimited(strings.TrimRight(*input, "\r\n"), d.separator, d.quote)
+	if err == nil && len(fields) != len(d.cfg.Columns) {
+		err = fmt.Errorf("found %d fields, expected %d columns", len(fields), len(d.cfg.Columns))
+	}
+	if err != nil {
+		if Debug {
+			level.Debug(d.logger).Log("msg", "failed to parse log line", "err", err)
+		}
+		return ErrMalformedDelimited
+	}
+
+	for i, col := range d.cfg.Columns {
+		if col == "" {
+			continue
+		}
+		if d.cfg.TrimSpace {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		extracted[col] = fields[i]
+	}
+	if Debug {
+		level.Debug(d.logger).Log("msg", "extracted data debug in delimited stage", "extracted data", fmt.Sprintf("%v", extracted))
+	}
+	return nil
+}
+
+// splitDelimited splits s into the fields separated by separator. A field which starts with quote extends until the
+// next quote which isn't doubled, and can contain separators. A zero quote disables quoting.
+func splitDelimited(s string, separator, quote rune) ([]string, error) {
+	var (
+		fields       []string
+		separatorLen = utf8.RuneLen(separator)
+	)
+	for {
+		if quote == 0 || !strings.HasPrefix(s, string(quote)) {
+			i := strings.IndexRune(s, separator)
+			if i < 0 {
+				return append(fields, s), nil
+			}
+			fields = append(fields, s[:i])
+			s = s[i+separatorLen:]
+			continue
+		}
+
+		var (
+			field    strings.Builder
+			quoteLen = utf8.RuneLen(quote)
+		)
+		s = s[quoteLen:]
+		for {
+			i := strings.IndexRune(s, quote)
+			if i < 0 {
+				return nil, errors.New("unterminated quoted field")
+			}
+			field.WriteString(s[:i])
+			s = s[i+quoteLen:]
+			// A doubled quote is an escaped quote.
+			if !strings.HasPrefix(s, string(quote)) {
+				break
+			}
+			field.WriteRune(quote)
+			s = s[quoteLen:]
+		}
+		fields = append(fields, field.String())
+
+		if s == "" {
+			return fields, nil
+		}
+		if !strings.HasPrefix(s, string(separator)) {
+			return nil, errors.New("unexpected characters after a quoted field")
+		}
+		s = s[separatorLen:]
+	}
+}
+
+// Name implements Stage
+func (d *delimitedStage) Name() string {
+	return StageTypeDelimited
+}
+
+// Cleanup implements Stage.
+func (*delimitedStage) Cleanup() {
+	// no-op
+}
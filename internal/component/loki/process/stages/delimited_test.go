@@ -0,0 +1,197 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+var testDelimitedAlloyCSV = `
+stage.delimited {
+    columns = ["time", "", "user", "message"]
+}
+`
+
+var testDelimitedAlloyPipeWithSource = `
+stage.regex {
+    expression = "^(?P<level>\\w+) (?P<fields>.*)$"
+}
+
+stage.delimited {
+    columns    = ["method", "path", "status"]
+    separator  = "|"
+    quote      = ""
+    trim_space = true
+    source     = "fields"
+}`
+
+func TestPipeline_Delimited(t *testing.T) {
+	t.Parallel()
+	logger := util.TestAlloyLogger(t)
+
+	tests := map[string]struct {
+		config          string
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"successfully run a pipeline with a csv stage": {
+			testDelimitedAlloyCSV,
+			`2024-11-01T22:08:41Z,127.0.0.1,marco,"he said ""hi"", then left"`,
+			map[string]interface{}{
+				"time":    "2024-11-01T22:08:41Z",
+				"user":    "marco",
+				"message": `he said "hi", then left`,
+			},
+		},
+		"successfully run a pipeline with a regex and a pipe delimited stage with source": {
+			testDelimitedAlloyPipeWithSource,
+			`INFO GET | "/index.html" | 200`,
+			map[string]interface{}{
+				"level":  "INFO",
+				"fields": `GET | "/index.html" | 200`,
+				"method": "GET",
+				"path":   `"/index.html"`,
+				"status": "200",
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(logger, loadConfig(testData.config), nil, prometheus.DefaultRegisterer, featuregate.StabilityExperimental)
+			require.NoError(t, err, "Expected pipeline creation to not result in error")
+			out := processEntries(pl, newEntry(nil, nil, testData.entry, time.Now()))[0]
+			assert.Equal(t, testData.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+func TestDelimitedConfig_Alloy(t *testing.T) {
+	t.Parallel()
+
+	var got DelimitedConfig
+	require.NoError(t, syntax.Unmarshal([]byte(`columns = ["a", "b"]`), &got))
+	require.Equal(t, DelimitedConfig{Columns: []string{"a", "b"}, Separator: ",", Quote: `"`}, got)
+
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		columns   = ["a", "b"]
+		separator = "\t"
+	`), &got))
+	require.Equal(t, "\t", got.Separator)
+}
+
+func TestDelimitedConfig_validate(t *testing.T) {
+	t.Parallel()
+	emptySource := ""
+	tests := map[string]struct {
+		config DelimitedConfig
+		err    string
+	}{
+		"no columns": {
+			DelimitedConfig{Separator: ","},
+			ErrColumnsRequired.Error(),
+		},
+		"duplicate columns": {
+			DelimitedConfig{Columns: []string{"a", "", "", "a"}, Separator: ","},
+			`duplicate column "a"`,
+		},
+		"empty source": {
+			DelimitedConfig{Columns: []string{"a"}, Separator: ",", Source: &emptySource},
+			ErrEmptyDelimitedStageSource.Error(),
+		},
+		"empty separator": {
+			DelimitedConfig{Columns: []string{"a"}},
+			ErrInvalidSeparator.Error(),
+		},
+		"long separator": {
+			DelimitedConfig{Columns: []string{"a"}, Separator: "||"},
+			ErrInvalidSeparator.Error(),
+		},
+		"long quote": {
+			DelimitedConfig{Columns: []string{"a"}, Separator: ",", Quote: `""`},
+			ErrInvalidQuote.Error(),
+		},
+		"quote same as separator": {
+			DelimitedConfig{Columns: []string{"a"}, Separator: "'", Quote: "'"},
+			ErrInvalidQuote.Error(),
+		},
+		"valid": {
+			DelimitedConfig{Columns: []string{"a", "", "b"}, Separator: "\t", Quote: "'"},
+			"",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := validateDelimitedConfig(&tt.config)
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestSplitDelimited(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		line     string
+		quote    rune
+		expected []string
+		err      string
+	}{
+		"simple":                  {line: "a,b,c", quote: '"', expected: []string{"a", "b", "c"}},
+		"empty fields":            {line: ",b,", quote: '"', expected: []string{"", "b", ""}},
+		"empty line":              {line: "", quote: '"', expected: []string{""}},
+		"quoted separator":        {line: `a,"b,c",d`, quote: '"', expected: []string{"a", "b,c", "d"}},
+		"escaped quote":           {line: `"a ""b""",c`, quote: '"', expected: []string{`a "b"`, "c"}},
+		"quote inside field":      {line: `a b"c,d`, quote: '"', expected: []string{`a b"c`, "d"}},
+		"quoted last field":       {line: `a,""`, quote: '"', expected: []string{"a", ""}},
+		"quoting disabled":        {line: `"a,b"`, expected: []string{`"a`, `b"`}},
+		"unterminated quote":      {line: `a,"b,c`, quote: '"', err: "unterminated quoted field"},
+		"text after quoted field": {line: `"a"b,c`, quote: '"', err: "unexpected characters after a quoted field"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			fields, err := splitDelimited(tt.line, ',', tt.quote)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, fields)
+		})
+	}
+}
+
+func TestValidateDelimitedDrop(t *testing.T) {
+	logger := util.TestAlloyLogger(t)
+	s, err := newDelimitedStage(logger, DelimitedConfig{
+		DropMalformed: true,
+		Columns:       []string{"method", "path"},
+		Separator:     " ",
+	})
+	require.NoError(t, err)
+
+	out := processEntries(s, newEntry(nil, nil, "GET /index.html", time.Now()))
+	assert.Equal(t, 1, len(out), "stage should have kept one valid line but got %v", out)
+
+	out = processEntries(s, newEntry(nil, nil, "GET /index.html HTTP/1.1", time.Now()))
+	assert.Equal(t, 0, len(out), "stage should have kept zero valid line but got %v", out)
+}
+
+func TestDelimitedStabilityLevel(t *testing.T) {
+	_, err := NewPipeline(util.TestAlloyLogger(t), loadConfig(testDelimitedAlloyCSV), nil, prometheus.NewRegistry(), featuregate.StabilityPublicPreview)
+	require.ErrorContains(t, err, `stage "delimited" is at stability level "experimental"`)
+}
@@ -0,0 +1,191 @@
+package stages
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-grok"
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Config Errors.
+var (
+	ErrGrokPatternRequired  = errors.New("at least one grok pattern is required")
+	ErrCouldNotCompileGrok  = errors.New("could not compile grok pattern")
+	ErrEmptyGrokStageSource = errors.New("empty source")
+)
+
+// GrokConfig configures a processing stage which uses Grok patterns to
+// extract values from log lines into the shared values map.
+type GrokConfig struct {
+	Patterns           []string          `alloy:"patterns,attr"`
+	PatternDefinitions map[string]string `alloy:"pattern_definitions,attr,optional"`
+	PatternFiles       []string          `alloy:"pattern_files,attr,optional"`
+	NamedCapturesOnly  bool              `alloy:"named_captures_only,attr,optional"`
+	Source             *string           `alloy:"source,attr,optional"`
+}
+
+// DefaultGrokConfig sets the defaults.
+var DefaultGrokConfig = GrokConfig{
+	NamedCapturesOnly: true,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *GrokConfig) SetToDefault() {
+	*c = DefaultGrokConfig
+}
+
+// validateGrokConfig validates the config and returns the compiled patterns, in the configured order.
+func validateGrokConfig(c GrokConfig) ([]*grok.Grok, error) {
+	if len(c.Patterns) == 0 {
+		return nil, ErrGrokPatternRequired
+	}
+
+	if c.Source != nil && *c.Source == "" {
+		return nil, ErrEmptyGrokStageSource
+	}
+
+	// The definitions of the configuration override the ones of the files, which override the built-in ones.
+	var definitions []map[string]string
+	for _, path := range c.PatternFiles {
+		defs, err := readGrokPatternFile(path)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, defs)
+	}
+	definitions = append(definitions, c.PatternDefinitions)
+
+	// A Grok holds a single compiled pattern, so each pattern gets its own.
+	res := make([]*grok.Grok, 0, len(c.Patterns))
+	for _, p := range c.Patterns {
+		g, err := grok.NewComplete(definitions...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grok pattern definitions: %w", err)
+		}
+		if err := g.Compile(p, c.NamedCapturesOnly); err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrCouldNotCompileGrok, p, err)
+		}
+		res = append(res, g)
+	}
+	return res, nil
+}
+
+// readGrokPatternFile reads pattern definitions from a file in the Logstash format, where each line has the name of
+// a pattern followed by a space and its definition. Empty lines and lines starting with # are ignored.
+func readGrokPatternFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open grok pattern file: %w", err)
+	}
+	defer f.Close()
+
+	defs := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, definition, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid grok pattern definition in %s at line %d: expected a name and a pattern", path, n)
+		}
+		defs[name] = strings.TrimSpace(definition)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read grok pattern file: %w", err)
+	}
+	return defs, nil
+}
+
+// grokStage sets extracted data using Grok patterns
+type grokStage struct {
+	config   *GrokConfig
+	patterns []*grok.Grok
+	logger   log.Logger
+}
+
+// newGrokStage creates a grokStage. The patterns are compiled once, when the stage is created.
+func newGrokStage(logger log.Logger, config GrokConfig) (Stage, error) {
+	patterns, err := validateGrokConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return toStage(&grokStage{
+		config:   &config,
+		patterns: patterns,
+		logger:   log.With(logger, "component", "stage", "type", "grok"),
+	}), nil
+}
+
+// Process implements Stage
+func (g *grokStage) Process(labels model.LabelSet, extracted map[string]interface{}, t *time.Time, entry *string) {
+	// If a source key is provided, the grok stage should process it
+	// from the extracted map, otherwise should fall back to the entry
+	input := entry
+
+	if g.config.Source != nil {
+		if _, ok := extracted[*g.config.Source]; !ok {
+			if Debug {
+				level.Debug(g.logger).Log("msg", "source does not exist in the set of extracted values", "source", *g.config.Source)
+			}
+			return
+		}
+
+		value, err := getString(extracted[*g.config.Source])
+		if err != nil {
+			if Debug {
+				level.Debug(g.logger).Log("msg", "failed to convert source value to string", "source", *g.config.Source, "err", err, "type", reflect.TypeOf(extracted[*g.config.Source]))
+			}
+			return
+		}
+
+		input = &value
+	}
+
+	if input == nil {
+		if Debug {
+			level.Debug(g.logger).Log("msg", "cannot parse a nil entry")
+		}
+		return
+	}
+
+	// The first matching pattern wins.
+	for i, p := range g.patterns {
+		values, err := p.ParseTypedString(*input)
+		// The captures are empty both when the pattern doesn't match, and when it has no captures.
+		if err == nil && len(values) == 0 && !p.MatchString(*input) {
+			continue
+		}
+		if err != nil {
+			if Debug {
+				level.Debug(g.logger).Log("msg", "failed to convert grok captures", "pattern", g.config.Patterns[i], "err", err)
+			}
+			return
+		}
+		for k, v := range values {
+			extracted[k] = v
+		}
+		if Debug {
+			level.Debug(g.logger).Log("msg", "extracted data debug in grok stage", "extracted data", fmt.Sprintf("%v", extracted))
+		}
+		return
+	}
+	if Debug {
+		level.Debug(g.logger).Log("msg", "no grok pattern matched", "input", *input)
+	}
+}
+
+// Name implements Stage
+func (g *grokStage) Name() string {
+	return StageTypeGrok
+}
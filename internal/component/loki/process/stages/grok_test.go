@@ -0,0 +1,250 @@
+package stages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+var testGrokAlloyMultiStageWithSource = `
+stage.grok {
+    patterns = ["%{SYSLOGTIMESTAMP:time} %{HOSTNAME:host} %{GREEDYDATA:payload}"]
+}
+
+stage.grok {
+    patterns            = ["user=%{USERNAME:user} latency=%{DURATION:latency}"]
+    pattern_definitions = {"DURATION" = "%{NUMBER}(?:ms|s)"}
+    source              = "payload"
+}`
+
+func TestPipeline_Grok(t *testing.T) {
+	t.Parallel()
+	logger := util.TestAlloyLogger(t)
+
+	pl, err := NewPipeline(logger, loadConfig(testGrokAlloyMultiStageWithSource), nil, prometheus.DefaultRegisterer, featuregate.StabilityExperimental)
+	require.NoError(t, err)
+
+	out := processEntries(pl, newEntry(nil, nil, "Jan 25 14:00:01 web-1 user=frank latency=25ms", time.Now()))[0]
+	assert.Equal(t, map[string]interface{}{
+		"time":    "Jan 25 14:00:01",
+		"host":    "web-1",
+		"payload": "user=frank latency=25ms",
+		"user":    "frank",
+		"latency": "25ms",
+	}, out.Extracted)
+}
+
+func TestGrokConfig_Alloy(t *testing.T) {
+	t.Parallel()
+
+	var got GrokConfig
+	require.NoError(t, syntax.Unmarshal([]byte(`patterns = ["%{COMBINEDAPACHELOG}"]`), &got))
+	require.Equal(t, GrokConfig{Patterns: []string{"%{COMBINEDAPACHELOG}"}, NamedCapturesOnly: true}, got)
+}
+
+func TestGrokConfig_validate(t *testing.T) {
+	t.Parallel()
+	emptySource := ""
+	tests := map[string]struct {
+		config GrokConfig
+		err    error
+	}{
+		"no patterns": {
+			GrokConfig{},
+			ErrGrokPatternRequired,
+		},
+		"empty source": {
+			GrokConfig{Patterns: []string{"%{WORD:word}"}, Source: &emptySource},
+			ErrEmptyGrokStageSource,
+		},
+		"unknown pattern": {
+			GrokConfig{Patterns: []string{"%{UNKNOWN:word}"}},
+			ErrCouldNotCompileGrok,
+		},
+		"invalid regular expression": {
+			GrokConfig{Patterns: []string{"%{WORD:word}("}},
+			ErrCouldNotCompileGrok,
+		},
+		"valid": {
+			GrokConfig{Patterns: []string{"%{WORD:word}", "%{MYPATTERN:custom}"}, PatternDefinitions: map[string]string{"MYPATTERN": `\d+`}},
+			nil,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := validateGrokConfig(tt.config)
+			if tt.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestGrokParser_Parse(t *testing.T) {
+	t.Parallel()
+
+	patternFile := filepath.Join(t.TempDir(), "patterns")
+	require.NoError(t, os.WriteFile(patternFile, []byte(`
+# Custom patterns
+QUEUE_ID [0-9A-F]{10,11}
+MYAPP_LINE %{QUEUE_ID:queue_id}: %{GREEDYDATA:message}
+`), 0o600))
+
+	tests := map[string]struct {
+		config          GrokConfig
+		extracted       map[string]interface{}
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"built-in pattern with type hints": {
+			GrokConfig{Patterns: []string{"%{COMMONAPACHELOG}"}, NamedCapturesOnly: true},
+			map[string]interface{}{},
+			regexLogFixture,
+			map[string]interface{}{
+				"source.address":            "11.11.11.11",
+				"user.name":                 "frank",
+				"timestamp":                 "25/Jan/2000:14:00:01 -0500",
+				"http.request.method":       "GET",
+				"url.original":              "/1986.js",
+				"http.version":              "1.1",
+				"http.response.status_code": 200,
+				"http.response.body.size":   932,
+			},
+		},
+		"first matching pattern wins": {
+			GrokConfig{Patterns: []string{"%{INT:number} %{WORD:first}", "%{WORD:first} %{WORD:second}", "%{GREEDYDATA:all}"}, NamedCapturesOnly: true},
+			map[string]interface{}{},
+			"hello world",
+			map[string]interface{}{
+				"first":  "hello",
+				"second": "world",
+			},
+		},
+		"no matching pattern": {
+			GrokConfig{Patterns: []string{"^%{INT:number}$"}, NamedCapturesOnly: true},
+			map[string]interface{}{"existing": "value"},
+			"hello world",
+			map[string]interface{}{"existing": "value"},
+		},
+		"unnamed captures": {
+			GrokConfig{Patterns: []string{"%{WORD} %{INT:number:int}"}},
+			map[string]interface{}{},
+			"hello 42",
+			map[string]interface{}{
+				"WORD":   "hello",
+				"number": 42,
+			},
+		},
+		"pattern file": {
+			GrokConfig{Patterns: []string{"%{MYAPP_LINE}"}, PatternFiles: []string{patternFile}, NamedCapturesOnly: true},
+			map[string]interface{}{},
+			"4D1E2A5B9F: message accepted",
+			map[string]interface{}{
+				"queue_id": "4D1E2A5B9F",
+				"message":  "message accepted",
+			},
+		},
+		"definitions override pattern files": {
+			GrokConfig{
+				Patterns:           []string{"%{MYAPP_LINE}"},
+				PatternFiles:       []string{patternFile},
+				PatternDefinitions: map[string]string{"QUEUE_ID": "[a-z]+"},
+				NamedCapturesOnly:  true,
+			},
+			map[string]interface{}{},
+			"abc: message accepted",
+			map[string]interface{}{
+				"queue_id": "abc",
+				"message":  "message accepted",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			logger := util.TestAlloyLogger(t)
+			p, err := New(logger, nil, StageConfig{GrokConfig: &tt.config}, nil, featuregate.StabilityExperimental)
+			require.NoError(t, err)
+			out := processEntries(p, newEntry(tt.extracted, nil, tt.entry, time.Now()))[0]
+			assert.Equal(t, tt.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+func TestGrokPatternFile_Invalid(t *testing.T) {
+	t.Parallel()
+
+	patternFile := filepath.Join(t.TempDir(), "patterns")
+	require.NoError(t, os.WriteFile(patternFile, []byte("QUEUE_ID\n"), 0o600))
+	_, err := validateGrokConfig(GrokConfig{Patterns: []string{"%{QUEUE_ID}"}, PatternFiles: []string{patternFile}})
+	require.ErrorContains(t, err, "at line 1")
+
+	_, err = validateGrokConfig(GrokConfig{Patterns: []string{"%{QUEUE_ID}"}, PatternFiles: []string{filepath.Join(t.TempDir(), "missing")}})
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// BenchmarkGrokStage compares a grok stage with a regex stage which extracts
+// the same values from the same log line.
+func BenchmarkGrokStage(b *testing.B) {
+	benchmarks := []struct {
+		name   string
+		config StageConfig
+	}{
+		{"grok combined apache log",
+			StageConfig{GrokConfig: &GrokConfig{Patterns: []string{"%{COMBINEDAPACHELOG}"}, NamedCapturesOnly: true}},
+		},
+		{"grok combined apache log without type hints",
+			StageConfig{GrokConfig: &GrokConfig{
+				Patterns:          []string{`%{IPORHOST:ip} %{USER:identd} %{USER:user} \[%{HTTPDATE:timestamp}\] "%{WORD:action} %{NOTSPACE:path} HTTP/%{NUMBER:protocol}" %{INT:status} %{INT:size} "%{DATA:referer}" "%{DATA:useragent}"`},
+				NamedCapturesOnly: true,
+			}},
+		},
+		{"regex combined apache log",
+			StageConfig{RegexConfig: &RegexConfig{
+				Expression: "^(?P<ip>\\S+) (?P<identd>\\S+) (?P<user>\\S+) \\[(?P<timestamp>[\\w:/]+\\s[+\\-]\\d{4})\\] \"(?P<action>\\S+)\\s?(?P<path>\\S+)?\\s?(?P<protocol>\\S+)?\" (?P<status>\\d{3}|-) (?P<size>\\d+|-)\\s?\"?(?P<referer>[^\"]*)\"?\\s?\"?(?P<useragent>[^\"]*)?\"?$",
+			}},
+		},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			logger := util.TestAlloyLogger(b)
+			stage, err := New(logger, nil, bm.config, nil, featuregate.StabilityExperimental)
+			if err != nil {
+				panic(err)
+			}
+			labels := model.LabelSet{}
+			ts := time.Now()
+			extr := map[string]interface{}{}
+
+			in := make(chan Entry)
+			out := stage.Run(in)
+			go func() {
+				for range out {
+				}
+			}()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				in <- newEntry(extr, labels, regexLogFixture, ts)
+			}
+			close(in)
+		})
+	}
+}
+
+func TestGrokStabilityLevel(t *testing.T) {
+	_, err := NewPipeline(util.TestAlloyLogger(t), loadConfig(testGrokAlloyMultiStageWithSource), nil, prometheus.NewRegistry(), featuregate.StabilityPublicPreview)
+	require.ErrorContains(t, err, `stage "grok" is at stability level "experimental"`)
+}
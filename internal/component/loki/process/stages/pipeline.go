@@ -19,10 +19,13 @@ import (
 type StageConfig struct {
 	CRIConfig             *CRIConfig             `alloy:"cri,block,optional"`
 	DecolorizeConfig      *DecolorizeConfig      `alloy:"decolorize,block,optional"`
+	DeduplicateConfig     *DeduplicateConfig     `alloy:"deduplicate,block,optional"`
+	DelimitedConfig       *DelimitedConfig       `alloy:"delimited,block,optional"`
 	DockerConfig          *DockerConfig          `alloy:"docker,block,optional"`
 	DropConfig            *DropConfig            `alloy:"drop,block,optional"`
 	EventLogMessageConfig *EventLogMessageConfig `alloy:"eventlogmessage,block,optional"`
 	GeoIPConfig           *GeoIPConfig           `alloy:"geoip,block,optional"`
+	GrokConfig            *GrokConfig            `alloy:"grok,block,optional"`
 	JSONConfig            *JSONConfig            `alloy:"json,block,optional"`
 	LabelAllowConfig      *LabelAllowConfig      `alloy:"label_keep,block,optional"`
 	LabelDropConfig       *LabelDropConfig       `alloy:"label_drop,block,optional"`
@@ -43,7 +46,9 @@ type StageConfig struct {
 	TemplateConfig        *TemplateConfig        `alloy:"template,block,optional"`
 	TenantConfig          *TenantConfig          `alloy:"tenant,block,optional"`
 	TimestampConfig       *TimestampConfig       `alloy:"timestamp,block,optional"`
+	UnpackConfig          *UnpackConfig          `alloy:"unpack,block,optional"`
 	WindowsEventConfig    *WindowsEventConfig    `alloy:"windowsevent,block,optional"`
+	XMLConfig             *XMLConfig             `alloy:"xml,block,optional"`
 }
 
 var rateLimiter *rate.Limiter
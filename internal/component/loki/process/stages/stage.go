@@ -16,13 +16,16 @@ import (
 
 // TODO(@tpaschalis) Let's use this as the list of stages we need to port over.
 const (
-	StageTypeCRI        = "cri"
-	StageTypeDecolorize = "decolorize"
-	StageTypeDocker     = "docker"
-	StageTypeDrop       = "drop"
+	StageTypeCRI         = "cri"
+	StageTypeDecolorize  = "decolorize"
+	StageTypeDeduplicate = "deduplicate"
+	StageTypeDelimited   = "delimited"
+	StageTypeDocker      = "docker"
+	StageTypeDrop        = "drop"
 	//TODO(thampiotr): Add support for eventlogmessage stage
 	StageTypeEventLogMessage    = "eventlogmessage"
 	StageTypeGeoIP              = "geoip"
+	StageTypeGrok               = "grok"
 	StageTypeJSON               = "json"
 	StageTypeLabel              = "labels"
 	StageTypeLabelAllow         = "labelallow"
@@ -44,12 +47,19 @@ const (
 	StageTypeTemplate           = "template"
 	StageTypeTenant             = "tenant"
 	StageTypeTimestamp          = "timestamp"
+	StageTypeUnpack             = "unpack"
 	StageTypeWindowsEvent       = "windowsevent"
+	StageTypeXML                = "xml"
 )
 
 // Add stages that are not GA. Stages that are not specified here are considered GA.
 var stagesUnstable = map[string]featuregate.Stability{
+	StageTypeDeduplicate:  featuregate.StabilityExperimental,
+	StageTypeDelimited:    featuregate.StabilityExperimental,
+	StageTypeGrok:         featuregate.StabilityExperimental,
+	StageTypeUnpack:       featuregate.StabilityExperimental,
 	StageTypeWindowsEvent: featuregate.StabilityExperimental,
+	StageTypeXML:          featuregate.StabilityExperimental,
 }
 
 // Processor takes an existing set of labels, timestamp and log entry and returns either a possibly mutated
@@ -148,6 +158,16 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		if err != nil {
 			return nil, err
 		}
+	case cfg.XMLConfig != nil:
+		s, err = newXMLStage(logger, *cfg.XMLConfig)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.DelimitedConfig != nil:
+		s, err = newDelimitedStage(logger, *cfg.DelimitedConfig)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.LogfmtConfig != nil:
 		s, err = newLogfmtStage(logger, *cfg.LogfmtConfig)
 		if err != nil {
@@ -178,6 +198,11 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		if err != nil {
 			return nil, err
 		}
+	case cfg.GrokConfig != nil:
+		s, err = newGrokStage(logger, *cfg.GrokConfig)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.TimestampConfig != nil:
 		s, err = newTimestampStage(logger, *cfg.TimestampConfig)
 		if err != nil {
@@ -218,6 +243,11 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		if err != nil {
 			return nil, err
 		}
+	case cfg.DeduplicateConfig != nil:
+		s, err = newDeduplicateStage(logger, *cfg.DeduplicateConfig, registerer)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.MultilineConfig != nil:
 		s, err = newMultilineStage(logger, *cfg.MultilineConfig)
 		if err != nil {
@@ -225,6 +255,11 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		}
 	case cfg.PackConfig != nil:
 		s = newPackStage(logger, *cfg.PackConfig, registerer)
+	case cfg.UnpackConfig != nil:
+		s, err = newUnpackStage(logger, *cfg.UnpackConfig)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.LabelAllowConfig != nil:
 		s, err = newLabelAllowStage(*cfg.LabelAllowConfig)
 		if err != nil {
@@ -0,0 +1,130 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/grafana/loki/v3/pkg/logqlmodel"
+	json "github.com/json-iterator/go"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Configuration errors.
+var (
+	ErrUnpackStageDuplicateField = errors.New("an embedded field can't be restored both as a label and as structured metadata")
+)
+
+// UnpackConfig contains the configuration for an unpackStage.
+type UnpackConfig struct {
+	// Labels are the embedded fields restored as labels. If both Labels and StructuredMetadata are empty, every
+	// embedded field is restored as a label.
+	Labels []string `alloy:"labels,attr,optional"`
+	// StructuredMetadata are the embedded fields restored as structured metadata.
+	StructuredMetadata []string `alloy:"structured_metadata,attr,optional"`
+}
+
+// validateUnpackConfig validates the unpack stage configuration.
+func validateUnpackConfig(c UnpackConfig) error {
+	for _, l := range c.Labels {
+		if !model.LabelName(l).IsValid() {
+			return fmt.Errorf("invalid label name %q", l)
+		}
+		if slices.Contains(c.StructuredMetadata, l) {
+			return fmt.Errorf("%w: %q", ErrUnpackStageDuplicateField, l)
+		}
+	}
+	return nil
+}
+
+// newUnpackStage creates an unpackStage from config.
+func newUnpackStage(logger log.Logger, config UnpackConfig) (Stage, error) {
+	if err := validateUnpackConfig(config); err != nil {
+		return nil, err
+	}
+	return &unpackStage{
+		logger: log.With(logger, "component", "stage", "type", "unpack"),
+		cfg:    config,
+	}, nil
+}
+
+// unpackStage reverses the packStage: it restores the log line embedded in a pack JSON envelope, and the embedded
+// fields as labels or structured metadata.
+type unpackStage struct {
+	logger log.Logger
+	cfg    UnpackConfig
+}
+
+// Run implements Stage
+func (m *unpackStage) Run(in chan Entry) chan Entry {
+	return RunWith(in, m.unpack)
+}
+
+func (m *unpackStage) unpack(e Entry) Entry {
+	fields, entry, ok := m.parse(e.Line)
+	if !ok {
+		return e
+	}
+
+	restoreAll := len(m.cfg.Labels) == 0 && len(m.cfg.StructuredMetadata) == 0
+	for k, v := range fields {
+		// Every embedded field is available to the following stages, like with any parsing stage.
+		e.Extracted[k] = v
+
+		switch {
+		case restoreAll || slices.Contains(m.cfg.Labels, k):
+			name := model.LabelName(k)
+			if !name.IsValid() {
+				level.Debug(m.logger).Log("msg", "embedded field is not a valid label name and can't be restored as a label", "field", k)
+				continue
+			}
+			e.Labels[name] = model.LabelValue(v)
+		case slices.Contains(m.cfg.StructuredMetadata, k):
+			e.StructuredMetadata = append(e.StructuredMetadata, logproto.LabelAdapter{Name: k, Value: v})
+		}
+	}
+	e.Line = entry
+	return e
+}
+
+// parse returns the embedded fields and log line of a pack JSON envelope. It returns false if the line isn't one.
+func (m *unpackStage) parse(line string) (map[string]string, string, bool) {
+	var raw map[string]interface{}
+	if err := json.UnmarshalFromString(line, &raw); err != nil {
+		level.Debug(m.logger).Log("msg", "log line is not a packed JSON object, it will be left unchanged", "err", err)
+		return nil, "", false
+	}
+	entry, ok := raw[logqlmodel.PackedEntryKey].(string)
+	if !ok {
+		level.Debug(m.logger).Log("msg", "log line has no string "+logqlmodel.PackedEntryKey+" key, it will be left unchanged")
+		return nil, "", false
+	}
+
+	fields := make(map[string]string, len(raw)-1)
+	for k, v := range raw {
+		if k == logqlmodel.PackedEntryKey {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			level.Debug(m.logger).Log("msg", "packed JSON object has a non-string field, the log line will be left unchanged", "field", k)
+			return nil, "", false
+		}
+		fields[k] = s
+	}
+	return fields, entry, true
+}
+
+// Name implements Stage
+func (m *unpackStage) Name() string {
+	return StageTypeUnpack
+}
+
+// Cleanup implements Stage.
+func (*unpackStage) Cleanup() {
+	// no-op
+}
@@ -0,0 +1,137 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/util"
+)
+
+var testPackUnpackAlloy = `
+stage.pack {
+		labels           = ["pod", "container", "trace_id"]
+		ingest_timestamp = false
+}
+stage.unpack {
+		labels              = ["pod", "container"]
+		structured_metadata = ["trace_id"]
+}`
+
+// TestPackUnpackPipeline verifies that the unpack stage restores the line
+// and labels of the entries packed by the pack stage.
+func TestPackUnpackPipeline(t *testing.T) {
+	logger := util.TestAlloyLogger(t)
+	plName := "test_unpack_pipeline"
+	pl, err := NewPipeline(logger, loadConfig(testPackUnpackAlloy), &plName, prometheus.NewRegistry(), featuregate.StabilityExperimental)
+	require.NoError(t, err)
+
+	lbls := model.LabelSet{
+		"pod":       "foo-xsfs3",
+		"container": "foo",
+		"trace_id":  "0af7651916cd43dd",
+		"namespace": "dev",
+	}
+	testTime := time.Now()
+	out := processEntries(pl, newEntry(nil, lbls, testMatchLogLineApp1, testTime))[0]
+
+	assert.Equal(t, testMatchLogLineApp1, out.Line)
+	assert.Equal(t, testTime, out.Timestamp)
+	assert.Equal(t, model.LabelSet{"pod": "foo-xsfs3", "container": "foo", "namespace": "dev"}, out.Labels)
+	assert.Equal(t, push.LabelsAdapter{{Name: "trace_id", Value: "0af7651916cd43dd"}}, out.StructuredMetadata)
+}
+
+func TestUnpackStage(t *testing.T) {
+	tests := []struct {
+		name                       string
+		config                     UnpackConfig
+		line                       string
+		expectedLine               string
+		expectedLabels             model.LabelSet
+		expectedStructuredMetadata push.LabelsAdapter
+		expectedExtracted          map[string]interface{}
+	}{
+		{
+			name:              "restore all fields as labels",
+			line:              `{"env":"dev","user_id":"f8fas0r","_entry":"something went wrong"}`,
+			expectedLine:      "something went wrong",
+			expectedLabels:    model.LabelSet{"level": "error", "env": "dev", "user_id": "f8fas0r"},
+			expectedExtracted: map[string]interface{}{"env": "dev", "user_id": "f8fas0r"},
+		},
+		{
+			name:                       "restore selected fields",
+			config:                     UnpackConfig{Labels: []string{"env"}, StructuredMetadata: []string{"user_id"}},
+			line:                       `{"env":"dev","user_id":"f8fas0r","pod":"foo","_entry":"something went wrong"}`,
+			expectedLine:               "something went wrong",
+			expectedLabels:             model.LabelSet{"level": "error", "env": "dev"},
+			expectedStructuredMetadata: push.LabelsAdapter{{Name: "user_id", Value: "f8fas0r"}},
+			expectedExtracted:          map[string]interface{}{"env": "dev", "user_id": "f8fas0r", "pod": "foo"},
+		},
+		{
+			name:              "embedded field overrides existing label",
+			line:              `{"level":"info","_entry":"{\"msg\":\"quoted line\"}"}`,
+			expectedLine:      `{"msg":"quoted line"}`,
+			expectedLabels:    model.LabelSet{"level": "info"},
+			expectedExtracted: map[string]interface{}{"level": "info"},
+		},
+		{
+			name:              "invalid label names are only extracted",
+			line:              `{"":"foo","_entry":"line"}`,
+			expectedLine:      "line",
+			expectedLabels:    model.LabelSet{"level": "error"},
+			expectedExtracted: map[string]interface{}{"": "foo"},
+		},
+		{
+			name:              "not a JSON object",
+			line:              "something went wrong",
+			expectedLine:      "something went wrong",
+			expectedLabels:    model.LabelSet{"level": "error"},
+			expectedExtracted: map[string]interface{}{},
+		},
+		{
+			name:              "no _entry key",
+			line:              `{"env":"dev","msg":"something went wrong"}`,
+			expectedLine:      `{"env":"dev","msg":"something went wrong"}`,
+			expectedLabels:    model.LabelSet{"level": "error"},
+			expectedExtracted: map[string]interface{}{},
+		},
+		{
+			name:              "non-string field",
+			line:              `{"env":"dev","count":1,"_entry":"something went wrong"}`,
+			expectedLine:      `{"env":"dev","count":1,"_entry":"something went wrong"}`,
+			expectedLabels:    model.LabelSet{"level": "error"},
+			expectedExtracted: map[string]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newUnpackStage(util.TestAlloyLogger(t), tt.config)
+			require.NoError(t, err)
+
+			out := processEntries(s, newEntry(nil, model.LabelSet{"level": "error"}, tt.line, time.Unix(1, 0)))[0]
+			assert.Equal(t, tt.expectedLine, out.Line)
+			assert.Equal(t, tt.expectedLabels, out.Labels)
+			assert.Equal(t, tt.expectedStructuredMetadata, out.StructuredMetadata)
+			assert.Equal(t, tt.expectedExtracted, out.Extracted)
+		})
+	}
+}
+
+func TestUnpackStage_Validation(t *testing.T) {
+	_, err := newUnpackStage(util.TestAlloyLogger(t), UnpackConfig{Labels: []string{""}})
+	require.EqualError(t, err, `invalid label name ""`)
+
+	_, err = newUnpackStage(util.TestAlloyLogger(t), UnpackConfig{Labels: []string{"pod"}, StructuredMetadata: []string{"pod"}})
+	require.ErrorIs(t, err, ErrUnpackStageDuplicateField)
+}
+
+func TestUnpackStabilityLevel(t *testing.T) {
+	_, err := NewPipeline(util.TestAlloyLogger(t), loadConfig(testPackUnpackAlloy), nil, prometheus.NewRegistry(), featuregate.StabilityPublicPreview)
+	require.ErrorContains(t, err, `stage "unpack" is at stability level "experimental"`)
+}
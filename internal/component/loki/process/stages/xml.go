@@ -0,0 +1,174 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/go-kit/log"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Config Errors
+var (
+	ErrEmptyXMLStageConfig  = errors.New("empty xml stage configuration")
+	ErrXPathRequired        = errors.New("XPath expression is required")
+	ErrEmptyXMLStageSource  = errors.New("empty source")
+	ErrCouldNotCompileXPath = errors.New("could not compile XPath expression")
+	ErrMalformedXML         = errors.New("malformed xml")
+)
+
+// XMLConfig represents an XML Stage configuration
+type XMLConfig struct {
+	Expressions   map[string]string `alloy:"expressions,attr"`
+	Source        *string           `alloy:"source,attr,optional"`
+	DropMalformed bool              `alloy:"drop_malformed,attr,optional"`
+}
+
+// validateXMLConfig validates an xml config and returns a map of compiled XPath expressions.
+func validateXMLConfig(c *XMLConfig) (map[string]*xpath.Expr, error) {
+	if c == nil {
+		return nil, ErrEmptyXMLStageConfig
+	}
+
+	if len(c.Expressions) == 0 {
+		return nil, ErrXPathRequired
+	}
+
+	if c.Source != nil && *c.Source == "" {
+		return nil, ErrEmptyXMLStageSource
+	}
+
+	expressions := make(map[string]*xpath.Expr, len(c.Expressions))
+	for n, e := range c.Expressions {
+		// If there is no expression, look for the first element with the name.
+		if e == "" {
+			e = "//" + n
+		}
+		expr, err := xpath.Compile(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrCouldNotCompileXPath, e, err)
+		}
+		expressions[n] = expr
+	}
+	return expressions, nil
+}
+
+// xmlStage sets extracted data using XPath expressions
+type xmlStage struct {
+	cfg         *XMLConfig
+	expressions map[string]*xpath.Expr
+	logger      log.Logger
+}
+
+// newXMLStage creates a new xml pipeline stage from a config.
+func newXMLStage(logger log.Logger, cfg XMLConfig) (Stage, error) {
+	expressions, err := validateXMLConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &xmlStage{
+		cfg:         &cfg,
+		expressions: expressions,
+		logger:      log.With(logger, "component", "stage", "type", "xml"),
+	}, nil
+}
+
+func (x *xmlStage) Run(in chan Entry) chan Entry {
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+		for e := range in {
+			err := x.processEntry(e.Extracted, &e.Line)
+			if err != nil && x.cfg.DropMalformed {
+				continue
+			}
+			out <- e
+		}
+	}()
+	return out
+}
+
+func (x *xmlStage) processEntry(extracted map[string]interface{}, entry *string) error {
+	// If a source key is provided, the xml stage should process it
+	// from the extracted map, otherwise should fall back to the entry
+	input := entry
+
+	if x.cfg.Source != nil {
+		if _, ok := extracted[*x.cfg.Source]; !ok {
+			if Debug {
+				level.Debug(x.logger).Log("msg", "source does not exist in the set of extracted values", "source", *x.cfg.Source)
+			}
+			return nil
+		}
+
+		value, err := getString(extracted[*x.cfg.Source])
+		if err != nil {
+			if Debug {
+				level.Debug(x.logger).Log("msg", "failed to convert source value to string", "source", *x.cfg.Source, "err", err, "type", reflect.TypeOf(extracted[*x.cfg.Source]))
+			}
+			return nil
+		}
+
+		input = &value
+	}
+
+	if input == nil {
+		if Debug {
+			level.Debug(x.logger).Log("msg", "cannot parse a nil entry")
+		}
+		return nil
+	}
+
+	doc, err := xmlquery.Parse(strings.NewReader(*input))
+	if err == nil && !hasElement(doc) {
+		// The parser accepts plain text, which isn't an XML document.
+		err = errors.New("no root element")
+	}
+	if err != nil {
+		if Debug {
+			level.Debug(x.logger).Log("msg", "failed to parse log line", "err", err)
+		}
+		return ErrMalformedXML
+	}
+
+	for n, e := range x.expressions {
+		switch r := e.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+		case *xpath.NodeIterator:
+			// Only the first matching node is extracted.
+			if r.MoveNext() {
+				extracted[n] = r.Current().Value()
+			}
+		case string, float64, bool:
+			extracted[n] = r
+		}
+	}
+	if Debug {
+		level.Debug(x.logger).Log("msg", "extracted data debug in xml stage", "extracted data", fmt.Sprintf("%v", extracted))
+	}
+	return nil
+}
+
+// hasElement returns true if the document has a root element.
+func hasElement(doc *xmlquery.Node) bool {
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == xmlquery.ElementNode {
+			return true
+		}
+	}
+	return false
+}
+
+// Name implements Stage
+func (x *xmlStage) Name() string {
+	return StageTypeXML
+}
+
+// Cleanup implements Stage.
+func (*xmlStage) Cleanup() {
+	// no-op
+}
@@ -0,0 +1,166 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/util"
+)
+
+var testXMLAlloySingleStageWithoutSource = `
+stage.xml {
+    expressions = {
+        "user"    = "",
+        "status"  = "/Envelope/Body/Response/@status",
+        "items"   = "count(//item)",
+        "first"   = "//item[1]",
+        "unknown" = "//unknown",
+    }
+}
+`
+
+var testXMLAlloyMultiStageWithSource = `
+stage.json {
+    expressions = { "payload" = "" }
+}
+
+stage.xml {
+    expressions = { "user" = "/request/user" }
+    source      = "payload"
+}`
+
+var testXMLLogLine = `<?xml version="1.0"?>
+<Envelope>
+	<Body>
+		<Response status="200">
+			<user>marco</user>
+			<item>apple</item>
+			<item>peach</item>
+		</Response>
+	</Body>
+</Envelope>`
+
+func TestPipeline_XML(t *testing.T) {
+	t.Parallel()
+	logger := util.TestAlloyLogger(t)
+
+	tests := map[string]struct {
+		config          string
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"successfully run a pipeline with 1 xml stage without source": {
+			testXMLAlloySingleStageWithoutSource,
+			testXMLLogLine,
+			map[string]interface{}{
+				"user":   "marco",
+				"status": "200",
+				"items":  float64(2),
+				"first":  "apple",
+			},
+		},
+		"successfully run a pipeline with a json and an xml stage with source": {
+			testXMLAlloyMultiStageWithSource,
+			`{"payload": "<request><user>marco</user></request>"}`,
+			map[string]interface{}{
+				"payload": "<request><user>marco</user></request>",
+				"user":    "marco",
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(logger, loadConfig(testData.config), nil, prometheus.DefaultRegisterer, featuregate.StabilityExperimental)
+			require.NoError(t, err, "Expected pipeline creation to not result in error")
+			out := processEntries(pl, newEntry(nil, nil, testData.entry, time.Now()))[0]
+			assert.Equal(t, testData.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+func TestXMLConfig_validate(t *testing.T) {
+	t.Parallel()
+	emptySource := ""
+	tests := map[string]struct {
+		config *XMLConfig
+		err    error
+	}{
+		"empty config": {
+			nil,
+			ErrEmptyXMLStageConfig,
+		},
+		"no expressions": {
+			&XMLConfig{},
+			ErrXPathRequired,
+		},
+		"empty source": {
+			&XMLConfig{Expressions: map[string]string{"user": ""}, Source: &emptySource},
+			ErrEmptyXMLStageSource,
+		},
+		"invalid expression": {
+			&XMLConfig{Expressions: map[string]string{"user": "//user["}},
+			ErrCouldNotCompileXPath,
+		},
+		"valid": {
+			&XMLConfig{Expressions: map[string]string{"user": "", "status": "//@status"}},
+			nil,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := validateXMLConfig(tt.config)
+			if tt.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestXMLParser_Parse(t *testing.T) {
+	t.Parallel()
+	s, err := newXMLStage(util.TestAlloyLogger(t), XMLConfig{Expressions: map[string]string{"user": ""}})
+	require.NoError(t, err)
+
+	for name, line := range map[string]string{
+		"plain text":      "user=marco",
+		"unclosed tag":    "<user>marco",
+		"mismatched tags": "<user>marco</name>",
+	} {
+		t.Run(name, func(t *testing.T) {
+			extracted := map[string]interface{}{}
+			require.ErrorIs(t, s.(*xmlStage).processEntry(extracted, &line), ErrMalformedXML)
+			require.Empty(t, extracted)
+		})
+	}
+}
+
+func TestValidateXMLDrop(t *testing.T) {
+	logger := util.TestAlloyLogger(t)
+	s, err := newXMLStage(logger, XMLConfig{
+		DropMalformed: true,
+		Expressions:   map[string]string{"page": ""},
+	})
+	require.NoError(t, err)
+
+	out := processEntries(s, newEntry(nil, nil, `<request><page>1</page></request>`, time.Now()))
+	assert.Equal(t, 1, len(out), "stage should have kept one valid xml line but got %v", out)
+
+	out = processEntries(s, newEntry(nil, nil, `<request><page>1</request>`, time.Now()))
+	assert.Equal(t, 0, len(out), "stage should have kept zero valid xml line but got %v", out)
+}
+
+func TestXMLStabilityLevel(t *testing.T) {
+	_, err := NewPipeline(util.TestAlloyLogger(t), loadConfig(testXMLAlloySingleStageWithoutSource), nil, prometheus.NewRegistry(), featuregate.StabilityPublicPreview)
+	require.ErrorContains(t, err, `stage "xml" is at stability level "experimental"`)
+}
@@ -126,7 +126,16 @@ func (c *Component) Run(ctx context.Context) error {
 			lbls := c.relabel(entry)
 
 			if c.debugDataPublisher.IsActive(componentID) {
-				c.debugDataPublisher.Publish(componentID, fmt.Sprintf("entry: %s, labels: %s => %s", entry.Line, entry.Labels.String(), lbls.String()))
+				before, after := livedebugging.LabelsFromLabelSet(entry.Labels), livedebugging.LabelsFromLabelSet(lbls)
+				line := entry.Line
+				c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.LokiLog,
+					1,
+					before,
+					func() string {
+						return fmt.Sprintf("entry: %s, labels: %s => %s", line, before.String(), after.String())
+					},
+				))
 			}
 
 			if len(lbls) == 0 {
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	commonconfig "github.com/prometheus/common/config"
+)
+
+// alertmanagerAlert is an alert as accepted by the Alertmanager v2 API.
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// alertmanagerClient sends alerts to an Alertmanager.
+type alertmanagerClient struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+}
+
+func newAlertmanagerClient(cfg AlertmanagerConfig) (*alertmanagerClient, error) {
+	client, err := commonconfig.NewClientFromConfig(*cfg.HTTPClientConfig.Convert(), "loki.rules.local")
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.JoinPath(cfg.URL, "/api/v2/alerts")
+	if err != nil {
+		return nil, err
+	}
+	return &alertmanagerClient{client: client, url: u, timeout: cfg.Timeout}, nil
+}
+
+// send posts the alerts to the Alertmanager.
+func (c *alertmanagerClient) send(ctx context.Context, alerts []alertmanagerAlert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d from alertmanager: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
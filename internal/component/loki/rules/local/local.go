@@ -0,0 +1,304 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/util"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.rules.local",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Exports holds the values exported by the loki.rules.local component.
+type Exports struct {
+	Receiver loki.LogsReceiver `alloy:"receiver,attr"`
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// Component implements the loki.rules.local component.
+type Component struct {
+	opts     component.Options
+	receiver loki.LogsReceiver
+	metrics  *metrics
+	ticker   *time.Ticker
+
+	// notifications holds the alerts of the last evaluation until they are
+	// sent, so that a slow Alertmanager doesn't block the log entries.
+	notifications chan []alertmanagerAlert
+
+	mut          sync.RWMutex
+	args         Arguments
+	rules        []*rule
+	alertmanager *alertmanagerClient
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+type metrics struct {
+	alerts              *prometheus.GaugeVec
+	notificationsSent   prometheus.Counter
+	notificationsFailed prometheus.Counter
+}
+
+func newMetrics(r prometheus.Registerer) *metrics {
+	m := &metrics{
+		alerts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "loki_rules_local_alerts",
+			Help: "Number of active alerts, partitioned by rule and state.",
+		}, []string{"rule", "state"}),
+		notificationsSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_rules_local_notifications_sent_total",
+			Help: "Total number of requests sent to the Alertmanager.",
+		}),
+		notificationsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_rules_local_notifications_failed_total",
+			Help: "Total number of requests to the Alertmanager which failed, or were dropped because a previous request was still in progress.",
+		}),
+	}
+	m.alerts = util.MustRegisterOrGet(r, m.alerts).(*prometheus.GaugeVec)
+	m.notificationsSent = util.MustRegisterOrGet(r, m.notificationsSent).(prometheus.Counter)
+	m.notificationsFailed = util.MustRegisterOrGet(r, m.notificationsFailed).(prometheus.Counter)
+	return m
+}
+
+// New creates a new loki.rules.local component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:          o,
+		receiver:      loki.NewLogsReceiver(),
+		metrics:       newMetrics(o.Registerer),
+		ticker:        time.NewTicker(args.EvaluationInterval),
+		notifications: make(chan []alertmanagerAlert, 1),
+	}
+
+	// Call to Update() once at the start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.runNotifier(ctx)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.receiver.Chan():
+			c.observe(entry)
+		case now := <-c.ticker.C:
+			c.evaluate(now)
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	client, err := newAlertmanagerClient(newArgs.Alertmanager)
+	if err != nil {
+		return fmt.Errorf("failed to create alertmanager client: %w", err)
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// Rules which keep their name and expression also keep their state.
+	existing := make(map[string]*rule, len(c.rules))
+	for _, r := range c.rules {
+		existing[r.cfg.Alert+"\xff"+r.cfg.Expr] = r
+	}
+
+	rules := make([]*rule, 0, len(newArgs.Rules))
+	for _, cfg := range newArgs.Rules {
+		r, err := newRule(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid rule %q: %w", cfg.Alert, err)
+		}
+		if old, ok := existing[cfg.Alert+"\xff"+cfg.Expr]; ok {
+			r.query, r.alerts = old.query, old.alerts
+			delete(existing, cfg.Alert+"\xff"+cfg.Expr)
+		}
+		rules = append(rules, r)
+	}
+	for _, r := range existing {
+		c.metrics.alerts.DeleteLabelValues(r.cfg.Alert, statePending)
+		c.metrics.alerts.DeleteLabelValues(r.cfg.Alert, stateFiring)
+	}
+
+	c.args = newArgs
+	c.rules = rules
+	c.alertmanager = client
+	c.ticker.Reset(newArgs.EvaluationInterval)
+
+	return nil
+}
+
+// observe adds a log entry to the rules which select it.
+func (c *Component) observe(entry loki.Entry) {
+	stream := toLabels(entry.Labels)
+	structuredMetadata := make([]labels.Label, 0, len(entry.StructuredMetadata))
+	for _, l := range entry.StructuredMetadata {
+		structuredMetadata = append(structuredMetadata, labels.Label{Name: l.Name, Value: l.Value})
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	for _, r := range c.rules {
+		r.query.observe(stream, entry.Timestamp, entry.Line, structuredMetadata)
+	}
+}
+
+// evaluate evaluates all the rules, and queues their alerts to be sent.
+func (c *Component) evaluate(now time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// Alerts are valid for several evaluations, so that the Alertmanager
+	// doesn't resolve them if a notification fails.
+	validUntil := now.Add(4 * c.args.EvaluationInterval)
+
+	var alerts []alertmanagerAlert
+	for _, r := range c.rules {
+		for _, a := range r.eval(now) {
+			alerts = append(alerts, c.toAlertmanagerAlert(a, validUntil))
+		}
+
+		pending, firing := r.stateCounts()
+		c.metrics.alerts.WithLabelValues(r.cfg.Alert, statePending).Set(float64(pending))
+		c.metrics.alerts.WithLabelValues(r.cfg.Alert, stateFiring).Set(float64(firing))
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	// Firing alerts are sent again on each evaluation, so if the previous
+	// notification is still queued, it's replaced by this one.
+	select {
+	case <-c.notifications:
+		c.metrics.notificationsFailed.Inc()
+		level.Warn(c.opts.Logger).Log("msg", "dropping alerts of a previous evaluation which weren't sent yet")
+	default:
+	}
+	c.notifications <- alerts
+}
+
+func (c *Component) toAlertmanagerAlert(a *alert, validUntil time.Time) alertmanagerAlert {
+	lbs := a.labels.Map()
+	for name, value := range c.args.ExternalLabels {
+		if _, ok := lbs[name]; !ok {
+			lbs[name] = value
+		}
+	}
+
+	res := alertmanagerAlert{
+		Labels:      lbs,
+		Annotations: a.annotations,
+		StartsAt:    a.firedAt,
+		EndsAt:      validUntil,
+	}
+	if !a.resolvedAt.IsZero() {
+		res.EndsAt = a.resolvedAt
+	}
+	return res
+}
+
+// runNotifier sends the queued alerts to the Alertmanager.
+func (c *Component) runNotifier(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alerts := <-c.notifications:
+			c.mut.RLock()
+			client := c.alertmanager
+			c.mut.RUnlock()
+
+			c.metrics.notificationsSent.Inc()
+			if err := client.send(ctx, alerts); err != nil {
+				c.metrics.notificationsFailed.Inc()
+				level.Error(c.opts.Logger).Log("msg", "failed to send alerts to alertmanager", "count", len(alerts), "err", err)
+				c.reportUnhealthy(err)
+				continue
+			}
+			c.reportHealthy()
+		}
+	}
+}
+
+func (c *Component) reportUnhealthy(err error) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = component.Health{
+		Health:     component.HealthTypeUnhealthy,
+		Message:    err.Error(),
+		UpdateTime: time.Now(),
+	}
+}
+
+func (c *Component) reportHealthy() {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = component.Health{
+		Health:     component.HealthTypeHealthy,
+		UpdateTime: time.Now(),
+	}
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
+
+func toLabels(ls model.LabelSet) labels.Labels {
+	b := labels.NewScratchBuilder(len(ls))
+	for name, value := range ls {
+		b.Add(string(name), string(value))
+	}
+	b.Sort()
+	return b.Labels()
+}
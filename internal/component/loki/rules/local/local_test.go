@@ -0,0 +1,200 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments_Alloy(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(`
+		external_labels = {"site" = "edge-1"}
+
+		alertmanager {
+			url = "http://alertmanager:9093"
+		}
+
+		rule {
+			alert       = "HighErrorRate"
+			expr        = "sum by (app) (rate({app=\"api\"} |= \"error\" [5m])) > 1"
+			for         = "2m"
+			labels      = {"severity" = "page"}
+			annotations = {"summary" = "{{ $labels.app }} logs {{ $value }} errors per second"}
+		}
+	`), &args)
+	require.NoError(t, err)
+	require.Equal(t, 15*time.Second, args.EvaluationInterval)
+	require.Equal(t, 10*time.Second, args.Alertmanager.Timeout)
+	require.Len(t, args.Rules, 1)
+	require.Equal(t, 2*time.Minute, args.Rules[0].For)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		errSubstring string
+	}{
+		{
+			name: "invalid expression",
+			config: `
+				alertmanager { url = "http://alertmanager:9093" }
+				rule {
+					alert = "A"
+					expr  = "{app=\"api\"}"
+				}`,
+			errSubstring: `invalid expression for rule "A"`,
+		},
+		{
+			name: "invalid annotation",
+			config: `
+				alertmanager { url = "http://alertmanager:9093" }
+				rule {
+					alert       = "A"
+					expr        = "count_over_time({app=\"api\"}[1m])"
+					annotations = {"summary" = "{{ $labels.app"}
+				}`,
+			errSubstring: `invalid annotations for rule "A"`,
+		},
+		{
+			name: "duplicate rule",
+			config: `
+				alertmanager { url = "http://alertmanager:9093" }
+				rule {
+					alert = "A"
+					expr  = "count_over_time({app=\"api\"}[1m])"
+				}
+				rule {
+					alert = "A"
+					expr  = "count_over_time({app=\"api\"}[1m])"
+				}`,
+			errSubstring: `duplicate rule "A"`,
+		},
+		{
+			name: "invalid url",
+			config: `
+				alertmanager { url = "alertmanager:9093" }`,
+			errSubstring: "invalid alertmanager url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tt.config), &args)
+			require.ErrorContains(t, err, tt.errSubstring)
+		})
+	}
+}
+
+func TestRule_Eval(t *testing.T) {
+	r, err := newRule(RuleConfig{
+		Alert:       "Errors",
+		Expr:        `count_over_time({app="api"} |= "error" [1m]) > 1`,
+		For:         time.Minute,
+		Labels:      map[string]string{"severity": "page"},
+		Annotations: map[string]string{"summary": "{{ $labels.app }} logged {{ $value }} errors"},
+	})
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	stream := toLabels(model.LabelSet{"app": "api"})
+	observe := func(ts time.Time, n int) {
+		for i := 0; i < n; i++ {
+			r.query.observe(stream, ts, "error", nil)
+		}
+	}
+
+	// The alert is pending until its condition has been true for a minute.
+	observe(now, 2)
+	require.Empty(t, r.eval(now))
+	pending, firing := r.stateCounts()
+	require.Equal(t, 1, pending)
+	require.Equal(t, 0, firing)
+
+	now = now.Add(time.Minute)
+	observe(now, 3)
+	alerts := r.eval(now)
+	require.Len(t, alerts, 1)
+	require.Equal(t, `{alertname="Errors", app="api", severity="page"}`, alerts[0].labels.String())
+	require.Equal(t, map[string]string{"summary": "api logged 3 errors"}, alerts[0].annotations)
+	require.Equal(t, now, alerts[0].firedAt)
+	require.True(t, alerts[0].resolvedAt.IsZero())
+
+	// The alert is resolved once, when its condition stops being true.
+	now = now.Add(time.Minute)
+	alerts = r.eval(now)
+	require.Len(t, alerts, 1)
+	require.Equal(t, now, alerts[0].resolvedAt)
+	require.Empty(t, r.eval(now.Add(time.Minute)))
+}
+
+func TestComponent(t *testing.T) {
+	var (
+		mut      sync.Mutex
+		received []alertmanagerAlert
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/alerts", r.URL.Path)
+		var alerts []alertmanagerAlert
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&alerts))
+		mut.Lock()
+		received = append(received, alerts...)
+		mut.Unlock()
+	}))
+	defer srv.Close()
+
+	var args Arguments
+	args.SetToDefault()
+	args.EvaluationInterval = 50 * time.Millisecond
+	args.ExternalLabels = map[string]string{"site": "edge-1"}
+	args.Alertmanager.SetToDefault()
+	args.Alertmanager.URL = srv.URL
+	args.Rules = []RuleConfig{{
+		Alert: "Panics",
+		Expr:  `sum by (app) (count_over_time({app="api"} |= "panic" [1m])) > 0`,
+	}}
+
+	c, err := New(component.Options{
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	c.receiver.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"app": "api", "pod": "a"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "panic: runtime error"},
+	}
+
+	require.Eventually(t, func() bool {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(received) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mut.Lock()
+	defer mut.Unlock()
+	require.Equal(t, map[string]string{"alertname": "Panics", "app": "api", "site": "edge-1"}, received[0].Labels)
+	require.True(t, received[0].EndsAt.After(received[0].StartsAt))
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+}
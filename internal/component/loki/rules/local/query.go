@@ -0,0 +1,328 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/loki/v3/pkg/logql/log"
+	"github.com/grafana/loki/v3/pkg/logql/syntax"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// query evaluates a LogQL metric query over the log entries it observes,
+// which are kept in memory for the duration of the range of the query.
+//
+// Only a subset of LogQL is supported: a single range aggregation of a log
+// selector, optionally wrapped by vector aggregations and by binary
+// operations with a number on their right-hand side.
+type query struct {
+	expr      syntax.SampleExpr
+	rng       *syntax.RangeAggregationExpr
+	matchers  []*labels.Matcher
+	extractor log.SampleExtractor
+
+	series map[uint64]*series
+}
+
+// series holds the values extracted from the log entries of a series,
+// aggregated per second.
+type series struct {
+	labels  labels.Labels
+	buckets []bucket
+}
+
+type bucket struct {
+	ts       int64 // Unix time in seconds.
+	count    int
+	sum      float64
+	min, max float64
+}
+
+// sample is the value of a series at evaluation time.
+type sample struct {
+	labels labels.Labels
+	value  float64
+}
+
+// parseQuery parses a LogQL metric query, and returns an error if it uses
+// LogQL features which aren't supported.
+func parseQuery(s string) (*query, error) {
+	expr, err := syntax.ParseSampleExpr(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var rng *syntax.RangeAggregationExpr
+	if err := validateExpr(expr, &rng); err != nil {
+		return nil, err
+	}
+
+	extractor, err := rng.Extractor()
+	if err != nil {
+		return nil, err
+	}
+
+	return &query{
+		expr:      expr,
+		rng:       rng,
+		matchers:  rng.Left.Left.Matchers(),
+		extractor: extractor,
+		series:    make(map[uint64]*series),
+	}, nil
+}
+
+func validateExpr(expr syntax.SampleExpr, rng **syntax.RangeAggregationExpr) error {
+	switch e := expr.(type) {
+	case *syntax.RangeAggregationExpr:
+		switch e.Operation {
+		case syntax.OpRangeTypeCount, syntax.OpRangeTypeRate, syntax.OpRangeTypeBytes, syntax.OpRangeTypeBytesRate,
+			syntax.OpRangeTypeSum, syntax.OpRangeTypeAvg, syntax.OpRangeTypeMin, syntax.OpRangeTypeMax:
+		default:
+			return fmt.Errorf("unsupported range aggregation %q", e.Operation)
+		}
+		if e.Left.Offset != 0 {
+			return errors.New("offset modifier is not supported")
+		}
+		*rng = e
+		return nil
+
+	case *syntax.VectorAggregationExpr:
+		switch e.Operation {
+		case syntax.OpTypeSum, syntax.OpTypeAvg, syntax.OpTypeMin, syntax.OpTypeMax, syntax.OpTypeCount:
+		default:
+			return fmt.Errorf("unsupported vector aggregation %q", e.Operation)
+		}
+		return validateExpr(e.Left, rng)
+
+	case *syntax.BinOpExpr:
+		switch e.Op {
+		case syntax.OpTypeAdd, syntax.OpTypeSub, syntax.OpTypeMul, syntax.OpTypeDiv, syntax.OpTypeMod, syntax.OpTypePow,
+			syntax.OpTypeCmpEQ, syntax.OpTypeNEQ, syntax.OpTypeGT, syntax.OpTypeGTE, syntax.OpTypeLT, syntax.OpTypeLTE:
+		default:
+			return fmt.Errorf("unsupported binary operation %q", e.Op)
+		}
+		if e.Opts != nil && e.Opts.ReturnBool {
+			return errors.New("bool modifier is not supported")
+		}
+		if _, ok := e.RHS.(*syntax.LiteralExpr); !ok {
+			return fmt.Errorf("the right-hand side of %q must be a number", e.Op)
+		}
+		return validateExpr(e.SampleExpr, rng)
+
+	default:
+		return fmt.Errorf("unsupported expression %q", expr.String())
+	}
+}
+
+// observe adds a log entry to the series it belongs to, if it's selected by the query.
+func (q *query) observe(stream labels.Labels, ts time.Time, line string, structuredMetadata []labels.Label) {
+	for _, m := range q.matchers {
+		if !m.Matches(stream.Get(m.Name)) {
+			return
+		}
+	}
+
+	value, lbs, ok := q.extractor.ForStream(stream).ProcessString(ts.UnixNano(), line, structuredMetadata...)
+	if !ok {
+		return
+	}
+
+	s, ok := q.series[lbs.Hash()]
+	if !ok {
+		s = &series{labels: lbs.Labels().Copy()}
+		q.series[lbs.Hash()] = s
+	}
+	s.add(ts.Unix(), value)
+}
+
+func (s *series) add(ts int64, value float64) {
+	if n := len(s.buckets); n > 0 && s.buckets[n-1].ts == ts {
+		b := &s.buckets[n-1]
+		b.count++
+		b.sum += value
+		b.min = math.Min(b.min, value)
+		b.max = math.Max(b.max, value)
+		return
+	}
+	s.buckets = append(s.buckets, bucket{ts: ts, count: 1, sum: value, min: value, max: value})
+}
+
+// eval evaluates the query at the given time, and forgets the log entries
+// which are older than the range of the query.
+func (q *query) eval(now time.Time) []sample {
+	res := evalExpr(q.expr, q.rangeVector(now))
+
+	// The extractor caches a pipeline per stream, so recreate it to forget
+	// the streams which are no longer sending log entries.
+	if extractor, err := q.rng.Extractor(); err == nil {
+		q.extractor = extractor
+	}
+	return res
+}
+
+// rangeVector returns the result of the range aggregation of the query, at
+// the given time. Log entries are aggregated per second, so the range has a
+// resolution of one second.
+func (q *query) rangeVector(now time.Time) []sample {
+	var (
+		interval = q.rng.Left.Interval
+		start    = now.Add(-interval).Unix()
+		end      = now.Unix()
+		res      = make([]sample, 0, len(q.series))
+	)
+
+	for h, s := range q.series {
+		var (
+			kept   = s.buckets[:0]
+			count  int
+			sum    float64
+			lo, hi = math.Inf(1), math.Inf(-1)
+		)
+		for _, b := range s.buckets {
+			if b.ts <= start {
+				continue
+			}
+			kept = append(kept, b)
+			// Entries with a timestamp in the future are kept for later evaluations.
+			if b.ts > end {
+				continue
+			}
+			count += b.count
+			sum += b.sum
+			lo = math.Min(lo, b.min)
+			hi = math.Max(hi, b.max)
+		}
+		s.buckets = kept
+
+		if len(s.buckets) == 0 {
+			delete(q.series, h)
+		}
+		if count == 0 {
+			continue
+		}
+
+		var value float64
+		switch q.rng.Operation {
+		case syntax.OpRangeTypeCount:
+			value = float64(count)
+		case syntax.OpRangeTypeRate, syntax.OpRangeTypeBytesRate:
+			value = sum / interval.Seconds()
+		case syntax.OpRangeTypeBytes, syntax.OpRangeTypeSum:
+			value = sum
+		case syntax.OpRangeTypeAvg:
+			value = sum / float64(count)
+		case syntax.OpRangeTypeMin:
+			value = lo
+		case syntax.OpRangeTypeMax:
+			value = hi
+		}
+		res = append(res, sample{labels: s.labels, value: value})
+	}
+	return res
+}
+
+// evalExpr evaluates the vector aggregations and binary operations of expr,
+// given the result of its range aggregation.
+func evalExpr(expr syntax.SampleExpr, vec []sample) []sample {
+	switch e := expr.(type) {
+	case *syntax.VectorAggregationExpr:
+		return aggregate(e, evalExpr(e.Left, vec))
+
+	case *syntax.BinOpExpr:
+		// The right-hand side was validated to be a number when parsing the query.
+		rhs, _ := e.RHS.(*syntax.LiteralExpr).Value()
+		in := evalExpr(e.SampleExpr, vec)
+		res := in[:0]
+		for _, s := range in {
+			if v, ok := binOp(e.Op, s.value, rhs); ok {
+				res = append(res, sample{labels: s.labels, value: v})
+			}
+		}
+		return res
+
+	default:
+		return vec
+	}
+}
+
+func aggregate(e *syntax.VectorAggregationExpr, vec []sample) []sample {
+	type group struct {
+		sample
+		count int
+	}
+
+	var (
+		groups = make(map[uint64]*group)
+		order  []uint64
+	)
+	for _, s := range vec {
+		var lbs labels.Labels
+		if e.Grouping != nil && (len(e.Grouping.Groups) > 0 || e.Grouping.Without) {
+			lbs = s.labels.MatchLabels(!e.Grouping.Without, e.Grouping.Groups...)
+		}
+
+		h := lbs.Hash()
+		g, ok := groups[h]
+		if !ok {
+			groups[h] = &group{sample: sample{labels: lbs, value: s.value}, count: 1}
+			order = append(order, h)
+			continue
+		}
+		g.count++
+		switch e.Operation {
+		case syntax.OpTypeSum, syntax.OpTypeAvg:
+			g.value += s.value
+		case syntax.OpTypeMin:
+			g.value = math.Min(g.value, s.value)
+		case syntax.OpTypeMax:
+			g.value = math.Max(g.value, s.value)
+		}
+	}
+
+	res := make([]sample, 0, len(groups))
+	for _, h := range order {
+		g := groups[h]
+		switch e.Operation {
+		case syntax.OpTypeAvg:
+			g.value /= float64(g.count)
+		case syntax.OpTypeCount:
+			g.value = float64(g.count)
+		}
+		res = append(res, g.sample)
+	}
+	return res
+}
+
+// binOp applies a binary operation to a value. Comparisons return the value
+// unchanged, and whether it must be kept.
+func binOp(op string, lhs, rhs float64) (float64, bool) {
+	switch op {
+	case syntax.OpTypeAdd:
+		return lhs + rhs, true
+	case syntax.OpTypeSub:
+		return lhs - rhs, true
+	case syntax.OpTypeMul:
+		return lhs * rhs, true
+	case syntax.OpTypeDiv:
+		return lhs / rhs, true
+	case syntax.OpTypeMod:
+		return math.Mod(lhs, rhs), true
+	case syntax.OpTypePow:
+		return math.Pow(lhs, rhs), true
+	case syntax.OpTypeCmpEQ:
+		return lhs, lhs == rhs
+	case syntax.OpTypeNEQ:
+		return lhs, lhs != rhs
+	case syntax.OpTypeGT:
+		return lhs, lhs > rhs
+	case syntax.OpTypeGTE:
+		return lhs, lhs >= rhs
+	case syntax.OpTypeLT:
+		return lhs, lhs < rhs
+	case syntax.OpTypeLTE:
+		return lhs, lhs <= rhs
+	}
+	return 0, false
+}
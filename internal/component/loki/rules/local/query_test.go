@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery_Unsupported(t *testing.T) {
+	tests := map[string]string{
+		"log query":              `{app="api"} |= "error"`,
+		"unsupported range":      `quantile_over_time(0.99, {app="api"} | unwrap latency [5m])`,
+		"unsupported vector":     `topk(3, count_over_time({app="api"}[5m]))`,
+		"offset":                 `count_over_time({app="api"}[5m] offset 1m)`,
+		"bool modifier":          `count_over_time({app="api"}[5m]) > bool 10`,
+		"vector right-hand side": `count_over_time({app="api"}[5m]) / count_over_time({app="db"}[5m])`,
+		"label_replace":          `label_replace(count_over_time({app="api"}[5m]), "a", "$1", "app", "(.*)")`,
+		"invalid syntax":         `count_over_time({app="api"})`,
+	}
+	for name, expr := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseQuery(expr)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestQuery_Eval(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	entries := []struct {
+		stream labels.Labels
+		ts     time.Time
+		line   string
+	}{
+		{labels.FromStrings("app", "api", "pod", "a"), now.Add(-10 * time.Minute), "level=error latency=100"},
+		{labels.FromStrings("app", "api", "pod", "a"), now.Add(-2 * time.Minute), "level=error latency=30"},
+		{labels.FromStrings("app", "api", "pod", "a"), now.Add(-1 * time.Minute), "level=error latency=10"},
+		{labels.FromStrings("app", "api", "pod", "a"), now.Add(-1 * time.Minute), "level=info latency=20"},
+		{labels.FromStrings("app", "api", "pod", "b"), now.Add(-30 * time.Second), "level=error latency=50"},
+		{labels.FromStrings("app", "db", "pod", "c"), now.Add(-30 * time.Second), "level=error latency=1"},
+		{labels.FromStrings("app", "api", "pod", "b"), now.Add(time.Minute), "level=error latency=1000"},
+	}
+
+	tests := map[string]struct {
+		expr     string
+		expected map[string]float64
+	}{
+		"count_over_time": {
+			`count_over_time({app="api"} |= "level=error" [5m])`,
+			map[string]float64{`{app="api", pod="a"}`: 2, `{app="api", pod="b"}`: 1},
+		},
+		"rate": {
+			`rate({app="api"}[5m])`,
+			map[string]float64{`{app="api", pod="a"}`: 3.0 / 300, `{app="api", pod="b"}`: 1.0 / 300},
+		},
+		"bytes_over_time": {
+			`bytes_over_time({app="db"}[5m])`,
+			map[string]float64{`{app="db", pod="c"}`: 21},
+		},
+		"sum by": {
+			`sum by (app) (count_over_time({app=~".+"}[5m]))`,
+			map[string]float64{`{app="api"}`: 4, `{app="db"}`: 1},
+		},
+		"count without": {
+			`count without (pod) (count_over_time({app=~".+"}[5m]))`,
+			map[string]float64{`{app="api"}`: 2, `{app="db"}`: 1},
+		},
+		"max_over_time with parser and unwrap": {
+			`max_over_time({app="api"} | logfmt | level="error" | unwrap latency [5m]) by (pod)`,
+			map[string]float64{`{pod="a"}`: 30, `{pod="b"}`: 50},
+		},
+		"avg_over_time": {
+			`avg_over_time({app="api", pod="a"} | logfmt | unwrap latency [5m]) by (pod)`,
+			map[string]float64{`{pod="a"}`: 20},
+		},
+		"arithmetic and comparison": {
+			`sum(count_over_time({app="api"}[5m])) * 10 > 30`,
+			map[string]float64{`{}`: 40},
+		},
+		"comparison filters series": {
+			`count_over_time({app="api"}[5m]) >= 3`,
+			map[string]float64{`{app="api", pod="a"}`: 3},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			q, err := parseQuery(tt.expr)
+			require.NoError(t, err)
+			for _, e := range entries {
+				q.observe(e.stream, e.ts, e.line, nil)
+			}
+			require.Equal(t, tt.expected, samplesToMap(q.eval(now)))
+		})
+	}
+}
+
+func TestQuery_EvalForgetsOldEntries(t *testing.T) {
+	q, err := parseQuery(`count_over_time({app="api"}[1m])`)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	stream := labels.FromStrings("app", "api")
+	q.observe(stream, now.Add(-30*time.Second), "a", nil)
+	q.observe(stream, now.Add(30*time.Second), "b", nil)
+
+	require.Equal(t, map[string]float64{`{app="api"}`: 1}, samplesToMap(q.eval(now)))
+	require.Equal(t, map[string]float64{`{app="api"}`: 1}, samplesToMap(q.eval(now.Add(time.Minute))))
+	require.Empty(t, q.eval(now.Add(2*time.Minute)))
+	require.Empty(t, q.series)
+}
+
+func samplesToMap(samples []sample) map[string]float64 {
+	res := make(map[string]float64, len(samples))
+	for _, s := range samples {
+		res[s.labels.String()] = s.value
+	}
+	return res
+}
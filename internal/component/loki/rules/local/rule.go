@@ -0,0 +1,134 @@
+package rules
+
+import (
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// Alert states, as reported by the loki_rules_local_alerts metric.
+const (
+	statePending = "pending"
+	stateFiring  = "firing"
+)
+
+// rule is an alerting rule, and the state of its alerts.
+type rule struct {
+	cfg         RuleConfig
+	query       *query
+	annotations map[string]*template.Template
+
+	alerts map[uint64]*alert
+}
+
+// alert is an active alert of a rule.
+type alert struct {
+	labels      labels.Labels
+	annotations map[string]string
+	value       float64
+
+	activeAt   time.Time
+	firedAt    time.Time
+	resolvedAt time.Time
+}
+
+func newRule(cfg RuleConfig) (*rule, error) {
+	q, err := parseQuery(cfg.Expr)
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := parseAnnotations(cfg.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	return &rule{
+		cfg:         cfg,
+		query:       q,
+		annotations: annotations,
+		alerts:      make(map[uint64]*alert),
+	}, nil
+}
+
+// eval evaluates the rule at the given time, and returns the alerts which
+// are firing, and the ones which were resolved by this evaluation.
+func (r *rule) eval(now time.Time) []*alert {
+	active := make(map[uint64]struct{})
+	for _, s := range r.query.eval(now) {
+		b := labels.NewBuilder(s.labels)
+		for name, value := range r.cfg.Labels {
+			b.Set(name, value)
+		}
+		b.Set(labels.AlertName, r.cfg.Alert)
+		lbs := b.Labels()
+
+		h := lbs.Hash()
+		active[h] = struct{}{}
+		a, ok := r.alerts[h]
+		if !ok {
+			a = &alert{labels: lbs, activeAt: now}
+			r.alerts[h] = a
+		}
+		a.value = s.value
+		a.annotations = r.expandAnnotations(lbs, s.value)
+		if a.firedAt.IsZero() && now.Sub(a.activeAt) >= r.cfg.For {
+			a.firedAt = now
+		}
+	}
+
+	var res []*alert
+	for h, a := range r.alerts {
+		if _, ok := active[h]; !ok {
+			delete(r.alerts, h)
+			if a.firedAt.IsZero() {
+				continue
+			}
+			a.resolvedAt = now
+		}
+		if !a.firedAt.IsZero() {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// expandAnnotations executes the annotation templates of the rule. An
+// annotation whose template fails is set to the error message, as in
+// Prometheus alerting rules.
+func (r *rule) expandAnnotations(lbs labels.Labels, value float64) map[string]string {
+	if len(r.annotations) == 0 {
+		return nil
+	}
+
+	data := struct {
+		Labels map[string]string
+		Value  float64
+	}{
+		Labels: lbs.Map(),
+		Value:  value,
+	}
+
+	res := make(map[string]string, len(r.annotations))
+	for name, tmpl := range r.annotations {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			res[name] = "error expanding template: " + err.Error()
+			continue
+		}
+		res[name] = sb.String()
+	}
+	return res
+}
+
+// stateCounts returns the number of pending and firing alerts of the rule.
+func (r *rule) stateCounts() (pending, firing int) {
+	for _, a := range r.alerts {
+		if a.firedAt.IsZero() {
+			pending++
+		} else {
+			firing++
+		}
+	}
+	return pending, firing
+}
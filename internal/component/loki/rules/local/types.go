@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/common/config"
+)
+
+// Arguments holds values which are used to configure the loki.rules.local
+// component.
+type Arguments struct {
+	EvaluationInterval time.Duration     `alloy:"evaluation_interval,attr,optional"`
+	ExternalLabels     map[string]string `alloy:"external_labels,attr,optional"`
+
+	Alertmanager AlertmanagerConfig `alloy:"alertmanager,block"`
+	Rules        []RuleConfig       `alloy:"rule,block,optional"`
+}
+
+// AlertmanagerConfig configures the Alertmanager which receives the alerts.
+type AlertmanagerConfig struct {
+	URL              string                  `alloy:"url,attr"`
+	Timeout          time.Duration           `alloy:"timeout,attr,optional"`
+	HTTPClientConfig config.HTTPClientConfig `alloy:",squash"`
+}
+
+// RuleConfig configures an alerting rule.
+type RuleConfig struct {
+	Alert       string            `alloy:"alert,attr"`
+	Expr        string            `alloy:"expr,attr"`
+	For         time.Duration     `alloy:"for,attr,optional"`
+	Labels      map[string]string `alloy:"labels,attr,optional"`
+	Annotations map[string]string `alloy:"annotations,attr,optional"`
+}
+
+// DefaultArguments defines the default settings for the loki.rules.local component.
+var DefaultArguments = Arguments{
+	EvaluationInterval: 15 * time.Second,
+}
+
+// DefaultAlertmanagerConfig defines the default settings of the Alertmanager client.
+var DefaultAlertmanagerConfig = AlertmanagerConfig{
+	Timeout:          10 * time.Second,
+	HTTPClientConfig: config.DefaultHTTPClientConfig,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.EvaluationInterval <= 0 {
+		return fmt.Errorf("evaluation_interval must be greater than 0")
+	}
+
+	seen := make(map[string]struct{}, len(args.Rules))
+	for _, r := range args.Rules {
+		key := r.Alert + "\xff" + r.Expr
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("duplicate rule %q with expression %q", r.Alert, r.Expr)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *AlertmanagerConfig) SetToDefault() {
+	*c = DefaultAlertmanagerConfig
+}
+
+// Validate implements syntax.Validator.
+func (c *AlertmanagerConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid alertmanager url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid alertmanager url %q: scheme must be http or https", c.URL)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+
+	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
+	return c.HTTPClientConfig.Validate()
+}
+
+// Validate implements syntax.Validator.
+func (r *RuleConfig) Validate() error {
+	if r.Alert == "" {
+		return fmt.Errorf("alert must not be empty")
+	}
+	if r.For < 0 {
+		return fmt.Errorf("for must not be negative")
+	}
+	if _, err := parseQuery(r.Expr); err != nil {
+		return fmt.Errorf("invalid expression for rule %q: %w", r.Alert, err)
+	}
+	if _, err := parseAnnotations(r.Annotations); err != nil {
+		return fmt.Errorf("invalid annotations for rule %q: %w", r.Alert, err)
+	}
+	return nil
+}
+
+// annotationTemplatePrefix defines the variables which annotations can use,
+// as in Prometheus alerting rules.
+const annotationTemplatePrefix = "{{$labels := .Labels}}{{$value := .Value}}"
+
+// parseAnnotations parses the templates of the annotations of a rule.
+func parseAnnotations(annotations map[string]string) (map[string]*template.Template, error) {
+	res := make(map[string]*template.Template, len(annotations))
+	for name, text := range annotations {
+		tmpl, err := template.New(name).Option("missingkey=zero").Parse(annotationTemplatePrefix + text)
+		if err != nil {
+			return nil, err
+		}
+		res[name] = tmpl
+	}
+	return res, nil
+}
@@ -0,0 +1,31 @@
+package secretfilter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/util"
+)
+
+type metrics struct {
+	secretsRedacted    *prometheus.CounterVec
+	secretsAllowlisted *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	var m metrics
+	m.secretsRedacted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_secretfilter_secrets_redacted_total",
+		Help: "Total number of secrets redacted, partitioned by rule and location in the log entry.",
+	}, []string{"rule", "location"})
+	m.secretsAllowlisted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_secretfilter_secrets_allowlisted_total",
+		Help: "Total number of secrets found but not redacted because of an allowlist, partitioned by rule and location in the log entry.",
+	}, []string{"rule", "location"})
+
+	if reg != nil {
+		m.secretsRedacted = util.MustRegisterOrGet(reg, m.secretsRedacted).(*prometheus.CounterVec)
+		m.secretsAllowlisted = util.MustRegisterOrGet(reg, m.secretsAllowlisted).(*prometheus.CounterVec)
+	}
+
+	return &m
+}
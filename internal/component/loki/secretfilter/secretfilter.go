@@ -5,6 +5,7 @@ import (
 	"crypto/sha1"
 	"embed"
 	"fmt"
+	"math"
 	"regexp"
 	"strings"
 	"sync"
@@ -15,6 +16,8 @@ import (
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
 	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/common/model"
 )
 
 //go:embed gitleaks.toml
@@ -30,8 +33,18 @@ type Rule struct {
 	regex       *regexp.Regexp
 	secretGroup int
 	allowlist   []AllowRule
+	keywords    []string // Lowercase keywords, one of which must be in the text for the rule to apply
+	entropy     float64  // Minimum Shannon entropy of a secret
 }
 
+// Locations of the secrets in a log entry, used as the value of the location
+// label of the metrics.
+const (
+	locationLine               = "line"
+	locationStructuredMetadata = "structured_metadata"
+	locationLabel              = "label"
+)
+
 func init() {
 	component.Register(component.Registration{
 		Name:      "loki.secretfilter",
@@ -55,6 +68,20 @@ type Arguments struct {
 	IncludeGeneric bool                `alloy:"include_generic,attr,optional"` // Include the generic API key rule (default: false)
 	AllowList      []string            `alloy:"allowlist,attr,optional"`       // List of regexes to allowlist (on top of what's in the Gitleaks config)
 	PartialMask    uint                `alloy:"partial_mask,attr,optional"`    // Show the first N characters of the secret (default: 0)
+
+	RedactStructuredMetadata bool         `alloy:"redact_structured_metadata,attr,optional"` // Redact the secrets in the structured metadata values too
+	RedactLabels             bool         `alloy:"redact_labels,attr,optional"`              // Redact the secrets in the label values too
+	Rules                    []RuleConfig `alloy:"rule,block,optional"`                      // Rules to use on top of the ones of the Gitleaks config
+}
+
+// RuleConfig is a rule to detect secrets defined in the Alloy configuration.
+type RuleConfig struct {
+	Name        string   `alloy:"name,attr"`
+	Regex       string   `alloy:"regex,attr"`
+	SecretGroup int      `alloy:"secret_group,attr,optional"`
+	Keywords    []string `alloy:"keywords,attr,optional"`
+	Entropy     float64  `alloy:"entropy,attr,optional"`
+	AllowList   []string `alloy:"allowlist,attr,optional"`
 }
 
 // Exports holds the values exported by the loki.secretfilter component.
@@ -70,6 +97,43 @@ func (args *Arguments) SetToDefault() {
 	*args = DefaultArguments
 }
 
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	names := make(map[string]struct{}, len(args.Rules))
+	for _, rule := range args.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule name must not be empty")
+		}
+		if _, ok := names[rule.Name]; ok {
+			return fmt.Errorf("duplicate rule name %q", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex of rule %q: %w", rule.Name, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("regex of rule %q matches the empty string", rule.Name)
+		}
+		if re.MatchString(args.redactionString(rule.Name)) {
+			return fmt.Errorf("regex of rule %q matches the redaction string", rule.Name)
+		}
+		if rule.SecretGroup < 0 || rule.SecretGroup > re.NumSubexp() {
+			return fmt.Errorf("secret_group of rule %q must be between 0 and the number of groups of its regex, %d", rule.Name, re.NumSubexp())
+		}
+		if rule.Entropy < 0 {
+			return fmt.Errorf("entropy of rule %q must not be negative", rule.Name)
+		}
+		for _, r := range rule.AllowList {
+			if _, err := regexp.Compile(r); err != nil {
+				return fmt.Errorf("invalid allowlist regex of rule %q: %w", rule.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
 var (
 	_ component.Component     = (*Component)(nil)
 	_ component.LiveDebugging = (*Component)(nil)
@@ -77,7 +141,8 @@ var (
 
 // Component implements the loki.secretfilter component.
 type Component struct {
-	opts component.Options
+	opts    component.Options
+	metrics *metrics
 
 	mut       sync.RWMutex
 	args      Arguments
@@ -124,6 +189,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 	c := &Component{
 		opts:               o,
+		metrics:            newMetrics(o.Registerer),
 		receiver:           loki.NewLogsReceiver(),
 		debugDataPublisher: debugDataPublisher.(livedebugging.DebugDataPublisher),
 	}
@@ -153,7 +219,13 @@ func (c *Component) Run(ctx context.Context) error {
 			// Start processing the log entry to redact secrets
 			newEntry := c.processEntry(entry)
 			if c.debugDataPublisher.IsActive(componentID) {
-				c.debugDataPublisher.Publish(componentID, fmt.Sprintf("%s => %s", entry.Line, newEntry.Line))
+				before, after := entry.Line, newEntry.Line
+				c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.LokiLog,
+					1,
+					livedebugging.LabelsFromLabelSet(entry.Labels),
+					func() string { return fmt.Sprintf("%s => %s", before, after) },
+				))
 			}
 
 			for _, f := range c.fanout {
@@ -170,7 +242,43 @@ func (c *Component) Run(ctx context.Context) error {
 }
 
 func (c *Component) processEntry(entry loki.Entry) loki.Entry {
+	entry.Line = c.redact(entry.Line, locationLine)
+
+	// The structured metadata and labels are copied, as they may be shared
+	// with the entries sent to other components.
+	if c.args.RedactStructuredMetadata && len(entry.StructuredMetadata) > 0 {
+		metadata := make([]logproto.LabelAdapter, len(entry.StructuredMetadata))
+		for i, l := range entry.StructuredMetadata {
+			metadata[i] = logproto.LabelAdapter{Name: l.Name, Value: c.redact(l.Value, locationStructuredMetadata)}
+		}
+		entry.StructuredMetadata = metadata
+	}
+	if c.args.RedactLabels && len(entry.Labels) > 0 {
+		labels := make(model.LabelSet, len(entry.Labels))
+		for name, value := range entry.Labels {
+			labels[name] = model.LabelValue(c.redact(string(value), locationLabel))
+		}
+		entry.Labels = labels
+	}
+
+	return entry
+}
+
+// redact returns text with the secrets found by the rules redacted. The
+// location of the text in the log entry is only used for the metrics.
+func (c *Component) redact(text string, location string) string {
+	var lowerText string
 	for _, r := range c.Rules {
+		// Rules with keywords only apply to texts which contain one of them.
+		if len(r.keywords) > 0 {
+			if lowerText == "" {
+				lowerText = strings.ToLower(text)
+			}
+			if !containsAny(lowerText, r.keywords) {
+				continue
+			}
+		}
+
 		// To find the secret within the text captured by the regex (and avoid being too greedy), we can use the 'secretGroup' field in the gitleaks.toml file.
 		// But it's rare for regexes to have this field set, so we can use a simple heuristic in other cases.
 		//
@@ -180,7 +288,7 @@ func (c *Component) processEntry(entry loki.Entry) loki.Entry {
 		//
 		// For the first case, we can replace the entire match with the redaction string.
 		// For the second case, we can replace the first submatch with the redaction string (to avoid redacting something else than the secret such as delimiters).
-		for _, occ := range r.regex.FindAllStringSubmatch(entry.Line, -1) {
+		for _, occ := range r.regex.FindAllStringSubmatch(text, -1) {
 			// By default, the secret is the full match group
 			secret := occ[0]
 
@@ -198,6 +306,12 @@ func (c *Component) processEntry(entry loki.Entry) loki.Entry {
 				continue
 			}
 
+			// If the secret isn't random enough, it's likely a false positive
+			if r.entropy > 0 && shannonEntropy(secret) < r.entropy {
+				level.Debug(c.opts.Logger).Log("msg", "secret below the entropy threshold", "rule", r.name)
+				continue
+			}
+
 			// Check if the secret is in the allowlist
 			var allowRule *AllowRule = nil
 			// First check the global allowlist
@@ -219,15 +333,42 @@ func (c *Component) processEntry(entry loki.Entry) loki.Entry {
 			// If allowed, skip redaction
 			if allowRule != nil {
 				level.Debug(c.opts.Logger).Log("msg", "secret in allowlist", "rule", r.name, "source", allowRule.Source)
+				c.metrics.secretsAllowlisted.WithLabelValues(r.name, location).Inc()
 				continue
 			}
 
 			// Redact the secret
-			entry.Line = c.redactLine(entry.Line, secret, r.name)
+			text = c.redactLine(text, secret, r.name)
+			c.metrics.secretsRedacted.WithLabelValues(r.name, location).Inc()
 		}
 	}
 
-	return entry
+	return text
+}
+
+func containsAny(text string, keywords []string) bool {
+	for _, k := range keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the Shannon entropy of s, in bits per character.
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	var total int
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
 }
 
 func (c *Component) redactLine(line string, secret string, ruleName string) string {
@@ -259,6 +400,15 @@ func (c *Component) redactLine(line string, secret string, ruleName string) stri
 	return line
 }
 
+// redactionString returns the string the secrets found by a rule are
+// redacted with, without their hash.
+func (args *Arguments) redactionString(ruleName string) string {
+	if args.RedactWith == "" {
+		return "<REDACTED-SECRET:" + ruleName + ">"
+	}
+	return strings.ReplaceAll(args.RedactWith, "$SECRET_NAME", ruleName)
+}
+
 func hashSecret(secret string) string {
 	hasher := sha1.New()
 	hasher.Write([]byte(secret))
@@ -269,15 +419,49 @@ func hashSecret(secret string) string {
 func (c *Component) Update(args component.Arguments) error {
 	newArgs := args.(Arguments)
 
-	c.mut.Lock()
-	defer c.mut.Unlock()
-	c.args = newArgs
+	// The rules and the allowlist are only given to the component once they
+	// all compile, so it never runs without them.
+	var (
+		rules     []Rule
+		allowList []AllowRule
+	)
+
+	// Compile the rules of the Alloy config. They're used before the rules of
+	// the Gitleaks config, and replace the Gitleaks rules with the same name.
+	alloyRules := make(map[string]struct{}, len(newArgs.Rules))
+	for _, rule := range newArgs.Rules {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return err
+		}
 
-	c.fanout = newArgs.ForwardTo
+		var allowlist []AllowRule
+		for _, r := range rule.AllowList {
+			re, err := regexp.Compile(r)
+			if err != nil {
+				return err
+			}
+			allowlist = append(allowlist, AllowRule{Regex: re, Source: fmt.Sprintf("rule %s", rule.Name)})
+		}
+		keywords := make([]string, 0, len(rule.Keywords))
+		for _, k := range rule.Keywords {
+			keywords = append(keywords, strings.ToLower(k))
+		}
+
+		rules = append(rules, Rule{
+			name:        rule.Name,
+			regex:       re,
+			secretGroup: rule.SecretGroup,
+			allowlist:   allowlist,
+			keywords:    keywords,
+			entropy:     rule.Entropy,
+		})
+		alloyRules[rule.Name] = struct{}{}
+	}
 
 	// Parse GitLeaks configuration
 	var gitleaksCfg GitLeaksConfig
-	if c.args.GitleaksConfig == "" {
+	if newArgs.GitleaksConfig == "" {
 		// If no config file is explicitely provided, use the embedded one
 		_, err := toml.DecodeFS(embedFs, "gitleaks.toml", &gitleaksCfg)
 		if err != nil {
@@ -285,7 +469,7 @@ func (c *Component) Update(args component.Arguments) error {
 		}
 	} else {
 		// If a config file is provided, use that
-		_, err := toml.DecodeFile(c.args.GitleaksConfig, &gitleaksCfg)
+		_, err := toml.DecodeFile(newArgs.GitleaksConfig, &gitleaksCfg)
 		if err != nil {
 			return err
 		}
@@ -299,10 +483,14 @@ func (c *Component) Update(args component.Arguments) error {
 		if rule.Regex == "" {
 			continue
 		}
+		// If a rule of the Alloy config has the same name, skip this rule
+		if _, ok := alloyRules[rule.ID]; ok {
+			continue
+		}
 		// If specific secret types are provided, only include rules that match the types
-		if len(c.args.Types) > 0 {
+		if len(newArgs.Types) > 0 {
 			var found bool
-			for _, t := range c.args.Types {
+			for _, t := range newArgs.Types {
 				if strings.HasPrefix(strings.ToLower(rule.ID), strings.ToLower(t)) {
 					found = true
 					break
@@ -324,12 +512,7 @@ func (c *Component) Update(args component.Arguments) error {
 			continue
 		}
 		// If the rule regex matches the redaction string, skip this rule
-		redactionString := "<REDACTED-SECRET:" + rule.ID + ">"
-		if c.args.RedactWith != "" {
-			redactionString = c.args.RedactWith
-			redactionString = strings.ReplaceAll(redactionString, "$SECRET_NAME", rule.ID)
-		}
-		if re.Match([]byte(redactionString)) {
+		if re.Match([]byte(newArgs.redactionString(rule.ID))) {
 			level.Warn(c.opts.Logger).Log("msg", "excluded rule due to matching the redaction string", "rule", rule.ID)
 			continue
 		}
@@ -367,19 +550,19 @@ func (c *Component) Update(args component.Arguments) error {
 		if strings.ToLower(rule.ID) == "generic-api-key" {
 			ruleGenericApiKey = &newRule
 		} else {
-			c.Rules = append(c.Rules, newRule)
+			rules = append(rules, newRule)
 		}
 	}
 
 	// Compiling global allowlist regexes
 	// From the arguments
-	for _, r := range c.args.AllowList {
+	for _, r := range newArgs.AllowList {
 		re, err := regexp.Compile(r)
 		if err != nil {
 			level.Error(c.opts.Logger).Log("msg", "error compiling allowlist regex", "error", err)
 			return err
 		}
-		c.AllowList = append(c.AllowList, AllowRule{Regex: re, Source: "alloy config"})
+		allowList = append(allowList, AllowRule{Regex: re, Source: "alloy config"})
 	}
 	// From the Gitleaks config
 	for _, r := range gitleaksCfg.AllowList.Regexes {
@@ -388,14 +571,20 @@ func (c *Component) Update(args component.Arguments) error {
 			level.Error(c.opts.Logger).Log("msg", "error compiling allowlist regex", "error", err)
 			return err
 		}
-		c.AllowList = append(c.AllowList, AllowRule{Regex: re, Source: "gitleaks config"})
+		allowList = append(allowList, AllowRule{Regex: re, Source: "gitleaks config"})
 	}
 
 	// Add the generic API key rule last if needed
-	if ruleGenericApiKey != nil && c.args.IncludeGeneric {
-		c.Rules = append(c.Rules, *ruleGenericApiKey)
+	if ruleGenericApiKey != nil && newArgs.IncludeGeneric {
+		rules = append(rules, *ruleGenericApiKey)
 	}
 
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = newArgs
+	c.fanout = newArgs.ForwardTo
+	c.Rules, c.AllowList = rules, allowList
+
 	level.Info(c.opts.Logger).Log("Compiled regexes for secret detection", len(c.Rules))
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -16,6 +17,8 @@ import (
 	"github.com/grafana/alloy/syntax"
 	"github.com/grafana/loki/v3/pkg/logproto"
 	"github.com/jaswdr/faker/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 )
@@ -497,6 +500,196 @@ func checkPartialMasking(t *testing.T, partialMasking int, secretLength int, exp
 	require.Equal(t, expectedLog, redacted)
 }
 
+func TestAlloyRules(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	c := newTestComponent(t, registry, `
+		forward_to = []
+		types      = ["gcp"]
+
+		rule {
+			name     = "session-token"
+			regex    = "session=([A-Za-z0-9]{12,})"
+			keywords = ["SESSION="]
+			entropy  = 3
+		}
+
+		rule {
+			name      = "gcp-api-key"
+			regex     = "\\b(AIza[0-9A-Za-z_-]{35})\\b"
+			allowlist = ["AIzaA{35}"]
+		}
+	`)
+
+	gcpKey := "AI" + "za" + strings.Repeat("B", 35)
+	allowedGCPKey := "AI" + "za" + strings.Repeat("A", 35)
+	for input, expected := range map[string]string{
+		// The secret is random enough.
+		"session=a8Fk2Lq9Zx7Wp3 user=bob": "session=<REDACTED-SECRET:session-token> user=bob",
+		// The secret isn't random enough.
+		"session=aaaaaaaaaaaaaaaa user=bob": "session=aaaaaaaaaaaaaaaa user=bob",
+		// The rule of the Alloy config replaces the Gitleaks rule with the same name.
+		"key=" + gcpKey:        "key=<REDACTED-SECRET:gcp-api-key>",
+		"key=" + allowedGCPKey: "key=" + allowedGCPKey,
+	} {
+		entry := c.processEntry(loki.Entry{Labels: model.LabelSet{}, Entry: logproto.Entry{Timestamp: time.Now(), Line: input}})
+		require.Equal(t, expected, entry.Line)
+	}
+
+	// Without the keyword, the rule doesn't apply.
+	c = newTestComponent(t, prometheus.NewRegistry(), `
+		forward_to = []
+
+		rule {
+			name     = "session-token"
+			regex    = "session=([A-Za-z0-9]{12,})"
+			keywords = ["token"]
+		}
+	`)
+	entry := c.processEntry(loki.Entry{Labels: model.LabelSet{}, Entry: logproto.Entry{Timestamp: time.Now(), Line: "session=a8Fk2Lq9Zx7Wp3"}})
+	require.Equal(t, "session=a8Fk2Lq9Zx7Wp3", entry.Line)
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP loki_secretfilter_secrets_allowlisted_total Total number of secrets found but not redacted because of an allowlist, partitioned by rule and location in the log entry.
+# TYPE loki_secretfilter_secrets_allowlisted_total counter
+loki_secretfilter_secrets_allowlisted_total{location="line",rule="gcp-api-key"} 1
+# HELP loki_secretfilter_secrets_redacted_total Total number of secrets redacted, partitioned by rule and location in the log entry.
+# TYPE loki_secretfilter_secrets_redacted_total counter
+loki_secretfilter_secrets_redacted_total{location="line",rule="gcp-api-key"} 1
+loki_secretfilter_secrets_redacted_total{location="line",rule="session-token"} 1
+`)))
+}
+
+func TestRedactStructuredMetadataAndLabels(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	c := newTestComponent(t, registry, `
+		forward_to                 = []
+		types                      = ["gcp"]
+		redact_structured_metadata = true
+		redact_labels              = true
+	`)
+
+	secret := fakeSecrets["gcp-api-key"].value
+	entry := loki.Entry{
+		Labels: model.LabelSet{"job": "app", "url": model.LabelValue("https://example.com/?key=" + secret)},
+		Entry: logproto.Entry{
+			Timestamp:          time.Now(),
+			Line:               "no secret here",
+			StructuredMetadata: []logproto.LabelAdapter{{Name: "api_key", Value: secret}, {Name: "user", Value: "bob"}},
+		},
+	}
+	newEntry := c.processEntry(entry)
+
+	require.Equal(t, "no secret here", newEntry.Line)
+	require.Equal(t, model.LabelSet{"job": "app", "url": "https://example.com/?key=<REDACTED-SECRET:gcp-api-key>"}, newEntry.Labels)
+	require.Equal(t, []logproto.LabelAdapter{{Name: "api_key", Value: "<REDACTED-SECRET:gcp-api-key>"}, {Name: "user", Value: "bob"}}, []logproto.LabelAdapter(newEntry.StructuredMetadata))
+
+	// The original entry isn't modified.
+	require.Equal(t, model.LabelValue("https://example.com/?key="+secret), entry.Labels["url"])
+	require.Equal(t, secret, entry.StructuredMetadata[0].Value)
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP loki_secretfilter_secrets_redacted_total Total number of secrets redacted, partitioned by rule and location in the log entry.
+# TYPE loki_secretfilter_secrets_redacted_total counter
+loki_secretfilter_secrets_redacted_total{location="label",rule="gcp-api-key"} 1
+loki_secretfilter_secrets_redacted_total{location="structured_metadata",rule="gcp-api-key"} 1
+`), "loki_secretfilter_secrets_redacted_total"))
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		args Arguments
+
+		// Empty if no error expected, substring of error otherwise.
+		errSubstring string
+	}{
+		{
+			name: "valid rule",
+			args: Arguments{Rules: []RuleConfig{{Name: "a", Regex: "(abc)", SecretGroup: 1, AllowList: []string{"abc"}}}},
+		},
+		{
+			name:         "empty name",
+			args:         Arguments{Rules: []RuleConfig{{Name: "", Regex: "abc"}}},
+			errSubstring: "rule name must not be empty",
+		},
+		{
+			name:         "duplicate name",
+			args:         Arguments{Rules: []RuleConfig{{Name: "a", Regex: "abc"}, {Name: "a", Regex: "def"}}},
+			errSubstring: `duplicate rule name "a"`,
+		},
+		{
+			name:         "invalid regex",
+			args:         Arguments{Rules: []RuleConfig{{Name: "a", Regex: "(abc"}}},
+			errSubstring: `invalid regex of rule "a"`,
+		},
+		{
+			// A regex matching the empty string would redact every log entry.
+			name:         "regex matching the empty string",
+			args:         Arguments{Rules: []RuleConfig{{Name: "a", Regex: "a*"}}},
+			errSubstring: `regex of rule "a" matches the empty string`,
+		},
+		{
+			name:         "regex matching the redaction string",
+			args:         Arguments{RedactWith: "<HIDDEN>", Rules: []RuleConfig{{Name: "a", Regex: "<[A-Z]+>"}}},
+			errSubstring: `regex of rule "a" matches the redaction string`,
+		},
+		{
+			name:         "invalid secret_group",
+			args:         Arguments{Rules: []RuleConfig{{Name: "a", Regex: "(abc)", SecretGroup: 2}}},
+			errSubstring: `secret_group of rule "a"`,
+		},
+		{
+			name:         "negative entropy",
+			args:         Arguments{Rules: []RuleConfig{{Name: "a", Regex: "abc", Entropy: -1}}},
+			errSubstring: `entropy of rule "a" must not be negative`,
+		},
+		{
+			name:         "invalid allowlist",
+			args:         Arguments{Rules: []RuleConfig{{Name: "a", Regex: "abc", AllowList: []string{"(abc"}}}},
+			errSubstring: `invalid allowlist regex of rule "a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.Validate()
+			if tt.errSubstring == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.errSubstring)
+			}
+		})
+	}
+}
+
+func TestUpdateErrorKeepsRules(t *testing.T) {
+	c := newTestComponent(t, prometheus.NewRegistry(), `
+		forward_to = []
+		types      = ["gcp"]
+	`)
+
+	// The Gitleaks config of the new arguments can't be read.
+	err := c.Update(Arguments{Types: []string{"gcp"}, GitleaksConfig: filepath.Join(t.TempDir(), "missing.toml")})
+	require.Error(t, err)
+
+	secret := fakeSecrets["gcp-api-key"].value
+	entry := c.processEntry(loki.Entry{Labels: model.LabelSet{}, Entry: logproto.Entry{Timestamp: time.Now(), Line: "key=" + secret}})
+	require.Equal(t, "key=<REDACTED-SECRET:gcp-api-key>", entry.Line)
+}
+
+func newTestComponent(t *testing.T, registry prometheus.Registerer, config string) *Component {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(config), &args))
+	c, err := New(component.Options{
+		Logger:         util.TestLogger(t),
+		Registerer:     registry,
+		OnStateChange:  func(e component.Exports) {},
+		GetServiceData: getServiceData,
+	}, args)
+	require.NoError(t, err)
+	return c
+}
+
 func runTest(t *testing.T, config string, gitLeaksConfigContent string, inputLog string, expectedLog string) {
 	ch1 := loki.NewLogsReceiver()
 	var args Arguments
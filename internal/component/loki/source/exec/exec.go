@@ -0,0 +1,340 @@
+package exec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.exec",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// streamLabel is the label which holds the output stream, stdout or stderr,
+// of the entries.
+const streamLabel = "stream"
+
+// waitDelay is how long to wait for the output of a command to be closed
+// after it exits or is killed, in case it started processes which inherited it.
+const waitDelay = 5 * time.Second
+
+// Arguments holds values which are used to configure the loki.source.exec
+// component.
+type Arguments struct {
+	Command          []string            `alloy:"command,attr"`
+	Env              map[string]string   `alloy:"env,attr,optional"`
+	WorkingDirectory string              `alloy:"working_directory,attr,optional"`
+	Continuous       bool                `alloy:"continuous,attr,optional"`
+	Interval         time.Duration       `alloy:"interval,attr,optional"`
+	Timeout          time.Duration       `alloy:"timeout,attr,optional"`
+	Labels           map[string]string   `alloy:"labels,attr,optional"`
+	ForwardTo        []loki.LogsReceiver `alloy:"forward_to,attr"`
+}
+
+// DefaultArguments defines the default settings of the loki.source.exec component.
+var DefaultArguments = Arguments{
+	Interval: time.Minute,
+	Timeout:  30 * time.Second,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if len(args.Command) == 0 || args.Command[0] == "" {
+		return fmt.Errorf("command must not be empty")
+	}
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	for name := range args.Labels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	return nil
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// Component implements the loki.source.exec component.
+type Component struct {
+	opts    component.Options
+	metrics *metrics
+
+	// updated is signaled when the arguments change, so that the command
+	// is restarted with them.
+	updated chan struct{}
+
+	mut  sync.RWMutex
+	args Arguments
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+// New creates a new loki.source.exec component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+		updated: make(chan struct{}, 1),
+	}
+
+	// Call to Update() once at the start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	// Run starts with the initial arguments, so it doesn't need to be signaled.
+	<-c.updated
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		c.mut.RLock()
+		args := c.args
+		c.mut.RUnlock()
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.schedule(runCtx, args)
+		}()
+
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return nil
+		case <-c.updated:
+			cancel()
+			<-done
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.mut.Lock()
+	c.args = newArgs
+	c.mut.Unlock()
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// schedule runs the command until ctx is canceled. Commands which run
+// continuously are restarted when they exit, and the others are run on each
+// interval.
+func (c *Component) schedule(ctx context.Context, args Arguments) {
+	if args.Continuous {
+		for {
+			c.runCommand(ctx, args, 0)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(args.Interval):
+			}
+		}
+	}
+
+	ticker := time.NewTicker(args.Interval)
+	defer ticker.Stop()
+	for {
+		c.runCommand(ctx, args, args.Timeout)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runCommand runs the command once, and forwards its output until it exits.
+// A timeout of zero means that the command can run forever.
+func (c *Component) runCommand(ctx context.Context, args Arguments, timeout time.Duration) {
+	runCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := osexec.CommandContext(runCtx, args.Command[0], args.Command[1:]...)
+	cmd.Dir = args.WorkingDirectory
+	cmd.Env = os.Environ()
+	for name, value := range args.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
+
+	// The output is copied to pipes owned by the component rather than read
+	// from the pipes of the command, so that Wait returns when the command
+	// exits even if its children keep the output open, and closes the output
+	// after waitDelay.
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.forward(ctx, stdout, "stdout", args)
+		// Keep draining the output so that the command isn't blocked.
+		_, _ = io.Copy(io.Discard, stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		c.forward(ctx, stderr, "stderr", args)
+		_, _ = io.Copy(io.Discard, stderr)
+	}()
+	defer func() {
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		wg.Wait()
+	}()
+
+	start := time.Now()
+	c.metrics.runs.Inc()
+	if err := cmd.Start(); err != nil {
+		c.reportFailure(-1, fmt.Errorf("failed to start command: %w", err))
+		return
+	}
+	if args.Continuous {
+		c.reportHealthy()
+	}
+
+	err := cmd.Wait()
+	c.metrics.runDuration.Observe(time.Since(start).Seconds())
+
+	var exitErr *osexec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		// The component is shutting down or restarting the command.
+		level.Debug(c.opts.Logger).Log("msg", "command stopped", "command", args.Command[0])
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		c.reportFailure(-1, fmt.Errorf("command timed out after %s", timeout))
+	case errors.As(err, &exitErr):
+		c.reportFailure(exitErr.ExitCode(), fmt.Errorf("command exited with code %d", exitErr.ExitCode()))
+	case errors.Is(err, osexec.ErrWaitDelay):
+		// The command exited successfully, but its children kept its output
+		// open.
+		level.Warn(c.opts.Logger).Log("msg", "command output was closed after the command exited", "command", args.Command[0])
+		c.metrics.lastExitCode.Set(0)
+		c.reportHealthy()
+	case err != nil:
+		c.reportFailure(-1, fmt.Errorf("command failed: %w", err))
+	default:
+		c.metrics.lastExitCode.Set(0)
+		c.reportHealthy()
+	}
+}
+
+// forward sends each line read from r as an entry to the receivers. Empty
+// lines are skipped.
+func (c *Component) forward(ctx context.Context, r io.Reader, stream string, args Arguments) {
+	lbls := make(model.LabelSet, len(args.Labels)+1)
+	for name, value := range args.Labels {
+		lbls[model.LabelName(name)] = model.LabelValue(value)
+	}
+	lbls[streamLabel] = model.LabelValue(stream)
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			c.metrics.entries.WithLabelValues(stream).Inc()
+			entry := loki.Entry{
+				Labels: lbls.Clone(),
+				Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+			}
+			for _, receiver := range args.ForwardTo {
+				select {
+				case <-ctx.Done():
+					return
+				case receiver.Chan() <- entry:
+				}
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				level.Warn(c.opts.Logger).Log("msg", "failed to read command output", "stream", stream, "err", err)
+			}
+			return
+		}
+	}
+}
+
+func (c *Component) reportFailure(exitCode int, err error) {
+	c.metrics.failures.Inc()
+	c.metrics.lastExitCode.Set(float64(exitCode))
+	level.Warn(c.opts.Logger).Log("msg", "command failed", "err", err)
+
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = component.Health{
+		Health:     component.HealthTypeUnhealthy,
+		Message:    err.Error(),
+		UpdateTime: time.Now(),
+	}
+}
+
+func (c *Component) reportHealthy() {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = component.Health{
+		Health:     component.HealthTypeHealthy,
+		UpdateTime: time.Now(),
+	}
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
@@ -0,0 +1,253 @@
+//go:build !windows
+
+package exec
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments_Alloy(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		command    = ["ipmitool", "sel", "list"]
+		labels     = {"job" = "ipmi"}
+		forward_to = []
+	`), &args))
+	require.Equal(t, []string{"ipmitool", "sel", "list"}, args.Command)
+	require.Equal(t, time.Minute, args.Interval)
+	require.Equal(t, 30*time.Second, args.Timeout)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         Arguments
+		errSubstring string
+	}{
+		{
+			name:         "empty command",
+			args:         Arguments{Interval: time.Minute},
+			errSubstring: "command must not be empty",
+		},
+		{
+			name:         "zero interval",
+			args:         Arguments{Command: []string{"true"}},
+			errSubstring: "interval must be greater than 0",
+		},
+		{
+			name:         "negative timeout",
+			args:         Arguments{Command: []string{"true"}, Interval: time.Minute, Timeout: -time.Second},
+			errSubstring: "timeout must not be negative",
+		},
+		{
+			name:         "invalid label name",
+			args:         Arguments{Command: []string{"true"}, Interval: time.Minute, Labels: map[string]string{"": "ipmi"}},
+			errSubstring: `invalid label name ""`,
+		},
+		{
+			name: "valid",
+			args: Arguments{Command: []string{"true"}, Interval: time.Minute, Labels: map[string]string{"job": "ipmi"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.Validate()
+			if tt.errSubstring == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.errSubstring)
+			}
+		})
+	}
+}
+
+func TestComponent_Periodic(t *testing.T) {
+	receiver := loki.NewLogsReceiver()
+	c, registry := startComponent(t, Arguments{
+		Command:   []string{"sh", "-c", `echo "$GREETING"; echo; echo oops >&2`},
+		Env:       map[string]string{"GREETING": "hello"},
+		Interval:  time.Hour,
+		Timeout:   time.Minute,
+		Labels:    map[string]string{"job": "test"},
+		ForwardTo: []loki.LogsReceiver{receiver},
+	})
+
+	lines := map[string]model.LabelSet{}
+	for i := 0; i < 2; i++ {
+		entry := receiveEntry(t, receiver)
+		lines[entry.Line] = entry.Labels
+	}
+	require.Equal(t, map[string]model.LabelSet{
+		"hello": {"job": "test", "stream": "stdout"},
+		"oops":  {"job": "test", "stream": "stderr"},
+	}, lines)
+
+	require.Eventually(t, func() bool {
+		return c.CurrentHealth().Health == component.HealthTypeHealthy
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 1.0, testutil.ToFloat64(c.metrics.runs))
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP loki_source_exec_entries_total Total number of log entries read from the output of the command, partitioned by stream.
+# TYPE loki_source_exec_entries_total counter
+loki_source_exec_entries_total{stream="stderr"} 1
+loki_source_exec_entries_total{stream="stdout"} 1
+`), "loki_source_exec_entries_total"))
+}
+
+func TestComponent_ExitCode(t *testing.T) {
+	c, _ := startComponent(t, Arguments{
+		Command:  []string{"sh", "-c", "exit 3"},
+		Interval: time.Hour,
+		Timeout:  time.Minute,
+	})
+
+	require.Eventually(t, func() bool {
+		h := c.CurrentHealth()
+		return h.Health == component.HealthTypeUnhealthy && h.Message == "command exited with code 3"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 3.0, testutil.ToFloat64(c.metrics.lastExitCode))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.metrics.failures))
+}
+
+func TestComponent_Timeout(t *testing.T) {
+	c, _ := startComponent(t, Arguments{
+		Command:  []string{"sleep", "10"},
+		Interval: time.Hour,
+		Timeout:  100 * time.Millisecond,
+	})
+
+	require.Eventually(t, func() bool {
+		h := c.CurrentHealth()
+		return h.Health == component.HealthTypeUnhealthy && h.Message == "command timed out after 100ms"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, -1.0, testutil.ToFloat64(c.metrics.lastExitCode))
+}
+
+func TestComponent_TimeoutWithChildProcess(t *testing.T) {
+	receiver := loki.NewLogsReceiver()
+	c, _ := startComponent(t, Arguments{
+		// The child process keeps the output of the shell open.
+		Command:   []string{"sh", "-c", "echo hi; sleep 30 & wait"},
+		Interval:  time.Hour,
+		Timeout:   500 * time.Millisecond,
+		ForwardTo: []loki.LogsReceiver{receiver},
+	})
+	require.Equal(t, "hi", receiveEntry(t, receiver).Line)
+
+	require.Eventually(t, func() bool {
+		h := c.CurrentHealth()
+		return h.Health == component.HealthTypeUnhealthy && h.Message == "command timed out after 500ms"
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestComponent_ShutdownWithChildProcess(t *testing.T) {
+	receiver := loki.NewLogsReceiver()
+	c, err := New(component.Options{
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, Arguments{
+		Command:    []string{"sh", "-c", "echo hi; sleep 30 & wait"},
+		Continuous: true,
+		Interval:   time.Second,
+		ForwardTo:  []loki.LogsReceiver{receiver},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	require.Equal(t, "hi", receiveEntry(t, receiver).Line)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("component didn't stop after its context was canceled")
+	}
+}
+
+func TestComponent_ContinuousRestarts(t *testing.T) {
+	receiver := loki.NewLogsReceiver()
+	c, _ := startComponent(t, Arguments{
+		Command:    []string{"sh", "-c", "echo started"},
+		Continuous: true,
+		Interval:   10 * time.Millisecond,
+		ForwardTo:  []loki.LogsReceiver{receiver},
+	})
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, "started", receiveEntry(t, receiver).Line)
+	}
+	require.GreaterOrEqual(t, testutil.ToFloat64(c.metrics.runs), 3.0)
+}
+
+func TestComponent_Update(t *testing.T) {
+	receiver := loki.NewLogsReceiver()
+	args := Arguments{
+		Command:   []string{"echo", "first"},
+		Interval:  time.Hour,
+		Timeout:   time.Minute,
+		ForwardTo: []loki.LogsReceiver{receiver},
+	}
+	c, _ := startComponent(t, args)
+	require.Equal(t, "first", receiveEntry(t, receiver).Line)
+
+	// Updating the arguments runs the new command immediately.
+	args.Command = []string{"echo", "second"}
+	require.NoError(t, c.Update(args))
+	require.Equal(t, "second", receiveEntry(t, receiver).Line)
+}
+
+func startComponent(t *testing.T, args Arguments) (*Component, *prometheus.Registry) {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	c, err := New(component.Options{
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    registry,
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return c, registry
+}
+
+func receiveEntry(t *testing.T, receiver loki.LogsReceiver) loki.Entry {
+	t.Helper()
+
+	select {
+	case entry := <-receiver.Chan():
+		return entry
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an entry")
+		return loki.Entry{}
+	}
+}
@@ -0,0 +1,47 @@
+package exec
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/util"
+)
+
+type metrics struct {
+	runs         prometheus.Counter
+	failures     prometheus.Counter
+	lastExitCode prometheus.Gauge
+	runDuration  prometheus.Histogram
+	entries      *prometheus.CounterVec
+}
+
+func newMetrics(r prometheus.Registerer) *metrics {
+	m := &metrics{
+		runs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_source_exec_runs_total",
+			Help: "Total number of times the command was started.",
+		}),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_source_exec_run_failures_total",
+			Help: "Total number of runs of the command which failed to start, timed out, or exited with a non-zero code.",
+		}),
+		lastExitCode: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "loki_source_exec_last_exit_code",
+			Help: "Exit code of the last run of the command, or -1 if it failed to start or timed out.",
+		}),
+		runDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "loki_source_exec_run_duration_seconds",
+			Help:    "Duration of the runs of the command.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loki_source_exec_entries_total",
+			Help: "Total number of log entries read from the output of the command, partitioned by stream.",
+		}, []string{"stream"}),
+	}
+	m.runs = util.MustRegisterOrGet(r, m.runs).(prometheus.Counter)
+	m.failures = util.MustRegisterOrGet(r, m.failures).(prometheus.Counter)
+	m.lastExitCode = util.MustRegisterOrGet(r, m.lastExitCode).(prometheus.Gauge)
+	m.runDuration = util.MustRegisterOrGet(r, m.runDuration).(prometheus.Histogram)
+	m.entries = util.MustRegisterOrGet(r, m.entries).(*prometheus.CounterVec)
+	return m
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package exec
+
+import (
+	osexec "os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs cmd in its own process group, and kills the whole
+// group when cmd is canceled, so that the children of the command which keep
+// its output open are killed too.
+func setProcessGroup(cmd *osexec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package exec
+
+import (
+	osexec "os/exec"
+)
+
+// setProcessGroup does nothing on Windows, where only the command is killed
+// when it's canceled. Its output is closed after waitDelay.
+func setProcessGroup(_ *osexec.Cmd) {}
@@ -19,6 +19,15 @@ func (ut CompressionFormat) String() string {
 	return string(ut)
 }
 
+// Extension returns the extension of the names of the files compressed with
+// the format, without its leading dot.
+func (ut CompressionFormat) Extension() string {
+	if ut == "zstd" {
+		return "zst"
+	}
+	return string(ut)
+}
+
 // MarshalText implements encoding.TextMarshaler.
 func (ut CompressionFormat) MarshalText() (text []byte, err error) {
 	return []byte(ut.String()), nil
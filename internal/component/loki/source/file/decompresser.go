@@ -20,6 +20,7 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/klauspost/compress/zstd"
 	"github.com/prometheus/common/model"
 	"go.uber.org/atomic"
 	"golang.org/x/text/encoding"
@@ -33,9 +34,10 @@ import (
 
 func supportedCompressedFormats() map[string]struct{} {
 	return map[string]struct{}{
-		"gz":  {},
-		"z":   {},
-		"bz2": {},
+		"gz":   {},
+		"z":    {},
+		"bz2":  {},
+		"zstd": {},
 		// TODO: add support for zip.
 	}
 }
@@ -140,6 +142,11 @@ func mountReader(f *os.File, logger log.Logger, format CompressionFormat) (reade
 	case "bz2":
 		decompressLib = "bzip2"
 		reader = bzip2.NewReader(f)
+	case "zstd":
+		decompressLib = "github.com/klauspost/compress/zstd"
+		// With a concurrency of 1, the stream is decoded synchronously, so
+		// the decoder doesn't need to be closed to release goroutines.
+		reader, err = zstd.NewReader(f, zstd.WithDecoderConcurrency(1))
 	}
 
 	if err != nil && err != io.EOF {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 
@@ -48,6 +49,7 @@ type Arguments struct {
 	DecompressionConfig DecompressionConfig `alloy:"decompression,block,optional"`
 	FileWatch           FileWatch           `alloy:"file_watch,block,optional"`
 	TailFromEnd         bool                `alloy:"tail_from_end,attr,optional"`
+	FollowRotations     bool                `alloy:"follow_rotations,attr,optional"`
 	LegacyPositionsFile string              `alloy:"legacy_positions_file,attr,optional"`
 }
 
@@ -68,6 +70,19 @@ func (a *Arguments) SetToDefault() {
 	*a = DefaultArguments
 }
 
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.FollowRotations {
+		if runtime.GOOS == "windows" {
+			return fmt.Errorf("follow_rotations isn't supported on Windows")
+		}
+		if a.DecompressionConfig.Enabled {
+			return fmt.Errorf("follow_rotations can't be used with decompression, rotated files are decompressed when they need to be read")
+		}
+	}
+	return nil
+}
+
 type DecompressionConfig struct {
 	Enabled      bool              `alloy:"enabled,attr"`
 	InitialDelay time.Duration     `alloy:"initial_delay,attr,optional"`
@@ -129,6 +144,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 	// Call to Update() to start readers and set receivers once at the start.
 	if err := c.Update(args); err != nil {
+		positionsFile.Stop()
 		return nil, err
 	}
 
@@ -181,6 +197,11 @@ func (c *Component) Update(args component.Arguments) error {
 
 	newArgs := args.(Arguments)
 
+	if newArgs.FollowRotations && !c.opts.MinStability.Permits(featuregate.StabilityExperimental) {
+		return fmt.Errorf("follow_rotations is at stability level %s, which is below the minimum allowed stability level %s. Use --stability.level command-line flag to enable %s features",
+			featuregate.StabilityExperimental, c.opts.MinStability, featuregate.StabilityExperimental)
+	}
+
 	c.mut.Lock()
 	defer c.mut.Unlock()
 	c.args = newArgs
@@ -236,6 +257,9 @@ func (c *Component) DebugInfo() interface{} {
 	var res readerDebugInfo
 	for e, task := range c.tasks {
 		offset, _ := c.posFile.Get(e.Path, e.Labels)
+		if t, ok := task.reader.(*rotatingTailer); ok {
+			offset = t.ReadOffset()
+		}
 		res.TargetsInfo = append(res.TargetsInfo, targetInfo{
 			Path:       e.Path,
 			Labels:     e.Labels,
@@ -302,6 +326,24 @@ func (c *Component) createReader(path string, labels model.LabelSet) (reader, er
 			return nil, fmt.Errorf("failed to create decompressor %s", err)
 		}
 		reader = decompressor
+	} else if c.args.FollowRotations {
+		tailer, err := newRotatingTailer(
+			c.metrics,
+			c.opts.Logger,
+			c.handler,
+			c.posFile,
+			path,
+			labels,
+			c.args.Encoding,
+			c.args.FileWatch.MinPollFrequency,
+			c.args.TailFromEnd,
+			c.IsStopping,
+		)
+		if err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to create tailer", "error", err, "filename", path)
+			return nil, fmt.Errorf("failed to create tailer %s", err)
+		}
+		reader = tailer
 	} else {
 		pollOptions := watch.PollingFileWatcherOptions{
 			MinPollFrequency: c.args.FileWatch.MinPollFrequency,
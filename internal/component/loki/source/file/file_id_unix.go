@@ -0,0 +1,20 @@
+//go:build !windows
+
+package file
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/grafana/alloy/internal/component/common/loki/positions"
+)
+
+// getFileID returns the device and inode numbers of a file, which identify it
+// even after it's renamed.
+func getFileID(fi os.FileInfo) (positions.FileID, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return positions.FileID{}, false
+	}
+	return positions.FileID{Device: uint64(st.Dev), Inode: uint64(st.Ino)}, true
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package file
+
+import (
+	"os"
+
+	"github.com/grafana/alloy/internal/component/common/loki/positions"
+)
+
+// getFileID isn't supported on Windows, where the file information returned
+// by os.Stat doesn't include a file index.
+func getFileID(_ os.FileInfo) (positions.FileID, bool) {
+	return positions.FileID{}, false
+}
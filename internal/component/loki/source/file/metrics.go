@@ -19,6 +19,7 @@ type metrics struct {
 	totalBytes       *prometheus.GaugeVec
 	readLines        *prometheus.CounterVec
 	encodingFailures *prometheus.CounterVec
+	rotatedFiles     *prometheus.CounterVec
 	filesActive      prometheus.Gauge
 }
 
@@ -44,6 +45,10 @@ func newMetrics(reg prometheus.Registerer) *metrics {
 		Name: "loki_source_file_encoding_failures_total",
 		Help: "Number of encoding failures.",
 	}, []string{"path"})
+	m.rotatedFiles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_source_file_rotated_files_total",
+		Help: "Number of rotated files read to the end.",
+	}, []string{"path"})
 	m.filesActive = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "loki_source_file_files_active_total",
 		Help: "Number of active files.",
@@ -54,6 +59,7 @@ func newMetrics(reg prometheus.Registerer) *metrics {
 		m.totalBytes = util.MustRegisterOrGet(reg, m.totalBytes).(*prometheus.GaugeVec)
 		m.readLines = util.MustRegisterOrGet(reg, m.readLines).(*prometheus.CounterVec)
 		m.encodingFailures = util.MustRegisterOrGet(reg, m.encodingFailures).(*prometheus.CounterVec)
+		m.rotatedFiles = util.MustRegisterOrGet(reg, m.rotatedFiles).(*prometheus.CounterVec)
 		m.filesActive = util.MustRegisterOrGet(reg, m.filesActive).(prometheus.Gauge)
 	}
 
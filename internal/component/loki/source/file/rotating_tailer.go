@@ -0,0 +1,595 @@
+package file
+
+// rotatingTailer implements the reader interface for files which are rotated
+// by renaming them, and optionally compressing them, before a new file is
+// created at the same path. Files are identified by their device and inode
+// numbers instead of their path, so that the lines written to a file right
+// before it's rotated are read even when Alloy was stopped or lagging behind.
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"go.uber.org/atomic"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/transform"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/positions"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// fingerprintSize is the number of bytes at the beginning of a file which are
+// used to recognize it once it's compressed, and its inode changed.
+const fingerprintSize = 1024
+
+type rotatingTailer struct {
+	metrics   *metrics
+	logger    log.Logger
+	receiver  loki.LogsReceiver
+	positions positions.Positions
+
+	path      string
+	labels    model.LabelSet
+	labelsStr string
+
+	decoder       *encoding.Decoder
+	pollFrequency time.Duration
+	tailFromEnd   bool
+
+	componentStopping func() bool
+
+	running *atomic.Bool
+	offset  *atomic.Int64
+
+	mut      sync.Mutex
+	stopping bool
+	quit     chan struct{} // closed by the Stop method to tell the Run method to exit
+	done     chan struct{} // closed by the Run method when it exited
+}
+
+func newRotatingTailer(metrics *metrics, logger log.Logger, receiver loki.LogsReceiver, positions positions.Positions, path string,
+	labels model.LabelSet, encodingFormat string, pollFrequency time.Duration, tailFromEnd bool, componentStopping func() bool) (*rotatingTailer, error) {
+
+	t := &rotatingTailer{
+		metrics:           metrics,
+		logger:            log.With(logger, "component", "rotating_tailer"),
+		receiver:          receiver,
+		positions:         positions,
+		path:              path,
+		labels:            labels,
+		labelsStr:         labels.String(),
+		pollFrequency:     pollFrequency,
+		tailFromEnd:       tailFromEnd,
+		componentStopping: componentStopping,
+		running:           atomic.NewBool(false),
+		offset:            atomic.NewInt64(0),
+	}
+	if t.pollFrequency <= 0 {
+		t.pollFrequency = DefaultArguments.FileWatch.MinPollFrequency
+	}
+
+	if encodingFormat != "" {
+		level.Info(t.logger).Log("msg", "Will decode messages", "from", encodingFormat, "to", "UTF8")
+		encoder, err := ianaindex.IANA.Encoding(encodingFormat)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get IANA encoding %s: %w", encodingFormat, err)
+		}
+		t.decoder = encoder.NewDecoder()
+	}
+
+	return t, nil
+}
+
+// openFile is a file being read by the rotatingTailer.
+type openFile struct {
+	f      *os.File
+	id     positions.FileID
+	reader *bufio.Reader
+	// offset is the offset of the end of the last complete line read.
+	offset int64
+	// partial holds the end of the file which isn't a complete line yet.
+	partial []byte
+	// fingerprint is computed over the first fingerprintLen bytes of the file.
+	fingerprint    string
+	fingerprintLen int64
+}
+
+func (t *rotatingTailer) Run() {
+	t.mut.Lock()
+	// Check if the stop function was called before Run.
+	if t.stopping {
+		t.mut.Unlock()
+		return
+	}
+	quit, done := make(chan struct{}), make(chan struct{})
+	t.quit, t.done = quit, done
+	t.mut.Unlock()
+	defer close(done)
+
+	labelsMiddleware := t.labels.Merge(model.LabelSet{filenameLabel: model.LabelValue(t.path)})
+	handler := loki.AddLabelsMiddleware(labelsMiddleware).Wrap(loki.NewEntryHandler(t.receiver.Chan(), func() {}))
+	defer handler.Stop()
+
+	level.Info(t.logger).Log("msg", "tail routine: started", "path", t.path)
+	t.running.Store(true)
+	t.metrics.filesActive.Add(1.)
+	defer func() {
+		t.running.Store(false)
+		t.cleanupMetrics()
+		level.Info(t.logger).Log("msg", "tail routine: exited", "path", t.path)
+	}()
+
+	current, err := t.open()
+	if err != nil {
+		level.Error(t.logger).Log("msg", "failed to open file", "path", t.path, "err", err)
+		return
+	}
+	defer func() {
+		if current != nil {
+			current.f.Close()
+		}
+	}()
+
+	// Lines of the files which were rotated since they were last read are
+	// read before the current file, which is then read from its beginning.
+	tailFromEnd := t.tailFromEnd && len(t.positions.FilesAt(t.path, t.labelsStr)) == 0
+	if !t.catchUp(current, handler.Chan(), quit) {
+		return
+	}
+	if err := t.seek(current, tailFromEnd); err != nil {
+		level.Error(t.logger).Log("msg", "failed to seek file", "path", t.path, "err", err)
+		return
+	}
+
+	ticker := time.NewTicker(t.pollFrequency)
+	defer ticker.Stop()
+	for {
+		ok := t.readLines(current, handler.Chan(), quit, false)
+		t.markPositionAndSize(current)
+		if !ok {
+			return
+		}
+
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		}
+
+		fi, err := os.Stat(t.path)
+		if err != nil {
+			// The file was renamed and the new one isn't created yet, so
+			// the old one is still read in case it's written to.
+			if !os.IsNotExist(err) {
+				level.Warn(t.logger).Log("msg", "failed to stat file", "path", t.path, "err", err)
+			}
+			continue
+		}
+		id, _ := getFileID(fi)
+		if id == current.id {
+			if fi.Size() < current.offset {
+				level.Info(t.logger).Log("msg", "file was truncated, reading it from the beginning", "path", t.path)
+				if err := t.reset(current, 0); err != nil {
+					level.Error(t.logger).Log("msg", "failed to seek file", "path", t.path, "err", err)
+					return
+				}
+			}
+			continue
+		}
+
+		// The file was rotated, so it's read to its end before the new file.
+		level.Info(t.logger).Log("msg", "file was rotated, reading it to the end before the new file", "path", t.path)
+		if !t.readLines(current, handler.Chan(), quit, true) {
+			t.markPositionAndSize(current)
+			return
+		}
+		t.positions.RemoveFile(current.id, t.labelsStr)
+		t.metrics.rotatedFiles.WithLabelValues(t.path).Inc()
+		current.f.Close()
+
+		current, err = t.open()
+		if err != nil {
+			level.Error(t.logger).Log("msg", "failed to open file", "path", t.path, "err", err)
+			return
+		}
+		if err := t.seek(current, false); err != nil {
+			level.Error(t.logger).Log("msg", "failed to seek file", "path", t.path, "err", err)
+			return
+		}
+	}
+}
+
+// open opens the file at the path of the tailer.
+func (t *rotatingTailer) open() (*openFile, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	id, ok := getFileID(fi)
+	if !ok {
+		f.Close()
+		return nil, fmt.Errorf("failed to get the device and inode numbers of %s", t.path)
+	}
+	return &openFile{f: f, id: id, reader: bufio.NewReader(f)}, nil
+}
+
+// seek moves to the position recorded for the file if any, or else to its
+// beginning or the beginning of its last line.
+func (t *rotatingTailer) seek(file *openFile, tailFromEnd bool) error {
+	var offset int64
+	if pos, ok := t.positions.GetFile(file.id, t.labelsStr); ok {
+		offset = pos.Offset
+	} else if pos, err := t.positions.Get(t.path, t.labelsStr); err == nil && pos > 0 {
+		// The file was read before rotations were followed, so its position
+		// was recorded by path.
+		offset = pos
+		t.positions.Remove(t.path, t.labelsStr)
+	} else if tailFromEnd {
+		offset, err = getLastLinePosition(t.path)
+		if err != nil {
+			level.Error(t.logger).Log("msg", "failed to get a position from the end of the file, default to start of file", "err", err)
+			offset = 0
+		}
+	}
+
+	fi, err := file.f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() < offset {
+		offset = 0
+	}
+	return t.reset(file, offset)
+}
+
+func (t *rotatingTailer) reset(file *openFile, offset int64) error {
+	if _, err := file.f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	file.reader.Reset(file.f)
+	file.offset = offset
+	file.partial = nil
+	if offset < file.fingerprintLen {
+		file.fingerprint, file.fingerprintLen = "", 0
+	}
+	return nil
+}
+
+// readLines sends the complete lines read until the end of the file. When
+// final is true, the file won't be written to anymore, so its last line is
+// sent even if it doesn't end with a newline. It returns false if the tailer
+// was stopped.
+func (t *rotatingTailer) readLines(file *openFile, entries chan<- loki.Entry, quit <-chan struct{}, final bool) bool {
+	for {
+		line, err := file.reader.ReadBytes('\n')
+		if len(file.partial) > 0 {
+			line = append(file.partial, line...)
+			file.partial = nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			level.Error(t.logger).Log("msg", "tail routine: error reading line", "path", t.path, "error", err)
+		}
+		if err != nil {
+			if !final || len(line) == 0 {
+				file.partial = line
+				return true
+			}
+		}
+
+		if !t.send(line, entries, quit) {
+			file.partial = line
+			return false
+		}
+		file.offset += int64(len(line))
+		t.offset.Store(file.offset)
+		if err != nil {
+			return true
+		}
+	}
+}
+
+func (t *rotatingTailer) send(line []byte, entries chan<- loki.Entry, quit <-chan struct{}) bool {
+	text := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	if t.decoder != nil {
+		var err error
+		text, err = t.convertToUTF8(text)
+		if err != nil {
+			level.Debug(t.logger).Log("msg", "failed to convert encoding", "error", err)
+			t.metrics.encodingFailures.WithLabelValues(t.path).Inc()
+			text = fmt.Sprintf("the requested encoding conversion for this line failed in Alloy: %s", err.Error())
+		}
+	}
+
+	select {
+	case <-quit:
+		return false
+	case entries <- loki.Entry{
+		Labels: model.LabelSet{},
+		Entry: logproto.Entry{
+			Timestamp: time.Now(),
+			Line:      text,
+		},
+	}:
+		t.metrics.readLines.WithLabelValues(t.path).Inc()
+		return true
+	}
+}
+
+// catchUp reads the files which were read from the path of the tailer, and
+// have been rotated since. It returns false if the tailer was stopped.
+func (t *rotatingTailer) catchUp(current *openFile, entries chan<- loki.Entry, quit <-chan struct{}) bool {
+	for id, pos := range t.positions.FilesAt(t.path, t.labelsStr) {
+		// The inode of a rotated file can be reused by the current file once
+		// the rotated file was compressed and removed.
+		if id == current.id && matchesFingerprint(current.f, pos.Fingerprint) {
+			continue
+		}
+
+		r, closer, err := t.findRotated(id, pos)
+		switch {
+		case err != nil:
+			level.Warn(t.logger).Log("msg", "couldn't find the rotated file, the lines written to it after it was last read are lost", "path", t.path, "err", err)
+		default:
+			level.Info(t.logger).Log("msg", "reading rotated file to the end before the current file", "path", t.path)
+			file := &openFile{id: id, reader: bufio.NewReader(r), offset: pos.Offset}
+			ok := t.readLines(file, entries, quit, true)
+			closer.Close()
+			if !ok {
+				// The rest of the file is read when the tailer runs again.
+				pos.Offset = file.offset
+				t.positions.PutFile(id, t.labelsStr, pos)
+				return false
+			}
+			t.metrics.rotatedFiles.WithLabelValues(t.path).Inc()
+		}
+		t.positions.RemoveFile(id, t.labelsStr)
+	}
+	return true
+}
+
+// findRotated finds a rotated file, either by its ID or, if it was compressed,
+// by its fingerprint, and returns a reader positioned at the offset it was
+// last read at.
+func (t *rotatingTailer) findRotated(id positions.FileID, pos positions.FilePosition) (io.Reader, io.Closer, error) {
+	candidates, err := rotatedCandidates(t.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, candidate := range candidates {
+		fi, err := os.Stat(candidate)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		if candidateID, ok := getFileID(fi); ok && candidateID == id {
+			f, err := os.Open(candidate)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, err := f.Seek(pos.Offset, io.SeekStart); err != nil {
+				f.Close()
+				return nil, nil, err
+			}
+			return f, f, nil
+		}
+	}
+
+	// Compressed files can only be recognized by the part of the file which
+	// was already read.
+	if n, _ := parseFingerprint(pos.Fingerprint); n == 0 || n > pos.Offset {
+		return nil, nil, fmt.Errorf("no file with device %d and inode %d", id.Device, id.Inode)
+	}
+
+	for _, candidate := range candidates {
+		format, ok := compressionFormatOf(candidate)
+		if !ok {
+			continue
+		}
+
+		r, closer, err := t.openCompressed(candidate, format, pos)
+		if err != nil {
+			level.Debug(t.logger).Log("msg", "compressed file doesn't match the rotated file", "path", candidate, "err", err)
+			continue
+		}
+		return r, closer, nil
+	}
+	return nil, nil, fmt.Errorf("no file with device %d and inode %d, and no compressed file with the same content", id.Device, id.Inode)
+}
+
+// compressionFormatOf returns the compression format of a file from the
+// extension of its name.
+func compressionFormatOf(path string) (CompressionFormat, bool) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	for format := range supportedCompressedFormats() {
+		if CompressionFormat(format).Extension() == ext {
+			return CompressionFormat(format), true
+		}
+	}
+	return "", false
+}
+
+// openCompressed returns a reader of the decompressed content of path
+// positioned at the offset of pos, if the content matches its fingerprint.
+func (t *rotatingTailer) openCompressed(path string, format CompressionFormat, pos positions.FilePosition) (io.Reader, io.Closer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := mountReader(f, t.logger, format)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	n, _ := parseFingerprint(pos.Fingerprint)
+	head := make([]byte, n)
+	if _, err := io.ReadFull(r, head); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if computeFingerprint(head) != pos.Fingerprint {
+		f.Close()
+		return nil, nil, fmt.Errorf("fingerprint mismatch")
+	}
+	if _, err := io.CopyN(io.Discard, r, pos.Offset-n); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return r, f, nil
+}
+
+// rotatedCandidates returns the files which may be rotated versions of the
+// file at path, such as app.log.1, app.log.2.gz, or app.log-20240101.
+func rotatedCandidates(path string) ([]string, error) {
+	var res []string
+	for _, sep := range []string{".", "-"} {
+		matches, err := filepath.Glob(escapeGlob(path) + sep + "*")
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, matches...)
+	}
+	return res, nil
+}
+
+func escapeGlob(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// markPositionAndSize records the position of the file, with its fingerprint
+// once enough of it was read.
+func (t *rotatingTailer) markPositionAndSize(file *openFile) {
+	if file.fingerprintLen < fingerprintSize && file.offset > file.fingerprintLen {
+		n := min(file.offset, fingerprintSize)
+		head := make([]byte, n)
+		if _, err := file.f.ReadAt(head, 0); err == nil {
+			file.fingerprint, file.fingerprintLen = computeFingerprint(head), n
+		}
+	}
+
+	t.positions.PutFile(file.id, t.labelsStr, positions.FilePosition{
+		Path:        t.path,
+		Offset:      file.offset,
+		Fingerprint: file.fingerprint,
+	})
+
+	if fi, err := file.f.Stat(); err == nil {
+		t.metrics.totalBytes.WithLabelValues(t.path).Set(float64(fi.Size()))
+	}
+	t.metrics.readBytes.WithLabelValues(t.path).Set(float64(file.offset))
+}
+
+// computeFingerprint returns the number of bytes of head followed by their
+// FNV-1a hash.
+func computeFingerprint(head []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(head)
+	return fmt.Sprintf("%d:%016x", len(head), h.Sum64())
+}
+
+// matchesFingerprint returns whether the beginning of f matches fingerprint.
+// An empty fingerprint matches any file.
+func matchesFingerprint(f *os.File, fingerprint string) bool {
+	if fingerprint == "" {
+		return true
+	}
+	n, err := parseFingerprint(fingerprint)
+	if err != nil {
+		return false
+	}
+	head := make([]byte, n)
+	if _, err := f.ReadAt(head, 0); err != nil {
+		return false
+	}
+	return computeFingerprint(head) == fingerprint
+}
+
+// parseFingerprint returns the number of bytes a fingerprint was computed on.
+func parseFingerprint(fingerprint string) (int64, error) {
+	var (
+		n    int64
+		hash string
+	)
+	if _, err := fmt.Sscanf(fingerprint, "%d:%s", &n, &hash); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Stop stops the tailer. Once stopped, a rotatingTailer can't be run again.
+func (t *rotatingTailer) Stop() {
+	t.mut.Lock()
+	t.stopping = true
+	quit, done := t.quit, t.done
+	t.mut.Unlock()
+
+	if quit != nil {
+		close(quit)
+		<-done
+	}
+	level.Info(t.logger).Log("msg", "stopped tailing file", "path", t.path)
+
+	// If the component is not stopping, then it means that the target for this component is gone and that
+	// we should clear the entries of its files from the positions file.
+	if !t.componentStopping() {
+		for id := range t.positions.FilesAt(t.path, t.labelsStr) {
+			t.positions.RemoveFile(id, t.labelsStr)
+		}
+	}
+}
+
+func (t *rotatingTailer) IsRunning() bool {
+	return t.running.Load()
+}
+
+// ReadOffset returns the offset of the file currently read.
+func (t *rotatingTailer) ReadOffset() int64 {
+	return t.offset.Load()
+}
+
+func (t *rotatingTailer) convertToUTF8(text string) (string, error) {
+	res, _, err := transform.String(t.decoder, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode text to UTF8: %w", err)
+	}
+
+	return res, nil
+}
+
+// cleanupMetrics removes all metrics exported by this tailer
+func (t *rotatingTailer) cleanupMetrics() {
+	t.metrics.filesActive.Add(-1.)
+	t.metrics.readLines.DeleteLabelValues(t.path)
+	t.metrics.readBytes.DeleteLabelValues(t.path)
+	t.metrics.totalBytes.DeleteLabelValues(t.path)
+	t.metrics.rotatedFiles.DeleteLabelValues(t.path)
+}
+
+func (t *rotatingTailer) Path() string {
+	return t.path
+}
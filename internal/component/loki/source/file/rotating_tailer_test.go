@@ -0,0 +1,212 @@
+//go:build !windows
+
+package file
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/positions"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/util"
+)
+
+func TestRotatingTailer_Rotation(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	receiver, positionsFile := loki.NewLogsReceiver(), newTestPositions(t, dir)
+	defer positionsFile.Stop()
+	tailer := newTestRotatingTailer(t, receiver, positionsFile, path, func() bool { return true })
+	go tailer.Run()
+
+	write(t, f, "first\n")
+	requireLines(t, receiver, path, "first")
+
+	// The lines written right before the file is rotated are read before
+	// the lines of the new file, including the last one without a newline.
+	write(t, f, "second\nthird")
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("fourth\n"), 0644))
+	requireLines(t, receiver, path, "second", "third", "fourth")
+
+	tailer.Stop()
+
+	// Only the position of the new file is kept.
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	id, ok := getFileID(fi)
+	require.True(t, ok)
+	files := positionsFile.FilesAt(path, model.LabelSet{"job": "app"}.String())
+	require.Len(t, files, 1)
+	require.Equal(t, int64(7), files[id].Offset)
+}
+
+func TestRotatingTailer_CatchUp(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+
+	tests := map[string]struct {
+		rotate func(t *testing.T, path string)
+	}{
+		"renamed": {
+			rotate: func(t *testing.T, path string) {
+				require.NoError(t, os.Rename(path, path+".1"))
+			},
+		},
+		"renamed and compressed": {
+			rotate: func(t *testing.T, path string) {
+				require.NoError(t, os.Rename(path, path+".1"))
+				gzipFile(t, path+".1")
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.log")
+			require.NoError(t, os.WriteFile(path, []byte("first\n"), 0644))
+
+			receiver, positionsFile := loki.NewLogsReceiver(), newTestPositions(t, dir)
+			defer positionsFile.Stop()
+			tailer := newTestRotatingTailer(t, receiver, positionsFile, path, func() bool { return true })
+			go tailer.Run()
+			requireLines(t, receiver, path, "first")
+			tailer.Stop()
+
+			// The file is written to and rotated while it's not read.
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+			require.NoError(t, err)
+			write(t, f, "second\n")
+			require.NoError(t, f.Close())
+			tt.rotate(t, path)
+			require.NoError(t, os.WriteFile(path, []byte("third\n"), 0644))
+
+			tailer = newTestRotatingTailer(t, receiver, positionsFile, path, func() bool { return false })
+			go tailer.Run()
+			requireLines(t, receiver, path, "second", "third")
+			tailer.Stop()
+
+			// The positions are removed once the target is gone.
+			require.Empty(t, positionsFile.FilesAt(path, model.LabelSet{"job": "app"}.String()))
+		})
+	}
+}
+
+func TestRotatingTailer_PositionByPath(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("first\nsecond\n"), 0644))
+
+	receiver, positionsFile := loki.NewLogsReceiver(), newTestPositions(t, dir)
+	defer positionsFile.Stop()
+	labelsStr := model.LabelSet{"job": "app"}.String()
+	positionsFile.Put(path, labelsStr, 6)
+
+	// The position recorded by path before rotations were followed is used.
+	tailer := newTestRotatingTailer(t, receiver, positionsFile, path, func() bool { return true })
+	go tailer.Run()
+	requireLines(t, receiver, path, "second")
+	tailer.Stop()
+
+	pos, err := positionsFile.Get(path, labelsStr)
+	require.NoError(t, err)
+	require.Zero(t, pos)
+}
+
+func newTestPositions(t *testing.T, dir string) positions.Positions {
+	positionsFile, err := positions.New(util.TestLogger(t), positions.Config{
+		SyncPeriod:    50 * time.Millisecond,
+		PositionsFile: filepath.Join(dir, "positions.yaml"),
+	})
+	require.NoError(t, err)
+	return positionsFile
+}
+
+func newTestRotatingTailer(t *testing.T, receiver loki.LogsReceiver, positionsFile positions.Positions, path string, componentStopping func() bool) *rotatingTailer {
+	tailer, err := newRotatingTailer(
+		newMetrics(nil),
+		util.TestLogger(t),
+		receiver,
+		positionsFile,
+		path,
+		model.LabelSet{"job": "app"},
+		"",
+		25*time.Millisecond,
+		false,
+		componentStopping,
+	)
+	require.NoError(t, err)
+	return tailer
+}
+
+func write(t *testing.T, f *os.File, s string) {
+	_, err := f.WriteString(s)
+	require.NoError(t, err)
+}
+
+func requireLines(t *testing.T, receiver loki.LogsReceiver, path string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		select {
+		case entry := <-receiver.Chan():
+			require.Equal(t, line, entry.Line)
+			require.Equal(t, model.LabelSet{"job": "app", "filename": model.LabelValue(path)}, entry.Labels)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed waiting for log line", "line %q", line)
+		}
+	}
+}
+
+func gzipFile(t *testing.T, path string) {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	f, err := os.Create(path + ".gz")
+	require.NoError(t, err)
+	w := gzip.NewWriter(f)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+	require.NoError(t, os.Remove(path))
+}
+
+func TestFollowRotationsStabilityLevel(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+
+	args := DefaultArguments
+	args.FollowRotations = true
+	_, err := New(component.Options{
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		DataPath:      t.TempDir(),
+		OnStateChange: func(e component.Exports) {},
+		MinStability:  featuregate.StabilityGenerallyAvailable,
+	}, args)
+	require.ErrorContains(t, err, `follow_rotations is at stability level "experimental"`)
+}
+
+func TestFollowRotationsWithDecompression(t *testing.T) {
+	args := DefaultArguments
+	args.FollowRotations = true
+	require.NoError(t, args.Validate())
+
+	args.DecompressionConfig = DecompressionConfig{Enabled: true, Format: "gz"}
+	require.EqualError(t, args.Validate(), "follow_rotations can't be used with decompression, rotated files are decompressed when they need to be read")
+}
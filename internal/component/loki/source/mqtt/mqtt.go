@@ -0,0 +1,181 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/config"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.mqtt",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the loki.source.mqtt
+// component.
+type Arguments struct {
+	Brokers        []string          `alloy:"brokers,attr"`
+	Topics         []string          `alloy:"topics,attr"`
+	QoS            int               `alloy:"qos,attr,optional"`
+	ClientID       string            `alloy:"client_id,attr,optional"`
+	CleanSession   bool              `alloy:"clean_session,attr,optional"`
+	Username       string            `alloy:"username,attr,optional"`
+	Password       alloytypes.Secret `alloy:"password,attr,optional"`
+	ConnectTimeout time.Duration     `alloy:"connect_timeout,attr,optional"`
+	TLSConfig      config.TLSConfig  `alloy:"tls_config,block,optional"`
+	Labels         map[string]string `alloy:"labels,attr,optional"`
+
+	ForwardTo    []loki.LogsReceiver `alloy:"forward_to,attr"`
+	RelabelRules alloy_relabel.Rules `alloy:"relabel_rules,attr,optional"`
+}
+
+// DefaultArguments provides the default arguments for a loki.source.mqtt component.
+var DefaultArguments = Arguments{
+	QoS:            0,
+	CleanSession:   true,
+	ConnectTimeout: 30 * time.Second,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Brokers) == 0 {
+		return fmt.Errorf("at least one broker is required")
+	}
+	for _, broker := range a.Brokers {
+		u, err := url.Parse(broker)
+		if err != nil {
+			return fmt.Errorf("invalid broker %q: %w", broker, err)
+		}
+		switch u.Scheme {
+		case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+		default:
+			return fmt.Errorf("invalid broker %q: unsupported scheme %q", broker, u.Scheme)
+		}
+	}
+	if len(a.Topics) == 0 {
+		return fmt.Errorf("at least one topic is required")
+	}
+	if a.QoS < 0 || a.QoS > 2 {
+		return fmt.Errorf("qos must be 0, 1, or 2")
+	}
+	if !a.CleanSession && a.ClientID == "" {
+		return fmt.Errorf("client_id is required when clean_session is false")
+	}
+	if a.ConnectTimeout <= 0 {
+		return fmt.Errorf("connect_timeout must be greater than 0")
+	}
+	return a.TLSConfig.Validate()
+}
+
+// Component implements the loki.source.mqtt component.
+type Component struct {
+	opts component.Options
+
+	mut    sync.RWMutex
+	fanout []loki.LogsReceiver
+	target *target
+
+	handler loki.LogsReceiver
+}
+
+// New creates a new loki.source.mqtt component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		fanout:  args.ForwardTo,
+		handler: loki.NewLogsReceiver(),
+	}
+
+	// Call to Update() to start readers and set receivers once at the start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		level.Info(c.opts.Logger).Log("msg", "loki.source.mqtt component shutting down, stopping target")
+		if c.target != nil {
+			c.target.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.handler.Chan():
+			c.mut.RLock()
+			for _, receiver := range c.fanout {
+				receiver.Chan() <- entry
+			}
+			c.mut.RUnlock()
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+	c.fanout = newArgs.ForwardTo
+
+	if c.target != nil {
+		c.target.Stop()
+	}
+
+	// The client ID identifies the session in the broker, so by default it's
+	// the ID of the component, which is unique in a configuration.
+	if newArgs.ClientID == "" {
+		newArgs.ClientID = c.opts.ID
+	}
+
+	t, err := newTarget(c.opts.Logger, newArgs, c.handler)
+	if err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to create mqtt client with provided config", "err", err)
+		return err
+	}
+	c.target = t
+
+	return nil
+}
+
+func (a *Arguments) labels() model.LabelSet {
+	lbls := make(model.LabelSet, len(a.Labels))
+	for k, v := range a.Labels {
+		lbls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return lbls
+}
@@ -0,0 +1,148 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/regexp"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments_Alloy(t *testing.T) {
+	tests := map[string]struct {
+		cfg     string
+		wantErr bool
+	}{
+		"valid": {cfg: `
+			brokers = ["tcp://localhost:1883"]
+			topics  = ["devices/+/logs"]
+			qos     = 1
+		`},
+		"no brokers": {cfg: `
+			brokers = []
+			topics  = ["devices/+/logs"]
+		`, wantErr: true},
+		"unsupported scheme": {cfg: `
+			brokers = ["http://localhost:1883"]
+			topics  = ["devices/+/logs"]
+		`, wantErr: true},
+		"no topics": {cfg: `
+			brokers = ["tcp://localhost:1883"]
+			topics  = []
+		`, wantErr: true},
+		"invalid qos": {cfg: `
+			brokers = ["tcp://localhost:1883"]
+			topics  = ["devices/+/logs"]
+			qos     = 3
+		`, wantErr: true},
+		"persistent session without client_id": {cfg: `
+			brokers       = ["tcp://localhost:1883"]
+			topics        = ["devices/+/logs"]
+			clean_session = false
+		`, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tt.cfg+"\nforward_to = []\n"), &args)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, args.CleanSession)
+		})
+	}
+}
+
+func TestComponent(t *testing.T) {
+	server := mochi.New(&mochi.Options{InlineClient: true})
+	require.NoError(t, server.AddHook(new(auth.AllowHook), nil))
+	tcp := listeners.NewTCP("tcp", "127.0.0.1:0", nil)
+	require.NoError(t, server.AddListener(tcp))
+	require.NoError(t, server.Serve())
+	defer server.Close()
+
+	// The retained message is delivered as soon as the component subscribes.
+	require.NoError(t, server.Publish("devices/sensor-1/logs", []byte("booted"), true, 0))
+
+	receiver := loki.NewLogsReceiver()
+	args := DefaultArguments
+	args.Brokers = []string{"tcp://" + tcp.Address()}
+	args.Topics = []string{"devices/+/logs"}
+	args.Labels = map[string]string{"job": "iot"}
+	args.ForwardTo = []loki.LogsReceiver{receiver}
+	args.RelabelRules = alloy_relabel.Rules{
+		{
+			SourceLabels: []string{"__meta_mqtt_topic"},
+			Regex:        mustNewRegexp("devices/([^/]+)/logs"),
+			Action:       alloy_relabel.Replace,
+			TargetLabel:  "device",
+			Replacement:  "$1",
+		},
+		{
+			SourceLabels: []string{"__meta_mqtt_retained"},
+			Regex:        mustNewRegexp("(.*)"),
+			Action:       alloy_relabel.Replace,
+			TargetLabel:  "retained",
+			Replacement:  "$1",
+		},
+	}
+
+	c, err := New(component.Options{
+		ID:            "loki.source.mqtt.test",
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	select {
+	case entry := <-receiver.Chan():
+		require.Equal(t, "booted", entry.Line)
+		require.Equal(t, model.LabelSet{"job": "iot", "device": "sensor-1", "retained": "true"}, entry.Labels)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the retained message")
+	}
+
+	require.NoError(t, server.Publish("devices/sensor-2/logs", []byte("temperature=21.5"), false, 0))
+	require.NoError(t, server.Publish("other/sensor-2/logs", []byte("ignored"), false, 0))
+
+	select {
+	case entry := <-receiver.Chan():
+		require.Equal(t, "temperature=21.5", entry.Line)
+		require.Equal(t, model.LabelSet{"job": "iot", "device": "sensor-2", "retained": "false"}, entry.Labels)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+
+	select {
+	case entry := <-receiver.Chan():
+		t.Fatalf("unexpected entry %v", entry)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func mustNewRegexp(s string) alloy_relabel.Regexp {
+	re, err := regexp.Compile("^(?:" + s + ")$")
+	if err != nil {
+		panic(err)
+	}
+	return alloy_relabel.Regexp{Regexp: re}
+}
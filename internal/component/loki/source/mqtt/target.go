@@ -0,0 +1,150 @@
+package mqtt
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+const (
+	labelKeyMQTTTopic    = "__meta_mqtt_topic"
+	labelKeyMQTTQoS      = "__meta_mqtt_qos"
+	labelKeyMQTTRetained = "__meta_mqtt_retained"
+
+	// disconnectQuiesce is how long to wait for the work in progress to
+	// complete when disconnecting from the broker, in milliseconds.
+	disconnectQuiesce = 250
+)
+
+// target subscribes to the topics of an MQTT broker, and forwards the
+// messages it receives as log entries.
+type target struct {
+	logger        log.Logger
+	client        paho.Client
+	handler       loki.LogsReceiver
+	lbs           model.LabelSet
+	relabelConfig []*relabel.Config
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newTarget(logger log.Logger, args Arguments, handler loki.LogsReceiver) (*target, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &target{
+		logger:        log.With(logger, "client_id", args.ClientID),
+		handler:       handler,
+		lbs:           args.labels(),
+		relabelConfig: alloy_relabel.ComponentToPromRelabelConfigs(args.RelabelRules),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+
+	filters := make(map[string]byte, len(args.Topics))
+	for _, topic := range args.Topics {
+		filters[topic] = byte(args.QoS)
+	}
+
+	opts := paho.NewClientOptions().
+		SetClientID(args.ClientID).
+		SetCleanSession(args.CleanSession).
+		SetUsername(args.Username).
+		SetPassword(string(args.Password)).
+		SetConnectTimeout(args.ConnectTimeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetMaxReconnectInterval(time.Minute).
+		SetOrderMatters(false).
+		// The subscriptions are made again each time the client connects,
+		// in case the broker didn't keep the session.
+		SetOnConnectHandler(func(c paho.Client) {
+			level.Info(t.logger).Log("msg", "connected to mqtt broker, subscribing to topics", "topics", strings.Join(args.Topics, ","))
+			token := c.SubscribeMultiple(filters, t.handleMessage)
+			go func() {
+				if token.Wait(); token.Error() != nil {
+					level.Error(t.logger).Log("msg", "failed to subscribe to mqtt topics", "err", token.Error())
+				}
+			}()
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			level.Warn(t.logger).Log("msg", "connection to mqtt broker lost", "err", err)
+		})
+	for _, broker := range args.Brokers {
+		opts.AddBroker(broker)
+	}
+
+	tlsConfig, err := promconfig.NewTLSConfig(args.TLSConfig.Convert())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	opts.SetTLSConfig(tlsConfig)
+
+	// The client keeps retrying to connect in the background, so an
+	// unavailable broker doesn't prevent the component from starting.
+	t.client = paho.NewClient(opts)
+	t.client.Connect()
+
+	return t, nil
+}
+
+func (t *target) handleMessage(_ paho.Client, msg paho.Message) {
+	lbs := format([]labels.Label{
+		{Name: labelKeyMQTTTopic, Value: msg.Topic()},
+		{Name: labelKeyMQTTQoS, Value: strconv.Itoa(int(msg.Qos()))},
+		{Name: labelKeyMQTTRetained, Value: strconv.FormatBool(msg.Retained())},
+	}, t.relabelConfig)
+
+	out := t.lbs.Clone()
+	if len(lbs) > 0 {
+		out = out.Merge(lbs)
+	}
+
+	entry := loki.Entry{
+		Labels: out,
+		Entry: logproto.Entry{
+			Timestamp: time.Now(),
+			Line:      string(msg.Payload()),
+		},
+	}
+	select {
+	case <-t.ctx.Done():
+	case t.handler.Chan() <- entry:
+	}
+}
+
+// Stop disconnects from the broker.
+func (t *target) Stop() {
+	t.cancel()
+	t.client.Disconnect(disconnectQuiesce)
+}
+
+// format applies the relabeling rules to the labels of a message, and drops
+// the labels which start with "__".
+func format(lbs labels.Labels, cfg []*relabel.Config) model.LabelSet {
+	if len(lbs) == 0 {
+		return nil
+	}
+	processed, _ := relabel.Process(lbs, cfg...)
+	labelOut := make(model.LabelSet, len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, "__") {
+			continue
+		}
+		labelOut[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return labelOut
+}
@@ -0,0 +1,257 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/config"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.nats",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the loki.source.nats
+// component.
+type Arguments struct {
+	URLs                 []string          `alloy:"urls,attr"`
+	Subjects             []string          `alloy:"subjects,attr,optional"`
+	QueueGroup           string            `alloy:"queue_group,attr,optional"`
+	Username             string            `alloy:"username,attr,optional"`
+	Password             alloytypes.Secret `alloy:"password,attr,optional"`
+	Token                alloytypes.Secret `alloy:"token,attr,optional"`
+	CredentialsFile      string            `alloy:"credentials_file,attr,optional"`
+	TLSConfig            *config.TLSConfig `alloy:"tls_config,block,optional"`
+	JetStream            *JetStreamConfig  `alloy:"jetstream,block,optional"`
+	UseIncomingTimestamp bool              `alloy:"use_incoming_timestamp,attr,optional"`
+	Labels               map[string]string `alloy:"labels,attr,optional"`
+
+	ForwardTo    []loki.LogsReceiver `alloy:"forward_to,attr"`
+	RelabelRules alloy_relabel.Rules `alloy:"relabel_rules,attr,optional"`
+}
+
+// JetStreamConfig configures the durable consumer which reads the messages
+// of a JetStream stream.
+type JetStreamConfig struct {
+	Stream        string        `alloy:"stream,attr"`
+	Durable       string        `alloy:"durable,attr,optional"`
+	DeliverPolicy string        `alloy:"deliver_policy,attr,optional"`
+	AckWait       time.Duration `alloy:"ack_wait,attr,optional"`
+	MaxAckPending int           `alloy:"max_ack_pending,attr,optional"`
+}
+
+// Supported values of deliver_policy.
+const (
+	deliverAll  = "all"
+	deliverNew  = "new"
+	deliverLast = "last"
+)
+
+// DefaultJetStreamConfig provides the default settings of a jetstream block.
+var DefaultJetStreamConfig = JetStreamConfig{
+	DeliverPolicy: deliverAll,
+	AckWait:       30 * time.Second,
+	MaxAckPending: 1000,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *JetStreamConfig) SetToDefault() {
+	*c = DefaultJetStreamConfig
+}
+
+// invalidDurableRune returns whether NATS rejects r in the name of a durable
+// consumer.
+func invalidDurableRune(r rune) bool {
+	return unicode.IsSpace(r) || !unicode.IsPrint(r) || strings.ContainsRune(".*>/\\", r)
+}
+
+// defaultDurable returns the default name of the durable consumer of a
+// component, which is its ID with the characters NATS rejects replaced.
+func defaultDurable(componentID string) string {
+	return strings.Map(func(r rune) rune {
+		if invalidDurableRune(r) {
+			return '_'
+		}
+		return r
+	}, componentID)
+}
+
+// Validate implements syntax.Validator.
+func (c *JetStreamConfig) Validate() error {
+	if c.Stream == "" {
+		return fmt.Errorf("stream must not be empty")
+	}
+	if strings.IndexFunc(c.Durable, invalidDurableRune) >= 0 {
+		return fmt.Errorf("invalid durable name %q: it must not contain whitespace, '.', '*', '>' or path separators", c.Durable)
+	}
+	switch c.DeliverPolicy {
+	case deliverAll, deliverNew, deliverLast:
+	default:
+		return fmt.Errorf("invalid deliver_policy %q: must be one of %q, %q or %q", c.DeliverPolicy, deliverAll, deliverNew, deliverLast)
+	}
+	if c.AckWait <= 0 {
+		return fmt.Errorf("ack_wait must be greater than 0")
+	}
+	if c.MaxAckPending <= 0 {
+		return fmt.Errorf("max_ack_pending must be greater than 0")
+	}
+	return nil
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.URLs) == 0 {
+		return fmt.Errorf("at least one url is required")
+	}
+	for _, u := range a.URLs {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return fmt.Errorf("invalid url %q: %w", u, err)
+		}
+		switch parsed.Scheme {
+		case "nats", "tls", "ws", "wss":
+		default:
+			return fmt.Errorf("invalid url %q: unsupported scheme %q", u, parsed.Scheme)
+		}
+	}
+	if a.JetStream == nil {
+		if len(a.Subjects) == 0 {
+			return fmt.Errorf("at least one subject is required")
+		}
+		if a.UseIncomingTimestamp {
+			return fmt.Errorf("use_incoming_timestamp requires a jetstream block")
+		}
+	} else {
+		if a.QueueGroup != "" {
+			return fmt.Errorf("queue_group can't be used with a jetstream block, the durable consumer already shares the messages")
+		}
+		// Filtering a consumer by several subjects requires NATS 2.10 or
+		// later, so it's left out to support older servers.
+		if len(a.Subjects) > 1 {
+			return fmt.Errorf("at most one subject can be used with a jetstream block")
+		}
+	}
+	if a.Username != "" && a.Token != "" {
+		return fmt.Errorf("at most one of username and token must be configured")
+	}
+	if a.CredentialsFile != "" && (a.Username != "" || a.Token != "") {
+		return fmt.Errorf("credentials_file can't be used with username or token")
+	}
+	if a.TLSConfig != nil {
+		return a.TLSConfig.Validate()
+	}
+	return nil
+}
+
+// Component implements the loki.source.nats component.
+type Component struct {
+	opts component.Options
+
+	mut    sync.RWMutex
+	fanout []loki.LogsReceiver
+	target *target
+
+	handler loki.LogsReceiver
+}
+
+// New creates a new loki.source.nats component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		fanout:  args.ForwardTo,
+		handler: loki.NewLogsReceiver(),
+	}
+
+	// Call to Update() to start readers and set receivers once at the start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		level.Info(c.opts.Logger).Log("msg", "loki.source.nats component shutting down, stopping target")
+		if c.target != nil {
+			c.target.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.handler.Chan():
+			c.mut.RLock()
+			for _, receiver := range c.fanout {
+				receiver.Chan() <- entry
+			}
+			c.mut.RUnlock()
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+	c.fanout = newArgs.ForwardTo
+
+	if c.target != nil {
+		c.target.Stop()
+	}
+
+	// The name of the durable consumer identifies its position in the
+	// stream, so by default it's derived from the ID of the component, which
+	// is unique in a configuration.
+	if newArgs.JetStream != nil && newArgs.JetStream.Durable == "" {
+		js := *newArgs.JetStream
+		js.Durable = defaultDurable(c.opts.ID)
+		newArgs.JetStream = &js
+	}
+
+	t, err := newTarget(c.opts.Logger, c.opts.ID, newArgs, c.handler)
+	if err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to create nats client with provided config", "err", err)
+		return err
+	}
+	c.target = t
+
+	return nil
+}
+
+func (a *Arguments) labels() model.LabelSet {
+	lbls := make(model.LabelSet, len(a.Labels))
+	for k, v := range a.Labels {
+		lbls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return lbls
+}
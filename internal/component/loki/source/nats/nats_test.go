@@ -0,0 +1,316 @@
+package nats
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/regexp"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments_Alloy(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(`
+		urls       = ["nats://localhost:4222"]
+		forward_to = []
+
+		jetstream {
+			stream = "LOGS"
+		}
+	`), &args)
+	require.NoError(t, err)
+	require.Equal(t, DefaultJetStreamConfig.DeliverPolicy, args.JetStream.DeliverPolicy)
+	require.Equal(t, DefaultJetStreamConfig.AckWait, args.JetStream.AckWait)
+	require.Equal(t, DefaultJetStreamConfig.MaxAckPending, args.JetStream.MaxAckPending)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		errSubstring string
+	}{
+		{
+			name: "no urls",
+			config: `
+				urls     = []
+				subjects = ["logs.>"]`,
+			errSubstring: "at least one url is required",
+		},
+		{
+			name: "unsupported scheme",
+			config: `
+				urls     = ["http://localhost:4222"]
+				subjects = ["logs.>"]`,
+			errSubstring: `unsupported scheme "http"`,
+		},
+		{
+			name: "no subjects",
+			config: `
+				urls = ["nats://localhost:4222"]`,
+			errSubstring: "at least one subject is required",
+		},
+		{
+			name: "incoming timestamp without jetstream",
+			config: `
+				urls                   = ["nats://localhost:4222"]
+				subjects               = ["logs.>"]
+				use_incoming_timestamp = true`,
+			errSubstring: "use_incoming_timestamp requires a jetstream block",
+		},
+		{
+			name: "queue group with jetstream",
+			config: `
+				urls        = ["nats://localhost:4222"]
+				queue_group = "alloy"
+				jetstream {
+					stream = "LOGS"
+				}`,
+			errSubstring: "queue_group can't be used with a jetstream block",
+		},
+		{
+			name: "several subjects with jetstream",
+			config: `
+				urls     = ["nats://localhost:4222"]
+				subjects = ["logs.a", "logs.b"]
+				jetstream {
+					stream = "LOGS"
+				}`,
+			errSubstring: "at most one subject can be used with a jetstream block",
+		},
+		{
+			name: "invalid durable",
+			config: `
+				urls = ["nats://localhost:4222"]
+				jetstream {
+					stream  = "LOGS"
+					durable = "loki.source.nats"
+				}`,
+			errSubstring: `invalid durable name "loki.source.nats"`,
+		},
+		{
+			name: "invalid deliver policy",
+			config: `
+				urls = ["nats://localhost:4222"]
+				jetstream {
+					stream         = "LOGS"
+					deliver_policy = "first"
+				}`,
+			errSubstring: `invalid deliver_policy "first"`,
+		},
+		{
+			name: "username and token",
+			config: `
+				urls     = ["nats://localhost:4222"]
+				subjects = ["logs.>"]
+				username = "alloy"
+				token    = "secret"`,
+			errSubstring: "at most one of username and token must be configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tt.config+"\nforward_to = []\n"), &args)
+			require.ErrorContains(t, err, tt.errSubstring)
+		})
+	}
+}
+
+func TestComponent(t *testing.T) {
+	srv := runServer(t)
+
+	receiver := loki.NewLogsReceiver()
+	args := Arguments{
+		URLs:      []string{srv.ClientURL()},
+		Subjects:  []string{"logs.>"},
+		Labels:    map[string]string{"job": "edge"},
+		ForwardTo: []loki.LogsReceiver{receiver},
+		RelabelRules: alloy_relabel.Rules{
+			{
+				SourceLabels: []string{"__meta_nats_subject"},
+				Regex:        mustNewRegexp(`logs\.([^.]+)`),
+				Action:       alloy_relabel.Replace,
+				TargetLabel:  "device",
+				Replacement:  "$1",
+			},
+		},
+	}
+	c := newComponent(t, args)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	// Wait for the subscription to be registered in the server.
+	require.NoError(t, c.target.conn.Flush())
+
+	nc, err := natsgo.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+	require.NoError(t, nc.Publish("metrics.gateway-1", []byte("ignored")))
+	require.NoError(t, nc.Publish("logs.gateway-1", []byte("link up")))
+
+	entry := receive(t, receiver)
+	require.Equal(t, "link up", entry.Line)
+	require.Equal(t, model.LabelSet{"job": "edge", "device": "gateway-1"}, entry.Labels)
+	requireNoEntry(t, receiver)
+}
+
+func TestComponent_JetStream(t *testing.T) {
+	srv := runServer(t)
+
+	nc, err := natsgo.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "LOGS", Subjects: []string{"logs.>"}})
+	require.NoError(t, err)
+
+	// Messages stored before the component starts are delivered.
+	ack, err := js.Publish(ctx, "logs.gateway-1", []byte("first"))
+	require.NoError(t, err)
+	msg, err := stream.GetMsg(ctx, ack.Sequence)
+	require.NoError(t, err)
+
+	receiver := loki.NewLogsReceiver()
+	jsCfg := DefaultJetStreamConfig
+	jsCfg.Stream = "LOGS"
+	args := Arguments{
+		URLs:                 []string{srv.ClientURL()},
+		JetStream:            &jsCfg,
+		UseIncomingTimestamp: true,
+		ForwardTo:            []loki.LogsReceiver{receiver},
+		RelabelRules: alloy_relabel.Rules{
+			{
+				SourceLabels: []string{"__meta_nats_stream"},
+				Regex:        mustNewRegexp("(.*)"),
+				Action:       alloy_relabel.Replace,
+				TargetLabel:  "stream",
+				Replacement:  "$1",
+			},
+		},
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	c := newComponent(t, args)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(runCtx)
+	}()
+
+	entry := receive(t, receiver)
+	require.Equal(t, "first", entry.Line)
+	require.Equal(t, model.LabelSet{"stream": "LOGS"}, entry.Labels)
+	require.True(t, msg.Time.Equal(entry.Timestamp))
+
+	// The message is acknowledged once it has been forwarded.
+	require.Eventually(t, func() bool {
+		cons, err := stream.Consumer(ctx, "loki_source_nats_test")
+		if err != nil {
+			return false
+		}
+		info, err := cons.Info(ctx)
+		return err == nil && info.NumAckPending == 0 && info.AckFloor.Stream == ack.Sequence
+	}, 10*time.Second, 10*time.Millisecond)
+
+	stop()
+	<-done
+
+	// The durable consumer resumes from where the previous one stopped.
+	_, err = js.Publish(ctx, "logs.gateway-1", []byte("second"))
+	require.NoError(t, err)
+
+	c = newComponent(t, args)
+	go c.Run(ctx)
+
+	entry = receive(t, receiver)
+	require.Equal(t, "second", entry.Line)
+	requireNoEntry(t, receiver)
+}
+
+func runServer(t *testing.T) interface{ ClientURL() string } {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	srv := natsserver.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func newComponent(t *testing.T, args Arguments) *Component {
+	c, err := New(component.Options{
+		ID:            "loki.source.nats.test",
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+	return c
+}
+
+func receive(t *testing.T, receiver loki.LogsReceiver) loki.Entry {
+	t.Helper()
+	select {
+	case entry := <-receiver.Chan():
+		return entry
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for an entry")
+		return loki.Entry{}
+	}
+}
+
+func requireNoEntry(t *testing.T, receiver loki.LogsReceiver) {
+	t.Helper()
+	select {
+	case entry := <-receiver.Chan():
+		t.Fatalf("unexpected entry %v", entry)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func mustNewRegexp(s string) alloy_relabel.Regexp {
+	re, err := regexp.Compile("^(?:" + s + ")$")
+	if err != nil {
+		panic(err)
+	}
+	return alloy_relabel.Regexp{Regexp: re}
+}
+
+func TestDefaultDurable(t *testing.T) {
+	tests := []struct {
+		componentID string
+		expected    string
+	}{
+		{"loki.source.nats.default", "loki_source_nats_default"},
+		{"custom_logs.default/loki.source.nats.default", "custom_logs_default_loki_source_nats_default"},
+		{`windows\loki.source.nats.a b`, "windows_loki_source_nats_a_b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.componentID, func(t *testing.T) {
+			durable := defaultDurable(tt.componentID)
+			require.Equal(t, tt.expected, durable)
+			require.Negative(t, strings.IndexFunc(durable, invalidDurableRune))
+		})
+	}
+}
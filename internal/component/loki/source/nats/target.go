@@ -0,0 +1,257 @@
+package nats
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+const (
+	labelKeyNATSSubject = "__meta_nats_subject"
+	labelKeyNATSStream  = "__meta_nats_stream"
+
+	// consumerRetryInterval is how long to wait before trying again to create
+	// the JetStream consumer, when the server isn't available or the stream
+	// doesn't exist yet.
+	consumerRetryInterval = 5 * time.Second
+)
+
+// target reads the messages of NATS subjects, or of a JetStream stream, and
+// forwards them as log entries.
+type target struct {
+	logger               log.Logger
+	conn                 *natsgo.Conn
+	handler              loki.LogsReceiver
+	lbs                  model.LabelSet
+	relabelConfig        []*relabel.Config
+	useIncomingTimestamp bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newTarget(logger log.Logger, name string, args Arguments, handler loki.LogsReceiver) (*target, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &target{
+		logger:               logger,
+		handler:              handler,
+		lbs:                  args.labels(),
+		relabelConfig:        alloy_relabel.ComponentToPromRelabelConfigs(args.RelabelRules),
+		useIncomingTimestamp: args.UseIncomingTimestamp,
+		ctx:                  ctx,
+		cancel:               cancel,
+	}
+
+	// The client keeps retrying to connect in the background, so an
+	// unavailable server doesn't prevent the component from starting.
+	opts := []natsgo.Option{
+		natsgo.Name(name),
+		natsgo.RetryOnFailedConnect(true),
+		natsgo.MaxReconnects(-1),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			if err != nil {
+				level.Warn(t.logger).Log("msg", "disconnected from nats server", "err", err)
+			}
+		}),
+		natsgo.ReconnectHandler(func(c *natsgo.Conn) {
+			level.Info(t.logger).Log("msg", "reconnected to nats server", "url", c.ConnectedUrlRedacted())
+		}),
+		natsgo.ErrorHandler(func(_ *natsgo.Conn, sub *natsgo.Subscription, err error) {
+			if sub != nil {
+				level.Warn(t.logger).Log("msg", "nats subscription error", "subject", sub.Subject, "err", err)
+				return
+			}
+			level.Warn(t.logger).Log("msg", "nats client error", "err", err)
+		}),
+	}
+	switch {
+	case args.Username != "":
+		opts = append(opts, natsgo.UserInfo(args.Username, string(args.Password)))
+	case args.Token != "":
+		opts = append(opts, natsgo.Token(string(args.Token)))
+	case args.CredentialsFile != "":
+		opts = append(opts, natsgo.UserCredentials(args.CredentialsFile))
+	}
+	if args.TLSConfig != nil {
+		tlsConfig, err := promconfig.NewTLSConfig(args.TLSConfig.Convert())
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		opts = append(opts, natsgo.Secure(tlsConfig))
+	}
+
+	conn, err := natsgo.Connect(strings.Join(args.URLs, ","), opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	t.conn = conn
+
+	if args.JetStream != nil {
+		js, err := jetstream.New(conn)
+		if err != nil {
+			t.Stop()
+			return nil, err
+		}
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.consume(js, args.Subjects, *args.JetStream)
+		}()
+		return t, nil
+	}
+
+	for _, subject := range args.Subjects {
+		if args.QueueGroup != "" {
+			_, err = conn.QueueSubscribe(subject, args.QueueGroup, t.handleMessage)
+		} else {
+			_, err = conn.Subscribe(subject, t.handleMessage)
+		}
+		if err != nil {
+			t.Stop()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// consume creates the durable consumer of the stream, and reads its messages
+// until the target is stopped. Messages are acknowledged once they've been
+// handed to the component.
+func (t *target) consume(js jetstream.JetStream, subjects []string, cfg JetStreamConfig) {
+	consumerCfg := jetstream.ConsumerConfig{
+		Durable:       cfg.Durable,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       cfg.AckWait,
+		MaxAckPending: cfg.MaxAckPending,
+	}
+	switch cfg.DeliverPolicy {
+	case deliverNew:
+		consumerCfg.DeliverPolicy = jetstream.DeliverNewPolicy
+	case deliverLast:
+		consumerCfg.DeliverPolicy = jetstream.DeliverLastPolicy
+	default:
+		consumerCfg.DeliverPolicy = jetstream.DeliverAllPolicy
+	}
+	if len(subjects) == 1 {
+		consumerCfg.FilterSubject = subjects[0]
+	}
+
+	logger := log.With(t.logger, "stream", cfg.Stream, "consumer", cfg.Durable)
+	for {
+		var consumeCtx jetstream.ConsumeContext
+		cons, err := js.CreateOrUpdateConsumer(t.ctx, cfg.Stream, consumerCfg)
+		if err == nil {
+			consumeCtx, err = cons.Consume(func(msg jetstream.Msg) {
+				t.handleJetStreamMessage(cfg.Stream, msg)
+			}, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+				level.Warn(logger).Log("msg", "failed to consume jetstream messages", "err", err)
+			}))
+		}
+		if err == nil {
+			level.Info(logger).Log("msg", "consuming jetstream messages")
+			<-t.ctx.Done()
+			consumeCtx.Stop()
+			return
+		}
+		if t.ctx.Err() != nil {
+			return
+		}
+		level.Error(logger).Log("msg", "failed to create jetstream consumer, retrying", "err", err, "retry_in", consumerRetryInterval)
+
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-time.After(consumerRetryInterval):
+		}
+	}
+}
+
+func (t *target) handleMessage(msg *natsgo.Msg) {
+	t.send(time.Now(), msg.Data, []labels.Label{
+		{Name: labelKeyNATSSubject, Value: msg.Subject},
+	})
+}
+
+func (t *target) handleJetStreamMessage(stream string, msg jetstream.Msg) {
+	ts := time.Now()
+	if t.useIncomingTimestamp {
+		if md, err := msg.Metadata(); err == nil {
+			ts = md.Timestamp
+		}
+	}
+	if !t.send(ts, msg.Data(), []labels.Label{
+		{Name: labelKeyNATSSubject, Value: msg.Subject()},
+		{Name: labelKeyNATSStream, Value: stream},
+	}) {
+		// The message is redelivered when the target is started again.
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		level.Warn(t.logger).Log("msg", "failed to acknowledge jetstream message", "subject", msg.Subject(), "err", err)
+	}
+}
+
+// send forwards a message as an entry, and reports whether it was sent before
+// the target was stopped.
+func (t *target) send(ts time.Time, data []byte, meta labels.Labels) bool {
+	lbs := format(meta, t.relabelConfig)
+	out := t.lbs.Clone()
+	if len(lbs) > 0 {
+		out = out.Merge(lbs)
+	}
+
+	entry := loki.Entry{
+		Labels: out,
+		Entry: logproto.Entry{
+			Timestamp: ts,
+			Line:      string(data),
+		},
+	}
+	select {
+	case <-t.ctx.Done():
+		return false
+	case t.handler.Chan() <- entry:
+		return true
+	}
+}
+
+// Stop closes the subscriptions and the connection to the server.
+func (t *target) Stop() {
+	t.cancel()
+	t.wg.Wait()
+	t.conn.Close()
+}
+
+// format applies the relabeling rules to the labels of a message, and drops
+// the labels which start with "__".
+func format(lbs labels.Labels, cfg []*relabel.Config) model.LabelSet {
+	if len(lbs) == 0 {
+		return nil
+	}
+	processed, _ := relabel.Process(lbs, cfg...)
+	labelOut := make(model.LabelSet, len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, "__") {
+			continue
+		}
+		labelOut[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return labelOut
+}
@@ -0,0 +1,303 @@
+package file
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/go-kit/log"
+	"github.com/klauspost/compress/zstd"
+
+	source_file "github.com/grafana/alloy/internal/component/loki/source/file"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// rotatedTimeFormat is the format of the time appended to the names of the
+// rotated files.
+const rotatedTimeFormat = "20060102T150405.000Z"
+
+// rotatedFileRegexp matches the names of the rotated files, which may be
+// compressed.
+var rotatedFileRegexp = regexp.MustCompile(`-\d{8}T\d{6}\.\d{3}Z(\.(gz|z|zst))?$`)
+
+// retryInterval is how often the rotated files which failed to be compressed
+// or uploaded are processed again.
+const retryInterval = time.Minute
+
+// compressors holds the compression formats the files can be written with.
+var compressors = map[source_file.CompressionFormat]func(io.Writer) (io.WriteCloser, error){
+	"gz": func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+	"z": func(w io.Writer) (io.WriteCloser, error) {
+		return zlib.NewWriter(w), nil
+	},
+	"zstd": func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	},
+}
+
+// rotatedPath returns the path a file is renamed to when it's rotated at t.
+// The time is moved forward until no rotated file, compressed or not, has the
+// same name.
+func rotatedPath(path string, t time.Time) string {
+	for {
+		rotated := path + "-" + t.UTC().Format(rotatedTimeFormat)
+		if !exists(rotated) && !existsCompressed(rotated) {
+			return rotated
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func existsCompressed(path string) bool {
+	for format := range compressors {
+		if exists(path + "." + format.Extension()) {
+			return true
+		}
+	}
+	return false
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// uploader uploads the rotated files to a bucket.
+type uploader struct {
+	client            *s3.Client
+	bucket            string
+	prefix            string
+	deleteAfterUpload bool
+}
+
+// archiver compresses and uploads the rotated files. The directory is
+// scanned for rotated files, so that the files rotated before a restart, or
+// which failed to be processed, are processed as well.
+type archiver struct {
+	logger  log.Logger
+	metrics *metrics
+
+	mut         sync.Mutex
+	directory   string
+	compression source_file.CompressionFormat
+	upload      *uploader
+	// uploaded holds the files which were uploaded but not deleted.
+	uploaded map[string]struct{}
+
+	signal chan struct{}
+}
+
+func newArchiver(logger log.Logger, m *metrics) *archiver {
+	return &archiver{
+		logger:   logger,
+		metrics:  m,
+		uploaded: make(map[string]struct{}),
+		signal:   make(chan struct{}, 1),
+	}
+}
+
+func (a *archiver) update(directory string, compression source_file.CompressionFormat, upload *uploader) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if directory != a.directory || upload == nil || a.upload == nil ||
+		upload.bucket != a.upload.bucket || upload.prefix != a.upload.prefix {
+		a.uploaded = make(map[string]struct{})
+	}
+	a.directory, a.compression, a.upload = directory, compression, upload
+	a.notify()
+}
+
+// notify tells the archiver that a file was rotated.
+func (a *archiver) notify() {
+	select {
+	case a.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (a *archiver) run(ctx context.Context) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.signal:
+		case <-ticker.C:
+		}
+		a.archive(ctx)
+	}
+}
+
+// archive compresses and uploads the rotated files of the directory.
+func (a *archiver) archive(ctx context.Context) {
+	a.mut.Lock()
+	directory, compression, upload := a.directory, a.compression, a.upload
+	a.mut.Unlock()
+	if compression == "" && upload == nil {
+		return
+	}
+
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m := rotatedFileRegexp.FindStringSubmatch(d.Name())
+		if d.IsDir() || m == nil {
+			return nil
+		}
+
+		if compression != "" && m[2] == "" {
+			compressed, err := compressFile(path, compression)
+			if err != nil {
+				level.Error(a.logger).Log("msg", "failed to compress rotated file", "path", path, "err", err)
+				a.metrics.compressionFailures.Inc()
+				return nil
+			}
+			path = compressed
+		}
+		if upload != nil {
+			a.uploadFile(ctx, upload, directory, path)
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		level.Error(a.logger).Log("msg", "failed to look for rotated files", "directory", directory, "err", err)
+	}
+}
+
+// compressFile compresses a file, and removes it once the compressed file is
+// written. It returns the path of the compressed file.
+func compressFile(path string, format source_file.CompressionFormat) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	compressed := path + "." + format.Extension()
+	tmp := compressed + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+
+	w, err := compressors[format](out)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, compressed); err != nil {
+		return "", err
+	}
+	return compressed, os.Remove(path)
+}
+
+// uploadFile uploads a rotated file unless it's already in the bucket. The
+// key of the object is the path of the file relative to the directory,
+// after the prefix.
+func (a *archiver) uploadFile(ctx context.Context, upload *uploader, directory, file string) {
+	a.mut.Lock()
+	_, uploaded := a.uploaded[file]
+	a.mut.Unlock()
+	if uploaded {
+		return
+	}
+
+	rel, err := filepath.Rel(directory, file)
+	if err != nil {
+		level.Error(a.logger).Log("msg", "failed to upload rotated file", "path", file, "err", err)
+		a.metrics.uploadFailures.Inc()
+		return
+	}
+	key := path.Join(upload.prefix, filepath.ToSlash(rel))
+
+	if err := a.putObject(ctx, upload, key, file); err != nil {
+		if ctx.Err() == nil {
+			level.Error(a.logger).Log("msg", "failed to upload rotated file", "path", file, "bucket", upload.bucket, "key", key, "err", err)
+			a.metrics.uploadFailures.Inc()
+		}
+		return
+	}
+	level.Debug(a.logger).Log("msg", "uploaded rotated file", "path", file, "bucket", upload.bucket, "key", key)
+
+	if upload.deleteAfterUpload {
+		if err := os.Remove(file); err != nil {
+			level.Error(a.logger).Log("msg", "failed to delete uploaded file", "path", file, "err", err)
+		}
+		return
+	}
+	a.mut.Lock()
+	a.uploaded[file] = struct{}{}
+	a.mut.Unlock()
+}
+
+func (a *archiver) putObject(ctx context.Context, upload *uploader, key, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The file may have been uploaded before a restart.
+	head, err := upload.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(upload.bucket),
+		Key:    aws.String(key),
+	})
+	var respErr *awshttp.ResponseError
+	switch {
+	case err == nil && aws.ToInt64(head.ContentLength) == fi.Size():
+		return nil
+	case err != nil && !(errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound):
+		return err
+	}
+
+	_, err = upload.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(upload.bucket),
+		Key:           aws.String(key),
+		Body:          f,
+		ContentLength: aws.Int64(fi.Size()),
+	})
+	if err != nil {
+		return err
+	}
+	a.metrics.uploads.Inc()
+	return nil
+}
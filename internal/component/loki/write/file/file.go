@@ -0,0 +1,319 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/alecthomas/units"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	source_file "github.com/grafana/alloy/internal/component/loki/source/file"
+	remote_s3 "github.com/grafana/alloy/internal/component/remote/s3"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.write.file",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Formats of the log entries written to the files.
+const (
+	FormatJSON = "json"
+	FormatRaw  = "raw"
+)
+
+// flushInterval is how often the buffered log entries are written to the
+// files, and the age of the files is checked.
+const flushInterval = time.Second
+
+// Arguments holds values which are used to configure the loki.write.file
+// component.
+type Arguments struct {
+	Directory    string                        `alloy:"directory,attr,optional"`
+	PathTemplate string                        `alloy:"path_template,attr,optional"`
+	Format       string                        `alloy:"format,attr,optional"`
+	Compression  source_file.CompressionFormat `alloy:"compression,attr,optional"`
+	Rotation     RotationConfig                `alloy:"rotation,block,optional"`
+	Upload       *UploadConfig                 `alloy:"upload,block,optional"`
+}
+
+// RotationConfig configures when the files are rotated.
+type RotationConfig struct {
+	MaxSize units.Base2Bytes `alloy:"max_size,attr,optional"`
+	MaxAge  time.Duration    `alloy:"max_age,attr,optional"`
+}
+
+// UploadConfig configures the upload of the rotated files to an
+// S3-compatible object storage.
+type UploadConfig struct {
+	Bucket            string           `alloy:"bucket,attr"`
+	Prefix            string           `alloy:"prefix,attr,optional"`
+	DeleteAfterUpload bool             `alloy:"delete_after_upload,attr,optional"`
+	Client            remote_s3.Client `alloy:"client,block,optional"`
+}
+
+// Exports holds the values exported by the loki.write.file component.
+type Exports struct {
+	Receiver loki.LogsReceiver `alloy:"receiver,attr"`
+}
+
+// DefaultArguments defines the default settings of the loki.write.file
+// component.
+var DefaultArguments = Arguments{
+	PathTemplate: "alloy.log",
+	Format:       FormatJSON,
+	Rotation: RotationConfig{
+		MaxSize: 100 * units.MiB,
+		MaxAge:  time.Hour,
+	},
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if _, err := parsePathTemplate(args.PathTemplate); err != nil {
+		return fmt.Errorf("invalid path_template: %w", err)
+	}
+	if args.Format != FormatJSON && args.Format != FormatRaw {
+		return fmt.Errorf("format must be %q or %q, got %q", FormatJSON, FormatRaw, args.Format)
+	}
+	if args.Compression != "" {
+		if _, ok := compressors[args.Compression]; !ok {
+			return fmt.Errorf("compression format %q isn't supported to write files", args.Compression)
+		}
+	}
+	if args.Rotation.MaxSize < 0 {
+		return fmt.Errorf("max_size must not be negative")
+	}
+	if args.Rotation.MaxAge < 0 {
+		return fmt.Errorf("max_age must not be negative")
+	}
+	if args.Upload != nil && args.Upload.Bucket == "" {
+		return fmt.Errorf("bucket must not be empty")
+	}
+	return nil
+}
+
+func parsePathTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("template must not be empty")
+	}
+	return template.New("path_template").Option("missingkey=zero").Parse(text)
+}
+
+var _ component.Component = (*Component)(nil)
+
+// Component implements the loki.write.file component.
+type Component struct {
+	opts     component.Options
+	metrics  *metrics
+	receiver loki.LogsReceiver
+	archiver *archiver
+
+	mut       sync.RWMutex
+	args      Arguments
+	template  *template.Template
+	directory string
+}
+
+// New creates a new loki.write.file component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	m := newMetrics(o.Registerer)
+	c := &Component{
+		opts:     o,
+		metrics:  m,
+		receiver: loki.NewLogsReceiver(),
+		archiver: newArchiver(o.Logger, m),
+	}
+
+	// Call to Update() once at the start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.archiver.run(ctx)
+	}()
+	defer wg.Wait()
+
+	writers := make(map[string]*fileWriter)
+	defer c.closeAll(writers)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.receiver.Chan():
+			c.write(writers, entry)
+		case <-ticker.C:
+			c.flush(writers)
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	tmpl, err := parsePathTemplate(newArgs.PathTemplate)
+	if err != nil {
+		return err
+	}
+
+	var up *uploader
+	if newArgs.Upload != nil {
+		client, err := remote_s3.NewClient(newArgs.Upload.Client)
+		if err != nil {
+			return err
+		}
+		up = &uploader{
+			client:            client,
+			bucket:            newArgs.Upload.Bucket,
+			prefix:            newArgs.Upload.Prefix,
+			deleteAfterUpload: newArgs.Upload.DeleteAfterUpload,
+		}
+	}
+
+	directory := newArgs.Directory
+	if directory == "" {
+		directory = c.opts.DataPath
+	}
+
+	c.mut.Lock()
+	c.args = newArgs
+	c.template = tmpl
+	c.directory = directory
+	c.mut.Unlock()
+
+	// The files which aren't written to anymore with the new arguments stay
+	// open until they're rotated.
+	c.archiver.update(directory, newArgs.Compression, up)
+	return nil
+}
+
+// write writes an entry to the file its labels map to, and rotates the file
+// first if the entry would make it larger than the maximum size.
+func (c *Component) write(writers map[string]*fileWriter, entry loki.Entry) {
+	c.mut.RLock()
+	tmpl, directory, format, maxSize := c.template, c.directory, c.args.Format, int64(c.args.Rotation.MaxSize)
+	c.mut.RUnlock()
+
+	path, err := renderPath(tmpl, directory, entry.Labels)
+	if err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to render the path of a log entry, dropping it", "labels", entry.Labels.String(), "err", err)
+		c.metrics.droppedEntries.Inc()
+		return
+	}
+	b, err := encodeEntry(format, entry)
+	if err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to encode a log entry, dropping it", "path", path, "err", err)
+		c.metrics.droppedEntries.Inc()
+		return
+	}
+
+	w, ok := writers[path]
+	if !ok {
+		if w, err = openFileWriter(path); err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to open file, dropping log entry", "path", path, "err", err)
+			c.metrics.droppedEntries.Inc()
+			return
+		}
+		writers[path] = w
+	}
+	if maxSize > 0 && w.size > 0 && w.size+int64(len(b)) > maxSize {
+		c.rotate(w)
+		if w, err = openFileWriter(path); err != nil {
+			delete(writers, path)
+			level.Error(c.opts.Logger).Log("msg", "failed to open file, dropping log entry", "path", path, "err", err)
+			c.metrics.droppedEntries.Inc()
+			return
+		}
+		writers[path] = w
+	}
+
+	if err := w.write(b); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to write log entry", "path", path, "err", err)
+		c.metrics.droppedEntries.Inc()
+		return
+	}
+	c.metrics.entries.Inc()
+	c.metrics.bytes.Add(float64(len(b)))
+}
+
+// flush writes the buffered entries to the files, and rotates the files
+// older than the maximum age.
+func (c *Component) flush(writers map[string]*fileWriter) {
+	c.mut.RLock()
+	maxAge := c.args.Rotation.MaxAge
+	c.mut.RUnlock()
+
+	for path, w := range writers {
+		if maxAge > 0 && time.Since(w.opened) >= maxAge {
+			delete(writers, path)
+			c.rotate(w)
+			continue
+		}
+		if err := w.flush(); err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to flush file", "path", path, "err", err)
+		}
+	}
+}
+
+// rotate closes a file and renames it, so that it's compressed and uploaded
+// by the archiver.
+func (c *Component) rotate(w *fileWriter) {
+	if err := w.close(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to close file", "path", w.path, "err", err)
+	}
+	rotated := rotatedPath(w.path, time.Now())
+	if err := os.Rename(w.path, rotated); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to rotate file", "path", w.path, "err", err)
+		return
+	}
+	level.Debug(c.opts.Logger).Log("msg", "rotated file", "path", w.path, "rotated_path", rotated)
+	c.metrics.rotations.Inc()
+	c.archiver.notify()
+}
+
+func (c *Component) closeAll(writers map[string]*fileWriter) {
+	for path, w := range writers {
+		if err := w.close(); err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to close file", "path", path, "err", err)
+		}
+		delete(writers, path)
+	}
+}
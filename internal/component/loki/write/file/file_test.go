@@ -0,0 +1,324 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	remote_s3 "github.com/grafana/alloy/internal/component/remote/s3"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments_Alloy(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		path_template = "{{ .job }}/{{ .instance }}.log"
+		compression   = "zstd"
+
+		rotation {
+			max_size = "10MiB"
+		}
+
+		upload {
+			bucket = "archive"
+		}
+	`), &args))
+	require.Equal(t, FormatJSON, args.Format)
+	require.Equal(t, "zstd", args.Compression.String())
+	require.Equal(t, int64(10<<20), int64(args.Rotation.MaxSize))
+	require.Equal(t, time.Hour, args.Rotation.MaxAge)
+	require.Equal(t, "archive", args.Upload.Bucket)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		errSubstring string
+	}{
+		{
+			name:         "invalid template",
+			config:       `path_template = "{{ .job"`,
+			errSubstring: "invalid path_template",
+		},
+		{
+			name:         "invalid format",
+			config:       `format = "xml"`,
+			errSubstring: `format must be "json" or "raw", got "xml"`,
+		},
+		{
+			name:         "invalid compression",
+			config:       `compression = "lz4"`,
+			errSubstring: `unsupported compression format: "lz4"`,
+		},
+		{
+			name:         "bzip2 compression",
+			config:       `compression = "bz2"`,
+			errSubstring: `compression format "bz2" isn't supported to write files`,
+		},
+		{
+			name: "negative max_age",
+			config: `
+				rotation {
+					max_age = "-1s"
+				}`,
+			errSubstring: "max_age must not be negative",
+		},
+		{
+			name: "empty bucket",
+			config: `
+				upload {
+					bucket = ""
+				}`,
+			errSubstring: "bucket must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tt.config), &args)
+			require.ErrorContains(t, err, tt.errSubstring)
+		})
+	}
+}
+
+func TestRenderPath(t *testing.T) {
+	tmpl, err := parsePathTemplate("{{ .job }}/{{ .instance }}.log")
+	require.NoError(t, err)
+
+	path, err := renderPath(tmpl, "/archive", model.LabelSet{"job": "node", "instance": "host:9100"})
+	require.NoError(t, err)
+	require.Equal(t, filepath.FromSlash("/archive/node/host:9100.log"), path)
+
+	// Label values can't add directories to the path.
+	path, err = renderPath(tmpl, "/archive", model.LabelSet{"job": "node", "instance": "../../etc/passwd"})
+	require.NoError(t, err)
+	require.Equal(t, filepath.FromSlash("/archive/node/.._.._etc_passwd.log"), path)
+	_, err = renderPath(tmpl, "/archive", model.LabelSet{"job": "..", "instance": "x"})
+	require.Error(t, err)
+
+	tmpl, err = parsePathTemplate("../{{ .job }}.log")
+	require.NoError(t, err)
+	_, err = renderPath(tmpl, "/archive", model.LabelSet{"job": "node"})
+	require.Error(t, err)
+}
+
+func TestComponent_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	c := startComponent(t, Arguments{
+		Directory:    dir,
+		PathTemplate: "{{ .job }}.log",
+		Format:       FormatRaw,
+		Compression:  "gz",
+		Rotation:     RotationConfig{MaxSize: 10},
+	})
+
+	for _, line := range []string{"first", "second", "third"} {
+		c.receiver.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"job": "app"},
+			Entry:  push.Entry{Timestamp: time.Now(), Line: line},
+		}
+	}
+
+	// Each line is larger than half the maximum size, so each file but the
+	// last one is rotated and compressed with a single line.
+	var rotated []string
+	require.Eventually(t, func() bool {
+		rotated, _ = filepath.Glob(filepath.Join(dir, "app.log-*.gz"))
+		return len(rotated) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	var lines []string
+	for _, path := range rotated {
+		lines = append(lines, readGzip(t, path))
+	}
+	require.ElementsMatch(t, []string{"first\n", "second\n"}, lines)
+
+	require.Eventually(t, func() bool {
+		b, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+		return string(b) == "third\n"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestComponent_JSON(t *testing.T) {
+	dir := t.TempDir()
+	c := startComponent(t, Arguments{
+		Directory:    dir,
+		PathTemplate: "{{ .job }}/{{ .level }}.log",
+		Format:       FormatJSON,
+	})
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.receiver.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"job": "app", "level": "error"},
+		Entry: push.Entry{
+			Timestamp:          ts,
+			Line:               "oops",
+			StructuredMetadata: push.LabelsAdapter{{Name: "trace_id", Value: "abc"}},
+		},
+	}
+
+	require.Eventually(t, func() bool {
+		b, _ := os.ReadFile(filepath.Join(dir, "app", "error.log"))
+		return string(b) == `{"timestamp":"2024-01-02T03:04:05Z","labels":{"job":"app","level":"error"},"structured_metadata":{"trace_id":"abc"},"line":"oops"}`+"\n"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestComponent_Upload(t *testing.T) {
+	bucket := newFakeBucket()
+	srv := httptest.NewServer(bucket)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	// A file rotated before the component started is uploaded as well.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.log-20240102T030405.000Z"), []byte("old\n"), 0640))
+
+	c := startComponent(t, Arguments{
+		Directory:    dir,
+		PathTemplate: "{{ .job }}.log",
+		Format:       FormatRaw,
+		Compression:  "gz",
+		Rotation:     RotationConfig{MaxAge: 10 * time.Millisecond},
+		Upload: &UploadConfig{
+			Bucket:            "archive",
+			Prefix:            "site-1",
+			DeleteAfterUpload: true,
+			Client: remote_s3.Client{
+				AccessKey:    "key",
+				Secret:       "secret",
+				Endpoint:     srv.URL,
+				UsePathStyle: true,
+				Region:       "us-east-1",
+			},
+		},
+	})
+
+	c.receiver.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"job": "app"},
+		Entry:  push.Entry{Timestamp: time.Now(), Line: "new"},
+	}
+
+	require.Eventually(t, func() bool {
+		return len(bucket.keys()) == 2
+	}, 10*time.Second, 10*time.Millisecond)
+
+	var contents []string
+	for _, key := range bucket.keys() {
+		require.True(t, strings.HasPrefix(key, "/archive/site-1/app.log-"), key)
+		require.True(t, strings.HasSuffix(key, ".gz"), key)
+		contents = append(contents, gunzip(t, bucket.get(key)))
+	}
+	require.ElementsMatch(t, []string{"old\n", "new\n"}, contents)
+
+	// The uploaded files are deleted.
+	require.Eventually(t, func() bool {
+		rotated, _ := filepath.Glob(filepath.Join(dir, "app.log-*"))
+		return len(rotated) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func startComponent(t *testing.T, args Arguments) *Component {
+	t.Helper()
+
+	c, err := New(component.Options{
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+		DataPath:      t.TempDir(),
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return c
+}
+
+func readGzip(t *testing.T, path string) string {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return gunzip(t, b)
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(content)
+}
+
+// fakeBucket implements the HEAD and PUT object requests of the S3 API.
+type fakeBucket struct {
+	mut     sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeBucket() *fakeBucket {
+	return &fakeBucket{objects: make(map[string][]byte)}
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	switch r.Method {
+	case http.MethodHead:
+		content, ok := b.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	case http.MethodPut:
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		b.objects[r.URL.Path] = content
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (b *fakeBucket) keys() []string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	var keys []string
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (b *fakeBucket) get(key string) []byte {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.objects[key]
+}
@@ -0,0 +1,58 @@
+package file
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/util"
+)
+
+type metrics struct {
+	entries             prometheus.Counter
+	bytes               prometheus.Counter
+	droppedEntries      prometheus.Counter
+	rotations           prometheus.Counter
+	compressionFailures prometheus.Counter
+	uploads             prometheus.Counter
+	uploadFailures      prometheus.Counter
+}
+
+func newMetrics(r prometheus.Registerer) *metrics {
+	m := &metrics{
+		entries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_write_file_entries_total",
+			Help: "Total number of log entries written to files.",
+		}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_write_file_bytes_total",
+			Help: "Total number of bytes written to files.",
+		}),
+		droppedEntries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_write_file_dropped_entries_total",
+			Help: "Total number of log entries which couldn't be written to files.",
+		}),
+		rotations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_write_file_rotations_total",
+			Help: "Total number of files rotated.",
+		}),
+		compressionFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_write_file_compression_failures_total",
+			Help: "Total number of rotated files which failed to be compressed.",
+		}),
+		uploads: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_write_file_uploads_total",
+			Help: "Total number of rotated files uploaded to the bucket.",
+		}),
+		uploadFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "loki_write_file_upload_failures_total",
+			Help: "Total number of rotated files which failed to be uploaded to the bucket.",
+		}),
+	}
+	m.entries = util.MustRegisterOrGet(r, m.entries).(prometheus.Counter)
+	m.bytes = util.MustRegisterOrGet(r, m.bytes).(prometheus.Counter)
+	m.droppedEntries = util.MustRegisterOrGet(r, m.droppedEntries).(prometheus.Counter)
+	m.rotations = util.MustRegisterOrGet(r, m.rotations).(prometheus.Counter)
+	m.compressionFailures = util.MustRegisterOrGet(r, m.compressionFailures).(prometheus.Counter)
+	m.uploads = util.MustRegisterOrGet(r, m.uploads).(prometheus.Counter)
+	m.uploadFailures = util.MustRegisterOrGet(r, m.uploadFailures).(prometheus.Counter)
+	return m
+}
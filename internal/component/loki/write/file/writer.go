@@ -0,0 +1,125 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+)
+
+// fileWriter buffers the log entries written to a file until it's rotated.
+type fileWriter struct {
+	path   string
+	f      *os.File
+	w      *bufio.Writer
+	size   int64
+	opened time.Time
+}
+
+// openFileWriter opens the file at path to append log entries to it,
+// creating the file and its directory if they don't exist.
+func openFileWriter(path string) (*fileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileWriter{
+		path:   path,
+		f:      f,
+		w:      bufio.NewWriter(f),
+		size:   fi.Size(),
+		opened: time.Now(),
+	}, nil
+}
+
+func (w *fileWriter) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.size += int64(n)
+	return err
+}
+
+func (w *fileWriter) flush() error {
+	return w.w.Flush()
+}
+
+func (w *fileWriter) close() error {
+	err := w.w.Flush()
+	if closeErr := w.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// pathReplacer replaces the path separators in label values, so that a value
+// can't add directories to the path of a file.
+var pathReplacer = strings.NewReplacer("/", "_", `\`, "_")
+
+// renderPath returns the path of the file the log entries with labels are
+// written to. The path must be inside directory.
+func renderPath(tmpl *template.Template, directory string, labels model.LabelSet) (string, error) {
+	data := make(map[string]string, len(labels))
+	for name, value := range labels {
+		data[string(name)] = pathReplacer.Replace(string(value))
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	rel := filepath.Clean(filepath.FromSlash(sb.String()))
+	if rel == "." || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %q isn't a file inside the directory", sb.String())
+	}
+	if rotatedFileRegexp.MatchString(rel) {
+		return "", fmt.Errorf("path %q has the name of a rotated file", sb.String())
+	}
+	return filepath.Join(directory, rel), nil
+}
+
+// jsonEntry is a log entry written in the json format.
+type jsonEntry struct {
+	Timestamp          time.Time         `json:"timestamp"`
+	Labels             model.LabelSet    `json:"labels"`
+	StructuredMetadata map[string]string `json:"structured_metadata,omitempty"`
+	Line               string            `json:"line"`
+}
+
+// encodeEntry encodes a log entry as a line of a file in the given format.
+func encodeEntry(format string, entry loki.Entry) ([]byte, error) {
+	if format == FormatRaw {
+		return []byte(entry.Line + "\n"), nil
+	}
+
+	e := jsonEntry{
+		Timestamp: entry.Timestamp,
+		Labels:    entry.Labels,
+		Line:      entry.Line,
+	}
+	if len(entry.StructuredMetadata) > 0 {
+		e.StructuredMetadata = make(map[string]string, len(entry.StructuredMetadata))
+		for _, l := range entry.StructuredMetadata {
+			e.StructuredMetadata[l.Name] = l.Value
+		}
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/alecthomas/units"
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/loki/v3/clients/pkg/logentry/logql"
 	lokiflagext "github.com/grafana/loki/v3/pkg/util/flagext"
 
 	types "github.com/grafana/alloy/internal/component/common/config"
@@ -29,6 +30,7 @@ type EndpointOptions struct {
 	MaxBackoffRetries int                     `alloy:"max_backoff_retries,attr,optional"` // give up after this many; zero means infinite retries
 	TenantID          string                  `alloy:"tenant_id,attr,optional"`
 	RetryOnHTTP429    bool                    `alloy:"retry_on_http_429,attr,optional"`
+	Selector          string                  `alloy:"selector,attr,optional"`
 	HTTPClientConfig  *types.HTTPClientConfig `alloy:",squash"`
 	QueueConfig       QueueConfig             `alloy:"queue_config,block,optional"`
 }
@@ -64,6 +66,12 @@ func (r *EndpointOptions) Validate() error {
 		return fmt.Errorf("failed to parse remote url %q: %w", r.URL, err)
 	}
 
+	if r.Selector != "" {
+		if _, err := logql.ParseMatchers(r.Selector); err != nil {
+			return fmt.Errorf("invalid selector %q: %w", r.Selector, err)
+		}
+	}
+
 	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
 	if r.HTTPClientConfig != nil {
 		return r.HTTPClientConfig.Validate()
@@ -107,6 +115,7 @@ func (args Arguments) convertClientConfigs() []client.Config {
 			Timeout:                cfg.RemoteTimeout,
 			TenantID:               cfg.TenantID,
 			DropRateLimitedBatches: !cfg.RetryOnHTTP429,
+			Selector:               cfg.Selector,
 			Queue: client.QueueConfig{
 				Capacity:     int(cfg.QueueConfig.Capacity),
 				DrainTimeout: cfg.QueueConfig.DrainTimeout,
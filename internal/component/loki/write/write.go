@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alecthomas/units"
+
 	"github.com/grafana/alloy/internal/alloyseed"
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/component/common/loki"
@@ -35,6 +37,46 @@ type Arguments struct {
 	ExternalLabels map[string]string `alloy:"external_labels,attr,optional"`
 	MaxStreams     int               `alloy:"max_streams,attr,optional"`
 	WAL            WalArguments      `alloy:"wal,block,optional"`
+
+	PersistentQueue PersistentQueueArguments `alloy:"persistent_queue,block,optional"`
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.WAL.Enabled && a.PersistentQueue.Enabled {
+		return fmt.Errorf("the WAL and the persistent queue can't be enabled at the same time")
+	}
+	if a.WAL.Enabled {
+		for _, e := range a.Endpoints {
+			if e.Selector != "" {
+				return fmt.Errorf("the selector of endpoints is not supported when the WAL is enabled")
+			}
+		}
+	}
+	return nil
+}
+
+// PersistentQueueArguments holds the settings of the on-disk queue where each
+// endpoint stores its batches until they're sent.
+type PersistentQueueArguments struct {
+	Enabled bool             `alloy:"enabled,attr,optional"`
+	MaxSize units.Base2Bytes `alloy:"max_size,attr,optional"`
+}
+
+// Validate implements syntax.Validator.
+func (pq *PersistentQueueArguments) Validate() error {
+	if pq.MaxSize <= 0 {
+		return fmt.Errorf("the persistent queue max_size must be greater than 0")
+	}
+	return nil
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (pq *PersistentQueueArguments) SetToDefault() {
+	*pq = PersistentQueueArguments{
+		Enabled: false,
+		MaxSize: 1 * units.GiB,
+	}
 }
 
 // WalArguments holds the settings for configuring the Write-Ahead Log (WAL) used
@@ -149,6 +191,17 @@ func (c *Component) Run(ctx context.Context) error {
 func (c *Component) Update(args component.Arguments) error {
 	newArgs := args.(Arguments)
 
+	if newArgs.PersistentQueue.Enabled && !c.opts.MinStability.Permits(featuregate.StabilityExperimental) {
+		return fmt.Errorf("the persistent queue is at stability level %s, which is below the minimum allowed stability level %s. Use --stability.level command-line flag to enable %s features",
+			featuregate.StabilityExperimental, c.opts.MinStability, featuregate.StabilityExperimental)
+	}
+	for _, e := range newArgs.Endpoints {
+		if e.Selector != "" && !c.opts.MinStability.Permits(featuregate.StabilityExperimental) {
+			return fmt.Errorf("the endpoint selector is at stability level %s, which is below the minimum allowed stability level %s. Use --stability.level command-line flag to enable %s features",
+				featuregate.StabilityExperimental, c.opts.MinStability, featuregate.StabilityExperimental)
+		}
+	}
+
 	c.mut.Lock()
 	defer c.mut.Unlock()
 	c.args = newArgs
@@ -162,6 +215,13 @@ func (c *Component) Update(args component.Arguments) error {
 	}
 
 	cfgs := newArgs.convertClientConfigs()
+	for i := range cfgs {
+		cfgs[i].PersistentQueue = client.PersistentQueueConfig{
+			Enabled: newArgs.PersistentQueue.Enabled,
+			Dir:     filepath.Join(c.opts.DataPath, "queue"),
+			MaxSize: int(newArgs.PersistentQueue.MaxSize),
+		}
+	}
 
 	uid := alloyseed.Get().UID
 	for i := range cfgs {
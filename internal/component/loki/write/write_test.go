@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/grafana/loki/v3/pkg/logproto"
 	loki_util "github.com/grafana/loki/v3/pkg/util"
 	"github.com/prometheus/common/model"
@@ -123,6 +124,113 @@ func TestUnmarshallWalAttrributes(t *testing.T) {
 	}
 }
 
+func TestUnmarshallPersistentQueueAttributes(t *testing.T) {
+	for name, tc := range map[string]struct {
+		raw           string
+		errorExpected string
+		expected      PersistentQueueArguments
+	}{
+		"persistent queue enabled with defaults": {
+			raw: `
+			persistent_queue {
+				enabled = true
+			}
+			`,
+			expected: PersistentQueueArguments{Enabled: true, MaxSize: units.GiB},
+		},
+		"persistent queue enabled with overrides": {
+			raw: `
+			persistent_queue {
+				enabled  = true
+				max_size = "100MiB"
+			}
+			`,
+			expected: PersistentQueueArguments{Enabled: true, MaxSize: 100 * units.MiB},
+		},
+		"invalid max size": {
+			raw: `
+			persistent_queue {
+				enabled  = true
+				max_size = "0B"
+			}
+			`,
+			errorExpected: "the persistent queue max_size must be greater than 0",
+		},
+		"wal and persistent queue enabled": {
+			raw: `
+			wal {
+				enabled = true
+			}
+			persistent_queue {
+				enabled = true
+			}
+			`,
+			errorExpected: "the WAL and the persistent queue can't be enabled at the same time",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tc.raw), &args)
+			if tc.errorExpected != "" {
+				require.ErrorContains(t, err, tc.errorExpected)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, args.PersistentQueue)
+		})
+	}
+}
+
+func TestUnmarshallEndpointSelector(t *testing.T) {
+	for name, tc := range map[string]struct {
+		raw           string
+		errorExpected string
+		expected      string
+	}{
+		"valid selector": {
+			raw: `
+			endpoint {
+				url      = "http://localhost:3100/loki/api/v1/push"
+				selector = "{cluster=\"eu\", app=~\"api.*\"}"
+			}
+			`,
+			expected: `{cluster="eu", app=~"api.*"}`,
+		},
+		"invalid selector": {
+			raw: `
+			endpoint {
+				url      = "http://localhost:3100/loki/api/v1/push"
+				selector = "cluster=eu"
+			}
+			`,
+			errorExpected: `invalid selector "cluster=eu"`,
+		},
+		"selector with wal enabled": {
+			raw: `
+			endpoint {
+				url      = "http://localhost:3100/loki/api/v1/push"
+				selector = "{cluster=\"eu\"}"
+			}
+			wal {
+				enabled = true
+			}
+			`,
+			errorExpected: "the selector of endpoints is not supported when the WAL is enabled",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tc.raw), &args)
+			if tc.errorExpected != "" {
+				require.ErrorContains(t, err, tc.errorExpected)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, args.convertClientConfigs()[0].Selector)
+		})
+	}
+}
+
 func TestWriteToSingleEndpoint(t *testing.T) {
 	t.Run("wal disabled", func(t *testing.T) {
 		testSingleEndpoint(t, func(args *Arguments) {})
@@ -133,6 +241,12 @@ func TestWriteToSingleEndpoint(t *testing.T) {
 			args.WAL.Enabled = true
 		})
 	})
+
+	t.Run("persistent queue enabled", func(t *testing.T) {
+		testSingleEndpoint(t, func(args *Arguments) {
+			args.PersistentQueue.Enabled = true
+		})
+	})
 }
 
 func testSingleEndpoint(t *testing.T, alterConfig func(arguments *Arguments)) {
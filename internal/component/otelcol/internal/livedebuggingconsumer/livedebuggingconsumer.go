@@ -7,6 +7,7 @@ import (
 
 	"github.com/grafana/alloy/internal/component/otelcol"
 	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/prometheus/prometheus/model/labels"
 	otelconsumer "go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
@@ -42,8 +43,15 @@ func (c *Consumer) Capabilities() otelconsumer.Capabilities {
 // ConsumeTraces implements otelcol.ConsumeTraces.
 func (c *Consumer) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
 	if c.debugDataPublisher.IsActive(c.componentID) {
+		// The data is marshaled right away because it may be modified by the
+		// rest of the pipeline once this function returns.
 		data, _ := c.tracesMarshaler.MarshalTraces(td)
-		c.debugDataPublisher.Publish(c.componentID, string(data))
+		c.debugDataPublisher.Publish(c.componentID, livedebugging.NewData(
+			livedebugging.OtelTrace,
+			uint64(td.SpanCount()),
+			labels.EmptyLabels(),
+			func() string { return string(data) },
+		))
 	}
 	return nil
 }
@@ -52,7 +60,12 @@ func (c *Consumer) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
 func (c *Consumer) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
 	if c.debugDataPublisher.IsActive(c.componentID) {
 		data, _ := c.metricsMarshaler.MarshalMetrics(md)
-		c.debugDataPublisher.Publish(c.componentID, string(data))
+		c.debugDataPublisher.Publish(c.componentID, livedebugging.NewData(
+			livedebugging.OtelMetric,
+			uint64(md.DataPointCount()),
+			labels.EmptyLabels(),
+			func() string { return string(data) },
+		))
 	}
 	return nil
 }
@@ -61,7 +74,12 @@ func (c *Consumer) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error
 func (c *Consumer) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
 	if c.debugDataPublisher.IsActive(c.componentID) {
 		data, _ := c.logsMarshaler.MarshalLogs(ld)
-		c.debugDataPublisher.Publish(c.componentID, string(data))
+		c.debugDataPublisher.Publish(c.componentID, livedebugging.NewData(
+			livedebugging.OtelLog,
+			uint64(ld.LogRecordCount()),
+			labels.EmptyLabels(),
+			func() string { return string(data) },
+		))
 	}
 	return nil
 }
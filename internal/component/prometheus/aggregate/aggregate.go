@@ -0,0 +1,343 @@
+package aggregate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/labelstore"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.aggregate",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Operations which can be applied to the series matched by a rule.
+const (
+	OperationSum   = "sum"
+	OperationAvg   = "avg"
+	OperationMin   = "min"
+	OperationMax   = "max"
+	OperationCount = "count"
+)
+
+var operations = []string{OperationSum, OperationAvg, OperationMin, OperationMax, OperationCount}
+
+// minStaleAfter is the minimum time after which a series which doesn't
+// receive samples is forgotten. It's the default lookback delta of
+// Prometheus.
+const minStaleAfter = 5 * time.Minute
+
+// Arguments holds values which are used to configure the prometheus.aggregate
+// component.
+type Arguments struct {
+	// Where the aggregated metrics should be forwarded to.
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// How often the aggregated samples are sent.
+	Interval time.Duration `alloy:"interval,attr,optional"`
+
+	// The aggregation rules to apply to the metrics.
+	Rules []RuleConfig `alloy:"rule,block,optional"`
+}
+
+// RuleConfig configures how the series matching a selector are aggregated.
+type RuleConfig struct {
+	Match      string   `alloy:"match,attr"`
+	By         []string `alloy:"by,attr,optional"`
+	Without    []string `alloy:"without,attr,optional"`
+	Operation  string   `alloy:"operation,attr,optional"`
+	MetricName string   `alloy:"metric_name,attr,optional"`
+	KeepInput  bool     `alloy:"keep_input,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (r *RuleConfig) SetToDefault() {
+	*r = RuleConfig{
+		Operation: OperationSum,
+	}
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (arg *Arguments) SetToDefault() {
+	*arg = Arguments{
+		Interval: time.Minute,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (arg *Arguments) Validate() error {
+	if arg.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	for i, r := range arg.Rules {
+		if _, err := parser.ParseMetricSelector(r.Match); err != nil {
+			return fmt.Errorf("invalid match of rule %d: %w", i, err)
+		}
+		if len(r.By) > 0 && len(r.Without) > 0 {
+			return fmt.Errorf("rule %d can't have both by and without", i)
+		}
+		if slices.Contains(r.Without, labels.MetricName) {
+			return fmt.Errorf("without of rule %d can't contain %s", i, labels.MetricName)
+		}
+		if !slices.Contains(operations, r.Operation) {
+			return fmt.Errorf("invalid operation %q of rule %d, must be one of %v", r.Operation, i, operations)
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.aggregate
+// component.
+type Exports struct {
+	Receiver storage.Appendable `alloy:"receiver,attr"`
+}
+
+// Component implements the prometheus.aggregate component.
+type Component struct {
+	opts     component.Options
+	ls       labelstore.LabelStore
+	fanout   *prometheus.Fanout
+	receiver *receiver
+	metrics  *metrics
+	exited   atomic.Bool
+	updated  chan struct{}
+
+	mut      sync.RWMutex
+	args     Arguments
+	rules    []*rule
+	metadata map[string]model.MetricType
+
+	// stateMut protects the state of the rules.
+	stateMut sync.Mutex
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.aggregate component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMetrics(o.Registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Component{
+		opts:     o,
+		ls:       data.(labelstore.LabelStore),
+		metrics:  m,
+		updated:  make(chan struct{}, 1),
+		metadata: make(map[string]model.MetricType),
+	}
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, c.ls)
+	c.receiver = &receiver{c: c}
+
+	// Call to Update() to set the rules once at the start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.exited.Store(true)
+
+	ticker := time.NewTicker(c.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.updated:
+			ticker.Reset(c.interval())
+		case <-ticker.C:
+			c.flush(ctx, time.Now())
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// The state of the rules is only reset when they change, so that the
+	// aggregated counters don't reset on every update.
+	if c.rules == nil || !reflect.DeepEqual(c.args.Rules, newArgs.Rules) {
+		rules := make([]*rule, 0, len(newArgs.Rules))
+		for _, cfg := range newArgs.Rules {
+			r, err := newRule(cfg)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, r)
+		}
+		c.stateMut.Lock()
+		c.rules = rules
+		c.stateMut.Unlock()
+	}
+
+	if c.args.Interval != newArgs.Interval {
+		select {
+		case c.updated <- struct{}{}:
+		default:
+		}
+	}
+	c.args = newArgs
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	return nil
+}
+
+func (c *Component) interval() time.Duration {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return c.args.Interval
+}
+
+// match returns the rules matching a series, and whether the series should be
+// forwarded as is.
+func (c *Component) match(l labels.Labels, histogram bool) (matched []*rule, forward bool) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	forward = true
+	for _, r := range c.rules {
+		if !r.matches(l) || (histogram && !r.supportsHistograms()) {
+			continue
+		}
+		matched = append(matched, r)
+		if !r.cfg.KeepInput {
+			forward = false
+		}
+	}
+	return matched, forward
+}
+
+// isCounter returns whether a series of float samples is a counter, from the
+// metadata of the metric or else from the suffix of its name.
+func (c *Component) isCounter(l labels.Labels) bool {
+	name := l.Get(labels.MetricName)
+
+	c.mut.RLock()
+	typ := c.metadata[name]
+	c.mut.RUnlock()
+	switch typ {
+	case model.MetricTypeCounter, model.MetricTypeHistogram:
+		return true
+	case model.MetricTypeSummary:
+		return !l.Has(model.QuantileLabel)
+	case model.MetricTypeGauge, model.MetricTypeGaugeHistogram:
+		return false
+	}
+	return hasCounterSuffix(name)
+}
+
+func (c *Component) setMetadata(l labels.Labels, m metadata.Metadata) {
+	name := l.Get(labels.MetricName)
+
+	c.mut.RLock()
+	typ, ok := c.metadata[name]
+	c.mut.RUnlock()
+	if ok && typ == m.Type {
+		return
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.metadata[name] = m.Type
+}
+
+// add aggregates the samples of a committed append.
+func (c *Component) add(samples []sample) {
+	now := time.Now()
+
+	c.stateMut.Lock()
+	defer c.stateMut.Unlock()
+
+	for _, s := range samples {
+		for _, r := range s.rules {
+			if !r.add(s, now, c.metrics) {
+				c.metrics.droppedSamples.Inc()
+				continue
+			}
+			c.metrics.samples.Inc()
+		}
+	}
+}
+
+// flush sends the aggregated samples of all rules.
+func (c *Component) flush(ctx context.Context, now time.Time) {
+	c.mut.RLock()
+	rules := c.rules
+	staleAfter := max(2*c.args.Interval, minStaleAfter)
+	c.mut.RUnlock()
+
+	var (
+		outputs []output
+		series  int
+	)
+	c.stateMut.Lock()
+	for _, r := range rules {
+		outputs = r.flush(outputs, now, staleAfter, c.metrics)
+		series += len(r.series)
+	}
+	c.stateMut.Unlock()
+	c.metrics.series.Set(float64(series))
+	c.metrics.outputSeries.Set(float64(len(outputs)))
+
+	if len(outputs) == 0 {
+		return
+	}
+
+	ts := now.UnixMilli()
+	app := c.fanout.Appender(ctx)
+	for _, o := range outputs {
+		var err error
+		if o.fh != nil {
+			_, err = app.AppendHistogram(0, o.labels, ts, nil, o.fh)
+		} else {
+			_, err = app.Append(0, o.labels, ts, o.value)
+		}
+		if err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to append aggregated sample", "labels", o.labels.String(), "err", err)
+		}
+	}
+	if err := app.Commit(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to send aggregated samples", "err", err)
+	}
+}
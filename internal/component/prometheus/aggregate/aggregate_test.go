@@ -0,0 +1,346 @@
+package aggregate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		forward_to = []
+
+		rule {
+			match = "{__name__=\"container_memory_working_set_bytes\"}"
+			by    = ["namespace", "deployment"]
+		}
+	`), &args))
+	require.Equal(t, time.Minute, args.Interval)
+	require.Equal(t, OperationSum, args.Rules[0].Operation)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		errSubstring string
+	}{
+		{
+			name: "invalid interval",
+			config: `
+				forward_to = []
+				interval   = "0s"`,
+			errSubstring: "interval must be greater than 0",
+		},
+		{
+			name: "invalid match",
+			config: `
+				forward_to = []
+				rule {
+					match = "{job="
+				}`,
+			errSubstring: "invalid match of rule 0",
+		},
+		{
+			name: "by and without",
+			config: `
+				forward_to = []
+				rule {
+					match   = "{job=\"a\"}"
+					by      = ["a"]
+					without = ["b"]
+				}`,
+			errSubstring: "rule 0 can't have both by and without",
+		},
+		{
+			name: "without __name__",
+			config: `
+				forward_to = []
+				rule {
+					match   = "{job=\"a\"}"
+					without = ["__name__"]
+				}`,
+			errSubstring: "without of rule 0 can't contain __name__",
+		},
+		{
+			name: "invalid operation",
+			config: `
+				forward_to = []
+				rule {
+					match     = "{job=\"a\"}"
+					operation = "median"
+				}`,
+			errSubstring: `invalid operation "median" of rule 0`,
+		},
+		{
+			name: "missing match",
+			config: `
+				forward_to = []
+				rule {
+					by = ["a"]
+				}`,
+			errSubstring: `missing required attribute "match"`,
+		},
+		{
+			name: "invalid forward_to",
+			config: `
+				forward_to = "a"`,
+			errSubstring: `"a" should be array, got string`,
+		},
+		{
+			name: "unknown argument",
+			config: `
+				forward_to = []
+				unknown    = true`,
+			errSubstring: `unrecognized attribute name "unknown"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tt.config), &args)
+			require.ErrorContains(t, err, tt.errSubstring)
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	c, out := newTestComponent(t, []RuleConfig{
+		{Match: `{__name__="memory_bytes"}`, By: []string{"deployment"}, Operation: OperationSum},
+		{Match: `{__name__="memory_bytes"}`, Operation: OperationMax, MetricName: "memory_bytes:max", KeepInput: true},
+	})
+	c2, out2 := newTestComponent(t, []RuleConfig{
+		{Match: `{__name__="memory_bytes"}`, Operation: OperationMax, MetricName: "memory_bytes:max", KeepInput: true},
+	})
+
+	// The series matched by rules which all keep the input are forwarded too.
+	app := c2.receiver.Appender(context.Background())
+	appendSample(t, app, 1000, 10, "__name__", "memory_bytes", "deployment", "a", "pod", "a-1")
+	require.NoError(t, app.Commit())
+	require.Equal(t, map[string]float64{`{__name__="memory_bytes", deployment="a", pod="a-1"}`: 10}, out2.TakeSamples())
+
+	app = c.receiver.Appender(context.Background())
+	appendSample(t, app, 1000, 10, "__name__", "memory_bytes", "deployment", "a", "pod", "a-1")
+	appendSample(t, app, 1000, 20, "__name__", "memory_bytes", "deployment", "a", "pod", "a-2")
+	appendSample(t, app, 1000, 5, "__name__", "memory_bytes", "deployment", "b", "pod", "b-1")
+	appendSample(t, app, 1000, 1, "__name__", "cpu_seconds_total", "pod", "a-1")
+	require.NoError(t, app.Commit())
+
+	// Only the series which aren't matched by any rule are forwarded as is.
+	require.Equal(t, map[string]float64{
+		`{__name__="cpu_seconds_total", pod="a-1"}`: 1,
+	}, out.TakeSamples())
+
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{
+		`{__name__="memory_bytes", deployment="a"}`: 30,
+		`{__name__="memory_bytes", deployment="b"}`: 5,
+		`{__name__="memory_bytes:max"}`:             20,
+	}, out.TakeSamples())
+
+	// The last value of each series is used.
+	app = c.receiver.Appender(context.Background())
+	appendSample(t, app, 2000, 15, "__name__", "memory_bytes", "deployment", "a", "pod", "a-1")
+	require.NoError(t, app.Commit())
+	out.TakeSamples()
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{
+		`{__name__="memory_bytes", deployment="a"}`: 35,
+		`{__name__="memory_bytes", deployment="b"}`: 5,
+		`{__name__="memory_bytes:max"}`:             20,
+	}, out.TakeSamples())
+}
+
+func TestOperations(t *testing.T) {
+	c, out := newTestComponent(t, []RuleConfig{
+		{Match: `{__name__="temperature"}`, Without: []string{"sensor"}, Operation: OperationAvg, MetricName: "temperature:avg"},
+		{Match: `{__name__="temperature"}`, Without: []string{"sensor"}, Operation: OperationMin, MetricName: "temperature:min"},
+		{Match: `{__name__="temperature"}`, Without: []string{"sensor"}, Operation: OperationMax, MetricName: "temperature:max"},
+		{Match: `{__name__="temperature"}`, Without: []string{"sensor"}, Operation: OperationCount, MetricName: "temperature:count"},
+	})
+
+	app := c.receiver.Appender(context.Background())
+	for i, v := range []float64{12, 20, 16} {
+		appendSample(t, app, 1000, v, "__name__", "temperature", "room", "kitchen", "sensor", fmt.Sprint(i))
+	}
+	require.NoError(t, app.Commit())
+	require.Empty(t, out.TakeSamples())
+
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{
+		`{__name__="temperature:avg", room="kitchen"}`:   16,
+		`{__name__="temperature:min", room="kitchen"}`:   12,
+		`{__name__="temperature:max", room="kitchen"}`:   20,
+		`{__name__="temperature:count", room="kitchen"}`: 3,
+	}, out.TakeSamples())
+}
+
+func TestCounterResets(t *testing.T) {
+	c, out := newTestComponent(t, []RuleConfig{
+		{Match: `{__name__="requests_total"}`, By: []string{"deployment"}, Operation: OperationSum},
+	})
+
+	commit := func(ts int64, values map[string]float64) {
+		app := c.receiver.Appender(context.Background())
+		for pod, v := range values {
+			appendSample(t, app, ts, v, "__name__", "requests_total", "deployment", "a", "pod", pod)
+		}
+		require.NoError(t, app.Commit())
+	}
+
+	// The initial values are summed.
+	commit(1000, map[string]float64{"a-1": 100, "a-2": 50})
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{`{__name__="requests_total", deployment="a"}`: 150}, out.TakeSamples())
+
+	// a-2 restarts, its new value is its increase.
+	commit(2000, map[string]float64{"a-1": 110, "a-2": 5})
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{`{__name__="requests_total", deployment="a"}`: 165}, out.TakeSamples())
+
+	// A new pod only adds its increases, and the increases of a removed pod
+	// are kept.
+	commit(3000, map[string]float64{"a-1": 120, "a-2": math.Float64frombits(value.StaleNaN), "a-3": 1000})
+	commit(4000, map[string]float64{"a-3": 1001})
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{`{__name__="requests_total", deployment="a"}`: 176}, out.TakeSamples())
+
+	// Out of order samples are dropped.
+	commit(2500, map[string]float64{"a-1": 0})
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{`{__name__="requests_total", deployment="a"}`: 176}, out.TakeSamples())
+
+	// A staleness marker is sent once no series is left.
+	c.flush(context.Background(), time.Now().Add(time.Hour))
+	result := out.TakeSamples()
+	require.Len(t, result, 1)
+	require.True(t, value.IsStaleNaN(result[`{__name__="requests_total", deployment="a"}`]))
+	c.flush(context.Background(), time.Now().Add(time.Hour))
+	require.Empty(t, out.TakeSamples())
+}
+
+func TestClassicHistograms(t *testing.T) {
+	c, out := newTestComponent(t, []RuleConfig{
+		{Match: `{__name__=~"latency_seconds_.*"}`, By: []string{"deployment"}, Operation: OperationSum},
+	})
+
+	app := c.receiver.Appender(context.Background())
+	for _, pod := range []string{"a-1", "a-2"} {
+		appendSample(t, app, 1000, 1, "__name__", "latency_seconds_bucket", "deployment", "a", "pod", pod, "le", "0.1")
+		appendSample(t, app, 1000, 3, "__name__", "latency_seconds_bucket", "deployment", "a", "pod", pod, "le", "+Inf")
+		appendSample(t, app, 1000, 3, "__name__", "latency_seconds_count", "deployment", "a", "pod", pod)
+	}
+	require.NoError(t, app.Commit())
+
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{
+		`{__name__="latency_seconds_bucket", deployment="a", le="0.1"}`:  2,
+		`{__name__="latency_seconds_bucket", deployment="a", le="+Inf"}`: 6,
+		`{__name__="latency_seconds_count", deployment="a"}`:             6,
+	}, out.TakeSamples())
+}
+
+func TestNativeHistograms(t *testing.T) {
+	c, out := newTestComponent(t, []RuleConfig{
+		{Match: `{__name__="latency_seconds"}`, By: []string{"deployment"}, Operation: OperationSum},
+		// Native histograms can't be aggregated with min.
+		{Match: `{__name__="latency_seconds"}`, Operation: OperationMin, MetricName: "latency_seconds:min"},
+	})
+
+	h := func(count float64) *histogram.FloatHistogram {
+		return &histogram.FloatHistogram{
+			Count:           count,
+			Sum:             count,
+			Schema:          0,
+			PositiveSpans:   []histogram.Span{{Offset: 0, Length: 1}},
+			PositiveBuckets: []float64{count},
+		}
+	}
+	commit := func(ts int64, values map[string]*histogram.FloatHistogram) {
+		app := c.receiver.Appender(context.Background())
+		for pod, fh := range values {
+			_, err := app.AppendHistogram(0, labels.FromStrings("__name__", "latency_seconds", "deployment", "a", "pod", pod), ts, nil, fh)
+			require.NoError(t, err)
+		}
+		require.NoError(t, app.Commit())
+	}
+
+	commit(1000, map[string]*histogram.FloatHistogram{"a-1": h(4), "a-2": h(2)})
+	require.Empty(t, out.TakeFloatHistograms())
+	c.flush(context.Background(), time.Now())
+	require.Empty(t, out.TakeSamples())
+	require.Equal(t, 6.0, out.TakeFloatHistograms()[`{__name__="latency_seconds", deployment="a"}`].Count)
+
+	// a-2 restarts.
+	commit(2000, map[string]*histogram.FloatHistogram{"a-1": h(5), "a-2": h(1)})
+	out.TakeFloatHistograms()
+	c.flush(context.Background(), time.Now())
+	sum := out.TakeFloatHistograms()[`{__name__="latency_seconds", deployment="a"}`]
+	require.Equal(t, 8.0, sum.Count)
+	require.Equal(t, []float64{8}, sum.PositiveBuckets)
+}
+
+func TestUpdate(t *testing.T) {
+	rules := []RuleConfig{{Match: `{__name__="requests_total"}`, Operation: OperationSum}}
+	c, out := newTestComponent(t, rules)
+
+	app := c.receiver.Appender(context.Background())
+	appendSample(t, app, 1000, 10, "__name__", "requests_total", "pod", "a")
+	require.NoError(t, app.Commit())
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{`{__name__="requests_total"}`: 10}, out.TakeSamples())
+
+	// The state is kept when the rules don't change.
+	require.NoError(t, c.Update(Arguments{ForwardTo: c.args.ForwardTo, Interval: time.Second, Rules: rules}))
+	c.flush(context.Background(), time.Now())
+	require.Equal(t, map[string]float64{`{__name__="requests_total"}`: 10}, out.TakeSamples())
+
+	require.NoError(t, c.Update(Arguments{ForwardTo: c.args.ForwardTo, Interval: time.Second}))
+	c.flush(context.Background(), time.Now())
+	require.Empty(t, out.TakeSamples())
+}
+
+func newTestComponent(t *testing.T, rules []RuleConfig) (*Component, *testappender.Collector) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	out := testappender.NewCollector()
+	c, err := New(component.Options{
+		ID:            "prometheus.aggregate.test",
+		Logger:        util.TestAlloyLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			if name != labelstore.ServiceName {
+				return nil, fmt.Errorf("service not found %s", name)
+			}
+			return ls, nil
+		},
+	}, Arguments{
+		ForwardTo: []storage.Appendable{out},
+		Interval:  time.Minute,
+		Rules:     rules,
+	})
+	require.NoError(t, err)
+	return c, out
+}
+
+func appendSample(t *testing.T, app storage.Appender, ts int64, v float64, ls ...string) {
+	_, err := app.Append(0, labels.FromStrings(ls...), ts, v)
+	require.NoError(t, err)
+}
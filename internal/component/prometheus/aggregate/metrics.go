@@ -0,0 +1,45 @@
+package aggregate
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	samples        prometheus.Counter
+	droppedSamples prometheus.Counter
+	counterResets  prometheus.Counter
+	series         prometheus.Gauge
+	outputSeries   prometheus.Gauge
+}
+
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		samples: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alloy_prometheus_aggregate_samples_total",
+			Help: "Total number of samples aggregated by a rule.",
+		}),
+		droppedSamples: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alloy_prometheus_aggregate_dropped_samples_total",
+			Help: "Total number of samples which couldn't be aggregated, because they were out of order or incompatible with the other samples.",
+		}),
+		counterResets: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alloy_prometheus_aggregate_counter_resets_total",
+			Help: "Total number of counter resets detected in the aggregated series.",
+		}),
+		series: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "alloy_prometheus_aggregate_series",
+			Help: "Number of series aggregated by the rules.",
+		}),
+		outputSeries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "alloy_prometheus_aggregate_output_series",
+			Help: "Number of aggregated series sent at the last interval.",
+		}),
+	}
+
+	for _, metric := range []prometheus.Collector{m.samples, m.droppedSamples, m.counterResets, m.series, m.outputSeries} {
+		if err := reg.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,133 @@
+package aggregate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// receiver is the storage.Appendable exported by the component. The samples
+// matched by a rule are aggregated when the append is committed, and the
+// other samples are forwarded as is.
+type receiver struct {
+	c *Component
+}
+
+var _ storage.Appendable = (*receiver)(nil)
+
+// Appender satisfies the Appendable interface.
+func (r *receiver) Appender(ctx context.Context) storage.Appender {
+	return &appender{
+		c:     r.c,
+		child: r.c.fanout.Appender(ctx),
+	}
+}
+
+// sample is a sample matched by at least one rule.
+type sample struct {
+	labels  labels.Labels
+	t       int64
+	value   float64
+	fh      *histogram.FloatHistogram
+	counter bool
+	rules   []*rule
+}
+
+type appender struct {
+	c       *Component
+	child   storage.Appender
+	samples []sample
+}
+
+var _ storage.Appender = (*appender)(nil)
+
+// Append satisfies the Appender interface.
+func (a *appender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	if a.c.exited.Load() {
+		return 0, fmt.Errorf("%s has exited", a.c.opts.ID)
+	}
+
+	rules, forward := a.c.match(l, false)
+	if len(rules) > 0 {
+		a.samples = append(a.samples, sample{labels: l, t: t, value: v, counter: a.c.isCounter(l), rules: rules})
+	}
+	if !forward {
+		return ref, nil
+	}
+	return a.child.Append(ref, l, t, v)
+}
+
+// AppendHistogram satisfies the Appender interface.
+func (a *appender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	if a.c.exited.Load() {
+		return 0, fmt.Errorf("%s has exited", a.c.opts.ID)
+	}
+
+	rules, forward := a.c.match(l, true)
+	if len(rules) > 0 {
+		s := sample{labels: l, t: t, rules: rules}
+		if fh != nil {
+			s.fh = fh.Copy()
+		} else {
+			s.fh = h.ToFloat(nil)
+		}
+		s.counter = s.fh.CounterResetHint != histogram.GaugeType
+		a.samples = append(a.samples, s)
+	}
+	if !forward {
+		return ref, nil
+	}
+	return a.child.AppendHistogram(ref, l, t, h, fh)
+}
+
+// AppendExemplar satisfies the Appender interface. The exemplars of the
+// aggregated series are dropped.
+func (a *appender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	if _, forward := a.c.match(l, false); !forward {
+		return ref, nil
+	}
+	return a.child.AppendExemplar(ref, l, e)
+}
+
+// UpdateMetadata satisfies the Appender interface. The metadata is used to
+// know which series are counters.
+func (a *appender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	a.c.setMetadata(l, m)
+	if _, forward := a.c.match(l, false); !forward {
+		return ref, nil
+	}
+	return a.child.UpdateMetadata(ref, l, m)
+}
+
+// AppendCTZeroSample satisfies the Appender interface.
+func (a *appender) AppendCTZeroSample(ref storage.SeriesRef, l labels.Labels, t, ct int64) (storage.SeriesRef, error) {
+	if _, forward := a.c.match(l, false); !forward {
+		return ref, nil
+	}
+	return a.child.AppendCTZeroSample(ref, l, t, ct)
+}
+
+// Commit satisfies the Appender interface.
+func (a *appender) Commit() error {
+	var multiErr error
+	if len(a.samples) > 0 {
+		a.c.add(a.samples)
+		a.samples = nil
+	}
+	if err := a.child.Commit(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+	return multiErr
+}
+
+// Rollback satisfies the Appender interface.
+func (a *appender) Rollback() error {
+	a.samples = nil
+	return a.child.Rollback()
+}
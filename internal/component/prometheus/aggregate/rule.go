@@ -0,0 +1,310 @@
+package aggregate
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// counterSuffixes are the suffixes of the names of the metrics which are
+// counters when their metadata is unknown.
+var counterSuffixes = []string{"_total", "_count", "_sum", "_bucket"}
+
+func hasCounterSuffix(name string) bool {
+	for _, suffix := range counterSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rule holds the state of the aggregation of the series matching a rule.
+type rule struct {
+	cfg      RuleConfig
+	matchers []*labels.Matcher
+
+	// series holds the series matched by the rule, by the hash of their
+	// labels.
+	series map[uint64]*series
+	// groups holds the aggregated series, by the hash of their labels.
+	groups map[uint64]*group
+}
+
+// group is an aggregated series.
+type group struct {
+	labels    labels.Labels
+	histogram bool
+	series    map[uint64]*series
+	// flushed is whether the aggregated series was sent at least once.
+	flushed bool
+
+	// total is the sum of the increases of the counter series, so that it
+	// doesn't decrease when a series is reset or removed.
+	total          float64
+	totalHistogram *histogram.FloatHistogram
+}
+
+// series is the last sample of a series matched by a rule.
+type series struct {
+	group    *group
+	counter  bool
+	t        int64
+	value    float64
+	fh       *histogram.FloatHistogram
+	lastSeen time.Time
+}
+
+// output is an aggregated sample.
+type output struct {
+	labels labels.Labels
+	value  float64
+	fh     *histogram.FloatHistogram
+}
+
+func newRule(cfg RuleConfig) (*rule, error) {
+	matchers, err := parser.ParseMetricSelector(cfg.Match)
+	if err != nil {
+		return nil, err
+	}
+	return &rule{
+		cfg:      cfg,
+		matchers: matchers,
+		series:   make(map[uint64]*series),
+		groups:   make(map[uint64]*group),
+	}, nil
+}
+
+func (r *rule) matches(l labels.Labels) bool {
+	for _, m := range r.matchers {
+		if !m.Matches(l.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// supportsHistograms returns whether the operation of the rule can be applied
+// to native histograms.
+func (r *rule) supportsHistograms() bool {
+	return r.cfg.Operation != OperationMin && r.cfg.Operation != OperationMax
+}
+
+// groupLabels returns the labels of the aggregated series a series belongs
+// to. The le label is kept, so that the buckets of classic histograms are
+// aggregated separately.
+func (r *rule) groupLabels(l labels.Labels) labels.Labels {
+	b := labels.NewBuilder(l)
+	switch {
+	case len(r.cfg.By) > 0:
+		b.Keep(append([]string{labels.MetricName, labels.BucketLabel}, r.cfg.By...)...)
+	case len(r.cfg.Without) > 0:
+		b.Del(r.cfg.Without...)
+	default:
+		b.Keep(labels.MetricName, labels.BucketLabel)
+	}
+	if r.cfg.MetricName != "" {
+		b.Set(labels.MetricName, r.cfg.MetricName)
+	}
+	return b.Labels()
+}
+
+// add adds a sample to the state of the rule. It returns false if the sample
+// can't be aggregated.
+func (r *rule) add(s sample, now time.Time, m *metrics) bool {
+	hash := s.labels.Hash()
+	se, ok := r.series[hash]
+
+	if value.IsStaleNaN(s.value) || (s.fh != nil && value.IsStaleNaN(s.fh.Sum)) {
+		if ok {
+			r.remove(hash, se)
+		}
+		return true
+	}
+
+	if !ok {
+		gl := r.groupLabels(s.labels)
+		gh := gl.Hash()
+		g, ok := r.groups[gh]
+		if !ok {
+			g = &group{labels: gl, histogram: s.fh != nil, series: make(map[uint64]*series)}
+			r.groups[gh] = g
+		}
+		if g.histogram != (s.fh != nil) {
+			return false
+		}
+
+		// The whole value of a new counter series is only added to the total
+		// before the aggregated series is first sent, so that it doesn't jump
+		// when a series with a large value appears.
+		if s.counter {
+			initial, fh := s.value, s.fh
+			if g.flushed {
+				initial = 0
+				if fh != nil {
+					fh = fh.Copy().Mul(0)
+				}
+			}
+			if !g.addTotal(initial, fh) {
+				return false
+			}
+		}
+
+		se = &series{group: g, counter: s.counter}
+		r.series[hash] = se
+		g.series[hash] = se
+		se.set(s, now)
+		return true
+	}
+
+	g := se.group
+	if g.histogram != (s.fh != nil) || s.t <= se.t {
+		return false
+	}
+	if se.counter {
+		if s.fh != nil {
+			delta := s.fh.Copy()
+			if s.fh.DetectReset(se.fh) {
+				m.counterResets.Inc()
+			} else if _, err := delta.Sub(se.fh); err != nil {
+				// The buckets are incompatible, the histogram was reset.
+				delta = s.fh.Copy()
+				m.counterResets.Inc()
+			}
+			if !g.addTotal(0, delta) {
+				return false
+			}
+		} else {
+			delta := s.value - se.value
+			if s.value < se.value {
+				delta = s.value
+				m.counterResets.Inc()
+			}
+			g.addTotal(delta, nil)
+		}
+	}
+	se.set(s, now)
+	return true
+}
+
+func (g *group) addTotal(v float64, fh *histogram.FloatHistogram) bool {
+	if fh == nil {
+		g.total += v
+		return true
+	}
+	if g.totalHistogram == nil {
+		g.totalHistogram = fh.Copy()
+		return true
+	}
+	_, err := g.totalHistogram.Add(fh)
+	return err == nil
+}
+
+func (se *series) set(s sample, now time.Time) {
+	se.t, se.value, se.fh, se.lastSeen = s.t, s.value, s.fh, now
+}
+
+func (r *rule) remove(hash uint64, se *series) {
+	delete(r.series, hash)
+	delete(se.group.series, hash)
+}
+
+// flush appends the aggregated samples of the rule to outputs. The series
+// which didn't receive samples for staleAfter are removed first, and a
+// staleness marker is sent for the aggregated series without any series
+// left.
+func (r *rule) flush(outputs []output, now time.Time, staleAfter time.Duration, m *metrics) []output {
+	for hash, se := range r.series {
+		if now.Sub(se.lastSeen) > staleAfter {
+			r.remove(hash, se)
+		}
+	}
+
+	for hash, g := range r.groups {
+		if len(g.series) == 0 {
+			if g.flushed {
+				outputs = append(outputs, output{labels: g.labels, value: math.Float64frombits(value.StaleNaN)})
+			}
+			delete(r.groups, hash)
+			continue
+		}
+
+		o, ok := r.aggregate(g)
+		if !ok {
+			m.droppedSamples.Inc()
+			continue
+		}
+		outputs = append(outputs, o)
+		g.flushed = true
+	}
+	return outputs
+}
+
+// aggregate applies the operation of the rule to the series of a group.
+func (r *rule) aggregate(g *group) (output, bool) {
+	o := output{labels: g.labels}
+
+	switch r.cfg.Operation {
+	case OperationCount:
+		o.value = float64(len(g.series))
+
+	case OperationSum, OperationAvg:
+		// The sum of the counter series is their total, unless the average
+		// of their values is requested.
+		useTotal := r.cfg.Operation == OperationSum
+		if g.histogram {
+			var sum *histogram.FloatHistogram
+			if useTotal && g.totalHistogram != nil {
+				sum = g.totalHistogram.Copy()
+			}
+			for _, se := range g.series {
+				if useTotal && se.counter {
+					continue
+				}
+				if sum == nil {
+					sum = se.fh.Copy()
+				} else if _, err := sum.Add(se.fh); err != nil {
+					return o, false
+				}
+			}
+			if sum == nil {
+				return o, false
+			}
+			if r.cfg.Operation == OperationAvg {
+				sum.Div(float64(len(g.series)))
+			}
+			o.fh = sum.Compact(0)
+			break
+		}
+
+		var sum float64
+		if useTotal {
+			sum = g.total
+		}
+		for _, se := range g.series {
+			if useTotal && se.counter {
+				continue
+			}
+			sum += se.value
+		}
+		if r.cfg.Operation == OperationAvg {
+			sum /= float64(len(g.series))
+		}
+		o.value = sum
+
+	case OperationMin, OperationMax:
+		first := true
+		for _, se := range g.series {
+			if first || (r.cfg.Operation == OperationMin && se.value < o.value) || (r.cfg.Operation == OperationMax && se.value > o.value) {
+				o.value = se.value
+				first = false
+			}
+		}
+	}
+	return o, true
+}
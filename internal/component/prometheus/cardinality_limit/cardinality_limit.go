@@ -0,0 +1,332 @@
+package cardinality_limit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/service/labelstore"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.cardinality_limit",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Actions applied to the new series which exceed a budget.
+const (
+	ActionDrop     = "drop"
+	ActionOverflow = "overflow"
+)
+
+// OverflowValue is the value of the labels which exceed their budget when
+// the action is overflow.
+const OverflowValue = "__overflow__"
+
+// maxGCInterval is the maximum interval between two removals of the inactive
+// series.
+const maxGCInterval = time.Minute
+
+// Arguments holds values which are used to configure the
+// prometheus.cardinality_limit component.
+type Arguments struct {
+	// Where the metrics should be forwarded to.
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// How long a series stays active without receiving samples.
+	Window time.Duration `alloy:"window,attr,optional"`
+
+	// The default budgets of each metric. 0 means no limit.
+	MaxSeriesPerMetric int `alloy:"max_series_per_metric,attr,optional"`
+	MaxValuesPerLabel  int `alloy:"max_values_per_label,attr,optional"`
+
+	// What to do with the new series which exceed a budget.
+	Action string `alloy:"action,attr,optional"`
+
+	// How many metrics and labels are reported as top offenders.
+	TopOffenders int `alloy:"top_offenders,attr,optional"`
+
+	// The budgets of specific metrics.
+	Metrics []MetricConfig `alloy:"metric,block,optional"`
+}
+
+// MetricConfig overrides the budgets of a metric.
+type MetricConfig struct {
+	Name              string `alloy:"name,attr"`
+	MaxSeries         *int   `alloy:"max_series,attr,optional"`
+	MaxValuesPerLabel *int   `alloy:"max_values_per_label,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (arg *Arguments) SetToDefault() {
+	*arg = Arguments{
+		Window:             10 * time.Minute,
+		MaxSeriesPerMetric: 10_000,
+		MaxValuesPerLabel:  1_000,
+		Action:             ActionDrop,
+		TopOffenders:       10,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (arg *Arguments) Validate() error {
+	if arg.Window <= 0 {
+		return fmt.Errorf("window must be greater than 0")
+	}
+	if arg.MaxSeriesPerMetric < 0 {
+		return fmt.Errorf("max_series_per_metric can't be negative")
+	}
+	if arg.MaxValuesPerLabel < 0 {
+		return fmt.Errorf("max_values_per_label can't be negative")
+	}
+	if arg.Action != ActionDrop && arg.Action != ActionOverflow {
+		return fmt.Errorf("invalid action %q, must be one of %q or %q", arg.Action, ActionDrop, ActionOverflow)
+	}
+	if arg.TopOffenders < 0 {
+		return fmt.Errorf("top_offenders can't be negative")
+	}
+
+	names := make(map[string]struct{}, len(arg.Metrics))
+	for _, m := range arg.Metrics {
+		if m.Name == "" {
+			return fmt.Errorf("name of metric block can't be empty")
+		}
+		if _, ok := names[m.Name]; ok {
+			return fmt.Errorf("metric %q has several metric blocks", m.Name)
+		}
+		names[m.Name] = struct{}{}
+		if m.MaxSeries != nil && *m.MaxSeries < 0 {
+			return fmt.Errorf("max_series of metric %q can't be negative", m.Name)
+		}
+		if m.MaxValuesPerLabel != nil && *m.MaxValuesPerLabel < 0 {
+			return fmt.Errorf("max_values_per_label of metric %q can't be negative", m.Name)
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the
+// prometheus.cardinality_limit component.
+type Exports struct {
+	Receiver storage.Appendable `alloy:"receiver,attr"`
+}
+
+// Component implements the prometheus.cardinality_limit component.
+type Component struct {
+	opts     component.Options
+	ls       labelstore.LabelStore
+	fanout   *prometheus.Fanout
+	receiver *prometheus.Interceptor
+	metrics  *metrics
+	exited   atomic.Bool
+	updated  chan struct{}
+
+	mut    sync.Mutex
+	args   Arguments
+	limits map[string]MetricConfig
+	// series holds the state of each metric, by metric name.
+	series map[string]*metricState
+}
+
+var (
+	_ component.Component      = (*Component)(nil)
+	_ component.DebugComponent = (*Component)(nil)
+)
+
+// New creates a new prometheus.cardinality_limit component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMetrics(o.Registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Component{
+		opts:    o,
+		ls:      data.(labelstore.LabelStore),
+		metrics: m,
+		updated: make(chan struct{}, 1),
+		series:  make(map[string]*metricState),
+	}
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, c.ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		c.ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+
+			newLbls, newRef, keep := c.admit(ref, l, value.IsStaleNaN(v))
+			if !keep {
+				return ref, nil
+			}
+			return next.Append(newRef, newLbls, t, v)
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+
+			stale := (h != nil && value.IsStaleNaN(h.Sum)) || (fh != nil && value.IsStaleNaN(fh.Sum))
+			newLbls, newRef, keep := c.admit(ref, l, stale)
+			if !keep {
+				return ref, nil
+			}
+			return next.AppendHistogram(newRef, newLbls, t, h, fh)
+		}),
+		prometheus.WithCTZeroSampleHook(func(ref storage.SeriesRef, l labels.Labels, t, ct int64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+
+			newLbls, newRef, keep := c.admit(ref, l, false)
+			if !keep {
+				return ref, nil
+			}
+			return next.AppendCTZeroSample(newRef, newLbls, t, ct)
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+
+			if !c.isActive(ref, l) {
+				return ref, nil
+			}
+			return next.AppendExemplar(ref, l, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+
+			if !c.isActive(ref, l) {
+				return ref, nil
+			}
+			return next.UpdateMetadata(ref, l, m)
+		}),
+	)
+
+	// Call to Update() to set the budgets once at the start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.exited.Store(true)
+
+	ticker := time.NewTicker(c.gcInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.updated:
+			ticker.Reset(c.gcInterval())
+		case now := <-ticker.C:
+			c.gc(now)
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	limits := make(map[string]MetricConfig, len(newArgs.Metrics))
+	for _, m := range newArgs.Metrics {
+		limits[m.Name] = m
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// The active series are kept, even if they exceed the new budgets.
+	if c.args.Window != newArgs.Window {
+		select {
+		case c.updated <- struct{}{}:
+		default:
+		}
+	}
+	c.args = newArgs
+	c.limits = limits
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	return nil
+}
+
+func (c *Component) gcInterval() time.Duration {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return min(c.args.Window, maxGCInterval)
+}
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	info := DebugInfo{
+		Metrics: c.topMetrics(),
+		Labels:  c.topLabels(),
+	}
+	for _, m := range c.series {
+		info.ActiveSeries += len(m.series)
+	}
+	return info
+}
+
+// DebugInfo reports the number of active series and the top offenders.
+type DebugInfo struct {
+	ActiveSeries int            `alloy:"active_series,attr"`
+	Metrics      []MetricStatus `alloy:"metric,block,optional"`
+	Labels       []LabelStatus  `alloy:"label,block,optional"`
+}
+
+// MetricStatus reports the active series of a metric.
+type MetricStatus struct {
+	Name           string `alloy:"name,attr"`
+	ActiveSeries   int    `alloy:"active_series,attr"`
+	MaxSeries      int    `alloy:"max_series,attr"`
+	LimitedSamples int    `alloy:"limited_samples,attr"`
+}
+
+// LabelStatus reports the active values of a label of a metric.
+type LabelStatus struct {
+	Metric       string `alloy:"metric,attr"`
+	Name         string `alloy:"name,attr"`
+	ActiveValues int    `alloy:"active_values,attr"`
+	MaxValues    int    `alloy:"max_values,attr"`
+}
@@ -0,0 +1,335 @@
+package cardinality_limit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		forward_to = []
+
+		metric {
+			name       = "http_requests_total"
+			max_series = 50000
+		}
+	`), &args))
+	require.Equal(t, 10*time.Minute, args.Window)
+	require.Equal(t, 10_000, args.MaxSeriesPerMetric)
+	require.Equal(t, 1_000, args.MaxValuesPerLabel)
+	require.Equal(t, ActionDrop, args.Action)
+	require.Equal(t, 10, args.TopOffenders)
+	require.Equal(t, 50000, *args.Metrics[0].MaxSeries)
+	require.Nil(t, args.Metrics[0].MaxValuesPerLabel)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		configure    func(args *Arguments)
+		errSubstring string
+	}{
+		{
+			name:      "default arguments are valid",
+			configure: func(args *Arguments) {},
+		},
+		{
+			name: "invalid window",
+			configure: func(args *Arguments) {
+				args.Window = 0
+			},
+			errSubstring: "window must be greater than 0",
+		},
+		{
+			name: "negative max_series_per_metric",
+			configure: func(args *Arguments) {
+				args.MaxSeriesPerMetric = -1
+			},
+			errSubstring: "max_series_per_metric can't be negative",
+		},
+		{
+			name: "negative max_values_per_label",
+			configure: func(args *Arguments) {
+				args.MaxValuesPerLabel = -1
+			},
+			errSubstring: "max_values_per_label can't be negative",
+		},
+		{
+			name: "invalid action",
+			configure: func(args *Arguments) {
+				args.Action = "relabel"
+			},
+			errSubstring: `invalid action "relabel"`,
+		},
+		{
+			name: "negative top_offenders",
+			configure: func(args *Arguments) {
+				args.TopOffenders = -1
+			},
+			errSubstring: "top_offenders can't be negative",
+		},
+		{
+			name: "empty metric name",
+			configure: func(args *Arguments) {
+				args.Metrics = []MetricConfig{{Name: ""}}
+			},
+			errSubstring: "name of metric block can't be empty",
+		},
+		{
+			name: "duplicate metric",
+			configure: func(args *Arguments) {
+				args.Metrics = []MetricConfig{{Name: "a"}, {Name: "a"}}
+			},
+			errSubstring: `metric "a" has several metric blocks`,
+		},
+		{
+			name: "negative metric max_series",
+			configure: func(args *Arguments) {
+				args.Metrics = []MetricConfig{{Name: "a", MaxSeries: ptr(-1)}}
+			},
+			errSubstring: `max_series of metric "a" can't be negative`,
+		},
+		{
+			name: "negative metric max_values_per_label",
+			configure: func(args *Arguments) {
+				args.Metrics = []MetricConfig{{Name: "a", MaxValuesPerLabel: ptr(-1)}}
+			},
+			errSubstring: `max_values_per_label of metric "a" can't be negative`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args Arguments
+			args.SetToDefault()
+			tt.configure(&args)
+			err := args.Validate()
+			if tt.errSubstring == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.errSubstring)
+			}
+		})
+	}
+}
+
+func TestSeriesBudget(t *testing.T) {
+	c, out, reg := newTestComponent(t, func(args *Arguments) {
+		args.MaxSeriesPerMetric = 2
+		args.Metrics = []MetricConfig{{Name: "requests_total", MaxSeries: ptr(3)}}
+	})
+
+	app := c.receiver.Appender(context.Background())
+	for i := range 4 {
+		appendSample(t, app, 1, "__name__", "up", "instance", fmt.Sprint(i))
+		appendSample(t, app, 1, "__name__", "requests_total", "instance", fmt.Sprint(i))
+	}
+	require.NoError(t, app.Commit())
+	require.Equal(t, map[string]float64{
+		`{__name__="up", instance="0"}`:             1,
+		`{__name__="up", instance="1"}`:             1,
+		`{__name__="requests_total", instance="0"}`: 1,
+		`{__name__="requests_total", instance="1"}`: 1,
+		`{__name__="requests_total", instance="2"}`: 1,
+	}, out.TakeSamples())
+
+	// The active series are still forwarded.
+	app = c.receiver.Appender(context.Background())
+	appendSample(t, app, 2, "__name__", "up", "instance", "1")
+	appendSample(t, app, 2, "__name__", "up", "instance", "3")
+	require.NoError(t, app.Commit())
+	require.Equal(t, map[string]float64{`{__name__="up", instance="1"}`: 2}, out.TakeSamples())
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP alloy_prometheus_cardinality_limit_limited_samples_total Total number of samples of new series which were dropped or overflowed because they exceeded a budget, partitioned by the exceeded budget.
+# TYPE alloy_prometheus_cardinality_limit_limited_samples_total counter
+alloy_prometheus_cardinality_limit_limited_samples_total{reason="series"} 4
+`), "alloy_prometheus_cardinality_limit_limited_samples_total"))
+}
+
+func TestLabelBudget(t *testing.T) {
+	c, out, _ := newTestComponent(t, func(args *Arguments) {
+		args.MaxValuesPerLabel = 2
+		args.Action = ActionOverflow
+	})
+
+	app := c.receiver.Appender(context.Background())
+	for i := range 4 {
+		appendSample(t, app, 1, "__name__", "requests_total", "job", "api", "request_id", fmt.Sprint(i))
+	}
+	require.NoError(t, app.Commit())
+	require.Equal(t, map[string]float64{
+		`{__name__="requests_total", job="api", request_id="0"}`:            1,
+		`{__name__="requests_total", job="api", request_id="1"}`:            1,
+		`{__name__="requests_total", job="api", request_id="__overflow__"}`: 1,
+	}, out.TakeSamples())
+}
+
+func TestSeriesOverflow(t *testing.T) {
+	c, out, _ := newTestComponent(t, func(args *Arguments) {
+		args.MaxSeriesPerMetric = 1
+		args.Action = ActionOverflow
+	})
+
+	app := c.receiver.Appender(context.Background())
+	appendSample(t, app, 1, "__name__", "latency_seconds_bucket", "instance", "a", "le", "0.1")
+	appendSample(t, app, 2, "__name__", "latency_seconds_bucket", "instance", "b", "le", "0.1")
+	appendSample(t, app, 3, "__name__", "latency_seconds_bucket", "instance", "b", "le", "+Inf")
+	require.NoError(t, app.Commit())
+	require.Equal(t, map[string]float64{
+		`{__name__="latency_seconds_bucket", instance="a", le="0.1"}`:             1,
+		`{__name__="latency_seconds_bucket", instance="__overflow__", le="0.1"}`:  2,
+		`{__name__="latency_seconds_bucket", instance="__overflow__", le="+Inf"}`: 3,
+	}, out.TakeSamples())
+}
+
+func TestExpiry(t *testing.T) {
+	c, out, _ := newTestComponent(t, func(args *Arguments) {
+		args.MaxSeriesPerMetric = 1
+	})
+
+	app := c.receiver.Appender(context.Background())
+	appendSample(t, app, 1, "__name__", "up", "instance", "a")
+	appendSample(t, app, 1, "__name__", "up", "instance", "b")
+	require.NoError(t, app.Commit())
+	require.Len(t, out.TakeSamples(), 1)
+
+	// A staleness marker removes the series right away.
+	app = c.receiver.Appender(context.Background())
+	appendSample(t, app, math.Float64frombits(value.StaleNaN), "__name__", "up", "instance", "a")
+	appendSample(t, app, 2, "__name__", "up", "instance", "b")
+	require.NoError(t, app.Commit())
+	result := out.TakeSamples()
+	require.True(t, value.IsStaleNaN(result[`{__name__="up", instance="a"}`]))
+	require.Equal(t, 2.0, result[`{__name__="up", instance="b"}`])
+
+	// The series which don't receive samples during the window are removed.
+	c.gc(time.Now().Add(time.Hour))
+	app = c.receiver.Appender(context.Background())
+	appendSample(t, app, 3, "__name__", "up", "instance", "c")
+	require.NoError(t, app.Commit())
+	require.Equal(t, map[string]float64{`{__name__="up", instance="c"}`: 3}, out.TakeSamples())
+}
+
+func TestTopOffenders(t *testing.T) {
+	c, _, reg := newTestComponent(t, func(args *Arguments) {
+		args.TopOffenders = 2
+		args.MaxSeriesPerMetric = 3
+	})
+
+	app := c.receiver.Appender(context.Background())
+	for i := range 4 {
+		appendSample(t, app, 1, "__name__", "requests_total", "instance", "a", "path", fmt.Sprint(i))
+	}
+	appendSample(t, app, 1, "__name__", "up", "instance", "a")
+	appendSample(t, app, 1, "__name__", "up", "instance", "b")
+	appendSample(t, app, 1, "__name__", "build_info", "instance", "a")
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, DebugInfo{
+		ActiveSeries: 6,
+		Metrics: []MetricStatus{
+			{Name: "requests_total", ActiveSeries: 3, MaxSeries: 3, LimitedSamples: 1},
+			{Name: "up", ActiveSeries: 2, MaxSeries: 3},
+		},
+		Labels: []LabelStatus{
+			{Metric: "requests_total", Name: "path", ActiveValues: 3, MaxValues: 1000},
+			{Metric: "up", Name: "instance", ActiveValues: 2, MaxValues: 1000},
+		},
+	}, c.DebugInfo())
+
+	c.gc(time.Now())
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP alloy_prometheus_cardinality_limit_active_series Number of active series tracked by the component.
+# TYPE alloy_prometheus_cardinality_limit_active_series gauge
+alloy_prometheus_cardinality_limit_active_series 6
+# HELP alloy_prometheus_cardinality_limit_top_label_values Number of active values of the labels with the most active values.
+# TYPE alloy_prometheus_cardinality_limit_top_label_values gauge
+alloy_prometheus_cardinality_limit_top_label_values{label="instance",metric="up"} 2
+alloy_prometheus_cardinality_limit_top_label_values{label="path",metric="requests_total"} 3
+# HELP alloy_prometheus_cardinality_limit_top_metric_series Number of active series of the metrics with the most active series.
+# TYPE alloy_prometheus_cardinality_limit_top_metric_series gauge
+alloy_prometheus_cardinality_limit_top_metric_series{metric="requests_total"} 3
+alloy_prometheus_cardinality_limit_top_metric_series{metric="up"} 2
+`),
+		"alloy_prometheus_cardinality_limit_active_series",
+		"alloy_prometheus_cardinality_limit_top_label_values",
+		"alloy_prometheus_cardinality_limit_top_metric_series",
+	))
+}
+
+func TestUpdate(t *testing.T) {
+	c, out, _ := newTestComponent(t, func(args *Arguments) {
+		args.MaxSeriesPerMetric = 2
+	})
+
+	app := c.receiver.Appender(context.Background())
+	appendSample(t, app, 1, "__name__", "up", "instance", "a")
+	appendSample(t, app, 1, "__name__", "up", "instance", "b")
+	require.NoError(t, app.Commit())
+	require.Len(t, out.TakeSamples(), 2)
+
+	// The active series are kept when the budget decreases.
+	args := c.args
+	args.MaxSeriesPerMetric = 1
+	require.NoError(t, c.Update(args))
+	app = c.receiver.Appender(context.Background())
+	appendSample(t, app, 2, "__name__", "up", "instance", "a")
+	appendSample(t, app, 2, "__name__", "up", "instance", "b")
+	appendSample(t, app, 2, "__name__", "up", "instance", "c")
+	require.NoError(t, app.Commit())
+	require.Len(t, out.TakeSamples(), 2)
+}
+
+func newTestComponent(t *testing.T, configure func(args *Arguments)) (*Component, *testappender.Collector, *prom.Registry) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	out := testappender.NewCollector()
+
+	var args Arguments
+	args.SetToDefault()
+	args.ForwardTo = []storage.Appendable{out}
+	configure(&args)
+
+	reg := prom.NewRegistry()
+	c, err := New(component.Options{
+		ID:            "prometheus.cardinality_limit.test",
+		Logger:        util.TestAlloyLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    reg,
+		GetServiceData: func(name string) (interface{}, error) {
+			if name != labelstore.ServiceName {
+				return nil, fmt.Errorf("service not found %s", name)
+			}
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+	return c, out, reg
+}
+
+func appendSample(t *testing.T, app storage.Appender, v float64, ls ...string) {
+	_, err := app.Append(0, labels.FromStrings(ls...), time.Now().UnixMilli(), v)
+	require.NoError(t, err)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,40 @@
+package cardinality_limit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	activeSeries    prometheus.Gauge
+	limitedSamples  *prometheus.CounterVec
+	topMetricSeries *prometheus.GaugeVec
+	topLabelValues  *prometheus.GaugeVec
+}
+
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		activeSeries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "alloy_prometheus_cardinality_limit_active_series",
+			Help: "Number of active series tracked by the component.",
+		}),
+		limitedSamples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alloy_prometheus_cardinality_limit_limited_samples_total",
+			Help: "Total number of samples of new series which were dropped or overflowed because they exceeded a budget, partitioned by the exceeded budget.",
+		}, []string{"reason"}),
+		topMetricSeries: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "alloy_prometheus_cardinality_limit_top_metric_series",
+			Help: "Number of active series of the metrics with the most active series.",
+		}, []string{"metric"}),
+		topLabelValues: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "alloy_prometheus_cardinality_limit_top_label_values",
+			Help: "Number of active values of the labels with the most active values.",
+		}, []string{"metric", "label"}),
+	}
+
+	for _, metric := range []prometheus.Collector{m.activeSeries, m.limitedSamples, m.topMetricSeries, m.topLabelValues} {
+		if err := reg.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,264 @@
+package cardinality_limit
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Reasons for which a new series is limited.
+const (
+	reasonSeries = "series"
+	reasonLabel  = "label"
+)
+
+// metricState holds the active series of a metric.
+type metricState struct {
+	// series holds the time the active series last received a sample, by
+	// global reference.
+	series map[storage.SeriesRef]*seriesState
+	// values holds the number of active series of each value of each label.
+	values map[string]map[string]int
+	// limited is the number of samples of new series which exceeded a budget.
+	limited int
+}
+
+type seriesState struct {
+	labels   labels.Labels
+	lastSeen time.Time
+}
+
+func newMetricState() *metricState {
+	return &metricState{
+		series: make(map[storage.SeriesRef]*seriesState),
+		values: make(map[string]map[string]int),
+	}
+}
+
+func (m *metricState) add(ref storage.SeriesRef, l labels.Labels, now time.Time) {
+	m.series[ref] = &seriesState{labels: l, lastSeen: now}
+	l.Range(func(l labels.Label) {
+		if l.Name == labels.MetricName {
+			return
+		}
+		values, ok := m.values[l.Name]
+		if !ok {
+			values = make(map[string]int)
+			m.values[l.Name] = values
+		}
+		values[l.Value]++
+	})
+}
+
+func (m *metricState) remove(ref storage.SeriesRef) {
+	s, ok := m.series[ref]
+	if !ok {
+		return
+	}
+	delete(m.series, ref)
+	s.labels.Range(func(l labels.Label) {
+		if l.Name == labels.MetricName {
+			return
+		}
+		values := m.values[l.Name]
+		if values[l.Value]--; values[l.Value] <= 0 {
+			delete(values, l.Value)
+		}
+		if len(values) == 0 {
+			delete(m.values, l.Name)
+		}
+	})
+}
+
+// admit tracks a sample, and returns the labels and reference it must be
+// forwarded with, or false if it must be dropped.
+//
+// The samples of active series are always forwarded as-is. A new series
+// becomes active if it doesn't exceed the budgets of its metric. Otherwise,
+// its samples are dropped or forwarded with the labels exceeding their budget
+// set to OverflowValue, until the series fits in the budgets.
+func (c *Component) admit(ref storage.SeriesRef, l labels.Labels, stale bool) (labels.Labels, storage.SeriesRef, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	name := l.Get(labels.MetricName)
+	m, ok := c.series[name]
+	if ok {
+		if s, active := m.series[ref]; active {
+			if stale {
+				// The series is removed right away, so that it doesn't use the
+				// budget during the window.
+				m.remove(ref)
+				c.removeIfEmpty(name, m)
+			} else {
+				s.lastSeen = time.Now()
+			}
+			return l, ref, true
+		}
+	}
+	// The staleness markers of the series which aren't active aren't useful
+	// downstream.
+	if stale {
+		return labels.EmptyLabels(), 0, false
+	}
+	if !ok {
+		m = newMetricState()
+		c.series[name] = m
+	}
+
+	maxSeries, maxValues := c.limitsFor(name)
+	if maxSeries > 0 && len(m.series) >= maxSeries {
+		return c.limit(m, reasonSeries, l, ref, nil)
+	}
+	var exceeded []string
+	if maxValues > 0 {
+		l.Range(func(l labels.Label) {
+			if l.Name == labels.MetricName {
+				return
+			}
+			values := m.values[l.Name]
+			if _, ok := values[l.Value]; !ok && len(values) >= maxValues {
+				exceeded = append(exceeded, l.Name)
+			}
+		})
+	}
+	if len(exceeded) > 0 {
+		return c.limit(m, reasonLabel, l, ref, exceeded)
+	}
+
+	m.add(ref, l, time.Now())
+	return l, ref, true
+}
+
+// limit handles a sample of a new series which exceeds a budget. When the
+// budget of labels is exceeded, exceeded holds the names of the labels.
+func (c *Component) limit(m *metricState, reason string, l labels.Labels, ref storage.SeriesRef, exceeded []string) (labels.Labels, storage.SeriesRef, bool) {
+	m.limited++
+	c.metrics.limitedSamples.WithLabelValues(reason).Inc()
+
+	if c.args.Action == ActionDrop {
+		return labels.EmptyLabels(), 0, false
+	}
+
+	b := labels.NewBuilder(l)
+	if reason == reasonSeries {
+		// The bucket and quantile labels are kept, so that the overflow
+		// series of histograms and summaries have the same structure.
+		l.Range(func(l labels.Label) {
+			switch l.Name {
+			case labels.MetricName, labels.BucketLabel, model.QuantileLabel:
+				return
+			}
+			b.Set(l.Name, OverflowValue)
+		})
+	}
+	for _, name := range exceeded {
+		b.Set(name, OverflowValue)
+	}
+	// The reference is reset, as the labels changed.
+	return b.Labels(), 0, true
+}
+
+func (c *Component) removeIfEmpty(name string, m *metricState) {
+	if len(m.series) == 0 {
+		delete(c.series, name)
+	}
+}
+
+// isActive returns whether a series is active.
+func (c *Component) isActive(ref storage.SeriesRef, l labels.Labels) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	m, ok := c.series[l.Get(labels.MetricName)]
+	if !ok {
+		return false
+	}
+	_, ok = m.series[ref]
+	return ok
+}
+
+// limitsFor returns the budgets of a metric.
+func (c *Component) limitsFor(name string) (maxSeries, maxValues int) {
+	maxSeries, maxValues = c.args.MaxSeriesPerMetric, c.args.MaxValuesPerLabel
+	if cfg, ok := c.limits[name]; ok {
+		if cfg.MaxSeries != nil {
+			maxSeries = *cfg.MaxSeries
+		}
+		if cfg.MaxValuesPerLabel != nil {
+			maxValues = *cfg.MaxValuesPerLabel
+		}
+	}
+	return maxSeries, maxValues
+}
+
+// gc removes the series which didn't receive samples during the window, and
+// updates the metrics.
+func (c *Component) gc(now time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	var active int
+	deadline := now.Add(-c.args.Window)
+	for name, m := range c.series {
+		for ref, s := range m.series {
+			if s.lastSeen.Before(deadline) {
+				m.remove(ref)
+			}
+		}
+		c.removeIfEmpty(name, m)
+		active += len(m.series)
+	}
+	c.metrics.activeSeries.Set(float64(active))
+
+	c.metrics.topMetricSeries.Reset()
+	for _, m := range c.topMetrics() {
+		c.metrics.topMetricSeries.WithLabelValues(m.Name).Set(float64(m.ActiveSeries))
+	}
+	c.metrics.topLabelValues.Reset()
+	for _, l := range c.topLabels() {
+		c.metrics.topLabelValues.WithLabelValues(l.Metric, l.Name).Set(float64(l.ActiveValues))
+	}
+}
+
+// topMetrics returns the metrics with the most active series.
+func (c *Component) topMetrics() []MetricStatus {
+	metrics := make([]MetricStatus, 0, len(c.series))
+	for name, m := range c.series {
+		maxSeries, _ := c.limitsFor(name)
+		metrics = append(metrics, MetricStatus{
+			Name:           name,
+			ActiveSeries:   len(m.series),
+			MaxSeries:      maxSeries,
+			LimitedSamples: m.limited,
+		})
+	}
+	slices.SortFunc(metrics, func(a, b MetricStatus) int {
+		return cmp.Or(cmp.Compare(b.ActiveSeries, a.ActiveSeries), cmp.Compare(a.Name, b.Name))
+	})
+	return metrics[:min(len(metrics), c.args.TopOffenders)]
+}
+
+// topLabels returns the labels with the most active values.
+func (c *Component) topLabels() []LabelStatus {
+	var statuses []LabelStatus
+	for name, m := range c.series {
+		_, maxValues := c.limitsFor(name)
+		for label, values := range m.values {
+			statuses = append(statuses, LabelStatus{
+				Metric:       name,
+				Name:         label,
+				ActiveValues: len(values),
+				MaxValues:    maxValues,
+			})
+		}
+	}
+	slices.SortFunc(statuses, func(a, b LabelStatus) int {
+		return cmp.Or(cmp.Compare(b.ActiveValues, a.ActiveValues), cmp.Compare(a.Metric, b.Metric), cmp.Compare(a.Name, b.Name))
+	})
+	return statuses[:min(len(statuses), c.args.TopOffenders)]
+}
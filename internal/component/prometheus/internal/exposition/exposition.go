@@ -0,0 +1,62 @@
+// Package exposition converts the values of the metrics of the Prometheus
+// exposition formats into the values appended to Prometheus storage.
+package exposition
+
+import (
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/model/histogram"
+)
+
+// NativeHistogram converts the native histogram data of h. It returns a
+// float histogram if h has float counts, and an integer histogram otherwise.
+// gauge must be true for a gauge histogram.
+func NativeHistogram(h *dto.Histogram, gauge bool) (*histogram.Histogram, *histogram.FloatHistogram) {
+	hint := histogram.UnknownCounterReset
+	if gauge {
+		hint = histogram.GaugeType
+	}
+
+	if h.SampleCountFloat != nil {
+		return nil, &histogram.FloatHistogram{
+			CounterResetHint: hint,
+			Schema:           h.GetSchema(),
+			ZeroThreshold:    h.GetZeroThreshold(),
+			ZeroCount:        h.GetZeroCountFloat(),
+			Count:            h.GetSampleCountFloat(),
+			Sum:              h.GetSampleSum(),
+			PositiveSpans:    Spans(h.GetPositiveSpan()),
+			PositiveBuckets:  h.GetPositiveCount(),
+			NegativeSpans:    Spans(h.GetNegativeSpan()),
+			NegativeBuckets:  h.GetNegativeCount(),
+		}
+	}
+	return &histogram.Histogram{
+		CounterResetHint: hint,
+		Schema:           h.GetSchema(),
+		ZeroThreshold:    h.GetZeroThreshold(),
+		ZeroCount:        h.GetZeroCount(),
+		Count:            h.GetSampleCount(),
+		Sum:              h.GetSampleSum(),
+		PositiveSpans:    Spans(h.GetPositiveSpan()),
+		PositiveBuckets:  h.GetPositiveDelta(),
+		NegativeSpans:    Spans(h.GetNegativeSpan()),
+		NegativeBuckets:  h.GetNegativeDelta(),
+	}, nil
+}
+
+// Spans converts the bucket spans of a native histogram.
+func Spans(in []*dto.BucketSpan) []histogram.Span {
+	out := make([]histogram.Span, 0, len(in))
+	for _, s := range in {
+		out = append(out, histogram.Span{Offset: s.GetOffset(), Length: s.GetLength()})
+	}
+	return out
+}
+
+// FormatFloat formats a float the way Prometheus formats the values of the
+// le and quantile labels.
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
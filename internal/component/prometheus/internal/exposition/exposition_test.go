@@ -0,0 +1,56 @@
+package exposition
+
+import (
+	"math"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNativeHistogram(t *testing.T) {
+	spans := []*dto.BucketSpan{{Offset: proto.Int32(0), Length: proto.Uint32(2)}}
+
+	h, fh := NativeHistogram(&dto.Histogram{
+		SampleCount:   proto.Uint64(3),
+		SampleSum:     proto.Float64(4.5),
+		Schema:        proto.Int32(1),
+		PositiveSpan:  spans,
+		PositiveDelta: []int64{1, 1},
+	}, false)
+	require.Nil(t, fh)
+	require.Equal(t, &histogram.Histogram{
+		Schema:          1,
+		Count:           3,
+		Sum:             4.5,
+		PositiveSpans:   []histogram.Span{{Offset: 0, Length: 2}},
+		PositiveBuckets: []int64{1, 1},
+		NegativeSpans:   []histogram.Span{},
+	}, h)
+
+	h, fh = NativeHistogram(&dto.Histogram{
+		SampleCountFloat: proto.Float64(3),
+		SampleSum:        proto.Float64(4.5),
+		Schema:           proto.Int32(1),
+		PositiveSpan:     spans,
+		PositiveCount:    []float64{1, 2},
+	}, true)
+	require.Nil(t, h)
+	require.Equal(t, &histogram.FloatHistogram{
+		CounterResetHint: histogram.GaugeType,
+		Schema:           1,
+		Count:            3,
+		Sum:              4.5,
+		PositiveSpans:    []histogram.Span{{Offset: 0, Length: 2}},
+		PositiveBuckets:  []float64{1, 2},
+		NegativeSpans:    []histogram.Span{},
+	}, fh)
+}
+
+func TestFormatFloat(t *testing.T) {
+	require.Equal(t, "0.5", FormatFloat(0.5))
+	require.Equal(t, "1", FormatFloat(1))
+	require.Equal(t, "+Inf", FormatFloat(math.Inf(1)))
+}
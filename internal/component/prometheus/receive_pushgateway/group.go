@@ -0,0 +1,292 @@
+package receive_pushgateway
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/alloy/internal/component/prometheus/internal/exposition"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// pushTimeMetric is the metric holding the time of the last push of each
+// group, as exposed by the Pushgateway.
+const pushTimeMetric = "push_time_seconds"
+
+// group holds the metrics pushed with a grouping key.
+type group struct {
+	labels   labels.Labels
+	families map[string]*family
+	lastPush time.Time
+	// sent holds the series forwarded the last time, so that the series which
+	// disappear are marked as stale.
+	sent map[uint64]labels.Labels
+}
+
+// family holds the samples of a metric family.
+type family struct {
+	metadata metadata.Metadata
+	samples  []sample
+}
+
+type sample struct {
+	labels labels.Labels
+	value  float64
+	h      *histogram.Histogram
+	fh     *histogram.FloatHistogram
+}
+
+// push replaces the metrics of a group. With replaceAll, all the metric
+// families of the group are replaced, otherwise only the pushed ones. The
+// group is then forwarded right away.
+func (c *Component) push(ctx context.Context, groupLabels labels.Labels, families map[string]*family, replaceAll bool, now time.Time) {
+	c.groupsMut.Lock()
+	defer c.groupsMut.Unlock()
+
+	key := groupLabels.String()
+	g, ok := c.groups[key]
+	if !ok {
+		g = &group{labels: groupLabels, sent: make(map[uint64]labels.Labels)}
+		c.groups[key] = g
+		c.metrics.groups.Set(float64(len(c.groups)))
+	}
+	if replaceAll || g.families == nil {
+		g.families = families
+	} else {
+		for name, f := range families {
+			g.families[name] = f
+		}
+	}
+	g.lastPush = now
+
+	app := c.fanout.Appender(ctx)
+	c.forward(app, g, now.UnixMilli())
+	if err := app.Commit(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to forward pushed metrics", "group", key, "err", err)
+	}
+}
+
+// deleteGroup deletes a group, and marks its series as stale.
+func (c *Component) deleteGroup(ctx context.Context, groupLabels labels.Labels, now time.Time) {
+	c.groupsMut.Lock()
+	defer c.groupsMut.Unlock()
+
+	key := groupLabels.String()
+	g, ok := c.groups[key]
+	if !ok {
+		return
+	}
+	delete(c.groups, key)
+	c.metrics.groups.Set(float64(len(c.groups)))
+
+	app := c.fanout.Appender(ctx)
+	c.markStale(app, g, now.UnixMilli())
+	if err := app.Commit(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to forward staleness markers", "group", key, "err", err)
+	}
+}
+
+// forwardAll forwards the metrics of all the groups, after deleting the
+// groups which expired.
+func (c *Component) forwardAll(ctx context.Context, now time.Time) {
+	ttl := c.groupTTL()
+
+	c.groupsMut.Lock()
+	defer c.groupsMut.Unlock()
+
+	ts := now.UnixMilli()
+	app := c.fanout.Appender(ctx)
+	for key, g := range c.groups {
+		if ttl > 0 && now.Sub(g.lastPush) > ttl {
+			level.Debug(c.opts.Logger).Log("msg", "deleting expired group", "group", key)
+			delete(c.groups, key)
+			c.metrics.expiredGroups.Inc()
+			c.markStale(app, g, ts)
+			continue
+		}
+		c.forward(app, g, ts)
+	}
+	c.metrics.groups.Set(float64(len(c.groups)))
+	if err := app.Commit(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to forward pushed metrics", "err", err)
+	}
+}
+
+// forward appends the samples of a group, and marks the series which
+// disappeared since the last time as stale.
+func (c *Component) forward(app storage.Appender, g *group, ts int64) {
+	sent := make(map[uint64]labels.Labels, len(g.sent))
+	appendSample := func(s sample, md metadata.Metadata) {
+		var err error
+		if s.h != nil || s.fh != nil {
+			_, err = app.AppendHistogram(0, s.labels, ts, s.h, s.fh)
+		} else {
+			_, err = app.Append(0, s.labels, ts, s.value)
+		}
+		if err == nil {
+			_, err = app.UpdateMetadata(0, s.labels, md)
+		}
+		if err != nil {
+			level.Debug(c.opts.Logger).Log("msg", "failed to append sample", "labels", s.labels.String(), "err", err)
+		}
+		sent[s.labels.Hash()] = s.labels
+	}
+
+	for _, f := range g.families {
+		for _, s := range f.samples {
+			appendSample(s, f.metadata)
+		}
+	}
+	pushTime := labels.NewBuilder(g.labels).Set(labels.MetricName, pushTimeMetric).Labels()
+	appendSample(sample{
+		labels: pushTime,
+		value:  float64(g.lastPush.UnixNano()) / 1e9,
+	}, metadata.Metadata{
+		Type: model.MetricTypeGauge,
+		Help: "Last Unix time when this group was changed in the Pushgateway.",
+	})
+
+	for hash, l := range g.sent {
+		if _, ok := sent[hash]; !ok {
+			appendStaleMarker(app, l, ts)
+		}
+	}
+	g.sent = sent
+}
+
+// markStale marks all the series of a group as stale.
+func (c *Component) markStale(app storage.Appender, g *group, ts int64) {
+	for _, l := range g.sent {
+		appendStaleMarker(app, l, ts)
+	}
+	g.sent = nil
+}
+
+func appendStaleMarker(app storage.Appender, l labels.Labels, ts int64) {
+	_, _ = app.Append(0, l, ts, math.Float64frombits(value.StaleNaN))
+}
+
+// convertFamily converts a pushed metric family to samples. The grouping
+// labels override the labels of the metrics.
+func convertFamily(mf *dto.MetricFamily, groupLabels labels.Labels) (*family, error) {
+	name := mf.GetName()
+	f := &family{metadata: metadata.Metadata{Type: metricType(mf.GetType()), Help: mf.GetHelp()}}
+
+	for _, m := range mf.GetMetric() {
+		if m.TimestampMs != nil {
+			return nil, fmt.Errorf("pushed metrics must not have timestamps, metric %q has one", name)
+		}
+
+		b := labels.NewBuilder(labels.EmptyLabels())
+		for _, l := range m.GetLabel() {
+			b.Set(l.GetName(), l.GetValue())
+		}
+		groupLabels.Range(func(l labels.Label) {
+			b.Set(l.Name, l.Value)
+		})
+		base := b.Labels()
+		series := func(suffix string, extra ...string) labels.Labels {
+			b := labels.NewBuilder(base)
+			b.Set(labels.MetricName, name+suffix)
+			for i := 0; i < len(extra); i += 2 {
+				b.Set(extra[i], extra[i+1])
+			}
+			return b.Labels()
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			f.samples = append(f.samples, sample{labels: series(""), value: m.GetCounter().GetValue()})
+		case dto.MetricType_GAUGE:
+			f.samples = append(f.samples, sample{labels: series(""), value: m.GetGauge().GetValue()})
+		case dto.MetricType_UNTYPED:
+			f.samples = append(f.samples, sample{labels: series(""), value: m.GetUntyped().GetValue()})
+		case dto.MetricType_SUMMARY:
+			s := m.GetSummary()
+			for _, q := range s.GetQuantile() {
+				f.samples = append(f.samples, sample{
+					labels: series("", model.QuantileLabel, exposition.FormatFloat(q.GetQuantile())),
+					value:  q.GetValue(),
+				})
+			}
+			f.samples = append(f.samples,
+				sample{labels: series("_sum"), value: s.GetSampleSum()},
+				sample{labels: series("_count"), value: float64(s.GetSampleCount())},
+			)
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			h := m.GetHistogram()
+			if isNativeHistogram(h) {
+				ih, fh := exposition.NativeHistogram(h, mf.GetType() == dto.MetricType_GAUGE_HISTOGRAM)
+				f.samples = append(f.samples, sample{labels: series(""), h: ih, fh: fh})
+			}
+			if len(h.GetBucket()) > 0 || !isNativeHistogram(h) {
+				f.samples = append(f.samples, classicHistogram(h, series)...)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported type %s of metric %q", mf.GetType(), name)
+		}
+	}
+	return f, nil
+}
+
+func classicHistogram(h *dto.Histogram, series func(suffix string, extra ...string) labels.Labels) []sample {
+	var samples []sample
+	count := float64(h.GetSampleCount())
+	if h.SampleCountFloat != nil {
+		count = h.GetSampleCountFloat()
+	}
+
+	var hasInf bool
+	for _, b := range h.GetBucket() {
+		v := float64(b.GetCumulativeCount())
+		if b.CumulativeCountFloat != nil {
+			v = b.GetCumulativeCountFloat()
+		}
+		if math.IsInf(b.GetUpperBound(), +1) {
+			hasInf = true
+		}
+		samples = append(samples, sample{labels: series("_bucket", labels.BucketLabel, exposition.FormatFloat(b.GetUpperBound())), value: v})
+	}
+	if !hasInf {
+		samples = append(samples, sample{labels: series("_bucket", labels.BucketLabel, "+Inf"), value: count})
+	}
+	return append(samples,
+		sample{labels: series("_sum"), value: h.GetSampleSum()},
+		sample{labels: series("_count"), value: count},
+	)
+}
+
+// isNativeHistogram returns whether a histogram has native histogram data.
+func isNativeHistogram(h *dto.Histogram) bool {
+	return h.GetZeroThreshold() > 0 ||
+		h.GetZeroCount() > 0 ||
+		h.GetZeroCountFloat() > 0 ||
+		len(h.GetPositiveSpan()) > 0 ||
+		len(h.GetNegativeSpan()) > 0
+}
+
+func metricType(t dto.MetricType) model.MetricType {
+	switch t {
+	case dto.MetricType_COUNTER:
+		return model.MetricTypeCounter
+	case dto.MetricType_GAUGE:
+		return model.MetricTypeGauge
+	case dto.MetricType_SUMMARY:
+		return model.MetricTypeSummary
+	case dto.MetricType_HISTOGRAM:
+		return model.MetricTypeHistogram
+	case dto.MetricType_GAUGE_HISTOGRAM:
+		return model.MetricTypeGaugeHistogram
+	default:
+		return model.MetricTypeUnknown
+	}
+}
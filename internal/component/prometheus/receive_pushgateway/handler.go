@@ -0,0 +1,164 @@
+package receive_pushgateway
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+const (
+	metricsPathPrefix = "/metrics/"
+	base64Suffix      = "@base64"
+)
+
+// handlePush handles the requests of the push API of the Pushgateway:
+//
+//   - PUT replaces all the metrics of a group.
+//   - POST replaces the metrics of a group with the same names as the pushed
+//     metrics.
+//   - DELETE deletes a group.
+func (c *Component) handlePush(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	defer func() {
+		c.metrics.pushes.WithLabelValues(r.Method, strconv.Itoa(status)).Inc()
+	}()
+	fail := func(err error) {
+		status = http.StatusBadRequest
+		level.Debug(c.opts.Logger).Log("msg", "invalid push request", "method", r.Method, "path", r.URL.Path, "err", err)
+		http.Error(w, err.Error(), status)
+	}
+
+	groupLabels, err := parseGroupingKey(strings.TrimPrefix(r.URL.EscapedPath(), metricsPathPrefix))
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		c.deleteGroup(r.Context(), groupLabels, time.Now())
+		status = http.StatusAccepted
+		w.WriteHeader(status)
+		return
+	}
+
+	families, err := decodeFamilies(r)
+	if err != nil {
+		fail(fmt.Errorf("failed to parse pushed metrics: %w", err))
+		return
+	}
+	converted := make(map[string]*family, len(families))
+	for name, mf := range families {
+		f, err := convertFamily(mf, groupLabels)
+		if err != nil {
+			fail(err)
+			return
+		}
+		converted[name] = f
+	}
+
+	c.push(r.Context(), groupLabels, converted, r.Method == http.MethodPut, time.Now())
+	w.WriteHeader(status)
+}
+
+// parseGroupingKey parses the grouping key of a push path, made of the job
+// and of optional label name and value pairs:
+//
+//	job/<JOB_NAME>{/<LABEL_NAME>/<LABEL_VALUE>}
+//
+// The values of the labels whose name has the @base64 suffix are encoded in
+// URL-safe base64.
+func parseGroupingKey(path string) (labels.Labels, error) {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(segments)%2 != 0 {
+		return labels.EmptyLabels(), errors.New("grouping key must be made of label name and value pairs")
+	}
+
+	b := labels.NewScratchBuilder(len(segments) / 2)
+	seen := make(map[string]struct{}, len(segments)/2)
+	for i := 0; i < len(segments); i += 2 {
+		name, err := url.PathUnescape(segments[i])
+		if err != nil {
+			return labels.EmptyLabels(), fmt.Errorf("invalid label name %q: %w", segments[i], err)
+		}
+		value, err := url.PathUnescape(segments[i+1])
+		if err != nil {
+			return labels.EmptyLabels(), fmt.Errorf("invalid value of label %q: %w", name, err)
+		}
+		if n, ok := strings.CutSuffix(name, base64Suffix); ok {
+			name = n
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+			if err != nil {
+				return labels.EmptyLabels(), fmt.Errorf("invalid base64 value of label %q: %w", name, err)
+			}
+			value = string(decoded)
+		}
+
+		if i == 0 && name != model.JobLabel {
+			return labels.EmptyLabels(), fmt.Errorf("grouping key must start with the %s label", model.JobLabel)
+		}
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+			return labels.EmptyLabels(), fmt.Errorf("invalid label name %q", name)
+		}
+		if _, ok := seen[name]; ok {
+			return labels.EmptyLabels(), fmt.Errorf("duplicate label %q in grouping key", name)
+		}
+		seen[name] = struct{}{}
+		if name == model.JobLabel && value == "" {
+			return labels.EmptyLabels(), fmt.Errorf("%s label can't be empty", model.JobLabel)
+		}
+		// A label with an empty value is the same as a missing label.
+		if value != "" {
+			b.Add(name, value)
+		}
+	}
+	b.Sort()
+	return b.Labels(), nil
+}
+
+// decodeFamilies decodes the metric families of a request body, in the text
+// or in the delimited protobuf exposition format.
+func decodeFamilies(r *http.Request) (map[string]*dto.MetricFamily, error) {
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	families := make(map[string]*dto.MetricFamily)
+	dec := expfmt.NewDecoder(body, expfmt.ResponseFormat(r.Header))
+	for {
+		mf := &dto.MetricFamily{}
+		if err := dec.Decode(mf); err != nil {
+			if errors.Is(err, io.EOF) {
+				return families, nil
+			}
+			return nil, err
+		}
+		if existing, ok := families[mf.GetName()]; ok {
+			if existing.GetType() != mf.GetType() {
+				return nil, fmt.Errorf("metric %q has several types", mf.GetName())
+			}
+			existing.Metric = append(existing.Metric, mf.Metric...)
+			continue
+		}
+		families[mf.GetName()] = mf
+	}
+}
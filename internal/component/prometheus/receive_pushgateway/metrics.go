@@ -0,0 +1,35 @@
+package receive_pushgateway
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	groups        prometheus.Gauge
+	expiredGroups prometheus.Counter
+	pushes        *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		groups: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "alloy_prometheus_receive_pushgateway_groups",
+			Help: "Number of groups of pushed metrics.",
+		}),
+		expiredGroups: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alloy_prometheus_receive_pushgateway_expired_groups_total",
+			Help: "Total number of groups deleted because they didn't receive pushes during group_ttl.",
+		}),
+		pushes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alloy_prometheus_receive_pushgateway_requests_total",
+			Help: "Total number of push and delete requests, partitioned by method and status code.",
+		}, []string{"method", "status_code"}),
+	}
+
+	for _, metric := range []prometheus.Collector{m.groups, m.expiredGroups, m.pushes} {
+		if err := reg.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,220 @@
+package receive_pushgateway
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/alloy/internal/component"
+	fnet "github.com/grafana/alloy/internal/component/common/net"
+	alloyprom "github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/util"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.receive_pushgateway",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.receive_pushgateway component.
+type Arguments struct {
+	Server    *fnet.ServerConfig   `alloy:",squash"`
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// How often the metrics of the groups are forwarded.
+	Interval time.Duration `alloy:"interval,attr,optional"`
+
+	// How long a group is kept without receiving pushes. 0 means forever.
+	GroupTTL time.Duration `alloy:"group_ttl,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		Server:   fnet.DefaultServerConfig(),
+		Interval: time.Minute,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.GroupTTL < 0 {
+		return fmt.Errorf("group_ttl can't be negative")
+	}
+	return nil
+}
+
+// Component implements the prometheus.receive_pushgateway component.
+type Component struct {
+	opts               component.Options
+	fanout             *alloyprom.Fanout
+	uncheckedCollector *util.UncheckedCollector
+	metrics            *metrics
+	updated            chan struct{}
+
+	updateMut sync.RWMutex
+	args      Arguments
+	server    *fnet.TargetServer
+
+	// groupsMut protects the groups, and serializes the samples sent for them.
+	groupsMut sync.Mutex
+	groups    map[string]*group
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.receive_pushgateway component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	service, err := opts.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := service.(labelstore.LabelStore)
+
+	m, err := newMetrics(opts.Registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	uncheckedCollector := util.NewUncheckedCollector(nil)
+	opts.Registerer.MustRegister(uncheckedCollector)
+
+	c := &Component{
+		opts:               opts,
+		fanout:             alloyprom.NewFanout(args.ForwardTo, opts.ID, opts.Registerer, ls),
+		uncheckedCollector: uncheckedCollector,
+		metrics:            m,
+		updated:            make(chan struct{}, 1),
+		groups:             make(map[string]*group),
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run satisfies the Component interface.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.updateMut.Lock()
+		defer c.updateMut.Unlock()
+		c.shutdownServer()
+	}()
+
+	ticker := time.NewTicker(c.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			level.Info(c.opts.Logger).Log("msg", "terminating due to context done")
+			return nil
+		case <-c.updated:
+			ticker.Reset(c.interval())
+		case now := <-ticker.C:
+			c.forwardAll(ctx, now)
+		}
+	}
+}
+
+// Update satisfies the Component interface.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	c.updateMut.Lock()
+	defer c.updateMut.Unlock()
+
+	if c.args.Interval != newArgs.Interval {
+		select {
+		case c.updated <- struct{}{}:
+		default:
+		}
+	}
+
+	serverNeedsUpdate := !reflect.DeepEqual(c.args.Server, newArgs.Server)
+	if !serverNeedsUpdate {
+		c.args = newArgs
+		return nil
+	}
+	c.shutdownServer()
+
+	s, err := c.createNewServer(newArgs)
+	if err != nil {
+		return err
+	}
+	c.server = s
+
+	err = c.server.MountAndRun(func(router *mux.Router) {
+		router.PathPrefix("/metrics/").Methods("PUT", "POST", "DELETE").HandlerFunc(c.handlePush)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.args = newArgs
+	return nil
+}
+
+func (c *Component) interval() time.Duration {
+	c.updateMut.RLock()
+	defer c.updateMut.RUnlock()
+	return c.args.Interval
+}
+
+func (c *Component) groupTTL() time.Duration {
+	c.updateMut.RLock()
+	defer c.updateMut.RUnlock()
+	return c.args.GroupTTL
+}
+
+func (c *Component) createNewServer(args Arguments) (*fnet.TargetServer, error) {
+	// [server.Server] registers new metrics every time it is created. To
+	// avoid issues with re-registering metrics with the same name, we create a
+	// new registry for the server every time we create one, and pass it to an
+	// unchecked collector to bypass uniqueness checking.
+	serverRegistry := prometheus.NewRegistry()
+	c.uncheckedCollector.SetCollector(serverRegistry)
+
+	s, err := fnet.NewTargetServer(
+		c.opts.Logger,
+		"prometheus_receive_pushgateway",
+		serverRegistry,
+		args.Server,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server: %v", err)
+	}
+
+	return s, nil
+}
+
+// shutdownServer will shut down the currently used server.
+// It is not goroutine-safe and an updateMut write lock must be held when it's called.
+func (c *Component) shutdownServer() {
+	if c.server != nil {
+		c.server.StopAndShutdown()
+		c.server = nil
+	}
+}
@@ -0,0 +1,350 @@
+package receive_pushgateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phayes/freeport"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/alloy/internal/component"
+	fnet "github.com/grafana/alloy/internal/component/common/net"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		forward_to = []
+		group_ttl  = "1h"
+	`), &args))
+	require.Equal(t, time.Minute, args.Interval)
+	require.Equal(t, time.Hour, args.GroupTTL)
+	require.Equal(t, fnet.DefaultServerConfig(), args.Server)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         Arguments
+		errSubstring string
+	}{
+		{
+			name:         "invalid interval",
+			args:         Arguments{Interval: 0},
+			errSubstring: "interval must be greater than 0",
+		},
+		{
+			name:         "negative group_ttl",
+			args:         Arguments{Interval: time.Minute, GroupTTL: -time.Minute},
+			errSubstring: "group_ttl can't be negative",
+		},
+		{
+			name: "valid",
+			args: Arguments{Interval: time.Minute, GroupTTL: time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.Validate()
+			if tt.errSubstring == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.errSubstring)
+			}
+		})
+	}
+}
+
+func TestParseGroupingKey(t *testing.T) {
+	for _, tc := range []struct {
+		path     string
+		expected labels.Labels
+		err      string
+	}{
+		{path: "job/batch", expected: labels.FromStrings("job", "batch")},
+		{path: "job/batch/", expected: labels.FromStrings("job", "batch")},
+		{path: "job/batch/instance/a/zone/eu", expected: labels.FromStrings("instance", "a", "job", "batch", "zone", "eu")},
+		{path: "job/batch/path/a%2Fb", expected: labels.FromStrings("job", "batch", "path", "a/b")},
+		{path: "job@base64/YmF0Y2gvam9i", expected: labels.FromStrings("job", "batch/job")},
+		{path: "job/batch/path@base64/L3Zhci90bXA=", expected: labels.FromStrings("job", "batch", "path", "/var/tmp")},
+		{path: "job/batch/instance@base64/=", expected: labels.FromStrings("job", "batch")},
+		{path: "instance/a", err: "grouping key must start with the job label"},
+		{path: "job", err: "grouping key must be made of label name and value pairs"},
+		{path: "job/batch/instance", err: "grouping key must be made of label name and value pairs"},
+		{path: "job@base64/=", err: "job label can't be empty"},
+		{path: "job/batch/job/other", err: `duplicate label "job" in grouping key`},
+		{path: "job/batch/__name__/a", err: `invalid label name "__name__"`},
+		{path: "job/batch/zone@base64/!", err: `invalid base64 value of label "zone"`},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			actual, err := parseGroupingKey(tc.path)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestPush(t *testing.T) {
+	c, out, url := newTestComponent(t, Arguments{})
+
+	// The labels of the grouping key override the labels of the metrics.
+	status := request(t, http.MethodPut, url+"/metrics/job/backup/instance/db-1", "text/plain; version=0.0.4", []byte(`
+# TYPE backup_last_success_timestamp_seconds gauge
+backup_last_success_timestamp_seconds{job="other"} 1700000000
+# TYPE backup_files_total counter
+backup_files_total{kind="full"} 12
+# TYPE backup_duration_seconds histogram
+backup_duration_seconds_bucket{le="10"} 1
+backup_duration_seconds_bucket{le="+Inf"} 2
+backup_duration_seconds_sum 30
+backup_duration_seconds_count 2
+# TYPE backup_size_bytes summary
+backup_size_bytes{quantile="0.5"} 100
+backup_size_bytes_sum 250
+backup_size_bytes_count 2
+backup_untyped 3
+`))
+	require.Equal(t, http.StatusOK, status)
+
+	samples := out.TakeSamples()
+	require.NotZero(t, samples[`{__name__="push_time_seconds", instance="db-1", job="backup"}`])
+	delete(samples, `{__name__="push_time_seconds", instance="db-1", job="backup"}`)
+	require.Equal(t, map[string]float64{
+		`{__name__="backup_last_success_timestamp_seconds", instance="db-1", job="backup"}`:     1700000000,
+		`{__name__="backup_files_total", instance="db-1", job="backup", kind="full"}`:           12,
+		`{__name__="backup_duration_seconds_bucket", instance="db-1", job="backup", le="10"}`:   1,
+		`{__name__="backup_duration_seconds_bucket", instance="db-1", job="backup", le="+Inf"}`: 2,
+		`{__name__="backup_duration_seconds_sum", instance="db-1", job="backup"}`:               30,
+		`{__name__="backup_duration_seconds_count", instance="db-1", job="backup"}`:             2,
+		`{__name__="backup_size_bytes", instance="db-1", job="backup", quantile="0.5"}`:         100,
+		`{__name__="backup_size_bytes_sum", instance="db-1", job="backup"}`:                     250,
+		`{__name__="backup_size_bytes_count", instance="db-1", job="backup"}`:                   2,
+		`{__name__="backup_untyped", instance="db-1", job="backup"}`:                            3,
+	}, samples)
+	require.Equal(t, model.MetricTypeCounter, out.MetadataOf(`{__name__="backup_files_total", instance="db-1", job="backup", kind="full"}`).Type)
+
+	// The metrics are forwarded again at every interval.
+	c.forwardAll(context.Background(), time.Now())
+	require.Len(t, out.TakeSamples(), 11)
+}
+
+func TestPushProtobuf(t *testing.T) {
+	_, out, url := newTestComponent(t, Arguments{})
+
+	var body bytes.Buffer
+	_, err := protodelim.MarshalTo(&body, &dto.MetricFamily{
+		Name: proto.String("job_duration_seconds"),
+		Type: dto.MetricType_HISTOGRAM.Enum(),
+		Metric: []*dto.Metric{{
+			Histogram: &dto.Histogram{
+				SampleCount:   proto.Uint64(3),
+				SampleSum:     proto.Float64(4.5),
+				Schema:        proto.Int32(0),
+				ZeroThreshold: proto.Float64(1e-128),
+				PositiveSpan:  []*dto.BucketSpan{{Offset: proto.Int32(0), Length: proto.Uint32(2)}},
+				PositiveDelta: []int64{1, 1},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	status := request(t, http.MethodPost, url+"/metrics/job/batch", "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited", body.Bytes())
+	require.Equal(t, http.StatusOK, status)
+
+	h := out.TakeHistograms()[`{__name__="job_duration_seconds", job="batch"}`]
+	require.NotNil(t, h)
+	require.Equal(t, uint64(3), h.Count)
+	require.Equal(t, 4.5, h.Sum)
+	require.Equal(t, []int64{1, 1}, h.PositiveBuckets)
+}
+
+func TestReplace(t *testing.T) {
+	_, out, url := newTestComponent(t, Arguments{})
+
+	status := request(t, http.MethodPut, url+"/metrics/job/batch", "", []byte("a 1\nb 1\n"))
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, out.TakeSamples(), 3)
+
+	// POST only replaces the metrics with the same names.
+	status = request(t, http.MethodPost, url+"/metrics/job/batch", "", []byte("a 2\n"))
+	require.Equal(t, http.StatusOK, status)
+	samples := out.TakeSamples()
+	require.Equal(t, 2.0, samples[`{__name__="a", job="batch"}`])
+	require.Equal(t, 1.0, samples[`{__name__="b", job="batch"}`])
+
+	// PUT replaces all the metrics, and the ones which disappeared are marked
+	// as stale.
+	status = request(t, http.MethodPut, url+"/metrics/job/batch", "", []byte("a 3\n"))
+	require.Equal(t, http.StatusOK, status)
+	samples = out.TakeSamples()
+	require.Equal(t, 3.0, samples[`{__name__="a", job="batch"}`])
+	require.True(t, value.IsStaleNaN(samples[`{__name__="b", job="batch"}`]))
+
+	// The other groups aren't affected.
+	status = request(t, http.MethodPut, url+"/metrics/job/batch/instance/a", "", []byte("a 4\n"))
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, map[string]float64{
+		`{__name__="a", instance="a", job="batch"}`: 4,
+	}, withoutPushTime(out.TakeSamples()))
+}
+
+func TestDelete(t *testing.T) {
+	_, out, url := newTestComponent(t, Arguments{})
+
+	status := request(t, http.MethodPut, url+"/metrics/job/batch", "", []byte("a 1\n"))
+	require.Equal(t, http.StatusOK, status)
+	out.TakeSamples()
+
+	status = request(t, http.MethodDelete, url+"/metrics/job/batch", "", nil)
+	require.Equal(t, http.StatusAccepted, status)
+	samples := out.TakeSamples()
+	require.Len(t, samples, 2)
+	for _, v := range samples {
+		require.True(t, value.IsStaleNaN(v))
+	}
+
+	// Deleting a missing group succeeds.
+	status = request(t, http.MethodDelete, url+"/metrics/job/batch", "", nil)
+	require.Equal(t, http.StatusAccepted, status)
+	require.Empty(t, out.TakeSamples())
+}
+
+func TestGroupTTL(t *testing.T) {
+	c, out, url := newTestComponent(t, Arguments{GroupTTL: time.Hour})
+
+	status := request(t, http.MethodPut, url+"/metrics/job/batch", "", []byte("a 1\n"))
+	require.Equal(t, http.StatusOK, status)
+	out.TakeSamples()
+
+	c.forwardAll(context.Background(), time.Now().Add(30*time.Minute))
+	require.Len(t, out.TakeSamples(), 2)
+
+	c.forwardAll(context.Background(), time.Now().Add(2*time.Hour))
+	samples := out.TakeSamples()
+	require.Len(t, samples, 2)
+	for _, v := range samples {
+		require.True(t, value.IsStaleNaN(v))
+	}
+	require.Empty(t, c.groups)
+}
+
+func TestInvalidPush(t *testing.T) {
+	_, out, url := newTestComponent(t, Arguments{})
+
+	for name, tc := range map[string]struct {
+		path string
+		body string
+	}{
+		"timestamp":    {path: "/metrics/job/batch", body: "a 1 1700000000000\n"},
+		"invalid body": {path: "/metrics/job/batch", body: "a{\n"},
+		"missing job":  {path: "/metrics/instance/a", body: "a 1\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			status := request(t, http.MethodPut, url+tc.path, "", []byte(tc.body))
+			require.Equal(t, http.StatusBadRequest, status)
+		})
+	}
+	require.Empty(t, out.TakeSamples())
+}
+
+func newTestComponent(t *testing.T, args Arguments) (*Component, *testappender.Collector, string) {
+	ls := labelstore.New(nil, prometheus.DefaultRegisterer)
+	out := testappender.NewCollector()
+
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+	grpcPort, err := freeport.GetFreePort()
+	require.NoError(t, err)
+	args.Server = &fnet.ServerConfig{
+		HTTP: &fnet.HTTPConfig{
+			ListenAddress: "localhost",
+			ListenPort:    port,
+		},
+		GRPC: &fnet.GRPCConfig{ListenAddress: "127.0.0.1", ListenPort: grpcPort},
+	}
+	args.ForwardTo = []storage.Appendable{out}
+	if args.Interval == 0 {
+		args.Interval = time.Hour
+	}
+
+	c, err := New(component.Options{
+		ID:         "prometheus.receive_pushgateway.test",
+		Logger:     util.TestAlloyLogger(t),
+		Registerer: prometheus.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			if name != labelstore.ServiceName {
+				return nil, fmt.Errorf("service not found %s", name)
+			}
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	url := fmt.Sprintf("http://localhost:%d", port)
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url + "/wrong/path")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusNotFound
+	}, 5*time.Second, 20*time.Millisecond, "server failed to start before timeout")
+
+	return c, out, url
+}
+
+func request(t *testing.T, method, url, contentType string, body []byte) int {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode
+}
+
+func withoutPushTime(samples map[string]float64) map[string]float64 {
+	for k := range samples {
+		if strings.HasPrefix(k, `{__name__="push_time_seconds"`) {
+			delete(samples, k)
+		}
+	}
+	return samples
+}
@@ -0,0 +1,176 @@
+package receive_statsd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/statsd_exporter/pkg/address"
+	"github.com/prometheus/statsd_exporter/pkg/event"
+	"github.com/prometheus/statsd_exporter/pkg/line"
+	"github.com/prometheus/statsd_exporter/pkg/listener"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/static/integrations/statsd_exporter"
+)
+
+// listeners holds the running UDP, TCP, and Unixgram listeners.
+type listeners struct {
+	logger  log.Logger
+	closers []io.Closer
+	// socket is the path of the Unixgram socket to remove when closing.
+	socket string
+}
+
+// startListeners starts the configured listeners, which send the parsed
+// events to handler.
+func startListeners(cfg listenConfig, handler event.EventHandler, m *statsd_exporter.Metrics, logger log.Logger) (*listeners, error) {
+	parser := line.NewParser()
+	if cfg.ParseDogStatsd {
+		parser.EnableDogstatsdParsing()
+	}
+	if cfg.ParseInfluxDB {
+		parser.EnableInfluxdbParsing()
+	}
+	if cfg.ParseLibrato {
+		parser.EnableLibratoParsing()
+	}
+	if cfg.ParseSignalFX {
+		parser.EnableSignalFXParsing()
+	}
+
+	l := &listeners{logger: logger}
+	if err := l.start(cfg, parser, handler, m); err != nil {
+		l.close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *listeners) start(cfg listenConfig, parser *line.Parser, handler event.EventHandler, m *statsd_exporter.Metrics) error {
+	if cfg.ListenUDP != "" {
+		addr, err := address.UDPAddrFromString(cfg.ListenUDP)
+		if err != nil {
+			return fmt.Errorf("invalid UDP listen address %s: %w", cfg.ListenUDP, err)
+		}
+		conn, err := net.ListenUDP("udp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to start UDP listener: %w", err)
+		}
+		l.closers = append(l.closers, conn)
+		if cfg.ReadBuffer != 0 {
+			if err := conn.SetReadBuffer(cfg.ReadBuffer); err != nil {
+				return fmt.Errorf("failed to set UDP read buffer: %w", err)
+			}
+		}
+
+		ul := &listener.StatsDUDPListener{
+			Conn:            conn,
+			EventHandler:    handler,
+			Logger:          l.logger,
+			LineParser:      parser,
+			UDPPackets:      m.UDPPackets,
+			LinesReceived:   m.LinesReceived,
+			EventsFlushed:   m.EventsFlushed,
+			SampleErrors:    *m.SampleErrors,
+			SamplesReceived: m.SamplesReceived,
+			TagErrors:       m.TagErrors,
+			TagsReceived:    m.TagsReceived,
+		}
+		go ul.Listen()
+	}
+
+	if cfg.ListenTCP != "" {
+		addr, err := address.TCPAddrFromString(cfg.ListenTCP)
+		if err != nil {
+			return fmt.Errorf("invalid TCP listen address %s: %w", cfg.ListenTCP, err)
+		}
+		conn, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to start TCP listener: %w", err)
+		}
+		l.closers = append(l.closers, conn)
+
+		tl := &listener.StatsDTCPListener{
+			Conn:            conn,
+			EventHandler:    handler,
+			Logger:          l.logger,
+			LineParser:      parser,
+			LinesReceived:   m.LinesReceived,
+			EventsFlushed:   m.EventsFlushed,
+			SampleErrors:    *m.SampleErrors,
+			SamplesReceived: m.SamplesReceived,
+			TagErrors:       m.TagErrors,
+			TagsReceived:    m.TagsReceived,
+			TCPConnections:  m.TCPConnections,
+			TCPErrors:       m.TCPErrors,
+			TCPLineTooLong:  m.TCPLineTooLong,
+		}
+		go tl.Listen()
+	}
+
+	if cfg.ListenUnixgram != "" {
+		if _, err := os.Stat(cfg.ListenUnixgram); !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unixgram socket %s already exists", cfg.ListenUnixgram)
+		}
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{
+			Net:  "unixgram",
+			Name: cfg.ListenUnixgram,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to listen on unixgram socket: %w", err)
+		}
+		l.closers = append(l.closers, conn)
+		if cfg.ReadBuffer != 0 {
+			if err := conn.SetReadBuffer(cfg.ReadBuffer); err != nil {
+				return fmt.Errorf("failed to set unixgram read buffer: %w", err)
+			}
+		}
+
+		ul := &listener.StatsDUnixgramListener{
+			Conn:            conn,
+			EventHandler:    handler,
+			Logger:          l.logger,
+			LineParser:      parser,
+			UnixgramPackets: m.UnixgramPackets,
+			LinesReceived:   m.LinesReceived,
+			EventsFlushed:   m.EventsFlushed,
+			SampleErrors:    *m.SampleErrors,
+			SamplesReceived: m.SamplesReceived,
+			TagErrors:       m.TagErrors,
+			TagsReceived:    m.TagsReceived,
+		}
+		go ul.Listen()
+
+		// An abstract unix domain socket doesn't exist on the file system, so
+		// its permissions can't be changed.
+		if _, err := os.Stat(cfg.ListenUnixgram); err == nil {
+			l.socket = cfg.ListenUnixgram
+			// The mode was already validated with the arguments.
+			perm, _ := strconv.ParseUint(cfg.UnixSocketMode, 8, 32)
+			if err := os.Chmod(cfg.ListenUnixgram, os.FileMode(perm)); err != nil {
+				level.Warn(l.logger).Log("msg", "failed to change unixgram socket permission", "socket", cfg.ListenUnixgram, "err", err)
+			}
+		}
+	}
+	return nil
+}
+
+// close stops the listeners. The TCP connections already accepted are
+// served until the clients close them.
+func (l *listeners) close() {
+	for _, c := range l.closers {
+		if err := c.Close(); err != nil {
+			level.Warn(l.logger).Log("msg", "failed to close listener", "err", err)
+		}
+	}
+	if l.socket != "" {
+		if err := os.Remove(l.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			level.Warn(l.logger).Log("msg", "failed to remove unixgram socket", "socket", l.socket, "err", err)
+		}
+	}
+}
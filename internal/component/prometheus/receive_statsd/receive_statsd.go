@@ -0,0 +1,305 @@
+package receive_statsd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/statsd_exporter/pkg/mapper"
+	"github.com/prometheus/statsd_exporter/pkg/mappercache/lru"
+	"github.com/prometheus/statsd_exporter/pkg/mappercache/randomreplacement"
+
+	"github.com/grafana/alloy/internal/component"
+	alloyprom "github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/static/integrations/statsd_exporter"
+	"github.com/grafana/alloy/internal/util"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.receive_statsd",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.receive_statsd component.
+type Arguments struct {
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	ListenUDP      string `alloy:"listen_udp,attr,optional"`
+	ListenTCP      string `alloy:"listen_tcp,attr,optional"`
+	ListenUnixgram string `alloy:"listen_unixgram,attr,optional"`
+	UnixSocketMode string `alloy:"unix_socket_mode,attr,optional"`
+	ReadBuffer     int    `alloy:"read_buffer,attr,optional"`
+
+	ParseDogStatsd bool `alloy:"parse_dogstatsd_tags,attr,optional"`
+	ParseInfluxDB  bool `alloy:"parse_influxdb_tags,attr,optional"`
+	ParseLibrato   bool `alloy:"parse_librato_tags,attr,optional"`
+	ParseSignalFX  bool `alloy:"parse_signalfx_tags,attr,optional"`
+
+	MappingConfig string `alloy:"mapping_config_path,attr,optional"`
+	CacheSize     int    `alloy:"cache_size,attr,optional"`
+	CacheType     string `alloy:"cache_type,attr,optional"`
+
+	// How often the received metrics are forwarded.
+	FlushInterval time.Duration `alloy:"flush_interval,attr,optional"`
+
+	NativeHistogramBucketFactor    float64 `alloy:"native_histogram_bucket_factor,attr,optional"`
+	NativeHistogramMaxBucketNumber uint32  `alloy:"native_histogram_max_bucket_number,attr,optional"`
+	SendClassicHistograms          bool    `alloy:"send_classic_histograms,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		ListenUDP:      statsd_exporter.DefaultConfig.ListenUDP,
+		ListenTCP:      statsd_exporter.DefaultConfig.ListenTCP,
+		UnixSocketMode: statsd_exporter.DefaultConfig.UnixSocketMode,
+
+		ParseDogStatsd: statsd_exporter.DefaultConfig.ParseDogStatsd,
+		ParseInfluxDB:  statsd_exporter.DefaultConfig.ParseInfluxDB,
+		ParseLibrato:   statsd_exporter.DefaultConfig.ParseLibrato,
+		ParseSignalFX:  statsd_exporter.DefaultConfig.ParseSignalFX,
+
+		CacheSize: statsd_exporter.DefaultConfig.CacheSize,
+		CacheType: statsd_exporter.DefaultConfig.CacheType,
+
+		FlushInterval:                  15 * time.Second,
+		NativeHistogramMaxBucketNumber: 160,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.ListenUDP == "" && args.ListenTCP == "" && args.ListenUnixgram == "" {
+		return fmt.Errorf("at least one of listen_udp, listen_tcp, or listen_unixgram must be set")
+	}
+	if _, err := strconv.ParseUint(args.UnixSocketMode, 8, 32); err != nil {
+		return fmt.Errorf("invalid unix_socket_mode %q: must be an octal file mode", args.UnixSocketMode)
+	}
+	if args.CacheType != "lru" && args.CacheType != "random" {
+		return fmt.Errorf("unsupported cache_type %q: must be lru or random", args.CacheType)
+	}
+	if args.FlushInterval <= 0 {
+		return fmt.Errorf("flush_interval must be greater than 0")
+	}
+	if args.NativeHistogramBucketFactor != 0 && args.NativeHistogramBucketFactor <= 1 {
+		return fmt.Errorf("native_histogram_bucket_factor must be greater than 1, or 0 to disable native histograms")
+	}
+	return nil
+}
+
+// listenConfig holds the arguments which require the listeners to be
+// restarted when they change.
+type listenConfig struct {
+	ListenUDP      string
+	ListenTCP      string
+	ListenUnixgram string
+	UnixSocketMode string
+	ReadBuffer     int
+
+	ParseDogStatsd bool
+	ParseInfluxDB  bool
+	ParseLibrato   bool
+	ParseSignalFX  bool
+}
+
+func (args *Arguments) listenConfig() listenConfig {
+	return listenConfig{
+		ListenUDP:      args.ListenUDP,
+		ListenTCP:      args.ListenTCP,
+		ListenUnixgram: args.ListenUnixgram,
+		UnixSocketMode: args.UnixSocketMode,
+		ReadBuffer:     args.ReadBuffer,
+		ParseDogStatsd: args.ParseDogStatsd,
+		ParseInfluxDB:  args.ParseInfluxDB,
+		ParseLibrato:   args.ParseLibrato,
+		ParseSignalFX:  args.ParseSignalFX,
+	}
+}
+
+// Component implements the prometheus.receive_statsd component.
+type Component struct {
+	opts               component.Options
+	fanout             *alloyprom.Fanout
+	uncheckedCollector *util.UncheckedCollector
+	metrics            *statsd_exporter.Metrics
+	store              *store
+	updated            chan struct{}
+
+	updateMut sync.RWMutex
+	args      Arguments
+	listeners *listeners
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.receive_statsd component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	service, err := opts.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := service.(labelstore.LabelStore)
+
+	m, err := statsd_exporter.NewMetrics(opts.Registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	uncheckedCollector := util.NewUncheckedCollector(nil)
+	opts.Registerer.MustRegister(uncheckedCollector)
+
+	c := &Component{
+		opts:               opts,
+		fanout:             alloyprom.NewFanout(args.ForwardTo, opts.ID, opts.Registerer, ls),
+		uncheckedCollector: uncheckedCollector,
+		metrics:            m,
+		updated:            make(chan struct{}, 1),
+	}
+	c.store = newStore(opts.Logger, m)
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run satisfies the Component interface.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.updateMut.Lock()
+		defer c.updateMut.Unlock()
+		c.closeListeners()
+	}()
+
+	ticker := time.NewTicker(c.currentArgs().FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			level.Info(c.opts.Logger).Log("msg", "terminating due to context done")
+			return nil
+		case <-c.updated:
+			ticker.Reset(c.currentArgs().FlushInterval)
+		case now := <-ticker.C:
+			c.store.flush(ctx, c.fanout, now)
+		}
+	}
+}
+
+// Update satisfies the Component interface.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	m, err := c.newMapper(newArgs)
+	if err != nil {
+		return err
+	}
+
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	c.store.update(m, histogramConfig{
+		bucketFactor: newArgs.NativeHistogramBucketFactor,
+		maxBuckets:   newArgs.NativeHistogramMaxBucketNumber,
+		sendClassic:  newArgs.SendClassicHistograms || newArgs.NativeHistogramBucketFactor == 0,
+		sendNative:   newArgs.NativeHistogramBucketFactor != 0,
+	})
+
+	c.updateMut.Lock()
+	defer c.updateMut.Unlock()
+
+	if c.args.FlushInterval != newArgs.FlushInterval {
+		select {
+		case c.updated <- struct{}{}:
+		default:
+		}
+	}
+
+	listenersNeedUpdate := c.listeners == nil || !reflect.DeepEqual(c.args.listenConfig(), newArgs.listenConfig())
+	if !listenersNeedUpdate {
+		c.args = newArgs
+		return nil
+	}
+	c.closeListeners()
+
+	l, err := startListeners(newArgs.listenConfig(), c.store, c.metrics, c.opts.Logger)
+	if err != nil {
+		return err
+	}
+	c.listeners = l
+
+	c.args = newArgs
+	return nil
+}
+
+// closeListeners stops the currently used listeners.
+// It is not goroutine-safe and an updateMut write lock must be held when it's called.
+func (c *Component) closeListeners() {
+	if c.listeners != nil {
+		c.listeners.close()
+		c.listeners = nil
+	}
+}
+
+func (c *Component) currentArgs() Arguments {
+	c.updateMut.RLock()
+	defer c.updateMut.RUnlock()
+	return c.args
+}
+
+// newMapper creates the mapper of the StatsD metric names.
+func (c *Component) newMapper(args Arguments) (*mapper.MetricMapper, error) {
+	// The mapper caches register new metrics every time they are created. To
+	// avoid issues with re-registering metrics with the same name, we create a
+	// new registry for the mapper every time we create one, and pass it to an
+	// unchecked collector to bypass uniqueness checking.
+	mapperRegistry := prometheus.NewRegistry()
+
+	m := &mapper.MetricMapper{
+		Registerer:    mapperRegistry,
+		MappingsCount: c.metrics.MappingsCount,
+		Logger:        c.opts.Logger,
+	}
+	var err error
+	if args.MappingConfig != "" {
+		err = m.InitFromFile(args.MappingConfig)
+	} else {
+		// Initialize the default histogram buckets and summary quantiles.
+		err = m.InitFromYAMLString("")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mapping config: %w", err)
+	}
+
+	if args.CacheSize > 0 {
+		var cache mapper.MetricMapperCache
+		switch args.CacheType {
+		case "lru":
+			cache, err = lru.NewMetricMapperLRUCache(mapperRegistry, args.CacheSize)
+		case "random":
+			cache, err = randomreplacement.NewMetricMapperRRCache(mapperRegistry, args.CacheSize)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.UseCache(cache)
+	}
+
+	c.uncheckedCollector.SetCollector(mapperRegistry)
+	return m, nil
+}
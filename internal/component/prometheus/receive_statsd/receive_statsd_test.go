@@ -0,0 +1,338 @@
+package receive_statsd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phayes/freeport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/statsd_exporter/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		forward_to                     = []
+		native_histogram_bucket_factor = 1.1
+	`), &args))
+	require.Equal(t, ":9125", args.ListenUDP)
+	require.Equal(t, ":9125", args.ListenTCP)
+	require.Equal(t, 15*time.Second, args.FlushInterval)
+	require.Equal(t, uint32(160), args.NativeHistogramMaxBucketNumber)
+	require.True(t, args.ParseDogStatsd)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		configure    func(args *Arguments)
+		errSubstring string
+	}{
+		{
+			name:      "default arguments are valid",
+			configure: func(args *Arguments) {},
+		},
+		{
+			name: "no listener",
+			configure: func(args *Arguments) {
+				args.ListenUDP = ""
+				args.ListenTCP = ""
+			},
+			errSubstring: "at least one of listen_udp, listen_tcp, or listen_unixgram must be set",
+		},
+		{
+			name: "invalid socket mode",
+			configure: func(args *Arguments) {
+				args.UnixSocketMode = "9"
+			},
+			errSubstring: `invalid unix_socket_mode "9"`,
+		},
+		{
+			name: "invalid cache type",
+			configure: func(args *Arguments) {
+				args.CacheType = "lfu"
+			},
+			errSubstring: `unsupported cache_type "lfu"`,
+		},
+		{
+			name: "invalid interval",
+			configure: func(args *Arguments) {
+				args.FlushInterval = 0
+			},
+			errSubstring: "flush_interval must be greater than 0",
+		},
+		{
+			name: "invalid factor",
+			configure: func(args *Arguments) {
+				args.NativeHistogramBucketFactor = 1
+			},
+			errSubstring: "native_histogram_bucket_factor must be greater than 1",
+		},
+		{
+			name: "negative factor",
+			configure: func(args *Arguments) {
+				args.NativeHistogramBucketFactor = -2
+			},
+			errSubstring: "native_histogram_bucket_factor must be greater than 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args Arguments
+			args.SetToDefault()
+			tt.configure(&args)
+			err := args.Validate()
+			if tt.errSubstring == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.errSubstring)
+			}
+		})
+	}
+}
+
+func TestReceive(t *testing.T) {
+	c, out, args := newTestComponent(t, Arguments{})
+
+	udp, err := net.Dial("udp", args.ListenUDP)
+	require.NoError(t, err)
+	defer udp.Close()
+	_, err = udp.Write([]byte("requests:1|c\nrequests:2|c|#route:/api\ntemperature:20|g\ntemperature:+5|g\nlatency:100|ms\nlatency:300|ms"))
+	require.NoError(t, err)
+
+	tcp, err := net.Dial("tcp", args.ListenTCP)
+	require.NoError(t, err)
+	defer tcp.Close()
+	_, err = tcp.Write([]byte("requests:3|c\n"))
+	require.NoError(t, err)
+
+	expected := map[string]float64{
+		`{__name__="requests"}`:                 4,
+		`{__name__="requests", route="/api"}`:   2,
+		`{__name__="temperature"}`:              25,
+		`{__name__="latency", quantile="0.5"}`:  0.1,
+		`{__name__="latency", quantile="0.9"}`:  0.3,
+		`{__name__="latency", quantile="0.99"}`: 0.3,
+		`{__name__="latency_sum"}`:              0.4,
+		`{__name__="latency_count"}`:            2,
+	}
+	require.EventuallyWithT(t, func(t *assert.CollectT) {
+		c.store.flush(context.Background(), c.fanout, time.Now())
+		assert.Equal(t, expected, out.TakeSamples())
+	}, 5*time.Second, 20*time.Millisecond)
+
+	require.Equal(t, model.MetricTypeCounter, out.MetadataOf(`{__name__="requests"}`).Type)
+	require.Equal(t, model.MetricTypeGauge, out.MetadataOf(`{__name__="temperature"}`).Type)
+	require.Equal(t, model.MetricTypeSummary, out.MetadataOf(`{__name__="latency_count"}`).Type)
+}
+
+func TestMapping(t *testing.T) {
+	mappingConfig := filepath.Join(t.TempDir(), "mapping.yaml")
+	require.NoError(t, os.WriteFile(mappingConfig, []byte(`
+defaults:
+  observer_type: histogram
+  histogram_options:
+    buckets: [0.1, 1]
+mappings:
+  - match: "app.*.requests"
+    name: "app_requests_total"
+    help: "Requests of the application."
+    labels:
+      service: "$1"
+  - match: "debug.*"
+    name: "dropped"
+    action: drop
+`), 0o644))
+
+	c, out, _ := newTestComponent(t, Arguments{MappingConfig: mappingConfig})
+	c.store.Queue(event.Events{
+		&event.CounterEvent{CMetricName: "app.api.requests", CValue: 2, CLabels: map[string]string{}},
+		&event.CounterEvent{CMetricName: "debug.requests", CValue: 1, CLabels: map[string]string{}},
+		&event.ObserverEvent{OMetricName: "latency", OValue: 0.5, OLabels: map[string]string{"method": "GET"}},
+	})
+	c.store.flush(context.Background(), c.fanout, time.Now())
+
+	require.Equal(t, map[string]float64{
+		`{__name__="app_requests_total", service="api"}`:       2,
+		`{__name__="latency_bucket", le="0.1", method="GET"}`:  0,
+		`{__name__="latency_bucket", le="1", method="GET"}`:    1,
+		`{__name__="latency_bucket", le="+Inf", method="GET"}`: 1,
+		`{__name__="latency_sum", method="GET"}`:               0.5,
+		`{__name__="latency_count", method="GET"}`:             1,
+	}, out.TakeSamples())
+	require.Equal(t, model.MetricTypeHistogram, out.MetadataOf(`{__name__="latency_count", method="GET"}`).Type)
+
+	require.NoError(t, testutil.GatherAndCompare(c.opts.Registerer.(prometheus.Gatherer), strings.NewReader(`
+# HELP statsd_exporter_events_actions_total The total number of StatsD events by action.
+# TYPE statsd_exporter_events_actions_total counter
+statsd_exporter_events_actions_total{action="drop"} 1
+statsd_exporter_events_actions_total{action="map"} 1
+# HELP statsd_exporter_events_unmapped_total The total number of StatsD events no mapping was found for.
+# TYPE statsd_exporter_events_unmapped_total counter
+statsd_exporter_events_unmapped_total 1
+# HELP statsd_exporter_metrics_total The total number of metrics.
+# TYPE statsd_exporter_metrics_total gauge
+statsd_exporter_metrics_total{type="counter"} 1
+statsd_exporter_metrics_total{type="gauge"} 0
+statsd_exporter_metrics_total{type="histogram"} 1
+statsd_exporter_metrics_total{type="summary"} 0
+`), "statsd_exporter_events_actions_total", "statsd_exporter_events_unmapped_total", "statsd_exporter_metrics_total"))
+}
+
+func TestNativeHistograms(t *testing.T) {
+	mappingConfig := filepath.Join(t.TempDir(), "mapping.yaml")
+	require.NoError(t, os.WriteFile(mappingConfig, []byte("defaults:\n  observer_type: histogram\n"), 0o644))
+
+	for _, sendClassic := range []bool{false, true} {
+		t.Run(fmt.Sprintf("send_classic_histograms=%t", sendClassic), func(t *testing.T) {
+			c, out, _ := newTestComponent(t, Arguments{
+				MappingConfig:               mappingConfig,
+				NativeHistogramBucketFactor: 1.1,
+				SendClassicHistograms:       sendClassic,
+			})
+			c.store.Queue(event.Events{
+				&event.ObserverEvent{OMetricName: "latency", OValue: 0.5, OLabels: map[string]string{}},
+				&event.ObserverEvent{OMetricName: "latency", OValue: 2, OLabels: map[string]string{}},
+			})
+			c.store.flush(context.Background(), c.fanout, time.Now())
+
+			histograms := out.TakeHistograms()
+			require.Len(t, histograms, 1)
+			h := histograms[`{__name__="latency"}`]
+			require.NotNil(t, h)
+			require.Equal(t, int32(3), h.Schema)
+			require.Equal(t, uint64(2), h.Count)
+			require.Equal(t, 2.5, h.Sum)
+			require.NoError(t, h.Validate())
+
+			samples := out.TakeSamples()
+			if sendClassic {
+				require.Equal(t, 2.0, samples[`{__name__="latency_count"}`])
+				require.Equal(t, 1.0, samples[`{__name__="latency_bucket", le="1"}`])
+			} else {
+				require.Empty(t, samples)
+			}
+		})
+	}
+}
+
+func TestTTL(t *testing.T) {
+	mappingConfig := filepath.Join(t.TempDir(), "mapping.yaml")
+	require.NoError(t, os.WriteFile(mappingConfig, []byte(`
+defaults:
+  ttl: 1m
+mappings:
+  - match: "long.*"
+    name: "long_$1"
+    ttl: 1h
+`), 0o644))
+
+	c, out, _ := newTestComponent(t, Arguments{MappingConfig: mappingConfig})
+	c.store.Queue(event.Events{
+		&event.GaugeEvent{GMetricName: "short", GValue: 1, GLabels: map[string]string{}},
+		&event.GaugeEvent{GMetricName: "long.lived", GValue: 2, GLabels: map[string]string{}},
+	})
+	now := time.Now()
+	c.store.flush(context.Background(), c.fanout, now)
+	require.Len(t, out.TakeSamples(), 2)
+
+	c.store.flush(context.Background(), c.fanout, now.Add(2*time.Minute))
+	samples := out.TakeSamples()
+	require.True(t, value.IsStaleNaN(samples[`{__name__="short"}`]))
+	require.Equal(t, 2.0, samples[`{__name__="long_lived"}`])
+
+	c.store.flush(context.Background(), c.fanout, now.Add(3*time.Minute))
+	require.Equal(t, map[string]float64{`{__name__="long_lived"}`: 2}, out.TakeSamples())
+}
+
+func TestConflicts(t *testing.T) {
+	c, out, _ := newTestComponent(t, Arguments{})
+	c.store.Queue(event.Events{
+		&event.CounterEvent{CMetricName: "requests", CValue: 1, CLabels: map[string]string{}},
+		&event.GaugeEvent{GMetricName: "requests", GValue: 5, GLabels: map[string]string{"a": "b"}},
+		&event.ObserverEvent{OMetricName: "latency", OValue: 1, OLabels: map[string]string{}},
+		&event.CounterEvent{CMetricName: "latency_count", CValue: 1, CLabels: map[string]string{}},
+		&event.CounterEvent{CMetricName: "negative", CValue: -1, CLabels: map[string]string{}},
+	})
+	c.store.flush(context.Background(), c.fanout, time.Now())
+
+	samples := out.TakeSamples()
+	require.Equal(t, 1.0, samples[`{__name__="requests"}`])
+	require.Equal(t, 1.0, samples[`{__name__="latency_count"}`])
+	require.Len(t, samples, 6)
+
+	require.NoError(t, testutil.GatherAndCompare(c.opts.Registerer.(prometheus.Gatherer), strings.NewReader(`
+# HELP statsd_exporter_events_conflict_total The total number of StatsD events with conflicting names.
+# TYPE statsd_exporter_events_conflict_total counter
+statsd_exporter_events_conflict_total{type="counter"} 1
+statsd_exporter_events_conflict_total{type="gauge"} 1
+# HELP statsd_exporter_events_error_total The total number of StatsD events discarded due to errors.
+# TYPE statsd_exporter_events_error_total counter
+statsd_exporter_events_error_total{reason="illegal_negative_counter"} 1
+`), "statsd_exporter_events_conflict_total", "statsd_exporter_events_error_total"))
+}
+
+func newTestComponent(t *testing.T, args Arguments) (*Component, *testappender.Collector, Arguments) {
+	ls := labelstore.New(nil, prometheus.DefaultRegisterer)
+	out := testappender.NewCollector()
+
+	ports, err := freeport.GetFreePorts(2)
+	require.NoError(t, err)
+	var defaults Arguments
+	defaults.SetToDefault()
+	defaults.ListenUDP = fmt.Sprintf("127.0.0.1:%d", ports[0])
+	defaults.ListenTCP = fmt.Sprintf("127.0.0.1:%d", ports[1])
+	defaults.FlushInterval = time.Hour
+	defaults.MappingConfig = args.MappingConfig
+	if args.NativeHistogramBucketFactor != 0 {
+		defaults.NativeHistogramBucketFactor = args.NativeHistogramBucketFactor
+		defaults.SendClassicHistograms = args.SendClassicHistograms
+	}
+	defaults.ForwardTo = []storage.Appendable{out}
+	args = defaults
+	require.NoError(t, args.Validate())
+
+	c, err := New(component.Options{
+		ID:         "prometheus.receive_statsd.test",
+		Logger:     util.TestAlloyLogger(t),
+		Registerer: prometheus.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			if name != labelstore.ServiceName {
+				return nil, fmt.Errorf("service not found %s", name)
+			}
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return c, out, args
+}
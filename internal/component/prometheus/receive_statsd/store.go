@@ -0,0 +1,401 @@
+package receive_statsd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/statsd_exporter/pkg/event"
+	"github.com/prometheus/statsd_exporter/pkg/mapper"
+
+	"github.com/grafana/alloy/internal/component/prometheus/internal/exposition"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/static/integrations/statsd_exporter"
+)
+
+const defaultHelp = "Metric autogenerated by prometheus.receive_statsd."
+
+// histogramConfig configures the histograms created for the observer events.
+type histogramConfig struct {
+	bucketFactor float64
+	maxBuckets   uint32
+	sendClassic  bool
+	sendNative   bool
+}
+
+// store aggregates the StatsD events into Prometheus series, and forwards the
+// series when flushed. It implements event.EventHandler so that the listeners
+// send the events to it directly.
+type store struct {
+	logger  log.Logger
+	metrics *statsd_exporter.Metrics
+
+	mut        sync.Mutex
+	mapper     *mapper.MetricMapper
+	histograms histogramConfig
+	families   map[string]*family
+}
+
+var _ event.EventHandler = (*store)(nil)
+
+// family holds the series of a metric.
+type family struct {
+	metadata metadata.Metadata
+	series   map[uint64]*series
+}
+
+type series struct {
+	labels labels.Labels
+	// value holds the value of counters and gauges.
+	value float64
+	// observer holds the histogram or the summary of observers.
+	observer   prometheus.Metric
+	histograms histogramConfig
+
+	ttl       time.Duration
+	lastEvent time.Time
+	// sent holds the series forwarded the last time, so that they're marked
+	// as stale when the series expires.
+	sent []labels.Labels
+}
+
+func newStore(logger log.Logger, metrics *statsd_exporter.Metrics) *store {
+	return &store{
+		logger:   logger,
+		metrics:  metrics,
+		families: make(map[string]*family),
+	}
+}
+
+// update sets the mapper and the histogram configuration used for the new
+// events. The existing histograms keep their configuration.
+func (s *store) update(m *mapper.MetricMapper, histograms histogramConfig) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.mapper = m
+	s.histograms = histograms
+}
+
+// Queue implements event.EventHandler.
+func (s *store) Queue(events event.Events) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	now := time.Now()
+	for _, e := range events {
+		s.handleEvent(e, now)
+	}
+}
+
+// handleEvent applies an event to its series, according to the mapping of
+// its metric.
+func (s *store) handleEvent(e event.Event, now time.Time) {
+	mapping, mappedLabels, present := s.mapper.GetMapping(e.MetricName(), e.MetricType())
+	if mapping == nil {
+		mapping = &mapper.MetricMapping{Ttl: s.mapper.Defaults.Ttl}
+	}
+	if mapping.Action == mapper.ActionTypeDrop {
+		s.metrics.EventsActions.WithLabelValues(string(mapper.ActionTypeDrop)).Inc()
+		return
+	}
+
+	help := defaultHelp
+	if mapping.HelpText != "" {
+		help = mapping.HelpText
+	}
+
+	var name string
+	lb := labels.NewBuilder(labels.EmptyLabels())
+	for n, v := range e.Labels() {
+		lb.Set(n, v)
+	}
+	if present {
+		if mapping.Name == "" {
+			level.Debug(s.logger).Log("msg", "the mapping generates an empty metric name", "metric_name", e.MetricName(), "match", mapping.Match)
+			s.metrics.ErrorEventStats.WithLabelValues("empty_metric_name").Inc()
+			return
+		}
+		name = mapper.EscapeMetricName(mapping.Name)
+		for n, v := range mappedLabels {
+			lb.Set(n, v)
+		}
+		s.metrics.EventsActions.WithLabelValues(string(mapping.Action)).Inc()
+	} else {
+		s.metrics.EventsUnmapped.Inc()
+		name = mapper.EscapeMetricName(e.MetricName())
+	}
+	lb.Set(labels.MetricName, name)
+	lbls := lb.Labels()
+
+	switch ev := e.(type) {
+	case *event.CounterEvent:
+		if ev.Value() < 0 {
+			level.Debug(s.logger).Log("msg", "counter must be non-negative value", "metric", name, "event_value", ev.Value())
+			s.metrics.ErrorEventStats.WithLabelValues("illegal_negative_counter").Inc()
+			return
+		}
+		sr, err := s.getSeries(lbls, model.MetricTypeCounter, help, mapping)
+		if err != nil {
+			level.Debug(s.logger).Log("msg", "failed to update metric", "metric", name, "err", err)
+			s.metrics.ConflictingEventStats.WithLabelValues("counter").Inc()
+			return
+		}
+		sr.value += ev.Value()
+		sr.lastEvent = now
+		s.metrics.EventStats.WithLabelValues("counter").Inc()
+
+	case *event.GaugeEvent:
+		sr, err := s.getSeries(lbls, model.MetricTypeGauge, help, mapping)
+		if err != nil {
+			level.Debug(s.logger).Log("msg", "failed to update metric", "metric", name, "err", err)
+			s.metrics.ConflictingEventStats.WithLabelValues("gauge").Inc()
+			return
+		}
+		if ev.GRelative {
+			sr.value += ev.Value()
+		} else {
+			sr.value = ev.Value()
+		}
+		sr.lastEvent = now
+		s.metrics.EventStats.WithLabelValues("gauge").Inc()
+
+	case *event.ObserverEvent:
+		typ := model.MetricTypeSummary
+		if observerType(mapping, s.mapper) == mapper.ObserverTypeHistogram {
+			typ = model.MetricTypeHistogram
+		}
+		sr, err := s.getSeries(lbls, typ, help, mapping)
+		if err != nil {
+			level.Debug(s.logger).Log("msg", "failed to update metric", "metric", name, "err", err)
+			s.metrics.ConflictingEventStats.WithLabelValues("observer").Inc()
+			return
+		}
+		sr.observer.(prometheus.Observer).Observe(ev.Value())
+		sr.lastEvent = now
+		s.metrics.EventStats.WithLabelValues("observer").Inc()
+
+	default:
+		level.Debug(s.logger).Log("msg", "unsupported event type")
+		s.metrics.EventStats.WithLabelValues("illegal").Inc()
+	}
+}
+
+func observerType(mapping *mapper.MetricMapping, m *mapper.MetricMapper) mapper.ObserverType {
+	if mapping.ObserverType != mapper.ObserverTypeDefault {
+		return mapping.ObserverType
+	}
+	return m.Defaults.ObserverType
+}
+
+// getSeries returns the series with the given labels, creating it if needed.
+// It fails if the metric of the series already exists with another type.
+func (s *store) getSeries(lbls labels.Labels, typ model.MetricType, help string, mapping *mapper.MetricMapping) (*series, error) {
+	name := lbls.Get(labels.MetricName)
+	f, ok := s.families[name]
+	if !ok {
+		if err := s.checkNameConflict(name, typ); err != nil {
+			return nil, err
+		}
+		f = &family{
+			metadata: metadata.Metadata{Type: typ, Help: help},
+			series:   make(map[uint64]*series),
+		}
+		s.families[name] = f
+	} else if f.metadata.Type != typ {
+		return nil, fmt.Errorf("metric %q already exists with type %s", name, f.metadata.Type)
+	}
+
+	hash := lbls.Hash()
+	if sr, ok := f.series[hash]; ok && labels.Equal(sr.labels, lbls) {
+		sr.ttl = mapping.Ttl
+		return sr, nil
+	}
+
+	sr := &series{labels: lbls, ttl: mapping.Ttl}
+	switch typ {
+	case model.MetricTypeHistogram:
+		sr.histograms = s.histograms
+		sr.observer = s.newHistogram(name, help, mapping)
+	case model.MetricTypeSummary:
+		sr.observer = s.newSummary(name, help, mapping)
+	}
+	f.series[hash] = sr
+	return sr, nil
+}
+
+// checkNameConflict checks that the series of a new metric don't have the
+// same names as the series of an existing histogram or summary, or the
+// other way around.
+func (s *store) checkNameConflict(name string, typ model.MetricType) error {
+	for _, suffix := range []string{"_sum", "_count", "_bucket"} {
+		if _, ok := s.families[name+suffix]; ok && hasSuffixedSeries(typ) {
+			return fmt.Errorf("metric %q conflicts with the series of metric %q", name, name+suffix)
+		}
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			if f, ok := s.families[base]; ok && hasSuffixedSeries(f.metadata.Type) {
+				return fmt.Errorf("metric %q conflicts with the series of %s %q", name, f.metadata.Type, base)
+			}
+		}
+	}
+	return nil
+}
+
+// hasSuffixedSeries returns whether the metrics of a type have series with
+// the _sum, _count, or _bucket suffixes.
+func hasSuffixedSeries(typ model.MetricType) bool {
+	return typ == model.MetricTypeHistogram || typ == model.MetricTypeSummary
+}
+
+func (s *store) newHistogram(name, help string, mapping *mapper.MetricMapping) prometheus.Histogram {
+	opts := prometheus.HistogramOpts{Name: name, Help: help}
+	if s.histograms.sendClassic {
+		opts.Buckets = s.mapper.Defaults.HistogramOptions.Buckets
+		if mapping.HistogramOptions != nil && len(mapping.HistogramOptions.Buckets) > 0 {
+			opts.Buckets = mapping.HistogramOptions.Buckets
+		}
+	}
+	if s.histograms.sendNative {
+		opts.NativeHistogramBucketFactor = s.histograms.bucketFactor
+		opts.NativeHistogramMaxBucketNumber = s.histograms.maxBuckets
+	}
+	return prometheus.NewHistogram(opts)
+}
+
+func (s *store) newSummary(name, help string, mapping *mapper.MetricMapping) prometheus.Summary {
+	options := s.mapper.Defaults.SummaryOptions
+	quantiles := options.Quantiles
+	if mapping.SummaryOptions != nil {
+		options = *mapping.SummaryOptions
+		if len(options.Quantiles) > 0 {
+			quantiles = options.Quantiles
+		}
+	}
+
+	objectives := make(map[float64]float64, len(quantiles))
+	for _, q := range quantiles {
+		objectives[q.Quantile] = q.Error
+	}
+	return prometheus.NewSummary(prometheus.SummaryOpts{
+		Name:       name,
+		Help:       help,
+		Objectives: objectives,
+		MaxAge:     options.MaxAge,
+		AgeBuckets: options.AgeBuckets,
+		BufCap:     options.BufCap,
+	})
+}
+
+// flush forwards the samples of all the series, after deleting the series
+// which expired.
+func (s *store) flush(ctx context.Context, appendable storage.Appendable, now time.Time) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ts := now.UnixMilli()
+	app := appendable.Appender(ctx)
+	counts := make(map[model.MetricType]int)
+	for name, f := range s.families {
+		for hash, sr := range f.series {
+			if sr.ttl > 0 && now.Sub(sr.lastEvent) > sr.ttl {
+				for _, l := range sr.sent {
+					_, _ = app.Append(0, l, ts, math.Float64frombits(value.StaleNaN))
+				}
+				delete(f.series, hash)
+				continue
+			}
+			sr.sent = sr.forward(app, f.metadata, ts, s.logger)
+		}
+		if len(f.series) == 0 {
+			delete(s.families, name)
+			continue
+		}
+		counts[f.metadata.Type] += len(f.series)
+	}
+	for _, typ := range []model.MetricType{model.MetricTypeCounter, model.MetricTypeGauge, model.MetricTypeHistogram, model.MetricTypeSummary} {
+		s.metrics.MetricsCount.WithLabelValues(string(typ)).Set(float64(counts[typ]))
+	}
+
+	if err := app.Commit(); err != nil {
+		level.Error(s.logger).Log("msg", "failed to forward StatsD metrics", "err", err)
+	}
+}
+
+// forward appends the samples of a series, and returns the labels of the
+// forwarded samples.
+func (sr *series) forward(app storage.Appender, md metadata.Metadata, ts int64, logger log.Logger) []labels.Labels {
+	sent := make([]labels.Labels, 0, len(sr.sent))
+	appendSample := func(l labels.Labels, v float64, h *histogram.Histogram) {
+		var err error
+		if h != nil {
+			_, err = app.AppendHistogram(0, l, ts, h, nil)
+		} else {
+			_, err = app.Append(0, l, ts, v)
+		}
+		if err == nil {
+			_, err = app.UpdateMetadata(0, l, md)
+		}
+		if err != nil {
+			level.Debug(logger).Log("msg", "failed to append sample", "labels", l.String(), "err", err)
+		}
+		sent = append(sent, l)
+	}
+	withSuffix := func(suffix string, extra ...string) labels.Labels {
+		b := labels.NewBuilder(sr.labels)
+		b.Set(labels.MetricName, sr.labels.Get(labels.MetricName)+suffix)
+		for i := 0; i < len(extra); i += 2 {
+			b.Set(extra[i], extra[i+1])
+		}
+		return b.Labels()
+	}
+
+	switch md.Type {
+	case model.MetricTypeCounter, model.MetricTypeGauge:
+		appendSample(sr.labels, sr.value, nil)
+
+	case model.MetricTypeSummary:
+		var m dto.Metric
+		if err := sr.observer.Write(&m); err != nil {
+			level.Debug(logger).Log("msg", "failed to read summary", "labels", sr.labels.String(), "err", err)
+			return sr.sent
+		}
+		for _, q := range m.GetSummary().GetQuantile() {
+			appendSample(withSuffix("", model.QuantileLabel, exposition.FormatFloat(q.GetQuantile())), q.GetValue(), nil)
+		}
+		appendSample(withSuffix("_sum"), m.GetSummary().GetSampleSum(), nil)
+		appendSample(withSuffix("_count"), float64(m.GetSummary().GetSampleCount()), nil)
+
+	case model.MetricTypeHistogram:
+		var m dto.Metric
+		if err := sr.observer.Write(&m); err != nil {
+			level.Debug(logger).Log("msg", "failed to read histogram", "labels", sr.labels.String(), "err", err)
+			return sr.sent
+		}
+		h := m.GetHistogram()
+		if sr.histograms.sendNative {
+			// The histograms of the Prometheus client have integer counts.
+			ih, _ := exposition.NativeHistogram(h, false)
+			appendSample(sr.labels, 0, ih)
+		}
+		if sr.histograms.sendClassic {
+			for _, b := range h.GetBucket() {
+				appendSample(withSuffix("_bucket", labels.BucketLabel, exposition.FormatFloat(b.GetUpperBound())), float64(b.GetCumulativeCount()), nil)
+			}
+			appendSample(withSuffix("_bucket", labels.BucketLabel, "+Inf"), float64(h.GetSampleCount()), nil)
+			appendSample(withSuffix("_sum"), h.GetSampleSum(), nil)
+			appendSample(withSuffix("_count"), float64(h.GetSampleCount()), nil)
+		}
+	}
+	return sent
+}
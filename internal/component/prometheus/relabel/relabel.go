@@ -277,7 +277,12 @@ func (c *Component) relabel(val float64, lbls labels.Labels) labels.Labels {
 
 	componentID := livedebugging.ComponentID(c.opts.ID)
 	if c.debugDataPublisher.IsActive(componentID) {
-		c.debugDataPublisher.Publish(componentID, fmt.Sprintf("%s => %s", lbls.String(), relabelled.String()))
+		c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+			livedebugging.PrometheusMetric,
+			1,
+			lbls,
+			func() string { return fmt.Sprintf("%s => %s", lbls.String(), relabelled.String()) },
+		))
 	}
 
 	return relabelled
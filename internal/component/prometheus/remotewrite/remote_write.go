@@ -130,7 +130,12 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				ls.GetOrAddLink(res.opts.ID, uint64(newRef), l)
 			}
 			if res.debugDataPublisher.IsActive(componentID) {
-				res.debugDataPublisher.Publish(componentID, fmt.Sprintf("sample: ts=%d, labels=%s, value=%f", t, l, v))
+				res.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.PrometheusMetric,
+					1,
+					l,
+					func() string { return fmt.Sprintf("sample: ts=%d, labels=%s, value=%f", t, l, v) },
+				))
 			}
 			return globalRef, nextErr
 		}),
@@ -145,6 +150,7 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				ls.GetOrAddLink(res.opts.ID, uint64(newRef), l)
 			}
 			if res.debugDataPublisher.IsActive(componentID) {
+				// Histograms may be reused by the caller, so they are rendered right away.
 				var data string
 				if h != nil {
 					data = fmt.Sprintf("histogram: ts=%d, labels=%s, value=%s", t, l, h.String())
@@ -153,7 +159,12 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				} else {
 					data = fmt.Sprintf("histogram_with_no_value: ts=%d, labels=%s", t, l)
 				}
-				res.debugDataPublisher.Publish(componentID, data)
+				res.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.PrometheusMetric,
+					1,
+					l,
+					func() string { return data },
+				))
 			}
 			return globalRef, nextErr
 		}),
@@ -168,7 +179,14 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				ls.GetOrAddLink(res.opts.ID, uint64(newRef), l)
 			}
 			if res.debugDataPublisher.IsActive(componentID) {
-				res.debugDataPublisher.Publish(componentID, fmt.Sprintf("metadata: labels=%s, type=%q, unit=%q, help=%q", l, m.Type, m.Unit, m.Help))
+				res.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.PrometheusMetric,
+					1,
+					l,
+					func() string {
+						return fmt.Sprintf("metadata: labels=%s, type=%q, unit=%q, help=%q", l, m.Type, m.Unit, m.Help)
+					},
+				))
 			}
 			return globalRef, nextErr
 		}),
@@ -183,7 +201,14 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				ls.GetOrAddLink(res.opts.ID, uint64(newRef), l)
 			}
 			if res.debugDataPublisher.IsActive(componentID) {
-				res.debugDataPublisher.Publish(componentID, fmt.Sprintf("exemplar: ts=%d, labels=%s, exemplar_labels=%s, value=%f", e.Ts, l, e.Labels, e.Value))
+				res.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.PrometheusMetric,
+					1,
+					l,
+					func() string {
+						return fmt.Sprintf("exemplar: ts=%d, labels=%s, exemplar_labels=%s, value=%f", e.Ts, l, e.Labels, e.Value)
+					},
+				))
 			}
 			return globalRef, nextErr
 		}),
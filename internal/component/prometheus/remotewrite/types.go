@@ -228,6 +228,13 @@ type Exports struct {
 	Receiver storage.Appendable `alloy:"receiver,attr"`
 }
 
+// ToPrometheusConfig converts the arguments of the component into a
+// Prometheus configuration holding the remote_write endpoints and the
+// external labels.
+func ToPrometheusConfig(args Arguments) (*config.Config, error) {
+	return convertConfigs(args)
+}
+
 func convertConfigs(cfg Arguments) (*config.Config, error) {
 	var rwConfigs []*config.RemoteWriteConfig
 	for _, rw := range cfg.Endpoints {
@@ -0,0 +1,418 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/util"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.rules.local",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Exports holds the values exported by the prometheus.rules.local component.
+type Exports struct {
+	Receiver storage.Appendable `alloy:"receiver,attr"`
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// Component implements the prometheus.rules.local component.
+type Component struct {
+	opts    component.Options
+	storage *localStorage
+	engine  *promql.Engine
+	fanout  *prometheus.Fanout
+	metrics *metrics
+	ticker  *time.Ticker
+
+	mut          sync.Mutex
+	args         Arguments
+	rules        []*rule
+	rulesFileMod time.Time
+	// staleSeries holds the series of the removed rules, which are marked as
+	// stale at the next evaluation.
+	staleSeries []labels.Labels
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+// rule is a recording rule, with the series it recorded at the last
+// evaluation so that they're marked as stale when they disappear.
+type rule struct {
+	key    string
+	rule   *rules.RecordingRule
+	series map[uint64]labels.Labels
+}
+
+type metrics struct {
+	evaluations        prometheus_client.Counter
+	evaluationFailures *prometheus_client.CounterVec
+	evaluationDuration prometheus_client.Histogram
+	samplesRecorded    prometheus_client.Counter
+	series             prometheus_client.Gauge
+}
+
+func newMetrics(r prometheus_client.Registerer) *metrics {
+	m := &metrics{
+		evaluations: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "prometheus_rules_local_evaluations_total",
+			Help: "Total number of evaluations of the rules.",
+		}),
+		evaluationFailures: prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+			Name: "prometheus_rules_local_evaluation_failures_total",
+			Help: "Total number of rule evaluations which failed, partitioned by rule.",
+		}, []string{"rule"}),
+		evaluationDuration: prometheus_client.NewHistogram(prometheus_client.HistogramOpts{
+			Name:    "prometheus_rules_local_evaluation_duration_seconds",
+			Help:    "Duration of the evaluations of all the rules.",
+			Buckets: prometheus_client.DefBuckets,
+		}),
+		samplesRecorded: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "prometheus_rules_local_samples_recorded_total",
+			Help: "Total number of samples recorded by the rules.",
+		}),
+		series: prometheus_client.NewGauge(prometheus_client.GaugeOpts{
+			Name: "prometheus_rules_local_storage_series",
+			Help: "Number of series in the local storage.",
+		}),
+	}
+	m.evaluations = util.MustRegisterOrGet(r, m.evaluations).(prometheus_client.Counter)
+	m.evaluationFailures = util.MustRegisterOrGet(r, m.evaluationFailures).(*prometheus_client.CounterVec)
+	m.evaluationDuration = util.MustRegisterOrGet(r, m.evaluationDuration).(prometheus_client.Histogram)
+	m.samplesRecorded = util.MustRegisterOrGet(r, m.samplesRecorded).(prometheus_client.Counter)
+	m.series = util.MustRegisterOrGet(r, m.series).(prometheus_client.Gauge)
+	return m
+}
+
+// New creates a new prometheus.rules.local component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	s, err := newLocalStorage(o.Logger, o.DataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local storage: %w", err)
+	}
+
+	c := &Component{
+		opts:    o,
+		storage: s,
+		fanout:  prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls),
+		metrics: newMetrics(o.Registerer),
+		ticker:  time.NewTicker(args.EvaluationInterval),
+	}
+
+	// Call to Update() once at the start.
+	if err := c.Update(args); err != nil {
+		_ = s.close()
+		return nil, err
+	}
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.storage})
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		if err := c.storage.close(); err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to close local storage", "err", err)
+		}
+	}()
+	defer c.ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-c.ticker.C:
+			c.evaluate(ctx, now)
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	var fileRules []RuleConfig
+	var modTime time.Time
+	if newArgs.RulesFile != "" {
+		var err error
+		if fileRules, modTime, err = c.readRulesFile(newArgs.RulesFile); err != nil {
+			return err
+		}
+	}
+	if err := c.setRules(fileRules, newArgs.Rules); err != nil {
+		return err
+	}
+
+	c.args = newArgs
+	c.rulesFileMod = modTime
+	c.engine = promql.NewEngine(promql.EngineOpts{
+		Logger:     c.opts.Logger,
+		MaxSamples: 50_000_000,
+		Timeout:    newArgs.EvaluationInterval,
+		NoStepSubqueryIntervalFn: func(int64) int64 {
+			return newArgs.EvaluationInterval.Milliseconds()
+		},
+		EnableAtModifier:     true,
+		EnableNegativeOffset: true,
+	})
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	c.ticker.Reset(newArgs.EvaluationInterval)
+
+	return nil
+}
+
+func (c *Component) readRulesFile(path string) ([]RuleConfig, time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	recording, alerting, err := loadRulesFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to load rules file: %w", err)
+	}
+	if len(alerting) > 0 {
+		level.Warn(c.opts.Logger).Log("msg", "ignoring the alerting rules of the rules file", "file", path, "rules", fmt.Sprint(alerting))
+	}
+	for _, r := range recording {
+		if err := r.Validate(); err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid rules file: %w", err)
+		}
+	}
+	return recording, fi.ModTime(), nil
+}
+
+// setRules replaces the rules. The rules of the file are evaluated first,
+// then the rules of the rule blocks. Rules which keep their name, expression,
+// and labels also keep their state.
+func (c *Component) setRules(configs ...[]RuleConfig) error {
+	existing := make(map[string]*rule, len(c.rules))
+	for _, r := range c.rules {
+		existing[r.key] = r
+	}
+
+	var newRules []*rule
+	for _, cfgs := range configs {
+		for _, cfg := range cfgs {
+			expr, err := parser.ParseExpr(cfg.Expr)
+			if err != nil {
+				return fmt.Errorf("invalid expression for rule %q: %w", cfg.Record, err)
+			}
+			lbls := labels.FromMap(cfg.Labels)
+			r := &rule{
+				key:    cfg.Record + "\xff" + cfg.Expr + "\xff" + lbls.String(),
+				rule:   rules.NewRecordingRule(cfg.Record, expr, lbls),
+				series: make(map[uint64]labels.Labels),
+			}
+			if old, ok := existing[r.key]; ok {
+				r.series = old.series
+				delete(existing, r.key)
+			}
+			newRules = append(newRules, r)
+		}
+	}
+	for _, r := range existing {
+		c.metrics.evaluationFailures.DeleteLabelValues(r.rule.Name())
+		for _, lbls := range r.series {
+			c.staleSeries = append(c.staleSeries, lbls)
+		}
+	}
+
+	c.rules = newRules
+	return nil
+}
+
+// reloadRulesFile reloads the rules file if it was modified since it was
+// last read. The previous rules are kept if it can't be loaded.
+func (c *Component) reloadRulesFile() error {
+	if c.args.RulesFile == "" {
+		return nil
+	}
+	fi, err := os.Stat(c.args.RulesFile)
+	if err != nil {
+		return fmt.Errorf("failed to read rules file: %w", err)
+	}
+	if fi.ModTime().Equal(c.rulesFileMod) {
+		return nil
+	}
+
+	fileRules, modTime, err := c.readRulesFile(c.args.RulesFile)
+	if err != nil {
+		return err
+	}
+	if err := c.setRules(fileRules, c.args.Rules); err != nil {
+		return err
+	}
+	c.rulesFileMod = modTime
+	level.Info(c.opts.Logger).Log("msg", "reloaded rules file", "file", c.args.RulesFile)
+	return nil
+}
+
+// evaluate evaluates the rules in order, and sends their samples. The
+// samples of each rule are also appended to the local storage, so that the
+// next rules can use them.
+func (c *Component) evaluate(ctx context.Context, now time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	start := time.Now()
+	defer func() {
+		c.metrics.evaluations.Inc()
+		c.metrics.evaluationDuration.Observe(time.Since(start).Seconds())
+		c.metrics.series.Set(float64(c.storage.numSeries()))
+	}()
+
+	var errs []error
+	if err := c.reloadRulesFile(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to reload rules file", "file", c.args.RulesFile, "err", err)
+		errs = append(errs, err)
+	}
+
+	if err := c.storage.truncate(now.Add(-c.args.Retention).UnixMilli()); err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to truncate local storage", "err", err)
+	}
+
+	ts := now.UnixMilli()
+	query := rules.EngineQueryFunc(c.engine, c.storage)
+	app := c.fanout.Appender(ctx)
+
+	// The series of the removed rules are marked as stale before the rules
+	// are evaluated, so that the rules using them don't see them anymore.
+	if len(c.staleSeries) > 0 {
+		local := c.storage.Appender(ctx)
+		for _, lbls := range c.staleSeries {
+			_, _ = local.Append(0, lbls, ts, math.Float64frombits(value.StaleNaN))
+			if _, err := app.Append(0, lbls, ts, math.Float64frombits(value.StaleNaN)); err != nil {
+				level.Warn(c.opts.Logger).Log("msg", "failed to append staleness marker", "labels", lbls.String(), "err", err)
+			}
+		}
+		if err := local.Commit(); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to append staleness markers to local storage", "err", err)
+		}
+		c.staleSeries = nil
+	}
+
+	for _, r := range c.rules {
+		vector, err := r.rule.Eval(ctx, 0, now, query, nil, 0)
+		if err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to evaluate rule", "rule", r.rule.Name(), "err", err)
+			c.metrics.evaluationFailures.WithLabelValues(r.rule.Name()).Inc()
+			errs = append(errs, fmt.Errorf("rule %q: %w", r.rule.Name(), err))
+			continue
+		}
+
+		local := c.storage.Appender(ctx)
+		seen := make(map[uint64]labels.Labels, len(vector))
+		for _, s := range vector {
+			seen[s.Metric.Hash()] = s.Metric
+			if s.H != nil {
+				_, _ = local.AppendHistogram(0, s.Metric, ts, nil, s.H)
+				_, err = app.AppendHistogram(0, s.Metric, ts, nil, s.H)
+			} else {
+				_, _ = local.Append(0, s.Metric, ts, s.F)
+				_, err = app.Append(0, s.Metric, ts, s.F)
+			}
+			if err != nil {
+				level.Warn(c.opts.Logger).Log("msg", "failed to append recorded sample", "rule", r.rule.Name(), "labels", s.Metric.String(), "err", err)
+			}
+		}
+		c.metrics.samplesRecorded.Add(float64(len(vector)))
+
+		// The series which disappeared are marked as stale.
+		for hash, lbls := range r.series {
+			if _, ok := seen[hash]; ok {
+				continue
+			}
+			_, _ = local.Append(0, lbls, ts, math.Float64frombits(value.StaleNaN))
+			if _, err := app.Append(0, lbls, ts, math.Float64frombits(value.StaleNaN)); err != nil {
+				level.Warn(c.opts.Logger).Log("msg", "failed to append staleness marker", "rule", r.rule.Name(), "labels", lbls.String(), "err", err)
+			}
+		}
+		r.series = seen
+
+		if err := local.Commit(); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to append recorded samples to local storage", "rule", r.rule.Name(), "err", err)
+		}
+	}
+	if err := app.Commit(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to send recorded samples", "err", err)
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		c.reportUnhealthy(errors.Join(errs...))
+	} else {
+		c.reportHealthy()
+	}
+}
+
+func (c *Component) reportUnhealthy(err error) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = component.Health{
+		Health:     component.HealthTypeUnhealthy,
+		Message:    err.Error(),
+		UpdateTime: time.Now(),
+	}
+}
+
+func (c *Component) reportHealthy() {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = component.Health{
+		Health:     component.HealthTypeHealthy,
+		UpdateTime: time.Now(),
+	}
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
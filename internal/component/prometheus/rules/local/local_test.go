@@ -0,0 +1,265 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		forward_to = []
+
+		rule {
+			record = "job:http_requests:rate5m"
+			expr   = "sum by (job) (rate(http_requests_total[5m]))"
+			labels = { source = "alloy" }
+		}
+	`), &args))
+	require.Equal(t, time.Minute, args.EvaluationInterval)
+	require.Equal(t, 15*time.Minute, args.Retention)
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		errSubstring string
+	}{
+		{
+			name: "invalid evaluation_interval",
+			config: `
+				forward_to          = []
+				evaluation_interval = "0s"`,
+			errSubstring: "evaluation_interval must be greater than 0",
+		},
+		{
+			name: "retention too short",
+			config: `
+				forward_to = []
+				retention  = "10s"`,
+			errSubstring: "retention must be greater than or equal to evaluation_interval",
+		},
+		{
+			name: "invalid record",
+			config: `
+				forward_to = []
+				rule {
+					record = ""
+					expr   = "up"
+				}`,
+			errSubstring: `invalid record ""`,
+		},
+		{
+			name: "invalid expr",
+			config: `
+				forward_to = []
+				rule {
+					record = "job:up"
+					expr   = "sum("
+				}`,
+			errSubstring: `invalid expression for rule "job:up"`,
+		},
+		{
+			name: "invalid label",
+			config: `
+				forward_to = []
+				rule {
+					record = "job:up"
+					expr   = "up"
+					labels = { __name__ = "c" }
+				}`,
+			errSubstring: `labels of rule "job:up" can't set __name__`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tt.config), &args)
+			require.ErrorContains(t, err, tt.errSubstring)
+		})
+	}
+}
+
+func TestRecordingRules(t *testing.T) {
+	c, out := newTestComponent(t, Arguments{
+		Rules: []RuleConfig{
+			{Record: "job:http_requests:rate1m", Expr: `sum by (job) (rate(http_requests_total[1m]))`},
+			// A rule can use the series recorded by the previous rules.
+			{Record: "http_requests:rate1m", Expr: `sum(job:http_requests:rate1m)`, Labels: map[string]string{"source": "alloy"}},
+		},
+	})
+
+	now := time.Now()
+	for i := 0; i <= 6; i++ {
+		ts := now.Add(time.Duration(i-6) * 10 * time.Second).UnixMilli()
+		app := c.storage.Appender(context.Background())
+		appendSample(t, app, ts, float64(i*10), "__name__", "http_requests_total", "job", "api", "instance", "a")
+		appendSample(t, app, ts, float64(i*20), "__name__", "http_requests_total", "job", "api", "instance", "b")
+		appendSample(t, app, ts, float64(i*5), "__name__", "http_requests_total", "job", "web", "instance", "c")
+		require.NoError(t, app.Commit())
+	}
+
+	c.evaluate(context.Background(), now)
+	require.Equal(t, map[string]float64{
+		`{__name__="job:http_requests:rate1m", job="api"}`:  3,
+		`{__name__="job:http_requests:rate1m", job="web"}`:  0.5,
+		`{__name__="http_requests:rate1m", source="alloy"}`: 3.5,
+	}, out.TakeSamples())
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+}
+
+func TestStaleness(t *testing.T) {
+	c, out := newTestComponent(t, Arguments{
+		Rules: []RuleConfig{{Record: "job:up:sum", Expr: `sum by (job) (up)`}},
+	})
+
+	now := time.Now()
+	app := c.storage.Appender(context.Background())
+	appendSample(t, app, now.Add(-time.Second).UnixMilli(), 1, "__name__", "up", "job", "api")
+	appendSample(t, app, now.Add(-time.Second).UnixMilli(), 1, "__name__", "up", "job", "web")
+	require.NoError(t, app.Commit())
+	c.evaluate(context.Background(), now)
+	require.Len(t, out.TakeSamples(), 2)
+
+	// The series of the web job is marked as stale once it disappears.
+	now = now.Add(time.Minute)
+	app = c.storage.Appender(context.Background())
+	appendSample(t, app, now.Add(-time.Second).UnixMilli(), 1, "__name__", "up", "job", "api")
+	appendSample(t, app, now.Add(-time.Second).UnixMilli(), math.Float64frombits(value.StaleNaN), "__name__", "up", "job", "web")
+	require.NoError(t, app.Commit())
+	c.evaluate(context.Background(), now)
+	result := out.TakeSamples()
+	require.Equal(t, 1.0, result[`{__name__="job:up:sum", job="api"}`])
+	require.True(t, value.IsStaleNaN(result[`{__name__="job:up:sum", job="web"}`]))
+}
+
+func TestNativeHistograms(t *testing.T) {
+	c, out := newTestComponent(t, Arguments{
+		Rules: []RuleConfig{{Record: "latency_seconds:sum", Expr: `sum(latency_seconds)`}},
+	})
+
+	now := time.Now()
+	app := c.storage.Appender(context.Background())
+	for _, instance := range []string{"a", "b"} {
+		_, err := app.AppendHistogram(0, labels.FromStrings("__name__", "latency_seconds", "instance", instance), now.Add(-time.Second).UnixMilli(), nil, &histogram.FloatHistogram{
+			Count:           2,
+			Sum:             1,
+			PositiveSpans:   []histogram.Span{{Offset: 0, Length: 1}},
+			PositiveBuckets: []float64{2},
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	c.evaluate(context.Background(), now)
+	require.Equal(t, 4.0, out.TakeFloatHistograms()[`{__name__="latency_seconds:sum"}`].Count)
+}
+
+func TestRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+groups:
+  - name: test
+    rules:
+      - record: job:up:sum
+        expr: sum by (job) (up)
+      - alert: InstanceDown
+        expr: up == 0
+`), 0644))
+
+	c, out := newTestComponent(t, Arguments{
+		RulesFile: path,
+		Rules:     []RuleConfig{{Record: "up:sum", Expr: `sum(job:up:sum)`}},
+	})
+	require.Len(t, c.rules, 2)
+
+	now := time.Now()
+	app := c.storage.Appender(context.Background())
+	appendSample(t, app, now.Add(-time.Second).UnixMilli(), 1, "__name__", "up", "job", "api")
+	require.NoError(t, app.Commit())
+	c.evaluate(context.Background(), now)
+	require.Equal(t, map[string]float64{
+		`{__name__="job:up:sum", job="api"}`: 1,
+		`{__name__="up:sum"}`:                1,
+	}, out.TakeSamples())
+
+	// The file is reloaded when it changes.
+	require.NoError(t, os.WriteFile(path, []byte(`
+groups:
+  - name: test
+    rules:
+      - record: job:up:count
+        expr: count by (job) (up)
+`), 0644))
+	require.NoError(t, os.Chtimes(path, now.Add(time.Minute), now.Add(time.Minute)))
+	c.evaluate(context.Background(), now.Add(time.Second))
+	result := out.TakeSamples()
+	require.Len(t, result, 3)
+	require.Equal(t, 1.0, result[`{__name__="job:up:count", job="api"}`])
+	// The series of the removed rule, and the series using them, are marked
+	// as stale.
+	require.True(t, value.IsStaleNaN(result[`{__name__="job:up:sum", job="api"}`]))
+	require.True(t, value.IsStaleNaN(result[`{__name__="up:sum"}`]))
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+
+	// An invalid file keeps the previous rules.
+	require.NoError(t, os.WriteFile(path, []byte(`groups: [`), 0644))
+	require.NoError(t, os.Chtimes(path, now.Add(2*time.Minute), now.Add(2*time.Minute)))
+	c.evaluate(context.Background(), now.Add(2*time.Second))
+	require.Equal(t, 1.0, out.TakeSamples()[`{__name__="job:up:count", job="api"}`])
+	require.Equal(t, component.HealthTypeUnhealthy, c.CurrentHealth().Health)
+}
+
+func newTestComponent(t *testing.T, args Arguments) (*Component, *testappender.Collector) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	out := testappender.NewCollector()
+	args.ForwardTo = []storage.Appendable{out}
+	if args.EvaluationInterval == 0 {
+		args.EvaluationInterval = DefaultArguments.EvaluationInterval
+	}
+	if args.Retention == 0 {
+		args.Retention = DefaultArguments.Retention
+	}
+
+	c, err := New(component.Options{
+		ID:            "prometheus.rules.local.test",
+		Logger:        util.TestAlloyLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prom.NewRegistry(),
+		DataPath:      t.TempDir(),
+		GetServiceData: func(name string) (interface{}, error) {
+			if name != labelstore.ServiceName {
+				return nil, fmt.Errorf("service not found %s", name)
+			}
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, c.storage.close()) })
+	return c, out
+}
+
+func appendSample(t *testing.T, app storage.Appender, ts int64, v float64, ls ...string) {
+	_, err := app.Append(0, labels.FromStrings(ls...), ts, v)
+	require.NoError(t, err)
+}
@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+)
+
+// localStorage is an in-memory TSDB holding the samples the rules are
+// evaluated against. It only has a head block, which is truncated to the
+// retention, and no WAL, so the samples are lost when the component stops.
+type localStorage struct {
+	head *tsdb.Head
+}
+
+var (
+	_ storage.Appendable = (*localStorage)(nil)
+	_ storage.Queryable  = (*localStorage)(nil)
+)
+
+func newLocalStorage(logger log.Logger, dir string) (*localStorage, error) {
+	// The memory-mapped chunks of a previous run can't be used without the
+	// WAL.
+	chunksDir := filepath.Join(dir, "chunks_head")
+	if err := os.RemoveAll(chunksDir); err != nil {
+		return nil, err
+	}
+
+	opts := tsdb.DefaultHeadOptions()
+	opts.ChunkDirRoot = dir
+	opts.EnableNativeHistograms.Store(true)
+	head, err := tsdb.NewHead(nil, logger, nil, nil, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := head.Init(math.MinInt64); err != nil {
+		return nil, err
+	}
+	return &localStorage{head: head}, nil
+}
+
+// Appender implements storage.Appendable.
+func (s *localStorage) Appender(ctx context.Context) storage.Appender {
+	return &localAppender{app: s.head.Appender(ctx)}
+}
+
+// Querier implements storage.Queryable.
+func (s *localStorage) Querier(mint, maxt int64) (storage.Querier, error) {
+	return tsdb.NewBlockQuerier(tsdb.NewRangeHead(s.head, mint, maxt), mint, maxt)
+}
+
+// truncate removes the samples older than mint.
+func (s *localStorage) truncate(mint int64) error {
+	return s.head.Truncate(mint)
+}
+
+func (s *localStorage) numSeries() uint64 {
+	return s.head.NumSeries()
+}
+
+func (s *localStorage) close() error {
+	return s.head.Close()
+}
+
+// localAppender appends samples to the head. The references of the series
+// are ignored, as they're the global references of Alloy rather than the
+// references of the head.
+type localAppender struct {
+	app storage.Appender
+}
+
+var _ storage.Appender = (*localAppender)(nil)
+
+// Append satisfies the Appender interface.
+func (a *localAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	_, err := a.app.Append(0, l, t, v)
+	return ref, err
+}
+
+// AppendHistogram satisfies the Appender interface.
+func (a *localAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	_, err := a.app.AppendHistogram(0, l, t, h, fh)
+	return ref, err
+}
+
+// AppendExemplar satisfies the Appender interface. The exemplars aren't
+// stored.
+func (a *localAppender) AppendExemplar(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+// UpdateMetadata satisfies the Appender interface. The metadata isn't
+// stored.
+func (a *localAppender) UpdateMetadata(ref storage.SeriesRef, _ labels.Labels, _ metadata.Metadata) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+// AppendCTZeroSample satisfies the Appender interface. The created
+// timestamps aren't stored.
+func (a *localAppender) AppendCTZeroSample(ref storage.SeriesRef, _ labels.Labels, _, _ int64) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+// Commit satisfies the Appender interface.
+func (a *localAppender) Commit() error {
+	return a.app.Commit()
+}
+
+// Rollback satisfies the Appender interface.
+func (a *localAppender) Rollback() error {
+	return a.app.Rollback()
+}
@@ -0,0 +1,97 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Arguments holds values which are used to configure the
+// prometheus.rules.local component.
+type Arguments struct {
+	ForwardTo          []storage.Appendable `alloy:"forward_to,attr"`
+	EvaluationInterval time.Duration        `alloy:"evaluation_interval,attr,optional"`
+	Retention          time.Duration        `alloy:"retention,attr,optional"`
+	RulesFile          string               `alloy:"rules_file,attr,optional"`
+
+	Rules []RuleConfig `alloy:"rule,block,optional"`
+}
+
+// RuleConfig configures a recording rule.
+type RuleConfig struct {
+	Record string            `alloy:"record,attr"`
+	Expr   string            `alloy:"expr,attr"`
+	Labels map[string]string `alloy:"labels,attr,optional"`
+}
+
+// DefaultArguments defines the default settings for the
+// prometheus.rules.local component.
+var DefaultArguments = Arguments{
+	EvaluationInterval: time.Minute,
+	Retention:          15 * time.Minute,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.EvaluationInterval <= 0 {
+		return fmt.Errorf("evaluation_interval must be greater than 0")
+	}
+	if args.Retention < args.EvaluationInterval {
+		return fmt.Errorf("retention must be greater than or equal to evaluation_interval")
+	}
+	return nil
+}
+
+// Validate implements syntax.Validator.
+func (r *RuleConfig) Validate() error {
+	if !model.IsValidMetricName(model.LabelValue(r.Record)) {
+		return fmt.Errorf("invalid record %q: it must be a valid metric name", r.Record)
+	}
+	if _, err := parser.ParseExpr(r.Expr); err != nil {
+		return fmt.Errorf("invalid expression for rule %q: %w", r.Record, err)
+	}
+	for name := range r.Labels {
+		if name == model.MetricNameLabel {
+			return fmt.Errorf("labels of rule %q can't set %s, use record instead", r.Record, model.MetricNameLabel)
+		}
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid label name %q for rule %q", name, r.Record)
+		}
+	}
+	return nil
+}
+
+// loadRulesFile returns the recording rules of a Prometheus rules file, in
+// the order of their groups. The alerting rules are returned separately, as
+// they're ignored.
+func loadRulesFile(path string) (recording []RuleConfig, alerting []string, err error) {
+	groups, errs := rulefmt.ParseFile(path)
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	for _, g := range groups.Groups {
+		for _, r := range g.Rules {
+			if r.Alert.Value != "" {
+				alerting = append(alerting, r.Alert.Value)
+				continue
+			}
+			recording = append(recording, RuleConfig{
+				Record: r.Record.Value,
+				Expr:   r.Expr.Value,
+				Labels: r.Labels,
+			})
+		}
+	}
+	return recording, alerting, nil
+}
@@ -407,6 +407,14 @@ func (c *Component) NotifyClusterChange() {
 	}
 }
 
+// ToPrometheusScrapeConfig converts the arguments of the component into a
+// Prometheus scrape_config. jobName is used when the arguments don't set a
+// job name. The targets and relabeling rules are left empty since they're
+// handled by other components.
+func ToPrometheusScrapeConfig(jobName string, args Arguments) *config.ScrapeConfig {
+	return getPromScrapeConfigs(jobName, args)
+}
+
 // Helper function to bridge the in-house configuration with the Prometheus
 // scrape_config.
 // As explained in the Config struct, the following fields are purposefully
@@ -528,13 +536,19 @@ func (c *Component) newInterceptor(ls labelstore.LabelStore) *prometheus.Interce
 		prometheus.WithAppendHook(func(globalRef storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
 			_, nextErr := next.Append(globalRef, l, t, v)
 			if c.debugDataPublisher.IsActive(componentID) {
-				c.debugDataPublisher.Publish(componentID, fmt.Sprintf("sample: ts=%d, labels=%s, value=%f", t, l, v))
+				c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.PrometheusMetric,
+					1,
+					l,
+					func() string { return fmt.Sprintf("sample: ts=%d, labels=%s, value=%f", t, l, v) },
+				))
 			}
 			return globalRef, nextErr
 		}),
 		prometheus.WithHistogramHook(func(globalRef storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
 			_, nextErr := next.AppendHistogram(globalRef, l, t, h, fh)
 			if c.debugDataPublisher.IsActive(componentID) {
+				// Histograms may be reused by the caller, so they are rendered right away.
 				var data string
 				if h != nil {
 					data = fmt.Sprintf("histogram: ts=%d, labels=%s, value=%s", t, l, h.String())
@@ -543,21 +557,40 @@ func (c *Component) newInterceptor(ls labelstore.LabelStore) *prometheus.Interce
 				} else {
 					data = fmt.Sprintf("histogram_with_no_value: ts=%d, labels=%s", t, l)
 				}
-				c.debugDataPublisher.Publish(componentID, data)
+				c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.PrometheusMetric,
+					1,
+					l,
+					func() string { return data },
+				))
 			}
 			return globalRef, nextErr
 		}),
 		prometheus.WithMetadataHook(func(globalRef storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
 			_, nextErr := next.UpdateMetadata(globalRef, l, m)
 			if c.debugDataPublisher.IsActive(componentID) {
-				c.debugDataPublisher.Publish(componentID, fmt.Sprintf("metadata: labels=%s, type=%q, unit=%q, help=%q", l, m.Type, m.Unit, m.Help))
+				c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.PrometheusMetric,
+					1,
+					l,
+					func() string {
+						return fmt.Sprintf("metadata: labels=%s, type=%q, unit=%q, help=%q", l, m.Type, m.Unit, m.Help)
+					},
+				))
 			}
 			return globalRef, nextErr
 		}),
 		prometheus.WithExemplarHook(func(globalRef storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
 			_, nextErr := next.AppendExemplar(globalRef, l, e)
 			if c.debugDataPublisher.IsActive(componentID) {
-				c.debugDataPublisher.Publish(componentID, fmt.Sprintf("exemplar: ts=%d, labels=%s, exemplar_labels=%s, value=%f", e.Ts, l, e.Labels, e.Value))
+				c.debugDataPublisher.Publish(componentID, livedebugging.NewData(
+					livedebugging.PrometheusMetric,
+					1,
+					l,
+					func() string {
+						return fmt.Sprintf("exemplar: ts=%d, labels=%s, exemplar_labels=%s, value=%f", e.Ts, l, e.Labels, e.Value)
+					},
+				))
 			}
 			return globalRef, nextErr
 		}),
@@ -6,16 +6,20 @@ import (
 	"fmt"
 
 	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/fluentbitconvert"
 	"github.com/grafana/alloy/internal/converter/internal/otelcolconvert"
 	"github.com/grafana/alloy/internal/converter/internal/prometheusconvert"
 	"github.com/grafana/alloy/internal/converter/internal/promtailconvert"
 	"github.com/grafana/alloy/internal/converter/internal/staticconvert"
+	"github.com/grafana/alloy/internal/converter/internal/vectorconvert"
 )
 
 // Input represents the type of config file being fed into the converter.
 type Input string
 
 const (
+	// InputFluentBit indicates that the input file is a Fluent Bit classic or YAML file.
+	InputFluentBit Input = "fluentbit"
 	// InputOtelCol indicates that the input file is an OpenTelemetry Collector YAML file.
 	InputOtelCol Input = "otelcol"
 	// InputPrometheus indicates that the input file is a prometheus YAML file.
@@ -24,13 +28,17 @@ const (
 	InputPromtail Input = "promtail"
 	// InputStatic indicates that the input file is a grafana agent static YAML file.
 	InputStatic Input = "static"
+	// InputVector indicates that the input file is a Vector TOML or YAML file.
+	InputVector Input = "vector"
 )
 
 var SupportedFormats = []string{
+	string(InputFluentBit),
 	string(InputOtelCol),
 	string(InputPrometheus),
 	string(InputPromtail),
 	string(InputStatic),
+	string(InputVector),
 }
 
 // Convert generates a Grafana Alloy config given an input configuration file.
@@ -50,6 +58,8 @@ var SupportedFormats = []string{
 // returned alongside the resulting config.
 func Convert(in []byte, kind Input, extraArgs []string) ([]byte, diag.Diagnostics) {
 	switch kind {
+	case InputFluentBit:
+		return fluentbitconvert.Convert(in, extraArgs)
 	case InputOtelCol:
 		return otelcolconvert.Convert(in, extraArgs)
 	case InputPrometheus:
@@ -58,9 +68,47 @@ func Convert(in []byte, kind Input, extraArgs []string) ([]byte, diag.Diagnostic
 		return promtailconvert.Convert(in, extraArgs)
 	case InputStatic:
 		return staticconvert.Convert(in, extraArgs)
+	case InputVector:
+		return vectorconvert.Convert(in, extraArgs)
 	}
 
 	var diags diag.Diagnostics
 	diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("unrecognized kind %q given to the config converter", kind))
 	return nil, diags
 }
+
+// Target represents the type of config file generated from a Grafana Alloy
+// config.
+type Target string
+
+const (
+	// TargetOtelCol indicates that the output file is an OpenTelemetry Collector YAML file.
+	TargetOtelCol Target = "otelcol"
+	// TargetPrometheus indicates that the output file is a prometheus YAML file.
+	TargetPrometheus Target = "prometheus"
+)
+
+var SupportedTargets = []string{
+	string(TargetOtelCol),
+	string(TargetPrometheus),
+}
+
+// ConvertFromAlloy generates a config file of the target format given a
+// Grafana Alloy config.
+//
+// Only the components which have an equivalent in the target format are
+// converted. Other components, and pipelines which the target format can't
+// represent, are reported with error diagnostics. As with Convert, the
+// resulting config should be reviewed before being used.
+func ConvertFromAlloy(in []byte, target Target) ([]byte, diag.Diagnostics) {
+	switch target {
+	case TargetOtelCol:
+		return otelcolconvert.FromAlloy(in)
+	case TargetPrometheus:
+		return prometheusconvert.FromAlloy(in)
+	}
+
+	var diags diag.Diagnostics
+	diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("unrecognized target %q given to the config converter", target))
+	return nil, diags
+}
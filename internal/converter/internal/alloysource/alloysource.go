@@ -0,0 +1,257 @@
+// Package alloysource decodes the components of an Alloy configuration so
+// that they can be converted to the configuration format of other programs.
+//
+// Components are never built: the exports of every component are replaced by
+// placeholders which identify the component they come from. Converters use
+// the placeholders to rebuild the pipelines of the configuration.
+package alloysource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/prometheus/prometheus/storage"
+	otelconsumer "go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/component/otelcol"
+	"github.com/grafana/alloy/internal/component/otelcol/auth"
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/syntax/ast"
+	"github.com/grafana/alloy/syntax/parser"
+	"github.com/grafana/alloy/syntax/vm"
+)
+
+// TargetLabel is the label of the placeholder targets exported by discovery
+// components. Its value is the ID of the component exporting the targets.
+const TargetLabel = "__alloy_component__"
+
+// unsupportedBlocks are the config blocks which change the components of the
+// configuration and therefore can't be ignored by a converter.
+var unsupportedBlocks = map[string]struct{}{
+	"argument": {},
+	"declare":  {},
+	"export":   {},
+	"foreach":  {},
+	"function": {},
+}
+
+// Component is a component block decoded from an Alloy configuration.
+type Component struct {
+	// Name of the component, such as prometheus.scrape.
+	Name string
+	// Label of the component block.
+	Label string
+	// Args holds a pointer to the decoded arguments of the component.
+	Args component.Arguments
+}
+
+// ID returns the ID of the component, such as prometheus.scrape.default.
+func (c *Component) ID() string {
+	return c.Name + "." + c.Label
+}
+
+// File is a decoded Alloy configuration.
+type File struct {
+	// Components in the order of the configuration.
+	Components []*Component
+
+	lookup   map[string]*Component
+	handlers map[string]*auth.Handler
+}
+
+// Component returns the component with the given ID, or nil if it doesn't
+// exist.
+func (f *File) Component(id string) *Component {
+	return f.lookup[id]
+}
+
+// AuthHandler returns the placeholder of the handler exported by the
+// otelcol.auth component with the given ID. Converters register the
+// extension of the component in the handler so that components using it can
+// be converted.
+func (f *File) AuthHandler(id string) *auth.Handler {
+	return f.handlers[id]
+}
+
+// Decode parses an Alloy configuration and decodes the arguments of the
+// components for which supported returns true. target is the name of the
+// format the configuration is converted to and is only used in diagnostics.
+//
+// Components which aren't supported or can't be decoded are reported with an
+// error diagnostic and left out of the returned file. The packages of the
+// supported components must be imported so that they are registered.
+func Decode(in []byte, target string, supported func(name string) bool) (*File, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	astFile, err := parser.ParseFile("", in)
+	if err != nil {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to parse Alloy config: %s", err))
+		return nil, diags
+	}
+
+	f := &File{
+		lookup:   make(map[string]*Component),
+		handlers: make(map[string]*auth.Handler),
+	}
+
+	var (
+		blocks []*ast.BlockStmt
+		regs   []component.Registration
+		scope  = make(map[string]any)
+	)
+	for _, stmt := range astFile.Body {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok {
+			diags.Add(diag.SeverityLevelError, fmt.Sprintf("top-level attributes can't be converted to %s", target))
+			continue
+		}
+
+		name := block.GetBlockName()
+		reg, ok := component.Get(name)
+		switch {
+		case ok && block.Label == "":
+			diags.Add(diag.SeverityLevelError, fmt.Sprintf("component %s must have a label", name))
+		case ok && supported(name):
+			blocks = append(blocks, block)
+			regs = append(regs, reg)
+			setExports(scope, strings.Split(name+"."+block.Label, "."), f.placeholderExports(reg, name+"."+block.Label))
+		case ok:
+			// The exports of unsupported components are still added to the
+			// scope so that only the unsupported component is reported.
+			setExports(scope, strings.Split(name+"."+block.Label, "."), f.placeholderExports(reg, name+"."+block.Label))
+			diags.Add(diag.SeverityLevelError, fmt.Sprintf("component %s.%s has no equivalent in %s", name, block.Label, target))
+		case isUnsupportedBlock(name):
+			diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s blocks can't be converted to %s", name, target))
+		default:
+			if len(block.Name) > 1 || block.Label != "" {
+				diags.Add(diag.SeverityLevelError, fmt.Sprintf("unknown component %s can't be converted to %s", name, target))
+				continue
+			}
+			diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the %s block has no equivalent in %s and is ignored", name, target))
+		}
+	}
+
+	for i, block := range blocks {
+		c := &Component{
+			Name:  regs[i].Name,
+			Label: block.Label,
+			Args:  regs[i].CloneArguments(),
+		}
+		if err := vm.New(block.Body).Evaluate(vm.NewScope(scope), c.Args); err != nil {
+			diags.Add(diag.SeverityLevelError, fmt.Sprintf("failed to decode %s: %s", c.ID(), err))
+			continue
+		}
+		f.Components = append(f.Components, c)
+		f.lookup[c.ID()] = c
+	}
+	return f, diags
+}
+
+func isUnsupportedBlock(name string) bool {
+	if strings.HasPrefix(name, "import.") {
+		return true
+	}
+	_, ok := unsupportedBlocks[name]
+	return ok
+}
+
+// setExports stores exports in the nested scope under the path of the
+// component ID, so that expressions such as prometheus.remote_write.default.receiver
+// resolve to the placeholders.
+func setExports(scope map[string]any, path []string, exports any) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := scope[key].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			scope[key] = next
+		}
+		scope = next
+	}
+	scope[path[len(path)-1]] = exports
+}
+
+var (
+	appendableType = reflect.TypeOf((*storage.Appendable)(nil)).Elem()
+	consumerType   = reflect.TypeOf((*otelcol.Consumer)(nil)).Elem()
+	handlerType    = reflect.TypeOf((*auth.Handler)(nil))
+	targetsType    = reflect.TypeOf([]discovery.Target{})
+)
+
+// placeholderExports returns the exports of the component with their zero
+// value, except for the fields other components use to build pipelines.
+func (f *File) placeholderExports(reg component.Registration, id string) any {
+	if reg.Exports == nil {
+		return map[string]any{}
+	}
+
+	exportsType := reflect.TypeOf(reg.Exports)
+	exports := reflect.New(exportsType).Elem()
+	if exportsType.Kind() != reflect.Struct {
+		return exports.Interface()
+	}
+
+	for i := 0; i < exportsType.NumField(); i++ {
+		field := exports.Field(i)
+		switch exportsType.Field(i).Type {
+		case appendableType:
+			field.Set(reflect.ValueOf(Appendable{ID: id}))
+		case consumerType:
+			field.Set(reflect.ValueOf(Consumer{ID: id}))
+		case handlerType:
+			handler := auth.NewHandler(id)
+			f.handlers[id] = handler
+			field.Set(reflect.ValueOf(handler))
+		case targetsType:
+			field.Set(reflect.ValueOf([]discovery.Target{
+				discovery.NewTargetFromMap(map[string]string{TargetLabel: id}),
+			}))
+		}
+	}
+	return exports.Interface()
+}
+
+// ComponentID returns the ID of the component which exported the target if
+// it's a placeholder.
+func ComponentID(t discovery.Target) (string, bool) {
+	return t.Get(TargetLabel)
+}
+
+// Appendable is the placeholder of the storage.Appendable exported by a
+// component.
+type Appendable struct {
+	// ID of the component exporting the appendable.
+	ID string
+}
+
+var _ storage.Appendable = Appendable{}
+
+// Appender implements storage.Appendable. It must not be called.
+func (Appendable) Appender(context.Context) storage.Appender { return nil }
+
+// Consumer is the placeholder of the otelcol.Consumer exported by a
+// component.
+type Consumer struct {
+	// ID of the component exporting the consumer.
+	ID string
+}
+
+var _ otelcol.Consumer = Consumer{}
+
+// Capabilities implements otelcol.Consumer.
+func (Consumer) Capabilities() otelconsumer.Capabilities { return otelconsumer.Capabilities{} }
+
+// ConsumeTraces implements otelcol.Consumer. It must not be called.
+func (Consumer) ConsumeTraces(context.Context, ptrace.Traces) error { return nil }
+
+// ConsumeMetrics implements otelcol.Consumer. It must not be called.
+func (Consumer) ConsumeMetrics(context.Context, pmetric.Metrics) error { return nil }
+
+// ConsumeLogs implements otelcol.Consumer. It must not be called.
+func (Consumer) ConsumeLogs(context.Context, plog.Logs) error { return nil }
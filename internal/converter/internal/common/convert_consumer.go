@@ -0,0 +1,28 @@
+package common
+
+import (
+	"github.com/grafana/alloy/internal/component/otelcol"
+	"github.com/grafana/alloy/syntax"
+	"github.com/grafana/alloy/syntax/token"
+	"github.com/grafana/alloy/syntax/token/builder"
+)
+
+// ConvertConsumer allows us to override how the otelcol.Consumer is tokenized.
+// See ConvertAppendable as another example with more details in comments.
+type ConvertConsumer struct {
+	otelcol.Consumer
+
+	Expr string
+}
+
+var _ otelcol.Consumer = (*ConvertConsumer)(nil)
+var _ builder.Tokenizer = ConvertConsumer{}
+var _ syntax.Capsule = ConvertConsumer{}
+
+func (f ConvertConsumer) AlloyCapsule() {}
+func (f ConvertConsumer) AlloyTokenize() []builder.Token {
+	return []builder.Token{{
+		Tok: token.STRING,
+		Lit: f.Expr,
+	}}
+}
@@ -0,0 +1,304 @@
+package fluentbitconvert
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+)
+
+// section is a section of a Fluent Bit config, such as an [INPUT], or a
+// plugin of the pipeline of a YAML config.
+type section struct {
+	// kind is the lower-cased name of the section, such as input.
+	kind    string
+	entries []entry
+	// used tracks the keys which were read during the conversion.
+	used map[string]struct{}
+}
+
+// entry is a key-value pair of a section. Keys are case-insensitive and
+// stored lower-cased. Some keys, such as the rules of the modify filter, can
+// be repeated.
+type entry struct {
+	key, value string
+}
+
+func newSection(kind string) *section {
+	return &section{kind: strings.ToLower(kind), used: make(map[string]struct{})}
+}
+
+func (s *section) add(key, value string) {
+	s.entries = append(s.entries, entry{key: strings.ToLower(key), value: value})
+}
+
+// get returns the last value of key, or def if it isn't set.
+func (s *section) get(key, def string) string {
+	values := s.getAll(key)
+	if len(values) == 0 {
+		return def
+	}
+	return values[len(values)-1]
+}
+
+// getAll returns every value of key.
+func (s *section) getAll(key string) []string {
+	s.used[key] = struct{}{}
+	var values []string
+	for _, e := range s.entries {
+		if e.key == key {
+			values = append(values, e.value)
+		}
+	}
+	return values
+}
+
+// getBool returns the boolean value of key, or def if it isn't set.
+func (s *section) getBool(key string, def bool) (bool, error) {
+	value := s.get(key, "")
+	switch strings.ToLower(value) {
+	case "":
+		return def, nil
+	case "on", "true", "yes", "1":
+		return true, nil
+	case "off", "false", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q for %s", value, key)
+}
+
+// ignore marks keys as used when they have no effect on the converted config.
+func (s *section) ignore(keys ...string) {
+	for _, key := range keys {
+		s.used[key] = struct{}{}
+	}
+}
+
+// unusedKeys returns the keys which weren't read during the conversion.
+func (s *section) unusedKeys() []string {
+	var keys []string
+	for _, e := range s.entries {
+		if _, ok := s.used[e.key]; !ok && !slices.Contains(keys, e.key) {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+var variableRegexp = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// parseConfig parses a Fluent Bit config in the classic or YAML format.
+func parseConfig(in []byte) ([]*section, diag.Diagnostics) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(in, &doc); err == nil && isYAMLConfig(&doc) {
+		return parseYAMLConfig(&doc)
+	}
+	return parseClassicConfig(in)
+}
+
+// isYAMLConfig returns true if the document is a YAML mapping with a pipeline
+// key, which is required in Fluent Bit YAML configs.
+func isYAMLConfig(doc *yaml.Node) bool {
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return false
+	}
+	root := doc.Content[0]
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == "pipeline" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClassicConfig parses a Fluent Bit config in the classic format, where
+// sections such as [INPUT] are followed by indented key-value pairs.
+func parseClassicConfig(in []byte) ([]*section, diag.Diagnostics) {
+	var (
+		diags    diag.Diagnostics
+		sections []*section
+		current  *section
+		vars     = make(map[string]string)
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(in))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			current = newSection(strings.TrimSpace(line[1 : len(line)-1]))
+			sections = append(sections, current)
+
+		case strings.HasPrefix(line, "@"):
+			command, args, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(command) {
+			case "@SET":
+				name, value, ok := strings.Cut(strings.TrimSpace(args), "=")
+				if !ok {
+					diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("line %d: invalid @SET command %q", lineNum, line))
+					return nil, diags
+				}
+				vars[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			case "@INCLUDE":
+				diags.Add(diag.SeverityLevelError, fmt.Sprintf("line %d: @INCLUDE isn't supported, the included files must be merged into the converted config", lineNum))
+			default:
+				diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("line %d: unknown command %s", lineNum, command))
+				return nil, diags
+			}
+
+		default:
+			if current == nil {
+				diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("line %d: the key-value pair %q isn't in a section", lineNum, line))
+				return nil, diags
+			}
+			key, value := line, ""
+			if idx := strings.IndexAny(line, " \t"); idx >= 0 {
+				key, value = line[:idx], strings.TrimSpace(line[idx+1:])
+			}
+			current.add(key, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to read Fluent Bit config: %s", err))
+		return nil, diags
+	}
+
+	diags.AddAll(substituteVariables(sections, vars))
+	return sections, diags
+}
+
+// parseYAMLConfig parses a Fluent Bit config in the YAML format.
+func parseYAMLConfig(doc *yaml.Node) ([]*section, diag.Diagnostics) {
+	var (
+		diags    diag.Diagnostics
+		sections []*section
+		vars     = make(map[string]string)
+	)
+
+	root := doc.Content[0]
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		switch key {
+		case "env":
+			if err := value.Decode(&vars); err != nil {
+				diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to parse env: %s", err))
+				return nil, diags
+			}
+		case "service":
+			s, err := yamlSection("service", value)
+			if err != nil {
+				diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to parse service: %s", err))
+				return nil, diags
+			}
+			sections = append(sections, s)
+		case "parsers":
+			parsed, err := yamlSections("parser", value)
+			if err != nil {
+				diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to parse parsers: %s", err))
+				return nil, diags
+			}
+			sections = append(sections, parsed...)
+		case "pipeline":
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				kind := strings.TrimSuffix(value.Content[j].Value, "s")
+				switch kind {
+				case "input", "filter", "output":
+				default:
+					diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("unknown pipeline section %q", value.Content[j].Value))
+					return nil, diags
+				}
+				parsed, err := yamlSections(kind, value.Content[j+1])
+				if err != nil {
+					diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to parse pipeline %ss: %s", kind, err))
+					return nil, diags
+				}
+				sections = append(sections, parsed...)
+			}
+		case "includes":
+			diags.Add(diag.SeverityLevelError, "includes aren't supported, the included files must be merged into the converted config")
+		default:
+			sections = append(sections, newSection(key))
+		}
+	}
+
+	diags.AddAll(substituteVariables(sections, vars))
+	return sections, diags
+}
+
+// yamlSections parses a sequence of YAML mappings.
+func yamlSections(kind string, node *yaml.Node) ([]*section, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: expected a list", node.Line)
+	}
+	sections := make([]*section, 0, len(node.Content))
+	for _, item := range node.Content {
+		s, err := yamlSection(kind, item)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
+
+// yamlSection parses a YAML mapping. Lists of scalars are converted to
+// repeated keys, and nested mappings are kept as keys without a value so that
+// they are reported as unsupported.
+func yamlSection(kind string, node *yaml.Node) (*section, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	s := newSection(kind)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch value.Kind {
+		case yaml.ScalarNode:
+			s.add(key, value.Value)
+		case yaml.SequenceNode:
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("line %d: expected a list of values for %s", item.Line, key)
+				}
+				s.add(key, item.Value)
+			}
+		default:
+			s.add(key, "")
+		}
+	}
+	return s, nil
+}
+
+// substituteVariables replaces the ${NAME} references with the value of the
+// variables. References to other variables are environment variables which
+// Fluent Bit resolves at runtime, so they are kept and reported.
+func substituteVariables(sections []*section, vars map[string]string) diag.Diagnostics {
+	var (
+		diags    diag.Diagnostics
+		reported = make(map[string]struct{})
+	)
+	for _, s := range sections {
+		for i, e := range s.entries {
+			s.entries[i].value = variableRegexp.ReplaceAllStringFunc(e.value, func(ref string) string {
+				name := ref[2 : len(ref)-1]
+				if value, ok := vars[name]; ok {
+					return value
+				}
+				if _, ok := reported[name]; !ok {
+					reported[name] = struct{}{}
+					diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the environment variable %s isn't resolved, its reference must be replaced manually, for example with sys.env(%q)", name, name))
+				}
+				return ref
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,215 @@
+package fluentbitconvert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+)
+
+// convertFilter converts a [FILTER] section into stages, which are added to
+// the route of every log input the filter matches.
+func (p *pipeline) convertFilter(s *section) {
+	if !p.hasName(s) {
+		return
+	}
+
+	var (
+		name  = s.get("name", "")
+		label = p.label(s)
+		desc  = fmt.Sprintf("filter %q", label)
+	)
+
+	var convert func(in *input) ([]logpipeline.Stage, error)
+	switch name {
+	case "parser":
+		keyName := s.get("key_name", "")
+		parsers := s.getAll("parser")
+		if len(parsers) > 1 {
+			p.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s: only the first of the parsers is converted, Fluent Bit tries the next parsers when the first one fails", desc))
+		}
+		// Fluent Bit keeps or removes the other fields of the record, which
+		// are only used to build labels in Alloy.
+		s.ignore("reserve_data", "preserve_key", "unescape_key")
+		if keyName == "" || len(parsers) == 0 {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the Key_Name and Parser options are required", desc))
+			return
+		}
+		convert = func(in *input) ([]logpipeline.Stage, error) {
+			return p.parserStages(parsers[0], fieldSource(in, keyName))
+		}
+
+	case "modify":
+		convert = p.modifyStages(s, desc)
+
+	case "grep":
+		convert = p.grepStages(s, desc)
+
+	case "lua":
+		p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: Lua scripts can't be converted, the script must be rewritten with loki.process stages", desc))
+		s.ignore(keys(s)...)
+		return
+
+	default:
+		p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the %s filter has no equivalent in Alloy", desc, name))
+		s.ignore(keys(s)...)
+		return
+	}
+	if convert == nil {
+		s.ignore(keys(s)...)
+		return
+	}
+
+	for _, in := range p.matchingInputs(s, desc) {
+		if in.source.Type == logpipeline.SourceScrape {
+			continue
+		}
+		stages, err := convert(in)
+		if err != nil {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", desc, err))
+			return
+		}
+		p.addStages(in, stages)
+	}
+}
+
+// fieldSource returns the name of the field holding the value of a record
+// key, where an empty name is the log line.
+func fieldSource(in *input, key string) string {
+	if key == in.logKey {
+		return ""
+	}
+	return key
+}
+
+// onigmoGroupRegexp matches the named groups of Onigmo regular expressions,
+// which are written (?P<name>...) in RE2.
+var onigmoGroupRegexp = regexp.MustCompile(`\(\?<([a-zA-Z_][a-zA-Z0-9_]*)>`)
+
+// parserStages converts a [PARSER] section into stages which parse the field
+// source.
+func (p *pipeline) parserStages(name string, source string) ([]logpipeline.Stage, error) {
+	parser, ok := p.parsers[name]
+	if !ok {
+		return nil, fmt.Errorf("the parser %s isn't defined, its [PARSER] section must be added to the converted config", name)
+	}
+
+	var stages []logpipeline.Stage
+	switch format := parser.get("format", ""); format {
+	case "json":
+		stages = append(stages, logpipeline.JSONStage{Source: source})
+	case "logfmt":
+		stages = append(stages, logpipeline.LogfmtStage{Source: source})
+	case "regex":
+		expr := onigmoGroupRegexp.ReplaceAllString(parser.get("regex", ""), "(?P<$1>")
+		if _, err := regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("the regex of the parser %s isn't supported by Go: %w", name, err)
+		}
+		stages = append(stages, logpipeline.RegexStage{Source: source, Expression: expr})
+	default:
+		return nil, fmt.Errorf("the %s format of the parser %s isn't supported", format, name)
+	}
+
+	// The unsupported options of the parsers are reported once all the
+	// sections are converted.
+	p.usedParsers[name] = parser
+	parser.ignore("name", "time_keep", "time_offset", "types", "decode_field", "decode_field_as", "skip_empty_values")
+	if timeKey := parser.get("time_key", ""); timeKey != "" {
+		layout, err := logpipeline.StrptimeToLayout(parser.get("time_format", ""))
+		if err != nil {
+			return nil, fmt.Errorf("parser %s: %w", name, err)
+		}
+		stages = append(stages, logpipeline.TimestampStage{Source: timeKey, Format: layout})
+	}
+	return stages, nil
+}
+
+// modifyStages converts the rules of a modify filter. Records don't have
+// fields in Alloy, so fields added to records are converted to labels.
+func (p *pipeline) modifyStages(s *section, desc string) func(in *input) ([]logpipeline.Stage, error) {
+	var (
+		staticLabels = make(map[string]string)
+		dropLabels   []string
+	)
+	for _, e := range s.entries {
+		switch e.key {
+		case "name", "match", "match_regex", "alias":
+		case "add", "set":
+			name, value, ok := strings.Cut(e.value, " ")
+			if !ok {
+				p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: invalid %s rule %q", desc, e.key, e.value))
+				continue
+			}
+			staticLabels[name] = strings.TrimSpace(value)
+		case "remove":
+			dropLabels = append(dropLabels, e.value)
+		default:
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the %s rule of the modify filter isn't supported", desc, e.key))
+		}
+	}
+	s.ignore(keys(s)...)
+	if len(staticLabels) > 0 || len(dropLabels) > 0 {
+		p.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s: the fields added or removed by the modify filter are converted to labels", desc))
+	}
+
+	var stages []logpipeline.Stage
+	if len(staticLabels) > 0 {
+		stages = append(stages, logpipeline.StaticLabelsStage{Values: staticLabels})
+	}
+	if len(dropLabels) > 0 {
+		stages = append(stages, logpipeline.LabelDropStage{Names: dropLabels})
+	}
+	return func(*input) ([]logpipeline.Stage, error) {
+		return stages, nil
+	}
+}
+
+// grepStages converts the rules of a grep filter. Regex rules keep the
+// records matching all of them, and Exclude rules drop the records matching
+// any of them.
+func (p *pipeline) grepStages(s *section, desc string) func(in *input) ([]logpipeline.Stage, error) {
+	type rule struct {
+		exclude   bool
+		key, expr string
+	}
+	var rules []rule
+	for _, e := range s.entries {
+		if e.key != "regex" && e.key != "exclude" {
+			continue
+		}
+		key, expr, ok := strings.Cut(e.value, " ")
+		expr = strings.TrimSpace(expr)
+		if !ok || expr == "" {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: invalid %s rule %q", desc, e.key, e.value))
+			return nil
+		}
+		if _, err := regexp.Compile(expr); err != nil {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the regular expression %q isn't supported by Go: %s", desc, expr, err))
+			return nil
+		}
+		rules = append(rules, rule{exclude: e.key == "exclude", key: strings.TrimPrefix(key, "$"), expr: expr})
+	}
+	s.ignore("regex", "exclude")
+	if strings.EqualFold(s.get("logical_op", "legacy"), "or") {
+		p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the Or Logical_Op isn't supported", desc))
+		return nil
+	}
+
+	return func(in *input) ([]logpipeline.Stage, error) {
+		var stages []logpipeline.Stage
+		for _, r := range rules {
+			source := fieldSource(in, r.key)
+			switch {
+			case r.exclude:
+				stages = append(stages, logpipeline.DropStage{Source: source, Expression: r.expr})
+			case source == "":
+				stages = append(stages, logpipeline.KeepStage{Expression: r.expr})
+			default:
+				return nil, fmt.Errorf("the Regex rule on the %s key can't be converted, only the log line can be filtered", r.key)
+			}
+		}
+		return stages, nil
+	}
+}
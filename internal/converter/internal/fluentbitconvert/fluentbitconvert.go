@@ -0,0 +1,199 @@
+// Package fluentbitconvert converts Fluent Bit configurations, in the classic
+// or YAML format, into Alloy configurations.
+package fluentbitconvert
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/common"
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+	"github.com/grafana/alloy/syntax/token/builder"
+)
+
+// Convert implements a Fluent Bit config converter.
+//
+// extraArgs are supported to mirror the other converter params due to shared
+// testing code but they should be passed empty to this converter.
+func Convert(in []byte, extraArgs []string) ([]byte, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if len(extraArgs) > 0 {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("extra arguments are not supported for the fluentbit converter: %s", extraArgs))
+		return nil, diags
+	}
+
+	sections, parseDiags := parseConfig(in)
+	diags.AddAll(parseDiags)
+	if sections == nil {
+		return nil, diags
+	}
+
+	f := builder.NewFile()
+	cfg, buildDiags := buildPipeline(sections)
+	diags.AddAll(buildDiags)
+	diags.AddAll(logpipeline.Append(f, cfg))
+	diags.AddAll(common.ValidateNodes(f))
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to render Alloy config: %s", err.Error()))
+		return nil, diags
+	}
+
+	if len(buf.Bytes()) == 0 {
+		return nil, diags
+	}
+
+	prettyByte, newDiags := common.PrettyPrint(buf.Bytes())
+	diags.AddAll(newDiags)
+	return prettyByte, diags
+}
+
+// input is a converted [INPUT] section.
+type input struct {
+	tag    string
+	source *logpipeline.Source
+	// logKey is the key of the record which holds the log line.
+	logKey string
+}
+
+// pipeline holds the state of the conversion of a Fluent Bit config.
+type pipeline struct {
+	diags       diag.Diagnostics
+	parsers     map[string]*section
+	usedParsers map[string]*section
+	inputs      []*input
+	labels      map[string]int
+}
+
+// buildPipeline converts the sections of a Fluent Bit config into a log
+// pipeline. Fluent Bit routes records by matching their tag against the Match
+// and Match_Regex options of filters and outputs, so every input becomes a
+// source with a single route holding the stages of the matching filters and
+// the matching outputs.
+func buildPipeline(sections []*section) (*logpipeline.Config, diag.Diagnostics) {
+	p := &pipeline{
+		parsers:     make(map[string]*section),
+		usedParsers: make(map[string]*section),
+		labels:      make(map[string]int),
+	}
+	cfg := &logpipeline.Config{}
+
+	for _, s := range sections {
+		if s.kind == "parser" {
+			p.parsers[s.get("name", "")] = s
+		}
+	}
+
+	var inputCount int
+	for _, s := range sections {
+		switch s.kind {
+		case "service", "parser":
+			// The options of the service don't affect the pipeline, and the
+			// parsers are converted where they are used.
+			continue
+		case "input":
+			in := p.convertInput(s, inputCount)
+			inputCount++
+			if in != nil {
+				p.inputs = append(p.inputs, in)
+				cfg.Sources = append(cfg.Sources, in.source)
+			}
+		case "filter":
+			p.convertFilter(s)
+		case "output":
+			if sink := p.convertOutput(s); sink != nil {
+				cfg.Sinks = append(cfg.Sinks, sink)
+			}
+		default:
+			p.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the [%s] section isn't supported and is ignored", strings.ToUpper(s.kind)))
+			continue
+		}
+		p.reportUnusedKeys(s)
+	}
+	for _, s := range sections {
+		if s.kind == "parser" && p.usedParsers[s.get("name", "")] == s {
+			p.reportUnusedKeys(s)
+		}
+	}
+	return cfg, p.diags
+}
+
+// hasName returns whether a section has a Name option, and reports an error
+// when it doesn't, since the plugin it configures is unknown.
+func (p *pipeline) hasName(s *section) bool {
+	if s.get("name", "") != "" {
+		return true
+	}
+	p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("[%s] section without a Name option is ignored", strings.ToUpper(s.kind)))
+	// The other options of the section aren't reported as unsupported.
+	for _, e := range s.entries {
+		s.ignore(e.key)
+	}
+	return false
+}
+
+// label returns a unique component label for a plugin.
+func (p *pipeline) label(s *section) string {
+	if alias := s.get("alias", ""); alias != "" {
+		return common.SanitizeIdentifierPanics(alias)
+	}
+	name := s.get("name", "")
+	label := common.LabelWithIndex(p.labels[name], common.SanitizeIdentifierPanics(name))
+	p.labels[name]++
+	return label
+}
+
+// matchingInputs returns the inputs whose tag matches the Match or
+// Match_Regex option of a filter or output.
+func (p *pipeline) matchingInputs(s *section, name string) []*input {
+	var (
+		pattern  = s.get("match", "")
+		expr     = s.get("match_regex", "")
+		matching []*input
+	)
+	var re *regexp.Regexp
+	switch {
+	case expr != "":
+		var err error
+		if re, err = regexp.Compile(expr); err != nil {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: invalid Match_Regex: %s", name, err))
+			return nil
+		}
+	case pattern != "":
+		re = wildcardRegexp(pattern)
+	default:
+		p.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s doesn't have a Match or Match_Regex option and doesn't receive any record", name))
+		return nil
+	}
+
+	for _, in := range p.inputs {
+		if re.MatchString(in.tag) {
+			matching = append(matching, in)
+		}
+	}
+	if len(matching) == 0 {
+		p.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s doesn't match the tag of any input", name))
+	}
+	return matching
+}
+
+// wildcardRegexp converts a Match pattern, where * matches any sequence of
+// characters, into a regular expression.
+func wildcardRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+func (p *pipeline) reportUnusedKeys(s *section) {
+	for _, key := range s.unusedKeys() {
+		p.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the %s option of the %s %s isn't supported and is ignored", key, s.get("name", ""), s.kind))
+	}
+}
@@ -0,0 +1,13 @@
+package fluentbitconvert_test
+
+import (
+	"testing"
+
+	"github.com/grafana/alloy/internal/converter/internal/fluentbitconvert"
+	"github.com/grafana/alloy/internal/converter/internal/test_common"
+)
+
+func TestConvert(t *testing.T) {
+	test_common.TestDirectory(t, "testdata", ".conf", true, []string{}, fluentbitconvert.Convert)
+	test_common.TestDirectory(t, "testdata", ".yaml", true, []string{}, fluentbitconvert.Convert)
+}
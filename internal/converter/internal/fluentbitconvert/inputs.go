@@ -0,0 +1,156 @@
+package fluentbitconvert
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+)
+
+// convertInput converts an [INPUT] section. index is the position of the
+// input in the config, which Fluent Bit uses in the default tag.
+func (p *pipeline) convertInput(s *section, index int) *input {
+	if !p.hasName(s) {
+		return nil
+	}
+
+	var (
+		name  = s.get("name", "")
+		label = p.label(s)
+		desc  = fmt.Sprintf("input %q", label)
+		in    = &input{
+			tag:    s.get("tag", fmt.Sprintf("%s.%d", name, index)),
+			source: &logpipeline.Source{Label: label},
+			logKey: "log",
+		}
+	)
+
+	switch name {
+	case "tail":
+		in.source.Type = logpipeline.SourceFile
+		in.source.Paths = splitList(s.get("path", ""))
+		in.source.ExcludePaths = splitList(s.get("exclude_path", ""))
+		readFromHead, err := s.getBool("read_from_head", false)
+		if err != nil {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", desc, err))
+		}
+		in.source.TailFromEnd = !readFromHead
+		in.logKey = s.get("key", "log")
+		// The offsets database and the buffering options are handled by
+		// loki.source.file, which stores positions in its data directory.
+		s.ignore("db", "db.sync", "db.locking", "db.journal_mode", "mem_buf_limit", "buffer_chunk_size", "buffer_max_size", "refresh_interval", "rotate_wait", "skip_long_lines")
+
+		if parser := s.get("parser", ""); parser != "" {
+			stages, err := p.parserStages(parser, "")
+			if err != nil {
+				p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", desc, err))
+			}
+			p.addStages(in, stages)
+		}
+		if len(in.source.Paths) == 0 {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the Path option is required", desc))
+		}
+
+	case "systemd":
+		in.source.Type = logpipeline.SourceJournal
+		in.source.JournalPath = s.get("path", "")
+		in.source.Matches = s.getAll("systemd_filter")
+		in.logKey = "MESSAGE"
+		s.ignore("db", "db.sync", "mem_buf_limit", "max_entries", "max_fields", "read_from_tail", "strip_underscores")
+		// The journal combines matches on different fields with a logical
+		// AND and matches on the same field with a logical OR, while Fluent
+		// Bit combines all the filters with a logical OR by default.
+		fields := make(map[string]struct{})
+		for _, match := range in.source.Matches {
+			field, _, _ := strings.Cut(match, "=")
+			fields[field] = struct{}{}
+		}
+		if strings.EqualFold(s.get("systemd_filter_type", "or"), "or") && len(fields) > 1 {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: Systemd_Filter on different fields can only be combined with a logical AND, set Systemd_Filter_Type to And", desc))
+		}
+
+	case "prometheus_scrape":
+		in.source.Type = logpipeline.SourceScrape
+		host := s.get("host", "127.0.0.1")
+		port := s.get("port", "80")
+		in.source.Targets = []string{net.JoinHostPort(host, port)}
+		in.source.MetricsPath = s.get("metrics_path", "")
+		if interval := s.get("scrape_interval", ""); interval != "" {
+			d, err := parseDuration(interval)
+			if err != nil {
+				p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: invalid Scrape_Interval: %s", desc, err))
+			}
+			in.source.ScrapeInterval = d
+		}
+
+	case "forward":
+		in.source.Type = logpipeline.SourceAPI
+		in.source.ListenAddress = s.get("listen", "")
+		if port := s.get("port", ""); port != "" {
+			n, err := strconv.Atoi(port)
+			if err != nil {
+				p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: invalid Port %q", desc, port))
+			}
+			in.source.ListenPort = n
+		}
+		s.ignore("buffer_chunk_size", "buffer_max_size", "mem_buf_limit")
+		p.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s: Alloy doesn't support the Fluent forward protocol, so the forward input is converted to a loki.source.api component, and the clients must be changed to send logs with the Loki push API", desc))
+
+	default:
+		p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the %s input has no equivalent in Alloy", desc, name))
+		s.ignore(keys(s)...)
+		return nil
+	}
+	return in
+}
+
+// addStages adds stages to the route of an input.
+func (p *pipeline) addStages(in *input, stages []logpipeline.Stage) {
+	if len(stages) == 0 {
+		return
+	}
+	if len(in.source.Routes) == 0 {
+		in.source.Routes = []*logpipeline.Route{{}}
+	}
+	in.source.Routes[0].Stages = append(in.source.Routes[0].Stages, stages...)
+}
+
+// addSink adds a sink to the route of an input.
+func (p *pipeline) addSink(in *input, label string) {
+	if len(in.source.Routes) == 0 {
+		in.source.Routes = []*logpipeline.Route{{}}
+	}
+	in.source.Routes[0].Sinks = append(in.source.Routes[0].Sinks, label)
+}
+
+// splitList splits a comma-separated list of values.
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseDuration parses a duration, which Fluent Bit accepts in seconds when
+// there is no unit.
+func parseDuration(value string) (time.Duration, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func keys(s *section) []string {
+	keys := make([]string, 0, len(s.entries))
+	for _, e := range s.entries {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
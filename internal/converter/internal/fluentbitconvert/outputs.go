@@ -0,0 +1,154 @@
+package fluentbitconvert
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+)
+
+// convertOutput converts an [OUTPUT] section into a sink, which is added to
+// the route of every input the output matches.
+func (p *pipeline) convertOutput(s *section) *logpipeline.Sink {
+	if !p.hasName(s) {
+		return nil
+	}
+
+	var (
+		name  = s.get("name", "")
+		label = p.label(s)
+		desc  = fmt.Sprintf("output %q", label)
+		sink  = &logpipeline.Sink{Label: label}
+	)
+
+	// labelFields holds the labels read from record fields by the loki
+	// output, which are extracted by the routes of the inputs.
+	var labelFields map[string]string
+
+	switch name {
+	case "loki":
+		sink.Type = logpipeline.SinkLoki
+		p.convertHTTPOptions(s, sink, desc, "3100", "/loki/api/v1/push")
+		sink.TenantID = s.get("tenant_id", "")
+		sink.BearerToken = s.get("bearer_token", "")
+
+		sink.ExternalLabels = make(map[string]string)
+		labelFields = make(map[string]string)
+		labels := append(splitList(s.get("labels", "")), splitList(s.get("label_keys", ""))...)
+		for _, l := range labels {
+			name, value, hasName := strings.Cut(l, "=")
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+			if !hasName {
+				name, value = "", name
+			}
+			if !strings.HasPrefix(value, "$") {
+				if !hasName {
+					p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: invalid label %q", desc, l))
+					continue
+				}
+				sink.ExternalLabels[name] = value
+				continue
+			}
+
+			field := strings.TrimPrefix(value, "$")
+			if strings.ContainsAny(field, "[]'\"") {
+				p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the label %s reads a nested key of the record, which isn't supported", desc, l))
+				continue
+			}
+			if name == "" {
+				name = labelNameRegexp.ReplaceAllString(field, "_")
+			}
+			labelFields[name] = field
+		}
+		if len(labels) == 0 {
+			// The loki output sets this label when no label is configured.
+			sink.ExternalLabels["job"] = "fluent-bit"
+		}
+		// Alloy sends the log line as is, rather than encoding the other
+		// fields of the record in it.
+		s.ignore("line_format", "drop_single_key")
+
+	case "prometheus_remote_write":
+		sink.Type = logpipeline.SinkRemoteWrite
+		p.convertHTTPOptions(s, sink, desc, "80", "/")
+		sink.Headers = p.pairs(s, "header", desc)
+		sink.ExternalLabels = p.pairs(s, "add_label", desc)
+
+	case "opentelemetry":
+		sink.Type = logpipeline.SinkOTLP
+		p.convertHTTPOptions(s, sink, desc, "80", "")
+		sink.Headers = p.pairs(s, "header", desc)
+		if uri := s.get("logs_uri", "/v1/logs"); uri != "/v1/logs" {
+			sink.LogsURL = sink.URL + uri
+		}
+		if uri := s.get("metrics_uri", "/v1/metrics"); uri != "/v1/metrics" {
+			sink.MetricsURL = sink.URL + uri
+		}
+		s.ignore("traces_uri")
+
+	default:
+		p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the %s output has no equivalent in Alloy", desc, name))
+		s.ignore(keys(s)...)
+		return nil
+	}
+
+	for _, in := range p.matchingInputs(s, desc) {
+		// Like Fluent Bit, outputs ignore the records of the inputs which
+		// produce a kind of data they don't support.
+		metrics := in.source.Type == logpipeline.SourceScrape
+		if (metrics && sink.Type == logpipeline.SinkLoki) || (!metrics && sink.Type == logpipeline.SinkRemoteWrite) {
+			continue
+		}
+		p.addSink(in, label)
+		if len(labelFields) > 0 {
+			p.addStages(in, []logpipeline.Stage{logpipeline.LabelsStage{Values: labelFields}})
+		}
+	}
+	return sink
+}
+
+var labelNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// convertHTTPOptions sets the URL of the sink and its HTTP client options.
+func (p *pipeline) convertHTTPOptions(s *section, sink *logpipeline.Sink, desc, defaultPort, defaultURI string) {
+	tls, err := s.getBool("tls", false)
+	if err != nil {
+		p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", desc, err))
+	}
+	verify, err := s.getBool("tls.verify", true)
+	if err != nil {
+		p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", desc, err))
+	}
+
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	host := net.JoinHostPort(s.get("host", "127.0.0.1"), s.get("port", defaultPort))
+	sink.URL = fmt.Sprintf("%s://%s%s", scheme, host, s.get("uri", defaultURI))
+	sink.InsecureSkipVerify = !verify
+	sink.Username = s.get("http_user", "")
+	sink.Password = s.get("http_passwd", "")
+}
+
+// pairs returns the values of a repeated key whose values are a name and a
+// value separated by a space, such as the header option.
+func (p *pipeline) pairs(s *section, key, desc string) map[string]string {
+	values := s.getAll(key)
+	if len(values) == 0 {
+		return nil
+	}
+	pairs := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, " ")
+		if !ok {
+			p.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: invalid %s %q", desc, key, v))
+			continue
+		}
+		pairs[name] = strings.TrimSpace(value)
+	}
+	return pairs
+}
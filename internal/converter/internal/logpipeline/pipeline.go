@@ -0,0 +1,325 @@
+// Package logpipeline builds Alloy components from a simplified description
+// of a log and metrics collection pipeline.
+//
+// Converters for agents such as Fluent Bit and Vector translate their
+// configuration into a [Config] and let this package generate the sources,
+// loki.process components and writers which implement it.
+package logpipeline
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/component/local/file_match"
+	"github.com/grafana/alloy/internal/component/loki/process"
+	"github.com/grafana/alloy/internal/component/loki/source/api"
+	lokisourcefile "github.com/grafana/alloy/internal/component/loki/source/file"
+	"github.com/grafana/alloy/internal/component/loki/source/journal"
+	"github.com/grafana/alloy/internal/component/prometheus/scrape"
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/common"
+	"github.com/grafana/alloy/syntax/token/builder"
+)
+
+// SourceType is the kind of data collected by a Source.
+type SourceType int
+
+const (
+	// SourceFile tails log files.
+	SourceFile SourceType = iota
+	// SourceJournal reads logs from the systemd journal.
+	SourceJournal
+	// SourceScrape scrapes Prometheus metrics.
+	SourceScrape
+	// SourceAPI receives logs pushed with the Loki push API.
+	SourceAPI
+)
+
+// Config describes the sources of a pipeline, the sinks they send data to
+// and the processing applied in between.
+type Config struct {
+	Sources []*Source
+	Sinks   []*Sink
+}
+
+// Source collects logs or metrics.
+type Source struct {
+	// Label of the components generated for the source.
+	Label string
+	Type  SourceType
+
+	// Paths and ExcludePaths are the glob patterns of the files tailed by a
+	// SourceFile.
+	Paths        []string
+	ExcludePaths []string
+	// TailFromEnd makes a SourceFile start reading new files from the end.
+	TailFromEnd bool
+
+	// JournalPath is the path of the journal read by a SourceJournal, and
+	// Matches the journal matches used to filter its entries.
+	JournalPath string
+	Matches     []string
+
+	// Targets are the addresses scraped by a SourceScrape.
+	Targets        []string
+	MetricsPath    string
+	ScrapeInterval time.Duration
+
+	// ListenAddress and ListenPort are the address a SourceAPI listens on.
+	ListenAddress string
+	ListenPort    int
+
+	// Labels are added to the logs collected by the source.
+	Labels map[string]string
+
+	// Routes send the data collected by the source to sinks.
+	Routes []*Route
+}
+
+// Route applies stages to the logs of a source and sends them to sinks.
+type Route struct {
+	Stages []Stage
+	// Sinks holds the labels of the sinks the route sends data to.
+	Sinks []string
+}
+
+// SinkType is the kind of endpoint a Sink writes to.
+type SinkType int
+
+const (
+	// SinkLoki writes logs to Loki.
+	SinkLoki SinkType = iota
+	// SinkRemoteWrite writes metrics to a Prometheus remote write endpoint.
+	SinkRemoteWrite
+	// SinkOTLP writes logs and metrics to an OTLP HTTP endpoint.
+	SinkOTLP
+)
+
+// Sink sends logs or metrics to an endpoint.
+type Sink struct {
+	// Label of the components generated for the sink.
+	Label string
+	Type  SinkType
+
+	// URL of the endpoint. For a SinkOTLP, it's the base URL which the
+	// signal-specific paths are appended to, unless LogsURL or MetricsURL are
+	// set.
+	URL        string
+	LogsURL    string
+	MetricsURL string
+
+	TenantID           string
+	Username           string
+	Password           string
+	BearerToken        string
+	InsecureSkipVerify bool
+	Headers            map[string]string
+	ExternalLabels     map[string]string
+}
+
+// signals tracks which kinds of data are sent to a sink.
+type signals struct {
+	logs, metrics bool
+}
+
+// Append generates the components of the pipeline and appends them to f.
+func Append(f *builder.File, cfg *Config) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	sinks := make(map[string]*Sink, len(cfg.Sinks))
+	for _, sink := range cfg.Sinks {
+		sinks[sink.Label] = sink
+	}
+	used := make(map[string]*signals, len(cfg.Sinks))
+
+	for _, src := range cfg.Sources {
+		var receivers []string
+		for i, route := range src.Routes {
+			var sinkExprs []string
+			for _, label := range route.Sinks {
+				sink, ok := sinks[label]
+				if !ok {
+					continue
+				}
+				if used[label] == nil {
+					used[label] = &signals{}
+				}
+				expr, err := sinkReceiver(sink, src.Type == SourceScrape, used[label])
+				if err != nil {
+					diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", src.Label, err))
+					continue
+				}
+				sinkExprs = append(sinkExprs, expr)
+			}
+
+			if len(route.Stages) == 0 {
+				receivers = append(receivers, sinkExprs...)
+				continue
+			}
+			if src.Type == SourceScrape {
+				diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: log processing can't be applied to scraped metrics", src.Label))
+				continue
+			}
+
+			label := common.LabelWithIndex(i, src.Label)
+			stages, stageDiags := renderStages(route.Stages)
+			diags.AddAll(stageDiags)
+			f.Body().AppendBlock(common.NewBlockWithOverride(
+				[]string{"loki", "process"},
+				label,
+				&process.Arguments{
+					ForwardTo: logsReceivers(sinkExprs),
+					Stages:    stages,
+				},
+			))
+			receivers = append(receivers, fmt.Sprintf("loki.process.%s.receiver", label))
+		}
+
+		if len(receivers) == 0 {
+			diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s doesn't send data to any output", src.Label))
+		}
+		appendSource(f, src, receivers)
+	}
+
+	for _, sink := range cfg.Sinks {
+		if s, ok := used[sink.Label]; ok {
+			appendSink(f, sink, *s)
+		} else {
+			diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s doesn't receive data from any input and is omitted", sink.Label))
+		}
+	}
+	return diags
+}
+
+// sinkReceiver returns the expression of the receiver which accepts logs or
+// metrics for the sink.
+func sinkReceiver(sink *Sink, metrics bool, used *signals) (string, error) {
+	switch {
+	case sink.Type == SinkLoki && !metrics:
+		used.logs = true
+		return fmt.Sprintf("loki.write.%s.receiver", sink.Label), nil
+	case sink.Type == SinkRemoteWrite && metrics:
+		used.metrics = true
+		return fmt.Sprintf("prometheus.remote_write.%s.receiver", sink.Label), nil
+	case sink.Type == SinkOTLP && !metrics:
+		used.logs = true
+		return fmt.Sprintf("otelcol.receiver.loki.%s.receiver", sink.Label), nil
+	case sink.Type == SinkOTLP && metrics:
+		used.metrics = true
+		return fmt.Sprintf("otelcol.receiver.prometheus.%s.receiver", sink.Label), nil
+	case metrics:
+		return "", fmt.Errorf("metrics can't be sent to the logs output %s", sink.Label)
+	default:
+		return "", fmt.Errorf("logs can't be sent to the metrics output %s", sink.Label)
+	}
+}
+
+func appendSource(f *builder.File, src *Source, receivers []string) {
+	switch src.Type {
+	case SourceFile:
+		appendFileSource(f, src, receivers)
+	case SourceJournal:
+		args := common.DefaultValue[journal.Arguments]()
+		args.Path = src.JournalPath
+		args.Matches = strings.Join(src.Matches, " ")
+		args.Receivers = logsReceivers(receivers)
+		args.Labels = src.Labels
+		f.Body().AppendBlock(common.NewBlockWithOverride([]string{"loki", "source", "journal"}, src.Label, &args))
+	case SourceScrape:
+		args := common.DefaultValue[scrape.Arguments]()
+		for _, target := range src.Targets {
+			labels := map[string]string{"__address__": target}
+			maps.Copy(labels, src.Labels)
+			args.Targets = append(args.Targets, discovery.NewTargetFromMap(labels))
+		}
+		args.ForwardTo = appendables(receivers)
+		if src.MetricsPath != "" {
+			args.MetricsPath = src.MetricsPath
+		}
+		if src.ScrapeInterval != 0 {
+			args.ScrapeInterval = src.ScrapeInterval
+		}
+		f.Body().AppendBlock(common.NewBlockWithOverride([]string{"prometheus", "scrape"}, src.Label, &args))
+	case SourceAPI:
+		args := common.DefaultValue[api.Arguments]()
+		if src.ListenAddress != "" {
+			args.Server.HTTP.ListenAddress = src.ListenAddress
+		}
+		if src.ListenPort != 0 {
+			args.Server.HTTP.ListenPort = src.ListenPort
+		}
+		args.ForwardTo = logsReceivers(receivers)
+		args.Labels = src.Labels
+		f.Body().AppendBlock(common.NewBlockWithOverride([]string{"loki", "source", "api"}, src.Label, &args))
+	}
+}
+
+func appendFileSource(f *builder.File, src *Source, receivers []string) {
+	var exclude string
+	switch len(src.ExcludePaths) {
+	case 0:
+	case 1:
+		exclude = src.ExcludePaths[0]
+	default:
+		exclude = "{" + strings.Join(src.ExcludePaths, ",") + "}"
+	}
+
+	var targets []discovery.Target
+	for _, path := range src.Paths {
+		labels := map[string]string{"__path__": path}
+		if exclude != "" {
+			labels["__path_exclude__"] = exclude
+		}
+		maps.Copy(labels, src.Labels)
+		targets = append(targets, discovery.NewTargetFromMap(labels))
+	}
+	fileMatchArgs := common.DefaultValue[file_match.Arguments]()
+	fileMatchArgs.PathTargets = targets
+	f.Body().AppendBlock(common.NewBlockWithOverride([]string{"local", "file_match"}, src.Label, &fileMatchArgs))
+
+	args := common.DefaultValue[lokisourcefile.Arguments]()
+	args.ForwardTo = logsReceivers(receivers)
+	args.TailFromEnd = src.TailFromEnd
+	targetsExpr := fmt.Sprintf("local.file_match.%s.targets", src.Label)
+	hook := func(val interface{}) interface{} {
+		if _, ok := val.([]discovery.Target); ok {
+			return common.CustomTokenizer{Expr: targetsExpr}
+		}
+		return val
+	}
+	f.Body().AppendBlock(common.NewBlockWithOverrideFn([]string{"loki", "source", "file"}, src.Label, &args, hook))
+}
+
+func logsReceivers(exprs []string) []loki.LogsReceiver {
+	receivers := make([]loki.LogsReceiver, 0, len(exprs))
+	for _, expr := range unique(exprs) {
+		receivers = append(receivers, common.ConvertLogsReceiver{Expr: expr})
+	}
+	return receivers
+}
+
+func appendables(exprs []string) []storage.Appendable {
+	appendables := make([]storage.Appendable, 0, len(exprs))
+	for _, expr := range unique(exprs) {
+		appendables = append(appendables, common.ConvertAppendable{Expr: expr})
+	}
+	return appendables
+}
+
+// unique removes duplicates from exprs, keeping the first occurrences.
+func unique(exprs []string) []string {
+	var out []string
+	for _, expr := range exprs {
+		if !slices.Contains(out, expr) {
+			out = append(out, expr)
+		}
+	}
+	return out
+}
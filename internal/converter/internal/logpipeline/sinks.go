@@ -0,0 +1,106 @@
+package logpipeline
+
+import (
+	"fmt"
+
+	"github.com/grafana/alloy/internal/component/common/config"
+	lokiwrite "github.com/grafana/alloy/internal/component/loki/write"
+	"github.com/grafana/alloy/internal/component/otelcol"
+	"github.com/grafana/alloy/internal/component/otelcol/auth"
+	"github.com/grafana/alloy/internal/component/otelcol/auth/basic"
+	"github.com/grafana/alloy/internal/component/otelcol/exporter/otlphttp"
+	otelcolloki "github.com/grafana/alloy/internal/component/otelcol/receiver/loki"
+	otelcolprometheus "github.com/grafana/alloy/internal/component/otelcol/receiver/prometheus"
+	"github.com/grafana/alloy/internal/component/prometheus/remotewrite"
+	"github.com/grafana/alloy/internal/converter/internal/common"
+	"github.com/grafana/alloy/syntax/alloytypes"
+	"github.com/grafana/alloy/syntax/token/builder"
+)
+
+func appendSink(f *builder.File, sink *Sink, used signals) {
+	switch sink.Type {
+	case SinkLoki:
+		endpoint := common.DefaultValue[lokiwrite.EndpointOptions]()
+		endpoint.URL = sink.URL
+		endpoint.TenantID = sink.TenantID
+		endpoint.Headers = sink.Headers
+		endpoint.HTTPClientConfig = httpClientConfig(sink)
+		args := &lokiwrite.Arguments{
+			Endpoints:      []lokiwrite.EndpointOptions{endpoint},
+			ExternalLabels: sink.ExternalLabels,
+		}
+		f.Body().AppendBlock(common.NewBlockWithOverride([]string{"loki", "write"}, sink.Label, args))
+
+	case SinkRemoteWrite:
+		endpoint := common.DefaultValue[remotewrite.EndpointOptions]()
+		endpoint.URL = sink.URL
+		endpoint.Headers = sink.Headers
+		endpoint.HTTPClientConfig = httpClientConfig(sink)
+		args := common.DefaultValue[remotewrite.Arguments]()
+		args.Endpoints = []*remotewrite.EndpointOptions{&endpoint}
+		args.ExternalLabels = sink.ExternalLabels
+		f.Body().AppendBlock(common.NewBlockWithOverride([]string{"prometheus", "remote_write"}, sink.Label, &args))
+
+	case SinkOTLP:
+		appendOTLPSink(f, sink, used)
+	}
+}
+
+func httpClientConfig(sink *Sink) *config.HTTPClientConfig {
+	cfg := config.CloneDefaultHTTPClientConfig()
+	if sink.Username != "" || sink.Password != "" {
+		cfg.BasicAuth = &config.BasicAuth{
+			Username: sink.Username,
+			Password: alloytypes.Secret(sink.Password),
+		}
+	}
+	cfg.BearerToken = alloytypes.Secret(sink.BearerToken)
+	cfg.TLSConfig.InsecureSkipVerify = sink.InsecureSkipVerify
+	return cfg
+}
+
+// appendOTLPSink appends an otelcol.exporter.otlphttp component and the
+// receivers which convert logs and metrics to OTLP for it.
+func appendOTLPSink(f *builder.File, sink *Sink, used signals) {
+	exporterInput := common.ConvertConsumer{Expr: fmt.Sprintf("otelcol.exporter.otlphttp.%s.input", sink.Label)}
+	if used.logs {
+		args := &otelcolloki.Arguments{
+			Output: &otelcol.ConsumerArguments{Logs: []otelcol.Consumer{exporterInput}},
+		}
+		f.Body().AppendBlock(common.NewBlockWithOverride([]string{"otelcol", "receiver", "loki"}, sink.Label, args))
+	}
+	if used.metrics {
+		args := common.DefaultValue[otelcolprometheus.Arguments]()
+		args.Output = &otelcol.ConsumerArguments{Metrics: []otelcol.Consumer{exporterInput}}
+		f.Body().AppendBlock(common.NewBlockWithOverride([]string{"otelcol", "receiver", "prometheus"}, sink.Label, &args))
+	}
+
+	hasAuth := sink.Username != "" || sink.Password != ""
+	if hasAuth {
+		args := common.DefaultValue[basic.Arguments]()
+		args.Username = sink.Username
+		args.Password = alloytypes.Secret(sink.Password)
+		f.Body().AppendBlock(common.NewBlockWithOverride([]string{"otelcol", "auth", "basic"}, sink.Label, &args))
+	}
+
+	args := common.DefaultValue[otlphttp.Arguments]()
+	args.Client.Endpoint = sink.URL
+	if len(sink.Headers) > 0 {
+		args.Client.Headers = sink.Headers
+	}
+	args.Client.TLS.InsecureSkipVerify = sink.InsecureSkipVerify
+	if hasAuth {
+		args.Client.Authentication = &auth.Handler{}
+	}
+	args.LogsEndpoint = sink.LogsURL
+	args.MetricsEndpoint = sink.MetricsURL
+
+	handlerExpr := fmt.Sprintf("otelcol.auth.basic.%s.handler", sink.Label)
+	hook := func(val interface{}) interface{} {
+		if _, ok := val.(auth.Handler); ok {
+			return common.CustomTokenizer{Expr: handlerExpr}
+		}
+		return val
+	}
+	f.Body().AppendBlock(common.NewBlockWithOverrideFn([]string{"otelcol", "exporter", "otlphttp"}, sink.Label, &args, hook))
+}
@@ -0,0 +1,200 @@
+package logpipeline
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/alloy/internal/component/loki/process/stages"
+	"github.com/grafana/alloy/internal/converter/diag"
+)
+
+// Stage is a processing step applied to the logs of a Route.
+//
+// Stages refer to the fields of a log entry by name. An empty field name
+// refers to the log line.
+type Stage interface {
+	// fields returns the names of the fields the stage reads.
+	fields() []string
+}
+
+// JSONStage parses a field as JSON.
+type JSONStage struct {
+	Source string
+}
+
+// LogfmtStage parses a field as logfmt.
+type LogfmtStage struct {
+	Source string
+}
+
+// RegexStage extracts the named capture groups of a regular expression
+// matched against a field. The expression must use the RE2 syntax.
+type RegexStage struct {
+	Source     string
+	Expression string
+}
+
+// TimestampStage sets the timestamp of log entries from a field. Format is a
+// Go time layout or one of the formats supported by stage.timestamp.
+type TimestampStage struct {
+	Source string
+	Format string
+}
+
+// DropStage drops the log entries whose field matches a regular expression.
+type DropStage struct {
+	Source     string
+	Expression string
+}
+
+// KeepStage drops the log entries whose log line doesn't match a regular
+// expression.
+type KeepStage struct {
+	Expression string
+}
+
+// StaticLabelsStage adds labels with static values.
+type StaticLabelsStage struct {
+	Values map[string]string
+}
+
+// LabelDropStage removes labels.
+type LabelDropStage struct {
+	Names []string
+}
+
+// LabelsStage turns fields into labels. Values maps label names to the
+// fields they are read from.
+type LabelsStage struct {
+	Values map[string]string
+}
+
+func (s JSONStage) fields() []string         { return []string{s.Source} }
+func (s LogfmtStage) fields() []string       { return []string{s.Source} }
+func (s RegexStage) fields() []string        { return []string{s.Source} }
+func (s TimestampStage) fields() []string    { return []string{s.Source} }
+func (s DropStage) fields() []string         { return []string{s.Source} }
+func (s KeepStage) fields() []string         { return nil }
+func (s StaticLabelsStage) fields() []string { return nil }
+func (s LabelDropStage) fields() []string    { return nil }
+func (s LabelsStage) fields() []string {
+	fields := make([]string, 0, len(s.Values))
+	for name, field := range s.Values {
+		if field == "" {
+			field = name
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// renderStages converts stages into stage blocks of loki.process.
+//
+// The json and logfmt stages of loki.process only extract the fields listed
+// in their configuration, so they are configured with the fields read by
+// the stages which follow them.
+func renderStages(in []Stage) ([]stages.StageConfig, diag.Diagnostics) {
+	var (
+		diags diag.Diagnostics
+		out   []stages.StageConfig
+	)
+	for i, stage := range in {
+		var used []string
+		for _, next := range in[i+1:] {
+			for _, field := range next.fields() {
+				if field != "" && !slices.Contains(used, field) {
+					used = append(used, field)
+				}
+			}
+		}
+		slices.Sort(used)
+
+		switch s := stage.(type) {
+		case JSONStage:
+			if len(used) == 0 {
+				diags.Add(diag.SeverityLevelInfo, "a JSON parser was omitted because none of the fields it extracts are used")
+				continue
+			}
+			cfg := &stages.JSONConfig{Expressions: make(map[string]string, len(used))}
+			for _, field := range used {
+				// An empty expression extracts the field with the same name,
+				// but JMESPath identifiers with special characters must be
+				// quoted.
+				if identifierRegexp.MatchString(field) {
+					cfg.Expressions[field] = ""
+				} else {
+					cfg.Expressions[field] = strconv.Quote(field)
+				}
+			}
+			if s.Source != "" {
+				cfg.Source = &s.Source
+			}
+			out = append(out, stages.StageConfig{JSONConfig: cfg})
+		case LogfmtStage:
+			if len(used) == 0 {
+				diags.Add(diag.SeverityLevelInfo, "a logfmt parser was omitted because none of the fields it extracts are used")
+				continue
+			}
+			cfg := &stages.LogfmtConfig{Mapping: make(map[string]string, len(used)), Source: s.Source}
+			for _, field := range used {
+				cfg.Mapping[field] = ""
+			}
+			out = append(out, stages.StageConfig{LogfmtConfig: cfg})
+		case RegexStage:
+			cfg := &stages.RegexConfig{Expression: s.Expression}
+			if s.Source != "" {
+				cfg.Source = &s.Source
+			}
+			out = append(out, stages.StageConfig{RegexConfig: cfg})
+		case TimestampStage:
+			out = append(out, stages.StageConfig{TimestampConfig: &stages.TimestampConfig{
+				Source: s.Source,
+				Format: s.Format,
+			}})
+		case DropStage:
+			out = append(out, stages.StageConfig{DropConfig: &stages.DropConfig{
+				Source:     s.Source,
+				Expression: s.Expression,
+			}})
+		case KeepStage:
+			// The match stage drops entries matching its selector, so the line
+			// filter is negated. The label matcher matches every entry.
+			out = append(out, stages.StageConfig{MatchConfig: &stages.MatchConfig{
+				Selector: fmt.Sprintf(`{filename=~".*"} !~ %s`, logQLString(s.Expression)),
+				Action:   "drop",
+			}})
+		case StaticLabelsStage:
+			values := make(map[string]*string, len(s.Values))
+			for name, value := range s.Values {
+				values[name] = &value
+			}
+			out = append(out, stages.StageConfig{StaticLabelsConfig: &stages.StaticLabelsConfig{Values: values}})
+		case LabelDropStage:
+			out = append(out, stages.StageConfig{LabelDropConfig: &stages.LabelDropConfig{Values: s.Names}})
+		case LabelsStage:
+			values := make(map[string]*string, len(s.Values))
+			for name, field := range s.Values {
+				if field == name {
+					field = ""
+				}
+				values[name] = &field
+			}
+			out = append(out, stages.StageConfig{LabelsConfig: &stages.LabelsConfig{Values: values}})
+		}
+	}
+	return out, diags
+}
+
+// logQLString quotes a string for a LogQL query, preferring raw strings which
+// don't require escaping regular expressions.
+func logQLString(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,82 @@
+package logpipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// strptimeLayouts maps strptime directives to Go time layout elements.
+var strptimeLayouts = map[byte]string{
+	'a': "Mon",
+	'A': "Monday",
+	'b': "Jan",
+	'B': "January",
+	'd': "02",
+	'e': "_2",
+	'F': "2006-01-02",
+	'h': "Jan",
+	'H': "15",
+	'I': "03",
+	'j': "002",
+	'm': "01",
+	'M': "04",
+	'p': "PM",
+	'S': "05",
+	'T': "15:04:05",
+	'y': "06",
+	'Y': "2006",
+	'z': "-0700",
+	'Z': "MST",
+	'%': "%",
+}
+
+// StrptimeToLayout converts a strptime format, as used by Fluent Bit and
+// Vector, into a Go time layout.
+func StrptimeToLayout(format string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			sb.WriteByte(format[i])
+			continue
+		}
+		if i+1 == len(format) {
+			return "", fmt.Errorf("time format %q ends with an incomplete directive", format)
+		}
+		i++
+
+		// Fractional seconds are written as %L by Fluent Bit and as %f, %3f
+		// or %.3f by Vector. Go parses any number of digits when the layout
+		// uses 9s, but the layout must include the separating dot.
+		if n := fractionLength(format[i:]); n > 0 {
+			if format[i] == '.' {
+				sb.WriteByte('.')
+			}
+			sb.WriteString("999999999")
+			i += n - 1
+			continue
+		}
+
+		layout, ok := strptimeLayouts[format[i]]
+		if !ok {
+			return "", fmt.Errorf("time format %q uses the unsupported directive %%%c", format, format[i])
+		}
+		sb.WriteString(layout)
+	}
+	return sb.String(), nil
+}
+
+// fractionLength returns the length of the fractional seconds directive at
+// the start of s, which follows a %, or 0 if there is none.
+func fractionLength(s string) int {
+	n := 0
+	if n < len(s) && s[n] == '.' {
+		n++
+	}
+	if n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	if n < len(s) && (s[n] == 'f' || (n == 0 && s[n] == 'L')) {
+		return n + 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package logpipeline_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+)
+
+func TestStrptimeToLayout(t *testing.T) {
+	tt := []struct {
+		name   string
+		format string
+		expect string
+		err    string
+	}{
+		{
+			name:   "nginx",
+			format: "%d/%b/%Y:%H:%M:%S %z",
+			expect: "02/Jan/2006:15:04:05 -0700",
+		},
+		{
+			name:   "fluent bit milliseconds",
+			format: "%Y-%m-%dT%H:%M:%S.%L",
+			expect: "2006-01-02T15:04:05.999999999",
+		},
+		{
+			name:   "vector fractional seconds",
+			format: "%Y-%m-%dT%H:%M:%S%.3fZ",
+			expect: "2006-01-02T15:04:05.999999999Z",
+		},
+		{
+			name:   "escaped percent",
+			format: "%H%%",
+			expect: "15%",
+		},
+		{
+			name:   "unsupported directive",
+			format: "%s",
+			err:    `time format "%s" uses the unsupported directive %s`,
+		},
+		{
+			name:   "incomplete directive",
+			format: "%H%",
+			err:    `time format "%H%" ends with an incomplete directive`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			layout, err := logpipeline.StrptimeToLayout(tc.format)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, layout)
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package otelcolconvert
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/extension"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/receiver"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/alloy/internal/component/otelcol"
+	"github.com/grafana/alloy/internal/component/otelcol/auth"
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/alloysource"
+)
+
+const otelcolTarget = "the OpenTelemetry Collector"
+
+// convertibleArguments is implemented by the arguments of the otelcol
+// components, except for authentication extensions.
+type convertibleArguments interface {
+	Convert() (component.Config, error)
+}
+
+// consumingArguments is implemented by the arguments of the otelcol
+// components which forward data to other components.
+type consumingArguments interface {
+	NextConsumers() *otelcol.ConsumerArguments
+}
+
+// collectorComponent is an otelcol component converted to an OpenTelemetry
+// Collector component.
+type collectorComponent struct {
+	alloy *alloysource.Component
+	kind  component.Kind
+	id    component.ID
+	cfg   component.Config
+}
+
+// collectorConfig is the OpenTelemetry Collector configuration written by
+// FromAlloy. It's a struct rather than a map to keep the usual order of the
+// sections.
+type collectorConfig struct {
+	Receivers  map[string]any `yaml:"receivers,omitempty"`
+	Processors map[string]any `yaml:"processors,omitempty"`
+	Exporters  map[string]any `yaml:"exporters,omitempty"`
+	Connectors map[string]any `yaml:"connectors,omitempty"`
+	Extensions map[string]any `yaml:"extensions,omitempty"`
+	Service    serviceConfig  `yaml:"service"`
+}
+
+type serviceConfig struct {
+	Extensions []string                  `yaml:"extensions,omitempty"`
+	Pipelines  map[string]pipelineConfig `yaml:"pipelines"`
+}
+
+type pipelineConfig struct {
+	Receivers  []string `yaml:"receivers"`
+	Processors []string `yaml:"processors,omitempty"`
+	Exporters  []string `yaml:"exporters"`
+}
+
+// FromAlloy converts the otelcol components of an Alloy configuration into an
+// OpenTelemetry Collector configuration. The pipelines are rebuilt from the
+// output blocks of the components.
+//
+// Only components for which a converter to Alloy exists are supported, since
+// their OpenTelemetry Collector factories are known.
+func FromAlloy(in []byte) ([]byte, diag.Diagnostics) {
+	f, diags := alloysource.Decode(in, otelcolTarget, supportedFromAlloy)
+	if f == nil {
+		return nil, diags
+	}
+
+	factories := buildFactoryTable()
+	var components []*collectorComponent
+
+	// Authentication extensions are converted first so that the components
+	// using them can reference their ID.
+	for _, c := range f.Components {
+		args, ok := c.Args.(auth.Arguments)
+		if !ok {
+			continue
+		}
+		cfg, err := args.ConvertClient()
+		if err == nil && cfg == nil {
+			cfg, err = args.ConvertServer()
+		}
+		cc, convertDiags := newCollectorComponent(factories, c, cfg, err)
+		diags.AddAll(convertDiags)
+		if cc == nil {
+			continue
+		}
+
+		handler := f.AuthHandler(c.ID())
+		for _, et := range []auth.ExtensionType{auth.Client, auth.Server} {
+			_ = handler.AddExtension(et, &auth.ExtensionHandler{ID: cc.id})
+		}
+		components = append(components, cc)
+	}
+	for _, c := range f.Components {
+		args, ok := c.Args.(convertibleArguments)
+		if !ok {
+			if _, isAuth := c.Args.(auth.Arguments); !isAuth {
+				diags.Add(diag.SeverityLevelError, fmt.Sprintf("component %s has no equivalent in %s", c.ID(), otelcolTarget))
+			}
+			continue
+		}
+		cfg, err := args.Convert()
+		cc, convertDiags := newCollectorComponent(factories, c, cfg, err)
+		diags.AddAll(convertDiags)
+		if cc != nil {
+			components = append(components, cc)
+		}
+	}
+
+	out := collectorConfig{
+		Service: serviceConfig{Pipelines: map[string]pipelineConfig{}},
+	}
+	for _, cc := range components {
+		conf := confmap.New()
+		if err := conf.Marshal(cc.cfg); err != nil {
+			diags.Add(diag.SeverityLevelError, fmt.Sprintf("failed to marshal %s: %s", cc.alloy.ID(), err))
+			continue
+		}
+
+		section := out.section(cc.kind)
+		if _, ok := (*section)[cc.id.String()]; ok {
+			diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s is converted to %s, which is already used by another component", cc.alloy.ID(), cc.id))
+			continue
+		}
+		if *section == nil {
+			*section = map[string]any{}
+		}
+		(*section)[cc.id.String()] = conf.ToStringMap()
+		if cc.kind == component.KindExtension {
+			out.Service.Extensions = append(out.Service.Extensions, cc.id.String())
+		}
+	}
+
+	pipelines, pipelineDiags := buildPipelines(components)
+	diags.AddAll(pipelineDiags)
+	for _, p := range pipelines {
+		out.Service.Pipelines[p.id.String()] = p.cfg
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to render OpenTelemetry Collector config: %s", err))
+		return nil, diags
+	}
+
+	for _, cc := range components {
+		if hasSecrets(reflect.ValueOf(cc.cfg)) {
+			diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the secrets of %s are redacted in the OpenTelemetry Collector config and must be set manually", cc.alloy.ID()))
+		}
+	}
+	return buf.Bytes(), diags
+}
+
+var opaqueType = reflect.TypeOf(configopaque.String(""))
+
+// hasSecrets returns true if v holds a non-empty configopaque.String, which
+// is redacted when marshaled.
+func hasSecrets(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil() && hasSecrets(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if hasSecrets(v.Field(i)) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if hasSecrets(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if hasSecrets(iter.Value()) {
+				return true
+			}
+		}
+	case reflect.String:
+		return v.Type() == opaqueType && v.Len() > 0
+	}
+	return false
+}
+
+func supportedFromAlloy(name string) bool {
+	return strings.HasPrefix(name, "otelcol.")
+}
+
+// factoryEntry is the kind and type of an OpenTelemetry Collector component.
+type factoryEntry struct {
+	kind component.Kind
+	typ  component.Type
+}
+
+// buildFactoryTable maps the type of the configuration of the OpenTelemetry
+// Collector components which have a converter to their kind and type.
+func buildFactoryTable() map[reflect.Type]factoryEntry {
+	table := make(map[reflect.Type]factoryEntry)
+	for _, conv := range converters {
+		fact := conv.Factory()
+
+		var kind component.Kind
+		switch fact.(type) {
+		case receiver.Factory:
+			kind = component.KindReceiver
+		case processor.Factory:
+			kind = component.KindProcessor
+		case exporter.Factory:
+			kind = component.KindExporter
+		case connector.Factory:
+			kind = component.KindConnector
+		case extension.Factory:
+			kind = component.KindExtension
+		default:
+			continue
+		}
+
+		cfgType := reflect.TypeOf(fact.CreateDefaultConfig())
+		if _, ok := table[cfgType]; !ok {
+			table[cfgType] = factoryEntry{kind: kind, typ: fact.Type()}
+		}
+	}
+	return table
+}
+
+func newCollectorComponent(factories map[reflect.Type]factoryEntry, c *alloysource.Component, cfg component.Config, err error) (*collectorComponent, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if err != nil {
+		diags.Add(diag.SeverityLevelError, fmt.Sprintf("failed to convert %s: %s", c.ID(), err))
+		return nil, diags
+	}
+
+	entry, ok := factories[reflect.TypeOf(cfg)]
+	if cfg == nil || !ok {
+		diags.Add(diag.SeverityLevelError, fmt.Sprintf("component %s has no equivalent in %s", c.ID(), otelcolTarget))
+		return nil, diags
+	}
+	return &collectorComponent{
+		alloy: c,
+		kind:  entry.kind,
+		id:    component.NewIDWithName(entry.typ, c.Label),
+		cfg:   cfg,
+	}, diags
+}
+
+func (cfg *collectorConfig) section(kind component.Kind) *map[string]any {
+	switch kind {
+	case component.KindReceiver:
+		return &cfg.Receivers
+	case component.KindProcessor:
+		return &cfg.Processors
+	case component.KindExporter:
+		return &cfg.Exporters
+	case component.KindConnector:
+		return &cfg.Connectors
+	default:
+		return &cfg.Extensions
+	}
+}
+
+type collectorPipeline struct {
+	id  pipeline.ID
+	cfg pipelineConfig
+}
+
+// buildPipelines rebuilds the pipelines from the outputs of the receivers and
+// connectors. A pipeline is created for each distinct chain of processors
+// between receivers and exporters, since OpenTelemetry Collector pipelines
+// are linear.
+func buildPipelines(components []*collectorComponent) ([]collectorPipeline, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	lookup := make(map[string]*collectorComponent, len(components))
+	for _, cc := range components {
+		lookup[cc.alloy.ID()] = cc
+	}
+
+	var (
+		pipelines []collectorPipeline
+		index     = map[string]int{}
+		reported  = map[string]struct{}{}
+	)
+	for _, signal := range []pipeline.Signal{pipeline.SignalTraces, pipeline.SignalMetrics, pipeline.SignalLogs} {
+		for _, source := range components {
+			if source.kind != component.KindReceiver && source.kind != component.KindConnector {
+				continue
+			}
+
+			// terminals maps each chain of processors to the exporters and
+			// connectors at its end.
+			var (
+				chains    [][]string
+				terminals = map[string][]string{}
+			)
+			var walk func(consumers []otelcol.Consumer, processors []string)
+			walk = func(consumers []otelcol.Consumer, processors []string) {
+				for _, consumer := range consumers {
+					ref, ok := consumer.(alloysource.Consumer)
+					next := lookup[ref.ID]
+					if !ok || next == nil {
+						if _, ok := reported[ref.ID]; !ok {
+							reported[ref.ID] = struct{}{}
+							diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s forwards data to %s, which can't be converted", source.alloy.ID(), ref.ID))
+						}
+						continue
+					}
+
+					switch next.kind {
+					case component.KindProcessor:
+						if slices.Contains(processors, next.id.String()) {
+							diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s is used more than once in a pipeline", next.alloy.ID()))
+							continue
+						}
+						walk(signalConsumers(next, signal), append(slices.Clone(processors), next.id.String()))
+					case component.KindExporter, component.KindConnector:
+						key := strings.Join(processors, ",")
+						if _, ok := terminals[key]; !ok {
+							chains = append(chains, processors)
+						}
+						if !slices.Contains(terminals[key], next.id.String()) {
+							terminals[key] = append(terminals[key], next.id.String())
+						}
+					}
+				}
+			}
+			walk(signalConsumers(source, signal), nil)
+
+			for _, processors := range chains {
+				exporters := terminals[strings.Join(processors, ",")]
+				slices.Sort(exporters)
+				key := strings.Join(processors, ",") + "|" + strings.Join(exporters, ",")
+				if i, ok := index[key+"|"+signal.String()]; ok {
+					if !slices.Contains(pipelines[i].cfg.Receivers, source.id.String()) {
+						pipelines[i].cfg.Receivers = append(pipelines[i].cfg.Receivers, source.id.String())
+					}
+					continue
+				}
+
+				index[key+"|"+signal.String()] = len(pipelines)
+				pipelines = append(pipelines, collectorPipeline{
+					id: pipelineID(signal, pipelines),
+					cfg: pipelineConfig{
+						Receivers:  []string{source.id.String()},
+						Processors: processors,
+						Exporters:  exporters,
+					},
+				})
+			}
+		}
+	}
+	return pipelines, diags
+}
+
+// signalConsumers returns the consumers a component forwards the given signal
+// to.
+func signalConsumers(cc *collectorComponent, signal pipeline.Signal) []otelcol.Consumer {
+	args, ok := cc.alloy.Args.(consumingArguments)
+	if !ok {
+		return nil
+	}
+	next := args.NextConsumers()
+	if next == nil {
+		return nil
+	}
+
+	switch signal {
+	case pipeline.SignalTraces:
+		return next.Traces
+	case pipeline.SignalMetrics:
+		return next.Metrics
+	default:
+		return next.Logs
+	}
+}
+
+// pipelineID returns the ID of the next pipeline of the signal. The first
+// pipeline of a signal has no name.
+func pipelineID(signal pipeline.Signal, pipelines []collectorPipeline) pipeline.ID {
+	var count int
+	for _, p := range pipelines {
+		if p.id.Signal() == signal {
+			count++
+		}
+	}
+	if count == 0 {
+		return pipeline.NewID(signal)
+	}
+	return pipeline.NewIDWithName(signal, strconv.Itoa(count))
+}
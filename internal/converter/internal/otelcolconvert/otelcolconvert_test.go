@@ -5,6 +5,8 @@ package otelcolconvert_test
 import (
 	"testing"
 
+	_ "github.com/grafana/alloy/internal/component/otelcol/exporter/loki"
+	_ "github.com/grafana/alloy/internal/component/prometheus/scrape"
 	"github.com/grafana/alloy/internal/converter/internal/otelcolconvert"
 	"github.com/grafana/alloy/internal/converter/internal/test_common"
 )
@@ -20,3 +22,7 @@ func TestConvert(t *testing.T) {
 func TestConvertErrors(t *testing.T) {
 	test_common.TestDirectory(t, "testdata/otelcol_errors", ".yaml", true, []string{}, otelcolconvert.Convert)
 }
+
+func TestFromAlloy(t *testing.T) {
+	test_common.TestDirectoryFromAlloy(t, "testdata_alloy", ".yaml", otelcolconvert.FromAlloy)
+}
@@ -0,0 +1,292 @@
+package prometheusconvert
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	prom_config "github.com/prometheus/prometheus/config"
+	prom_discover "github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/model/labels"
+	prom_relabel "github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/storage"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/alloy/internal/component"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/component/discovery"
+	disc_relabel "github.com/grafana/alloy/internal/component/discovery/relabel"
+	"github.com/grafana/alloy/internal/component/prometheus/relabel"
+	"github.com/grafana/alloy/internal/component/prometheus/remotewrite"
+	"github.com/grafana/alloy/internal/component/prometheus/scrape"
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/alloysource"
+)
+
+const prometheusTarget = "Prometheus"
+
+// convertibleDiscovery is implemented by the arguments of the discovery
+// components which wrap a Prometheus service discovery.
+type convertibleDiscovery interface {
+	Convert() discovery.DiscovererConfig
+}
+
+// FromAlloy converts the prometheus.scrape, prometheus.relabel,
+// prometheus.remote_write and discovery components of an Alloy configuration
+// into a Prometheus configuration.
+//
+// Pipelines which can't be represented in Prometheus, for example a scrape
+// forwarding metrics to only some of the remote_write components, are
+// reported with diagnostics.
+func FromAlloy(in []byte) ([]byte, diag.Diagnostics) {
+	f, diags := alloysource.Decode(in, prometheusTarget, supportedFromAlloy)
+	if f == nil {
+		return nil, diags
+	}
+
+	promConfig := &prom_config.Config{
+		GlobalConfig: prom_config.DefaultGlobalConfig,
+	}
+	var (
+		remoteWrites   []string
+		externalLabels = map[string]string{}
+	)
+	for _, c := range f.Components {
+		if c.Name != "prometheus.remote_write" {
+			continue
+		}
+		remoteWrites = append(remoteWrites, c.ID())
+
+		rwConfig, err := remotewrite.ToPrometheusConfig(*c.Args.(*remotewrite.Arguments))
+		if err != nil {
+			diags.Add(diag.SeverityLevelError, fmt.Sprintf("failed to convert %s: %s", c.ID(), err))
+			continue
+		}
+		promConfig.RemoteWriteConfigs = append(promConfig.RemoteWriteConfigs, rwConfig.RemoteWriteConfigs...)
+		for _, l := range rwConfig.GlobalConfig.ExternalLabels {
+			if v, ok := externalLabels[l.Name]; ok && v != l.Value {
+				diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s sets the external label %q to %q, which conflicts with the value %q of another prometheus.remote_write component", c.ID(), l.Name, l.Value, v))
+				continue
+			}
+			externalLabels[l.Name] = l.Value
+		}
+	}
+	promConfig.GlobalConfig.ExternalLabels = labels.FromMap(externalLabels)
+
+	for _, c := range f.Components {
+		if c.Name != "prometheus.scrape" {
+			continue
+		}
+		sc, scrapeDiags := toScrapeConfig(f, c, remoteWrites)
+		diags.AddAll(scrapeDiags)
+		if sc != nil {
+			promConfig.ScrapeConfigs = append(promConfig.ScrapeConfigs, sc)
+		}
+	}
+
+	out, err := yaml.Marshal(promConfig)
+	if err != nil {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to render Prometheus config: %s", err))
+		return nil, diags
+	}
+	if strings.Contains(string(out), "<secret>") {
+		diags.Add(diag.SeverityLevelWarn, "secrets are redacted in the Prometheus config and must be set manually")
+	}
+	return out, diags
+}
+
+func supportedFromAlloy(name string) bool {
+	switch name {
+	case "prometheus.scrape", "prometheus.relabel", "prometheus.remote_write", "discovery.relabel":
+		return true
+	}
+	if !strings.HasPrefix(name, "discovery.") {
+		return false
+	}
+	reg, ok := component.Get(name)
+	if !ok {
+		return false
+	}
+	_, ok = reg.CloneArguments().(convertibleDiscovery)
+	return ok
+}
+
+// toScrapeConfig converts a prometheus.scrape component and the components it
+// gets targets from and forwards metrics to into a scrape_config.
+func toScrapeConfig(f *alloysource.File, c *alloysource.Component, remoteWrites []string) (*prom_config.ScrapeConfig, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	args := c.Args.(*scrape.Arguments)
+	sc := scrape.ToPrometheusScrapeConfig(c.ID(), *args)
+	if args.Clustering.Enabled {
+		diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s: clustering has no equivalent in Prometheus and is ignored", c.ID()))
+	}
+
+	sdConfigs, relabelConfigs, err := resolveTargets(f, args.Targets)
+	if err != nil {
+		diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", c.ID(), err))
+		return nil, diags
+	}
+	sc.ServiceDiscoveryConfigs = sdConfigs
+	sc.RelabelConfigs = relabelConfigs
+
+	metricRelabelConfigs, reached, err := resolveForwardTo(f, args.ForwardTo)
+	if err != nil {
+		diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", c.ID(), err))
+		return nil, diags
+	}
+	sc.MetricRelabelConfigs = metricRelabelConfigs
+
+	for _, rw := range remoteWrites {
+		if !slices.Contains(reached, rw) {
+			diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s doesn't forward metrics to %s, but Prometheus sends the metrics of every scrape_config to every remote_write", c.ID(), rw))
+		}
+	}
+	return sc, diags
+}
+
+// resolveTargets returns the service discovery configs and the relabeling
+// rules which produce targets. Prometheus applies the relabel_configs of a
+// scrape_config to all of its targets, so the targets must all go through
+// the same chain of discovery.relabel components.
+func resolveTargets(f *alloysource.File, targets []discovery.Target) (prom_discover.Configs, []*prom_relabel.Config, error) {
+	var (
+		sdConfigs     prom_discover.Configs
+		staticConfig  prom_discover.StaticConfig
+		relabelChains [][]string
+	)
+
+	var visit func(targets []discovery.Target, chain []string) error
+	visit = func(targets []discovery.Target, chain []string) error {
+		for _, t := range targets {
+			id, ok := alloysource.ComponentID(t)
+			if !ok {
+				relabelChains = append(relabelChains, chain)
+				staticConfig = appendStaticTarget(staticConfig, t)
+				continue
+			}
+
+			c := f.Component(id)
+			if c == nil {
+				return fmt.Errorf("targets exported by %s can't be converted", id)
+			}
+			switch args := c.Args.(type) {
+			case *disc_relabel.Arguments:
+				if slices.Contains(chain, id) {
+					return fmt.Errorf("cycle between discovery.relabel components: %s", id)
+				}
+				if err := visit(args.Targets, append(slices.Clone(chain), id)); err != nil {
+					return err
+				}
+			case convertibleDiscovery:
+				relabelChains = append(relabelChains, chain)
+				sdConfigs = append(sdConfigs, args.Convert())
+			default:
+				return fmt.Errorf("targets exported by %s can't be converted", id)
+			}
+		}
+		return nil
+	}
+	if err := visit(targets, nil); err != nil {
+		return nil, nil, err
+	}
+
+	for _, chain := range relabelChains[min(1, len(relabelChains)):] {
+		if !slices.Equal(chain, relabelChains[0]) {
+			return nil, nil, fmt.Errorf("targets must all be relabeled by the same discovery.relabel components, since Prometheus applies relabel_configs to every target of a scrape_config")
+		}
+	}
+	// The chain starts with the discovery.relabel component closest to the
+	// scrape, while its rules are applied last.
+	var rules []*alloy_relabel.Config
+	if len(relabelChains) > 0 {
+		for i := len(relabelChains[0]) - 1; i >= 0; i-- {
+			rules = append(rules, f.Component(relabelChains[0][i]).Args.(*disc_relabel.Arguments).RelabelConfigs...)
+		}
+	}
+
+	if len(staticConfig) > 0 {
+		sdConfigs = append(sdConfigs, staticConfig)
+	}
+	return sdConfigs, alloy_relabel.ComponentToPromRelabelConfigs(rules), nil
+}
+
+// appendStaticTarget adds a static target to the group having the same labels.
+func appendStaticTarget(cfg prom_discover.StaticConfig, t discovery.Target) prom_discover.StaticConfig {
+	groupLabels := model.LabelSet{}
+	var address model.LabelValue
+	t.ForEachLabel(func(key, value string) bool {
+		if key == model.AddressLabel {
+			address = model.LabelValue(value)
+		} else {
+			groupLabels[model.LabelName(key)] = model.LabelValue(value)
+		}
+		return true
+	})
+
+	target := model.LabelSet{model.AddressLabel: address}
+	for _, group := range cfg {
+		if group.Labels.Equal(groupLabels) {
+			group.Targets = append(group.Targets, target)
+			return cfg
+		}
+	}
+	return append(cfg, &targetgroup.Group{
+		Targets: []model.LabelSet{target},
+		Labels:  groupLabels,
+	})
+}
+
+// resolveForwardTo returns the metric relabeling rules applied by the
+// prometheus.relabel components between a scrape and the remote_write
+// components, and the IDs of the remote_write components which are reached.
+func resolveForwardTo(f *alloysource.File, forwardTo []storage.Appendable) ([]*prom_relabel.Config, []string, error) {
+	var (
+		rules   []*alloy_relabel.Config
+		reached []string
+		// visited holds the prometheus.relabel components already reached,
+		// to stop at a cycle.
+		visited = make(map[string]struct{})
+	)
+	for len(forwardTo) > 0 {
+		var (
+			next            []storage.Appendable
+			relabelSeen     bool
+			remoteWriteSeen bool
+		)
+		for _, appendable := range forwardTo {
+			ref, ok := appendable.(alloysource.Appendable)
+			if !ok {
+				return nil, nil, fmt.Errorf("forward_to must only reference prometheus.relabel and prometheus.remote_write components")
+			}
+			c := f.Component(ref.ID)
+			if c == nil {
+				return nil, nil, fmt.Errorf("metrics forwarded to %s can't be converted", ref.ID)
+			}
+
+			switch args := c.Args.(type) {
+			case *remotewrite.Arguments:
+				reached = append(reached, ref.ID)
+				remoteWriteSeen = true
+			case *relabel.Arguments:
+				if relabelSeen {
+					return nil, nil, fmt.Errorf("metrics can't be forwarded to several prometheus.relabel components, since Prometheus applies metric_relabel_configs to every series of a scrape_config")
+				}
+				if _, ok := visited[ref.ID]; ok {
+					return nil, nil, fmt.Errorf("metrics forwarded to %s are forwarded back to it, which is a cycle", ref.ID)
+				}
+				visited[ref.ID] = struct{}{}
+				relabelSeen = true
+				rules = append(rules, args.MetricRelabelConfigs...)
+				next = args.ForwardTo
+			}
+		}
+		if relabelSeen && remoteWriteSeen {
+			return nil, nil, fmt.Errorf("metrics can't be forwarded to prometheus.remote_write components both with and without a prometheus.relabel component, since Prometheus applies metric_relabel_configs to every series of a scrape_config")
+		}
+		forwardTo = next
+	}
+	return alloy_relabel.ComponentToPromRelabelConfigs(rules), reached, nil
+}
@@ -3,6 +3,7 @@ package prometheusconvert_test
 import (
 	"testing"
 
+	_ "github.com/grafana/alloy/internal/component/prometheus/exporter/self"
 	"github.com/grafana/alloy/internal/converter/internal/prometheusconvert"
 	"github.com/grafana/alloy/internal/converter/internal/test_common"
 	_ "github.com/grafana/alloy/internal/static/metrics/instance"
@@ -11,3 +12,7 @@ import (
 func TestConvert(t *testing.T) {
 	test_common.TestDirectory(t, "testdata", ".yaml", true, []string{}, prometheusconvert.Convert)
 }
+
+func TestFromAlloy(t *testing.T) {
+	test_common.TestDirectoryFromAlloy(t, "testdata_alloy", ".yaml", prometheusconvert.FromAlloy)
+}
@@ -79,6 +79,49 @@ func TestDirectory(t *testing.T, folderPath string, sourceSuffix string, loadAll
 	}))
 }
 
+// TestDirectoryFromAlloy will execute tests for converting from an Alloy
+// configuration file to the configuration format of another program for all
+// files in a provided folder path.
+//
+// For each file in the folderPath which ends with .alloy, the output of the
+// convert func is compared to the matching file ending with targetSuffix and
+// its diags are compared to the matching .diags file, as in TestDirectory.
+func TestDirectoryFromAlloy(t *testing.T, folderPath string, targetSuffix string, convert func(in []byte) ([]byte, diag.Diagnostics)) {
+	require.NoError(t, filepath.WalkDir(folderPath, func(path string, d fs.DirEntry, _ error) error {
+		// Only skip iterating child folders
+		if d.IsDir() && path != folderPath {
+			return filepath.SkipDir
+		}
+
+		if strings.HasSuffix(path, alloySuffix) {
+			tc := filepath.Base(path)
+			t.Run(tc, func(t *testing.T) {
+				targetFile := strings.TrimSuffix(path, alloySuffix) + targetSuffix
+				diagsFile := strings.TrimSuffix(path, alloySuffix) + diagsSuffix
+				if !fileExists(targetFile) && !fileExists(diagsFile) {
+					t.Fatalf("no expected diags or output for %s - missing test expectations?", path)
+				}
+
+				actualOutput, actualDiags := convert(getSourceContents(t, path))
+
+				// Skip Info level diags for this testing. These would create
+				// a lot of unnecessary noise.
+				actualDiags.RemoveDiagsBySeverity(diag.SeverityLevelInfo)
+
+				expectedDiags := getExpectedDiags(t, diagsFile)
+				validateDiags(t, expectedDiags, actualDiags)
+
+				// Reuse the comparison of Alloy files without loading the
+				// output, which isn't an Alloy configuration.
+				expectedOutput := getExpectedAlloy(t, targetFile)
+				validateAlloy(t, expectedOutput, actualOutput, false)
+			})
+		}
+
+		return nil
+	}))
+}
+
 // getSourceContents reads the source file and retrieve its contents.
 func getSourceContents(t *testing.T, path string) []byte {
 	sourceBytes, err := os.ReadFile(path)
@@ -0,0 +1,216 @@
+package vectorconvert
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// component is a source, transform or sink of a Vector config.
+type component struct {
+	id     string
+	kind   string
+	fields map[string]any
+	// used tracks the fields which were read during the conversion.
+	used map[string]struct{}
+}
+
+// typ returns the type of the component, such as file.
+func (c *component) typ() string {
+	s, _ := c.get("type").(string)
+	return s
+}
+
+// get returns the value of a field. Nested fields are separated by dots.
+func (c *component) get(path string) any {
+	c.used[path] = struct{}{}
+	var value any = c.fields
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// getString returns a string field, or def if it isn't set.
+func (c *component) getString(path, def string) (string, error) {
+	switch v := c.get(path).(type) {
+	case nil:
+		return def, nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("%s must be a string", path)
+	}
+}
+
+// getStrings returns a list of strings.
+func (c *component) getStrings(path string) ([]string, error) {
+	switch v := c.get(path).(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings", path)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%s must be a list of strings", path)
+	}
+}
+
+// getStringMap returns a map of strings.
+func (c *component) getStringMap(path string) (map[string]string, error) {
+	v := c.get(path)
+	if v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a map", path)
+	}
+	values := make(map[string]string, len(m))
+	for key, item := range m {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a string", path, key)
+		}
+		values[key] = s
+	}
+	return values, nil
+}
+
+// getInt returns an integer field, or def if it isn't set.
+func (c *component) getInt(path string, def int) (int, error) {
+	switch v := c.get(path).(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("%s must be an integer", path)
+	}
+}
+
+// ignore marks fields as used when they have no effect on the converted
+// config.
+func (c *component) ignore(paths ...string) {
+	for _, path := range paths {
+		c.used[path] = struct{}{}
+	}
+}
+
+// unusedFields returns the fields which weren't read during the conversion.
+// A nested field is used if it or one of its parents was read.
+func (c *component) unusedFields() []string {
+	var (
+		unused []string
+		walk   func(prefix string, m map[string]any)
+	)
+	walk = func(prefix string, m map[string]any) {
+		for key, value := range m {
+			path := prefix + key
+			if _, ok := c.used[path]; ok {
+				continue
+			}
+			nested, ok := value.(map[string]any)
+			if !ok || !c.hasUsedChild(path) {
+				unused = append(unused, path)
+				continue
+			}
+			walk(path+".", nested)
+		}
+	}
+	walk("", c.fields)
+	slices.Sort(unused)
+	return unused
+}
+
+func (c *component) hasUsedChild(path string) bool {
+	for used := range c.used {
+		if strings.HasPrefix(used, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// config is a parsed Vector config.
+type config struct {
+	sources    []*component
+	transforms []*component
+	sinks      []*component
+	// ignored holds the top-level keys which aren't converted.
+	ignored []string
+}
+
+// parseConfig parses a Vector config in the TOML, YAML or JSON format.
+func parseConfig(in []byte) (*config, error) {
+	raw := make(map[string]any)
+	if _, tomlErr := toml.Decode(string(in), &raw); tomlErr != nil {
+		raw = make(map[string]any)
+		if yamlErr := yaml.Unmarshal(in, &raw); yamlErr != nil {
+			return nil, fmt.Errorf("the config is neither valid TOML (%s) nor valid YAML (%s)", tomlErr, yamlErr)
+		}
+	}
+
+	cfg := &config{}
+	for _, key := range sortedKeys(raw) {
+		var target *[]*component
+		switch key {
+		case "sources":
+			target = &cfg.sources
+		case "transforms":
+			target = &cfg.transforms
+		case "sinks":
+			target = &cfg.sinks
+		case "data_dir", "api":
+			// These options configure the Vector process.
+			continue
+		default:
+			cfg.ignored = append(cfg.ignored, key)
+			continue
+		}
+
+		components, ok := raw[key].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a map of components", key)
+		}
+		for _, id := range sortedKeys(components) {
+			fields, ok := components[id].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s must be a map", key, id)
+			}
+			*target = append(*target, &component{
+				id:     id,
+				kind:   strings.TrimSuffix(key, "s"),
+				fields: fields,
+				used:   make(map[string]struct{}),
+			})
+		}
+	}
+	return cfg, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
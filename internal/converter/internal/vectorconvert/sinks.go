@@ -0,0 +1,159 @@
+package vectorconvert
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/common"
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+)
+
+// fieldTemplateRegexp matches a template which is only made of a reference to
+// an event field, such as {{ kubernetes.pod_name }}.
+var fieldTemplateRegexp = regexp.MustCompile(`^\{\{\s*\.?(\w+)\s*\}\}$`)
+
+// convertSink converts a sink into a log pipeline sink.
+func (c *converter) convertSink(comp *component) *sink {
+	var (
+		desc = fmt.Sprintf("sink %q", comp.id)
+		out  = &sink{sink: &logpipeline.Sink{Label: common.SanitizeIdentifierPanics(comp.id)}}
+		errs []error
+	)
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s := out.sink
+
+	switch comp.typ() {
+	case "loki":
+		s.Type = logpipeline.SinkLoki
+		endpoint, err := comp.getString("endpoint", "")
+		check(err)
+		path, err := comp.getString("path", "/loki/api/v1/push")
+		check(err)
+		s.URL = strings.TrimSuffix(endpoint, "/") + path
+		check(c.convertAuth(comp, "auth", s))
+
+		s.TenantID, err = comp.getString("tenant_id", "")
+		check(err)
+		if strings.Contains(s.TenantID, "{{") {
+			check(fmt.Errorf("the tenant_id template can't be converted, the tenant ID must be static"))
+		}
+
+		labels, err := comp.getStringMap("labels")
+		check(err)
+		for _, name := range slices.Sorted(maps.Keys(labels)) {
+			value := labels[name]
+			switch {
+			case fieldTemplateRegexp.MatchString(value):
+				if out.labelFields == nil {
+					out.labelFields = make(map[string]string)
+				}
+				out.labelFields[name] = fieldTemplateRegexp.FindStringSubmatch(value)[1]
+			case strings.Contains(value, "{{"):
+				check(fmt.Errorf("the template of the label %s can't be converted, only templates made of a single field are supported", name))
+			default:
+				if s.ExternalLabels == nil {
+					s.ExternalLabels = make(map[string]string)
+				}
+				s.ExternalLabels[name] = value
+			}
+		}
+
+		// Alloy sends the log line as is, and the encoding options of the
+		// sink only decide how events are serialized in the line.
+		if codec, _ := comp.getString("encoding.codec", "text"); codec != "text" {
+			c.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s: the %s codec isn't converted, the log lines are sent as they are", desc, codec))
+		}
+		comp.ignore("encoding", "out_of_order_action", "remove_label_fields", "remove_timestamp", "compression", "batch", "buffer", "request")
+
+	case "prometheus_remote_write":
+		s.Type = logpipeline.SinkRemoteWrite
+		var err error
+		s.URL, err = comp.getString("endpoint", "")
+		check(err)
+		check(c.convertAuth(comp, "auth", s))
+		tenantID, err := comp.getString("tenant_id", "")
+		check(err)
+		if tenantID != "" {
+			s.Headers = map[string]string{"X-Scope-OrgID": tenantID}
+		}
+		comp.ignore("batch", "buffer", "request", "compression")
+
+	case "opentelemetry":
+		s.Type = logpipeline.SinkOTLP
+		typ, err := comp.getString("protocol.type", "")
+		check(err)
+		if typ != "http" {
+			check(fmt.Errorf("the %s protocol isn't supported, only http can be converted", typ))
+		}
+		uri, err := comp.getString("protocol.uri", "")
+		check(err)
+		if base, ok := strings.CutSuffix(uri, "/v1/logs"); ok {
+			s.URL = base
+		} else if u, err := url.Parse(uri); err != nil {
+			check(fmt.Errorf("invalid protocol.uri %q: %w", uri, err))
+		} else if u.Path == "" || u.Path == "/" {
+			s.URL = strings.TrimSuffix(uri, "/")
+		} else {
+			s.URL = u.Scheme + "://" + u.Host
+			s.LogsURL = uri
+		}
+		check(c.convertAuth(comp, "protocol.auth", s))
+		s.Headers, err = comp.getStringMap("protocol.request.headers")
+		check(err)
+		// The events are encoded as OTLP by otelcol.receiver.loki.
+		comp.ignore("protocol.encoding", "protocol.method", "protocol.payload_prefix", "protocol.payload_suffix", "protocol.batch", "protocol.compression")
+
+	default:
+		c.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the %s sink has no equivalent in Alloy", desc, comp.typ()))
+		comp.ignore(sortedKeys(comp.fields)...)
+		return nil
+	}
+	comp.ignore("healthcheck", "acknowledgements")
+
+	if s.URL == "" {
+		check(fmt.Errorf("the endpoint is required"))
+	}
+	c.checkEnvVars(s.URL)
+	c.checkEnvVars(s.Password)
+	c.checkEnvVars(s.BearerToken)
+
+	for _, err := range errs {
+		c.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", desc, err))
+	}
+	return out
+}
+
+// convertAuth converts the basic or bearer authentication of a sink.
+func (c *converter) convertAuth(comp *component, path string, s *logpipeline.Sink) error {
+	strategy, err := comp.getString(path+".strategy", "")
+	if err != nil {
+		return err
+	}
+	switch strategy {
+	case "":
+	case "basic":
+		if s.Username, err = comp.getString(path+".user", ""); err != nil {
+			return err
+		}
+		if s.Password, err = comp.getString(path+".password", ""); err != nil {
+			return err
+		}
+	case "bearer":
+		if s.BearerToken, err = comp.getString(path+".token", ""); err != nil {
+			return err
+		}
+	default:
+		comp.ignore(path)
+		return fmt.Errorf("the %s authentication strategy isn't supported", strategy)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package vectorconvert
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/common"
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+)
+
+// convertSource converts a source into a log pipeline source.
+func (c *converter) convertSource(comp *component) *logpipeline.Source {
+	var (
+		desc = fmt.Sprintf("source %q", comp.id)
+		src  = &logpipeline.Source{Label: common.SanitizeIdentifierPanics(comp.id)}
+		errs []error
+	)
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch comp.typ() {
+	case "file":
+		var err error
+		src.Type = logpipeline.SourceFile
+		src.Paths, err = comp.getStrings("include")
+		check(err)
+		src.ExcludePaths, err = comp.getStrings("exclude")
+		check(err)
+		readFrom, err := comp.getString("read_from", "beginning")
+		check(err)
+		switch readFrom {
+		case "beginning":
+		case "end":
+			src.TailFromEnd = true
+		default:
+			check(fmt.Errorf("invalid read_from %q", readFrom))
+		}
+		// The checkpoints are stored by loki.source.file in its data
+		// directory.
+		comp.ignore("data_dir", "ignore_checkpoints", "glob_minimum_cooldown_ms", "fingerprint")
+		if len(src.Paths) == 0 {
+			check(fmt.Errorf("include is required"))
+		}
+
+	case "journald":
+		src.Type = logpipeline.SourceJournal
+		path, err := comp.getString("journal_directory", "")
+		check(err)
+		src.JournalPath = path
+		units, err := comp.getStrings("include_units")
+		check(err)
+		for _, unit := range units {
+			if !strings.Contains(unit, ".") {
+				unit += ".service"
+			}
+			src.Matches = append(src.Matches, "_SYSTEMD_UNIT="+unit)
+		}
+		if len(units) > 0 && comp.get("include_matches") != nil {
+			check(fmt.Errorf("include_units and include_matches can't be combined, since loki.source.journal combines matches on different fields with a logical AND"))
+		}
+		if matches, ok := comp.get("include_matches").(map[string]any); ok {
+			for _, field := range sortedKeys(matches) {
+				values, err := comp.getStrings("include_matches." + field)
+				check(err)
+				for _, value := range values {
+					src.Matches = append(src.Matches, field+"="+value)
+				}
+			}
+			if len(matches) > 1 {
+				check(fmt.Errorf("include_matches on different fields can't be converted, since loki.source.journal combines them with a logical AND"))
+			}
+		}
+		comp.ignore("data_dir", "current_boot_only", "since_now")
+
+	case "prometheus_scrape":
+		src.Type = logpipeline.SourceScrape
+		endpoints, err := comp.getStrings("endpoints")
+		check(err)
+		for _, endpoint := range endpoints {
+			u, err := url.Parse(endpoint)
+			if err != nil {
+				check(fmt.Errorf("invalid endpoint %q: %w", endpoint, err))
+				continue
+			}
+			if u.Scheme != "http" {
+				check(fmt.Errorf("the endpoint %q doesn't use HTTP, which is the only supported scheme", endpoint))
+			}
+			if src.MetricsPath != "" && src.MetricsPath != u.Path {
+				check(fmt.Errorf("the endpoints must all use the same path"))
+			}
+			src.MetricsPath = u.Path
+			src.Targets = append(src.Targets, u.Host)
+		}
+		interval, err := comp.getInt("scrape_interval_secs", 0)
+		check(err)
+		src.ScrapeInterval = time.Duration(interval) * time.Second
+
+	case "fluent":
+		src.Type = logpipeline.SourceAPI
+		address, err := comp.getString("address", "")
+		check(err)
+		if address != "" {
+			host, port, err := net.SplitHostPort(address)
+			check(err)
+			src.ListenAddress = host
+			src.ListenPort, err = strconv.Atoi(port)
+			check(err)
+		}
+		c.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s: Alloy doesn't support the Fluent forward protocol, so the fluent source is converted to a loki.source.api component, and the clients must be changed to send logs with the Loki push API", desc))
+
+	default:
+		c.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: the %s source has no equivalent in Alloy", desc, comp.typ()))
+		comp.ignore(sortedKeys(comp.fields)...)
+		return nil
+	}
+
+	for _, err := range errs {
+		c.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", desc, err))
+	}
+	return src
+}
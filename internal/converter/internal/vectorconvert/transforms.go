@@ -0,0 +1,207 @@
+package vectorconvert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+)
+
+// The VRL statements and conditions which can be converted. Fields are
+// written .name, and the arguments of functions may be wrapped in string!().
+var (
+	parseStatementRegexp     = regexp.MustCompile(`^\.\s*\|?=\s*(?:merge\(\s*\.\s*,\s*)?parse_(json|logfmt)!\(\s*(?:string!\(\s*)?\.(\w+)[\s)]*$`)
+	regexStatementRegexp     = regexp.MustCompile(`^\.\s*\|?=\s*(?:merge\(\s*\.\s*,\s*)?parse_regex!\(\s*(?:string!\(\s*)?\.(\w+)[\s)]*,\s*r'([^']*)'[\s)]*$`)
+	timestampStatementRegexp = regexp.MustCompile(`^\.timestamp\s*=\s*parse_timestamp!\(\s*(?:string!\(\s*)?\.(\w+)[\s)]*,\s*(?:format:\s*)?"([^"]*)"[\s)]*$`)
+	assignStatementRegexp    = regexp.MustCompile(`^\.(\w+)\s*=\s*"([^"]*)"$`)
+	delStatementRegexp       = regexp.MustCompile(`^del\(\s*\.(\w+)\s*\)$`)
+
+	matchConditionRegexp    = regexp.MustCompile(`^(!)?\s*match\(\s*(?:string!\(\s*)?\.(\w+)[\s)]*,\s*r'([^']*)'\s*\)$`)
+	containsConditionRegexp = regexp.MustCompile(`^(!)?\s*contains\(\s*(?:string!\(\s*)?\.(\w+)[\s)]*,\s*"([^"]*)"\s*\)$`)
+	compareConditionRegexp  = regexp.MustCompile(`^\.(\w+)\s*(==|!=)\s*"([^"]*)"$`)
+)
+
+// vrlGroupRegexp matches the named groups of VRL regular expressions, which
+// are written (?P<name>...) in RE2.
+var vrlGroupRegexp = regexp.MustCompile(`\(\?<([a-zA-Z_][a-zA-Z0-9_]*)>`)
+
+// convertTransform converts a transform into stages. It returns false if the
+// transform can't be converted, in which case the routes going through it are
+// dropped.
+func (c *converter) convertTransform(comp *component) ([]logpipeline.Stage, bool) {
+	desc := fmt.Sprintf("transform %q", comp.id)
+
+	var (
+		stages []logpipeline.Stage
+		err    error
+	)
+	switch comp.typ() {
+	case "remap":
+		stages, err = c.remapStages(comp, desc)
+	case "filter":
+		stages, err = filterStages(comp)
+	default:
+		err = fmt.Errorf("the %s transform has no equivalent in Alloy", comp.typ())
+		comp.ignore(sortedKeys(comp.fields)...)
+	}
+	if err != nil {
+		c.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s: %s", desc, err))
+		return nil, false
+	}
+	return stages, true
+}
+
+// remapStages converts the VRL program of a remap transform, which must be
+// made of statements parsing the message or setting and deleting fields.
+func (c *converter) remapStages(comp *component, desc string) ([]logpipeline.Stage, error) {
+	// Stages don't abort, so there are no events to drop or reroute.
+	comp.ignore("drop_on_error", "drop_on_abort", "reroute_dropped", "timezone")
+	if comp.get("file") != nil {
+		return nil, fmt.Errorf("VRL programs read from files can't be converted, the program must be inlined in the source option")
+	}
+	source, err := comp.getString("source", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		stages       []logpipeline.Stage
+		staticLabels = make(map[string]string)
+		dropLabels   []string
+	)
+	for _, statement := range vrlStatements(source) {
+		if m := parseStatementRegexp.FindStringSubmatch(statement); m != nil {
+			if m[1] == "json" {
+				stages = append(stages, logpipeline.JSONStage{Source: fieldSource(m[2])})
+			} else {
+				stages = append(stages, logpipeline.LogfmtStage{Source: fieldSource(m[2])})
+			}
+			continue
+		}
+		if m := regexStatementRegexp.FindStringSubmatch(statement); m != nil {
+			expr := vrlGroupRegexp.ReplaceAllString(m[2], "(?P<$1>")
+			if _, err := regexp.Compile(expr); err != nil {
+				return nil, fmt.Errorf("the regular expression %q isn't supported by Go: %w", m[2], err)
+			}
+			stages = append(stages, logpipeline.RegexStage{Source: fieldSource(m[1]), Expression: expr})
+			continue
+		}
+		if m := timestampStatementRegexp.FindStringSubmatch(statement); m != nil {
+			layout, err := logpipeline.StrptimeToLayout(m[2])
+			if err != nil {
+				return nil, err
+			}
+			stages = append(stages, logpipeline.TimestampStage{Source: m[1], Format: layout})
+			continue
+		}
+		if m := assignStatementRegexp.FindStringSubmatch(statement); m != nil {
+			staticLabels[m[1]] = m[2]
+			continue
+		}
+		if m := delStatementRegexp.FindStringSubmatch(statement); m != nil {
+			dropLabels = append(dropLabels, m[1])
+			continue
+		}
+		return nil, fmt.Errorf("the VRL statement %q can't be converted, it must be rewritten with loki.process stages", statement)
+	}
+
+	if len(staticLabels) > 0 || len(dropLabels) > 0 {
+		c.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("%s: the fields set or deleted by the VRL program are converted to labels", desc))
+	}
+	if len(staticLabels) > 0 {
+		stages = append(stages, logpipeline.StaticLabelsStage{Values: staticLabels})
+	}
+	if len(dropLabels) > 0 {
+		stages = append(stages, logpipeline.LabelDropStage{Names: dropLabels})
+	}
+	return stages, nil
+}
+
+// filterStages converts the condition of a filter transform. The condition
+// may combine conditions with &&, each of which keeps the events matching it.
+func filterStages(comp *component) ([]logpipeline.Stage, error) {
+	condition, ok := comp.get("condition").(string)
+	if !ok {
+		// The condition may also be written as a map with the type and the
+		// source of the condition.
+		typ, err := comp.getString("condition.type", "vrl")
+		if err != nil {
+			return nil, err
+		}
+		if typ != "vrl" {
+			return nil, fmt.Errorf("the %s condition type isn't supported", typ)
+		}
+		if condition, err = comp.getString("condition.source", ""); err != nil {
+			return nil, err
+		}
+	}
+	condition = strings.TrimSpace(condition)
+	if condition == "" {
+		return nil, fmt.Errorf("condition is required")
+	}
+	if strings.Contains(condition, "||") {
+		return nil, fmt.Errorf("the condition %q can't be converted, conditions can only be combined with &&", condition)
+	}
+
+	var stages []logpipeline.Stage
+	for _, part := range strings.Split(condition, "&&") {
+		part = strings.TrimSpace(part)
+
+		var (
+			field, expr string
+			negated     bool
+		)
+		if m := matchConditionRegexp.FindStringSubmatch(part); m != nil {
+			negated, field, expr = m[1] != "", m[2], m[3]
+			if _, err := regexp.Compile(expr); err != nil {
+				return nil, fmt.Errorf("the regular expression %q isn't supported by Go: %w", expr, err)
+			}
+		} else if m := containsConditionRegexp.FindStringSubmatch(part); m != nil {
+			negated, field, expr = m[1] != "", m[2], regexp.QuoteMeta(m[3])
+		} else if m := compareConditionRegexp.FindStringSubmatch(part); m != nil {
+			negated, field, expr = m[2] == "!=", m[1], "^"+regexp.QuoteMeta(m[3])+"$"
+		} else {
+			return nil, fmt.Errorf("the condition %q can't be converted, it must be rewritten with loki.process stages", part)
+		}
+
+		// Events which don't satisfy the condition are dropped, so a
+		// negated condition drops the events matching the expression.
+		source := fieldSource(field)
+		switch {
+		case negated:
+			stages = append(stages, logpipeline.DropStage{Source: source, Expression: expr})
+		case source == "":
+			stages = append(stages, logpipeline.KeepStage{Expression: expr})
+		default:
+			return nil, fmt.Errorf("the condition %q can't be converted, only the message can be matched to keep events", part)
+		}
+	}
+	return stages, nil
+}
+
+// vrlStatements splits a VRL program into statements, skipping blank lines
+// and comments.
+func vrlStatements(source string) []string {
+	var statements []string
+	for _, line := range strings.Split(source, "\n") {
+		for _, statement := range strings.Split(line, ";") {
+			statement = strings.TrimSpace(statement)
+			if statement == "" || strings.HasPrefix(statement, "#") {
+				continue
+			}
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// fieldSource returns the name of the field holding the value of an event
+// field, where an empty name is the log line.
+func fieldSource(field string) string {
+	if field == "message" {
+		return ""
+	}
+	return field
+}
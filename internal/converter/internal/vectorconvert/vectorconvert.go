@@ -0,0 +1,222 @@
+// Package vectorconvert converts Vector configurations, in the TOML, YAML or
+// JSON format, into Alloy configurations.
+package vectorconvert
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/grafana/alloy/internal/converter/diag"
+	"github.com/grafana/alloy/internal/converter/internal/common"
+	"github.com/grafana/alloy/internal/converter/internal/logpipeline"
+	"github.com/grafana/alloy/syntax/token/builder"
+)
+
+// Convert implements a Vector config converter.
+//
+// extraArgs are supported to mirror the other converter params due to shared
+// testing code but they should be passed empty to this converter.
+func Convert(in []byte, extraArgs []string) ([]byte, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if len(extraArgs) > 0 {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("extra arguments are not supported for the vector converter: %s", extraArgs))
+		return nil, diags
+	}
+
+	cfg, err := parseConfig(in)
+	if err != nil {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to parse Vector config: %s", err))
+		return nil, diags
+	}
+
+	f := builder.NewFile()
+	pipeline, buildDiags := buildPipeline(cfg)
+	diags.AddAll(buildDiags)
+	diags.AddAll(logpipeline.Append(f, pipeline))
+	diags.AddAll(common.ValidateNodes(f))
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		diags.Add(diag.SeverityLevelCritical, fmt.Sprintf("failed to render Alloy config: %s", err.Error()))
+		return nil, diags
+	}
+
+	if len(buf.Bytes()) == 0 {
+		return nil, diags
+	}
+
+	prettyByte, newDiags := common.PrettyPrint(buf.Bytes())
+	diags.AddAll(newDiags)
+	return prettyByte, diags
+}
+
+// sink is a converted sink.
+type sink struct {
+	sink *logpipeline.Sink
+	// labelFields maps the labels of a loki sink which are read from event
+	// fields to the fields.
+	labelFields map[string]string
+}
+
+// converter holds the state of the conversion of a Vector config.
+type converter struct {
+	diags      diag.Diagnostics
+	sources    map[string]*logpipeline.Source
+	transforms map[string][]logpipeline.Stage
+	sinks      map[string]*sink
+	// consumers maps the ID of a component to the transforms and sinks which
+	// have it as input.
+	consumers map[string][]string
+}
+
+// buildPipeline converts the components of a Vector config into a log
+// pipeline. Every path from a source to a sink through transforms becomes a
+// route of the source, and paths going through the same transforms share a
+// route.
+func buildPipeline(cfg *config) (*logpipeline.Config, diag.Diagnostics) {
+	c := &converter{
+		sources:    make(map[string]*logpipeline.Source),
+		transforms: make(map[string][]logpipeline.Stage),
+		sinks:      make(map[string]*sink),
+		consumers:  make(map[string][]string),
+	}
+	for _, key := range cfg.ignored {
+		c.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the %s section isn't supported and is ignored", key))
+	}
+
+	var ids []string
+	for _, src := range cfg.sources {
+		ids = append(ids, src.id)
+	}
+	for _, t := range cfg.transforms {
+		ids = append(ids, t.id)
+	}
+
+	out := &logpipeline.Config{}
+	for _, src := range cfg.sources {
+		if source := c.convertSource(src); source != nil {
+			c.sources[src.id] = source
+			out.Sources = append(out.Sources, source)
+		}
+	}
+	for _, t := range cfg.transforms {
+		c.resolveInputs(t, ids)
+		if stages, ok := c.convertTransform(t); ok {
+			c.transforms[t.id] = stages
+		}
+	}
+	for _, s := range cfg.sinks {
+		c.resolveInputs(s, ids)
+		if converted := c.convertSink(s); converted != nil {
+			c.sinks[s.id] = converted
+			out.Sinks = append(out.Sinks, converted.sink)
+		}
+	}
+
+	for _, src := range cfg.sources {
+		if source, ok := c.sources[src.id]; ok {
+			source.Routes = c.routes(src.id)
+		}
+	}
+
+	for _, components := range [][]*component{cfg.sources, cfg.transforms, cfg.sinks} {
+		for _, comp := range components {
+			for _, field := range comp.unusedFields() {
+				c.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the %s option of the %s %s %q isn't supported and is ignored", field, comp.typ(), comp.kind, comp.id))
+			}
+		}
+	}
+	return out, c.diags
+}
+
+// resolveInputs registers a transform or sink as a consumer of its inputs.
+// Inputs can use * as a wildcard.
+func (c *converter) resolveInputs(comp *component, ids []string) {
+	inputs, err := comp.getStrings("inputs")
+	if err != nil {
+		c.diags.Add(diag.SeverityLevelError, fmt.Sprintf("%s %q: %s", comp.kind, comp.id, err))
+		return
+	}
+	for _, input := range inputs {
+		re := wildcardRegexp(input)
+		var matched bool
+		for _, id := range ids {
+			if id != comp.id && re.MatchString(id) {
+				c.consumers[id] = append(c.consumers[id], comp.id)
+				matched = true
+			}
+		}
+		if !matched {
+			c.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the input %q of the %s %q doesn't match any component", input, comp.kind, comp.id))
+		}
+	}
+}
+
+// routes returns the routes of the source with the given ID.
+func (c *converter) routes(sourceID string) []*logpipeline.Route {
+	var (
+		routes []*logpipeline.Route
+		byKey  = make(map[string]*logpipeline.Route)
+		walk   func(id string, chain []string)
+	)
+	walk = func(id string, chain []string) {
+		for _, consumer := range c.consumers[id] {
+			if _, ok := c.transforms[consumer]; ok {
+				if !slices.Contains(chain, consumer) {
+					walk(consumer, append(slices.Clone(chain), consumer))
+				}
+				continue
+			}
+			s, ok := c.sinks[consumer]
+			if !ok {
+				continue
+			}
+
+			// Sinks which extract labels from fields need their own route.
+			key := strings.Join(chain, ",")
+			if len(s.labelFields) > 0 {
+				key += "|" + consumer
+			}
+			route, ok := byKey[key]
+			if !ok {
+				route = &logpipeline.Route{}
+				for _, t := range chain {
+					route.Stages = append(route.Stages, c.transforms[t]...)
+				}
+				if len(s.labelFields) > 0 {
+					route.Stages = append(route.Stages, logpipeline.LabelsStage{Values: s.labelFields})
+				}
+				byKey[key] = route
+				routes = append(routes, route)
+			}
+			route.Sinks = append(route.Sinks, s.sink.Label)
+		}
+	}
+	walk(sourceID, nil)
+	return routes
+}
+
+// wildcardRegexp converts a component ID pattern, where * matches any
+// sequence of characters, into a regular expression.
+func wildcardRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// envVarRegexp matches the environment variables Vector interpolates in the
+// config.
+var envVarRegexp = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// checkEnvVars reports the environment variables used in a value.
+func (c *converter) checkEnvVars(value string) {
+	for _, match := range envVarRegexp.FindAllStringSubmatch(value, -1) {
+		c.diags.Add(diag.SeverityLevelWarn, fmt.Sprintf("the environment variable %s isn't resolved, its reference must be replaced manually, for example with sys.env(%q)", match[1], match[1]))
+	}
+}
@@ -0,0 +1,117 @@
+package pipelinetest
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+)
+
+// compareLogs matches the expected log entries against the entries received
+// by a sink. Entries are matched regardless of their order. Labels and
+// structured metadata must match exactly, while timestamps are only compared
+// when set in the expected entry.
+func compareLogs(component string, expected []LogEntry, actual []loki.Entry) []string {
+	received := make([]LogEntry, 0, len(actual))
+	for _, e := range actual {
+		entry := LogEntry{
+			Line:      e.Line,
+			Labels:    make(map[string]string, len(e.Labels)),
+			Timestamp: e.Timestamp,
+		}
+		for k, v := range e.Labels {
+			entry.Labels[string(k)] = string(v)
+		}
+		if len(e.StructuredMetadata) > 0 {
+			entry.StructuredMetadata = make(map[string]string, len(e.StructuredMetadata))
+			for _, l := range e.StructuredMetadata {
+				entry.StructuredMetadata[l.Name] = l.Value
+			}
+		}
+		received = append(received, entry)
+	}
+
+	missing, unexpected := match(expected, received, func(want, got LogEntry) bool {
+		return want.Line == got.Line &&
+			maps.Equal(want.Labels, got.Labels) &&
+			maps.Equal(want.StructuredMetadata, got.StructuredMetadata) &&
+			timestampMatches(want.Timestamp, got.Timestamp)
+	})
+
+	var failures []string
+	for _, e := range missing {
+		failures = append(failures, fmt.Sprintf("%s: missing log entry %s", component, formatLogEntry(e)))
+	}
+	for _, e := range unexpected {
+		failures = append(failures, fmt.Sprintf("%s: unexpected log entry %s", component, formatLogEntry(e)))
+	}
+	return failures
+}
+
+// compareSamples matches the expected samples against the samples received by
+// a sink. Samples are matched regardless of their order. Timestamps are only
+// compared when set in the expected sample.
+func compareSamples(component string, expected []Sample, actual []Sample) []string {
+	missing, unexpected := match(expected, actual, func(want, got Sample) bool {
+		return want.Value == got.Value &&
+			maps.Equal(want.Labels, got.Labels) &&
+			timestampMatches(want.Timestamp, got.Timestamp)
+	})
+
+	var failures []string
+	for _, s := range missing {
+		failures = append(failures, fmt.Sprintf("%s: missing sample %s", component, formatSample(s)))
+	}
+	for _, s := range unexpected {
+		failures = append(failures, fmt.Sprintf("%s: unexpected sample %s", component, formatSample(s)))
+	}
+	return failures
+}
+
+// match pairs every expected item with a distinct actual item. It returns the
+// expected items without a match and the actual items which weren't paired.
+func match[T any](expected, actual []T, equal func(want, got T) bool) (missing, unexpected []T) {
+	paired := make([]bool, len(actual))
+	for _, want := range expected {
+		found := false
+		for i, got := range actual {
+			if !paired[i] && equal(want, got) {
+				paired[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+	for i, got := range actual {
+		if !paired[i] {
+			unexpected = append(unexpected, got)
+		}
+	}
+	return missing, unexpected
+}
+
+func timestampMatches(want, got time.Time) bool {
+	return want.IsZero() || want.Equal(got)
+}
+
+func formatLogEntry(e LogEntry) string {
+	lbls := make(model.LabelSet, len(e.Labels))
+	for k, v := range e.Labels {
+		lbls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	s := fmt.Sprintf("%s %q", lbls, e.Line)
+	if len(e.StructuredMetadata) > 0 {
+		s += fmt.Sprintf(" with structured metadata %s", labels.FromMap(e.StructuredMetadata))
+	}
+	return s
+}
+
+func formatSample(s Sample) string {
+	return fmt.Sprintf("%s %v", labels.FromMap(s.Labels), s.Value)
+}
@@ -0,0 +1,338 @@
+package pipelinetest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/service/labelstore"
+)
+
+// The components below are substituted for the components of a configuration
+// under test. They aren't meant to be used in regular configurations.
+const (
+	logsInputName    = "pipelinetest.logs"
+	samplesInputName = "pipelinetest.samples"
+	targetsInputName = "pipelinetest.targets"
+	logsSinkName     = "pipelinetest.logs_sink"
+	samplesSinkName  = "pipelinetest.samples_sink"
+)
+
+// harnessComponents holds the registrations of the harness components. They
+// are only given to the controllers running tests, and never registered to
+// the global component registry.
+var harnessComponents = registrationsByName([]component.Registration{
+	{
+		Name:      logsInputName,
+		Stability: featuregate.StabilityGenerallyAvailable,
+		Args:      LogsArguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			c := &logsInput{}
+			return c, c.Update(args)
+		},
+	},
+	{
+		Name:      samplesInputName,
+		Stability: featuregate.StabilityGenerallyAvailable,
+		Args:      SamplesArguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			c := &samplesInput{}
+			return c, c.Update(args)
+		},
+	},
+	{
+		Name:      targetsInputName,
+		Stability: featuregate.StabilityGenerallyAvailable,
+		Args:      TargetsArguments{},
+		Exports:   discovery.Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			c := &targetsInput{opts: opts}
+			return c, c.Update(args)
+		},
+	},
+	{
+		Name:      logsSinkName,
+		Stability: featuregate.StabilityGenerallyAvailable,
+		Args:      SinkArguments{},
+		Exports:   LogsSinkExports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			c := &logsSink{receiver: loki.NewLogsReceiver()}
+			opts.OnStateChange(LogsSinkExports{Receiver: c.receiver})
+			return c, nil
+		},
+	},
+	{
+		Name:      samplesSinkName,
+		Stability: featuregate.StabilityGenerallyAvailable,
+		Args:      SinkArguments{},
+		Exports:   SamplesSinkExports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			data, err := opts.GetServiceData(labelstore.ServiceName)
+			if err != nil {
+				return nil, err
+			}
+			c := &samplesSink{}
+			receiver := prometheus.NewInterceptor(nil, data.(labelstore.LabelStore), prometheus.WithAppendHook(c.append))
+			opts.OnStateChange(SamplesSinkExports{Receiver: receiver})
+			return c, nil
+		},
+	},
+})
+
+func registrationsByName(regs []component.Registration) map[string]component.Registration {
+	m := make(map[string]component.Registration, len(regs))
+	for _, reg := range regs {
+		m[reg.Name] = reg
+	}
+	return m
+}
+
+// LogEntry is a log line used as a fixture or as an expected result.
+type LogEntry struct {
+	Line               string            `alloy:"line,attr"`
+	Labels             map[string]string `alloy:"labels,attr,optional"`
+	StructuredMetadata map[string]string `alloy:"structured_metadata,attr,optional"`
+	Timestamp          time.Time         `alloy:"timestamp,attr,optional"`
+}
+
+func (e LogEntry) lokiEntry(now time.Time) loki.Entry {
+	ts := e.Timestamp
+	if ts.IsZero() {
+		ts = now
+	}
+
+	lbls := make(model.LabelSet, len(e.Labels))
+	for k, v := range e.Labels {
+		lbls[model.LabelName(k)] = model.LabelValue(v)
+	}
+
+	entry := loki.Entry{
+		Labels: lbls,
+		Entry: logproto.Entry{
+			Timestamp: ts,
+			Line:      e.Line,
+		},
+	}
+	for k, v := range e.StructuredMetadata {
+		entry.StructuredMetadata = append(entry.StructuredMetadata, logproto.LabelAdapter{Name: k, Value: v})
+	}
+	return entry
+}
+
+// Sample is a metric sample used as a fixture or as an expected result. The
+// metric name is set with the __name__ label.
+type Sample struct {
+	Labels    map[string]string `alloy:"labels,attr"`
+	Value     float64           `alloy:"value,attr"`
+	Timestamp time.Time         `alloy:"timestamp,attr,optional"`
+}
+
+// input is implemented by components which send fixtures into the pipeline.
+type input interface {
+	// Sent returns true once all fixtures have been sent.
+	Sent() bool
+}
+
+// LogsArguments holds the arguments of the pipelinetest.logs component.
+type LogsArguments struct {
+	ForwardTo []loki.LogsReceiver `alloy:"forward_to,attr"`
+	Entries   []LogEntry          `alloy:"entry,block,optional"`
+}
+
+type logsInput struct {
+	mut  sync.Mutex
+	args LogsArguments
+	sent atomic.Bool
+}
+
+var _ input = (*logsInput)(nil)
+
+func (c *logsInput) Run(ctx context.Context) error {
+	c.mut.Lock()
+	args := c.args
+	c.mut.Unlock()
+
+	now := time.Now()
+	for _, e := range args.Entries {
+		for _, receiver := range args.ForwardTo {
+			select {
+			case <-ctx.Done():
+				return nil
+			case receiver.Chan() <- e.lokiEntry(now):
+			}
+		}
+	}
+	c.sent.Store(true)
+
+	<-ctx.Done()
+	return nil
+}
+
+func (c *logsInput) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = args.(LogsArguments)
+	return nil
+}
+
+func (c *logsInput) Sent() bool { return c.sent.Load() }
+
+// SamplesArguments holds the arguments of the pipelinetest.samples component.
+type SamplesArguments struct {
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+	Samples   []Sample             `alloy:"sample,block,optional"`
+}
+
+type samplesInput struct {
+	mut  sync.Mutex
+	args SamplesArguments
+	sent atomic.Bool
+}
+
+var _ input = (*samplesInput)(nil)
+
+func (c *samplesInput) Run(ctx context.Context) error {
+	c.mut.Lock()
+	args := c.args
+	c.mut.Unlock()
+
+	now := time.Now()
+	for _, receiver := range args.ForwardTo {
+		app := receiver.Appender(ctx)
+		for _, s := range args.Samples {
+			ts := s.Timestamp
+			if ts.IsZero() {
+				ts = now
+			}
+			if _, err := app.Append(0, labels.FromMap(s.Labels), ts.UnixMilli(), s.Value); err != nil {
+				_ = app.Rollback()
+				return err
+			}
+		}
+		if err := app.Commit(); err != nil {
+			return err
+		}
+	}
+	c.sent.Store(true)
+
+	<-ctx.Done()
+	return nil
+}
+
+func (c *samplesInput) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = args.(SamplesArguments)
+	return nil
+}
+
+func (c *samplesInput) Sent() bool { return c.sent.Load() }
+
+// TargetsArguments holds the arguments of the pipelinetest.targets component.
+type TargetsArguments struct {
+	Targets []discovery.Target `alloy:"targets,attr"`
+}
+
+type targetsInput struct {
+	opts component.Options
+}
+
+func (c *targetsInput) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (c *targetsInput) Update(args component.Arguments) error {
+	c.opts.OnStateChange(discovery.Exports{Targets: args.(TargetsArguments).Targets})
+	return nil
+}
+
+// SinkArguments holds the arguments of the sink components. Sinks don't
+// have any argument.
+type SinkArguments struct{}
+
+// LogsSinkExports holds the exports of the pipelinetest.logs_sink component.
+type LogsSinkExports struct {
+	Receiver loki.LogsReceiver `alloy:"receiver,attr"`
+}
+
+type logsSink struct {
+	receiver loki.LogsReceiver
+
+	mut     sync.Mutex
+	entries []loki.Entry
+}
+
+func (c *logsSink) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-c.receiver.Chan():
+			c.mut.Lock()
+			c.entries = append(c.entries, e)
+			c.mut.Unlock()
+		}
+	}
+}
+
+func (c *logsSink) Update(component.Arguments) error { return nil }
+
+// Entries returns the entries received so far.
+func (c *logsSink) Entries() []loki.Entry {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return append([]loki.Entry(nil), c.entries...)
+}
+
+// SamplesSinkExports holds the exports of the pipelinetest.samples_sink
+// component.
+type SamplesSinkExports struct {
+	Receiver storage.Appendable `alloy:"receiver,attr"`
+}
+
+type samplesSink struct {
+	mut     sync.Mutex
+	samples []Sample
+}
+
+func (c *samplesSink) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (c *samplesSink) Update(component.Arguments) error { return nil }
+
+func (c *samplesSink) append(ref storage.SeriesRef, l labels.Labels, t int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.samples = append(c.samples, Sample{
+		Labels:    l.Map(),
+		Value:     v,
+		Timestamp: time.UnixMilli(t),
+	})
+	return ref, nil
+}
+
+// Samples returns the samples received so far.
+func (c *samplesSink) Samples() []Sample {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return append([]Sample(nil), c.samples...)
+}
@@ -0,0 +1,201 @@
+package pipelinetest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/grafana/alloy/syntax/ast"
+	"github.com/grafana/alloy/syntax/parser"
+)
+
+// edit replaces the bytes of a source between start (inclusive) and end
+// (exclusive) with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// rewriteConfig replaces the components of config which are referenced by
+// test with harness components:
+//
+//   - Components replaced by fixture logs or samples become components
+//     sending the fixtures to the receivers of their forward_to argument.
+//   - Components replaced by fixture targets become components exporting the
+//     fixture targets.
+//   - Components with expectations become sinks capturing the data sent to
+//     their receiver.
+//
+// References to the replaced components are updated to point to their
+// replacement. The rest of the configuration is left untouched so that
+// diagnostics keep pointing to the lines written by the user.
+func rewriteConfig(name string, config []byte, test *Test) ([]byte, error) {
+	file, err := parser.ParseFile(name, config)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(map[string]*ast.BlockStmt)
+	for _, stmt := range file.Body {
+		if block, ok := stmt.(*ast.BlockStmt); ok {
+			blocks[blockID(block)] = block
+		}
+	}
+
+	replacements := make(map[string]string)
+	for _, in := range test.Logs {
+		replacements[in.Component] = logsInputName
+	}
+	for _, in := range test.Samples {
+		replacements[in.Component] = samplesInputName
+	}
+	for _, in := range test.Targets {
+		replacements[in.Component] = targetsInputName
+	}
+	for _, expect := range test.ExpectLogs {
+		replacements[expect.Component] = logsSinkName
+	}
+	for _, expect := range test.ExpectSamples {
+		replacements[expect.Component] = samplesSinkName
+	}
+
+	finder := &referenceFinder{replacements: replacements}
+	ast.Walk(finder, file)
+
+	var edits []edit
+	for id, harnessName := range replacements {
+		block, ok := blocks[id]
+		if !ok {
+			return nil, fmt.Errorf("component %q not found in %s", id, name)
+		}
+
+		var body strings.Builder
+		switch harnessName {
+		case logsInputName, samplesInputName:
+			forwardTo := findAttribute(block.Body, "forward_to")
+			if forwardTo == nil {
+				return nil, fmt.Errorf("component %q has no forward_to argument and can't be replaced with fixtures", id)
+			}
+			start, end := ast.StartPos(forwardTo.Value).Offset(), ast.EndPos(forwardTo.Value).Offset()+1
+			fmt.Fprintf(&body, "\n\tforward_to = %s", applyEdits(config[start:end], start, finder.edits))
+			body.Write(test.fixtures[id])
+		case targetsInputName:
+			body.Write(test.fixtures[id])
+		}
+
+		edits = append(edits, edit{
+			start: block.NamePos.Offset(),
+			end:   block.RCurlyPos.Offset() + 1,
+			text:  fmt.Sprintf("%s %q {%s\n}", harnessName, harnessLabel(id), body.String()),
+		})
+	}
+
+	// References within replaced blocks are either dropped or have already
+	// been applied to the forward_to arguments above.
+	for _, ref := range finder.edits {
+		inside := slices.ContainsFunc(edits, func(e edit) bool {
+			return ref.start >= e.start && ref.end <= e.end
+		})
+		if !inside {
+			edits = append(edits, ref)
+		}
+	}
+
+	return []byte(applyEdits(config, 0, edits)), nil
+}
+
+// applyEdits applies the edits which fall within src, where offset is the
+// position of src in the source the edits refer to.
+func applyEdits(src []byte, offset int, edits []edit) string {
+	var inRange []edit
+	for _, e := range edits {
+		if e.start >= offset && e.end <= offset+len(src) {
+			inRange = append(inRange, e)
+		}
+	}
+	slices.SortFunc(inRange, func(a, b edit) int { return a.start - b.start })
+
+	var (
+		sb   strings.Builder
+		last = offset
+	)
+	for _, e := range inRange {
+		sb.Write(src[last-offset : e.start-offset])
+		sb.WriteString(e.text)
+		last = e.end
+	}
+	sb.Write(src[last-offset:])
+	return sb.String()
+}
+
+// referenceFinder collects edits updating the references to replaced
+// components.
+type referenceFinder struct {
+	replacements map[string]string // Component ID -> harness component name.
+	edits        []edit
+}
+
+func (f *referenceFinder) Visit(node ast.Node) ast.Visitor {
+	access, ok := node.(*ast.AccessExpr)
+	if !ok {
+		return f
+	}
+
+	id, ok := flattenReference(access.Value)
+	if !ok {
+		return f
+	}
+	if harnessName, found := f.replacements[id]; found {
+		f.edits = append(f.edits, edit{
+			start: ast.StartPos(access.Value).Offset(),
+			end:   ast.EndPos(access.Value).Offset() + 1,
+			text:  harnessID(harnessName, id),
+		})
+		return nil
+	}
+	return f
+}
+
+// flattenReference returns the dotted name of an expression made of
+// identifiers and field accesses only.
+func flattenReference(expr ast.Expr) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.IdentifierExpr:
+		return expr.Ident.Name, true
+	case *ast.AccessExpr:
+		prefix, ok := flattenReference(expr.Value)
+		if !ok {
+			return "", false
+		}
+		return prefix + "." + expr.Name.Name, true
+	default:
+		return "", false
+	}
+}
+
+func blockID(block *ast.BlockStmt) string {
+	id := strings.Join(block.Name, ".")
+	if block.Label != "" {
+		id += "." + block.Label
+	}
+	return id
+}
+
+func findAttribute(body ast.Body, name string) *ast.AttributeStmt {
+	for _, stmt := range body {
+		if attr, ok := stmt.(*ast.AttributeStmt); ok && attr.Name.Name == name {
+			return attr
+		}
+	}
+	return nil
+}
+
+// harnessLabel returns the label of the harness component replacing the
+// component with the given ID.
+func harnessLabel(id string) string {
+	return strings.ReplaceAll(id, ".", "_")
+}
+
+func harnessID(harnessName, id string) string {
+	return harnessName + "." + harnessLabel(id)
+}
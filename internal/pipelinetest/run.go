@@ -0,0 +1,268 @@
+// Package pipelinetest runs Alloy configurations against fixture data.
+//
+// A test replaces components of a configuration with harness components
+// sending fixture logs, samples, or targets into the pipeline, and replaces
+// the components the data flows to with sinks. The configuration then runs
+// through a regular Alloy controller, and the data captured by the sinks is
+// compared to the expected results of the test.
+package pipelinetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/featuregate"
+	alloy_runtime "github.com/grafana/alloy/internal/runtime"
+	"github.com/grafana/alloy/internal/runtime/logging"
+	"github.com/grafana/alloy/internal/service/standalone"
+)
+
+// settleInterval is how long the outputs of a pipeline must remain unchanged
+// after all fixtures have been sent for the pipeline to be considered done.
+const settleInterval = 200 * time.Millisecond
+
+// Options configures how tests are run.
+type Options struct {
+	// Logger receives the logs of the components under test. A no-op logger
+	// is used if nil.
+	Logger *logging.Logger
+
+	// MinStability is the minimum stability level of the components which
+	// can be used by the configuration under test.
+	MinStability featuregate.Stability
+
+	// EnableCommunityComps enables the use of community components.
+	EnableCommunityComps bool
+}
+
+// Result is the outcome of a test.
+type Result struct {
+	Test string
+
+	// Err is set if the test could not run, for example because the
+	// configuration failed to load.
+	Err error
+
+	// Failures lists the differences between the expected and the actual
+	// results.
+	Failures []string
+}
+
+// Passed returns true if the test ran and all expectations were met.
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// Run runs every test of file against the given configuration. Tests are run
+// sequentially, each with its own Alloy controller.
+func Run(ctx context.Context, opts Options, configName string, config []byte, file *File) []Result {
+	if opts.Logger == nil {
+		opts.Logger = logging.NewNop()
+	}
+
+	results := make([]Result, 0, len(file.Tests))
+	for _, test := range file.Tests {
+		result := Result{Test: test.Name}
+		result.Failures, result.Err = runTest(ctx, opts, configName, config, test)
+		results = append(results, result)
+	}
+	return results
+}
+
+func runTest(ctx context.Context, opts Options, configName string, config []byte, test *Test) ([]string, error) {
+	rewritten, err := rewriteConfig(configName, config, test)
+	if err != nil {
+		return nil, err
+	}
+	source, err := alloy_runtime.ParseSource(configName, rewritten)
+	if err != nil {
+		return nil, err
+	}
+
+	dataPath, err := os.MkdirTemp("", "alloy-test-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating data directory: %w", err)
+	}
+	defer os.RemoveAll(dataPath)
+
+	services, err := standalone.Services(standalone.Options{
+		Logger:   opts.Logger,
+		DataPath: dataPath,
+		NodeName: "alloy-test",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rt := alloy_runtime.New(alloy_runtime.Options{
+		Logger:               opts.Logger,
+		DataPath:             dataPath,
+		Reg:                  prometheus.NewRegistry(),
+		MinStability:         opts.MinStability,
+		EnableCommunityComps: opts.EnableCommunityComps,
+		Services:             services,
+		ExtraComponents:      harnessComponents,
+	})
+	if err := rt.LoadSource(source, nil, configName); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, test.Timeout)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rt.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	h, err := newHarness(rt, test)
+	if err != nil {
+		return nil, err
+	}
+	h.wait(ctx)
+	return h.compare(), nil
+}
+
+// harness holds the components substituted in the configuration of a test.
+type harness struct {
+	test *Test
+
+	inputs       []input
+	logsSinks    map[string]*logsSink
+	samplesSinks map[string]*samplesSink
+}
+
+func newHarness(rt *alloy_runtime.Runtime, test *Test) (*harness, error) {
+	h := &harness{
+		test:         test,
+		logsSinks:    make(map[string]*logsSink),
+		samplesSinks: make(map[string]*samplesSink),
+	}
+
+	get := func(name, id string) (component.Component, error) {
+		info, err := rt.GetComponent(component.ID{LocalID: harnessID(name, id)}, component.InfoOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting replacement of %q: %w", id, err)
+		}
+		if info.Component == nil {
+			return nil, fmt.Errorf("replacement of %q is not running", id)
+		}
+		return info.Component, nil
+	}
+
+	for _, in := range test.Logs {
+		c, err := get(logsInputName, in.Component)
+		if err != nil {
+			return nil, err
+		}
+		h.inputs = append(h.inputs, c.(input))
+	}
+	for _, in := range test.Samples {
+		c, err := get(samplesInputName, in.Component)
+		if err != nil {
+			return nil, err
+		}
+		h.inputs = append(h.inputs, c.(input))
+	}
+	for _, expect := range test.ExpectLogs {
+		c, err := get(logsSinkName, expect.Component)
+		if err != nil {
+			return nil, err
+		}
+		h.logsSinks[expect.Component] = c.(*logsSink)
+	}
+	for _, expect := range test.ExpectSamples {
+		c, err := get(samplesSinkName, expect.Component)
+		if err != nil {
+			return nil, err
+		}
+		h.samplesSinks[expect.Component] = c.(*samplesSink)
+	}
+	return h, nil
+}
+
+// wait blocks until all fixtures have been sent and the sinks have stopped
+// receiving data for settleInterval, or until ctx is canceled.
+func (h *harness) wait(ctx context.Context) {
+	ticker := time.NewTicker(settleInterval / 4)
+	defer ticker.Stop()
+
+	var (
+		lastCount   = -1
+		lastChanged time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !h.sent() {
+				continue
+			}
+			if count := h.received(); count != lastCount {
+				lastCount, lastChanged = count, now
+				continue
+			}
+			if now.Sub(lastChanged) >= settleInterval && h.satisfied() {
+				return
+			}
+		}
+	}
+}
+
+func (h *harness) sent() bool {
+	for _, in := range h.inputs {
+		if !in.Sent() {
+			return false
+		}
+	}
+	return true
+}
+
+// received returns the total number of items received by the sinks.
+func (h *harness) received() int {
+	var count int
+	for _, sink := range h.logsSinks {
+		count += len(sink.Entries())
+	}
+	for _, sink := range h.samplesSinks {
+		count += len(sink.Samples())
+	}
+	return count
+}
+
+// satisfied returns true if every sink received at least as many items as
+// expected. Pipelines may batch or delay data, so the harness keeps waiting
+// for missing items until the test times out.
+func (h *harness) satisfied() bool {
+	for _, expect := range h.test.ExpectLogs {
+		if len(h.logsSinks[expect.Component].Entries()) < len(expect.Entries) {
+			return false
+		}
+	}
+	for _, expect := range h.test.ExpectSamples {
+		if len(h.samplesSinks[expect.Component].Samples()) < len(expect.Samples) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *harness) compare() []string {
+	var failures []string
+	for _, expect := range h.test.ExpectLogs {
+		failures = append(failures, compareLogs(expect.Component, expect.Entries, h.logsSinks[expect.Component].Entries())...)
+	}
+	for _, expect := range h.test.ExpectSamples {
+		failures = append(failures, compareSamples(expect.Component, expect.Samples, h.samplesSinks[expect.Component].Samples())...)
+	}
+	return failures
+}
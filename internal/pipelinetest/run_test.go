@@ -0,0 +1,343 @@
+package pipelinetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/grafana/alloy/internal/component/discovery/relabel"
+	_ "github.com/grafana/alloy/internal/component/loki/process"
+	_ "github.com/grafana/alloy/internal/component/loki/source/file"
+	_ "github.com/grafana/alloy/internal/component/loki/write"
+	_ "github.com/grafana/alloy/internal/component/prometheus/relabel"
+	_ "github.com/grafana/alloy/internal/component/prometheus/remotewrite"
+	_ "github.com/grafana/alloy/internal/component/prometheus/scrape"
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+const testConfig = `
+discovery.kubernetes "pods" {
+	role = "pod"
+}
+
+discovery.relabel "pods" {
+	targets = discovery.kubernetes.pods.targets
+
+	rule {
+		source_labels = ["__meta_kubernetes_namespace"]
+		target_label  = "namespace"
+	}
+}
+
+loki.source.file "app" {
+	targets    = discovery.relabel.pods.output
+	forward_to = [loki.process.app.receiver]
+}
+
+loki.process "app" {
+	forward_to = [loki.write.default.receiver]
+
+	stage.drop {
+		expression = ".*level=debug.*"
+	}
+
+	stage.static_labels {
+		values = { pipeline = "app" }
+	}
+}
+
+loki.write "default" {
+	endpoint {
+		url = "http://loki:3100/loki/api/v1/push"
+	}
+}
+
+prometheus.scrape "app" {
+	targets    = discovery.relabel.pods.output
+	forward_to = [prometheus.relabel.app.receiver]
+}
+
+prometheus.relabel "app" {
+	forward_to = [prometheus.remote_write.default.receiver]
+
+	rule {
+		source_labels = ["__name__"]
+		regex         = "go_.*"
+		action        = "drop"
+	}
+}
+
+prometheus.remote_write "default" {
+	endpoint {
+		url = "http://mimir:9009/api/v1/push"
+	}
+}
+`
+
+func TestParseFile(t *testing.T) {
+	tt := []struct {
+		name      string
+		source    string
+		expectErr string
+	}{
+		{
+			name: "valid",
+			source: `
+				test "a" {
+					timeout = "1s"
+					logs {
+						component = "loki.source.file.app"
+						entry {
+							line      = "hello"
+							labels    = { job = "app" }
+							timestamp = "2024-01-01T00:00:00Z"
+						}
+					}
+					expect_logs { component = "loki.write.default" }
+				}
+			`,
+		},
+		{
+			name:      "not a test block",
+			source:    `foo = "bar"`,
+			expectErr: "test files may only contain test blocks",
+		},
+		{
+			name:      "missing label",
+			source:    `test { }`,
+			expectErr: "test blocks must have a label",
+		},
+		{
+			name:      "duplicate test",
+			source:    "test \"a\" { }\ntest \"a\" { }",
+			expectErr: `test "a" already declared`,
+		},
+		{
+			name: "component replaced twice",
+			source: `
+				test "a" {
+					logs { component = "loki.source.file.app" }
+					expect_logs { component = "loki.source.file.app" }
+				}
+			`,
+			expectErr: `component "loki.source.file.app" is replaced more than once`,
+		},
+		{
+			name:      "invalid block",
+			source:    `test "a" { foo "bar" { } }`,
+			expectErr: `unrecognized block name "foo"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ParseFile("test.alloy", []byte(tc.source))
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, f.Tests, 1)
+			require.Equal(t, time.Second, f.Tests[0].Timeout)
+			require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), f.Tests[0].Logs[0].Entries[0].Timestamp)
+		})
+	}
+}
+
+func TestRewriteConfig(t *testing.T) {
+	f, err := ParseFile("test.alloy", []byte(`
+		test "a" {
+			targets {
+				component = "discovery.kubernetes.pods"
+				targets = [{ __meta_kubernetes_namespace = "default" }]
+			}
+			logs { component = "loki.source.file.app" }
+			expect_logs { component = "loki.write.default" }
+		}
+	`))
+	require.NoError(t, err)
+
+	config := `
+discovery.kubernetes "pods" {
+	role = "pod"
+}
+
+discovery.relabel "pods" {
+	targets = discovery.kubernetes.pods.targets
+}
+
+loki.source.file "app" {
+	targets    = discovery.relabel.pods.output
+	forward_to = [loki.write.default.receiver]
+}
+
+loki.write "default" {
+	endpoint {
+		url = "http://loki:3100/loki/api/v1/push"
+	}
+}
+`
+	expect := `
+pipelinetest.targets "discovery_kubernetes_pods" {
+	targets = [{ __meta_kubernetes_namespace = "default" }]
+}
+
+discovery.relabel "pods" {
+	targets = pipelinetest.targets.discovery_kubernetes_pods.targets
+}
+
+pipelinetest.logs "loki_source_file_app" {
+	forward_to = [pipelinetest.logs_sink.loki_write_default.receiver]
+}
+
+pipelinetest.logs_sink "loki_write_default" {
+}
+`
+
+	actual, err := rewriteConfig("config.alloy", []byte(config), f.Tests[0])
+	require.NoError(t, err)
+	require.Equal(t, expect, string(actual))
+}
+
+func TestRewriteConfigErrors(t *testing.T) {
+	tt := []struct {
+		name      string
+		test      string
+		expectErr string
+	}{
+		{
+			name:      "unknown component",
+			test:      `test "a" { logs { component = "loki.source.file.missing" } }`,
+			expectErr: `component "loki.source.file.missing" not found in config.alloy`,
+		},
+		{
+			name:      "component without forward_to",
+			test:      `test "a" { logs { component = "loki.write.default" } }`,
+			expectErr: `component "loki.write.default" has no forward_to argument`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ParseFile("test.alloy", []byte(tc.test))
+			require.NoError(t, err)
+
+			_, err = rewriteConfig("config.alloy", []byte(testConfig), f.Tests[0])
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	f, err := ParseFile("test.alloy", []byte(`
+		test "drops_debug_logs" {
+			targets {
+				component = "discovery.kubernetes.pods"
+				targets = []
+			}
+
+			logs {
+				component = "loki.source.file.app"
+				entry {
+					line   = "level=info msg=hello"
+					labels = { job = "app" }
+				}
+				entry {
+					line   = "level=debug msg=noisy"
+					labels = { job = "app" }
+				}
+				entry {
+					line                = "level=warn msg=slow"
+					labels              = { job = "app" }
+					structured_metadata = { trace_id = "abc" }
+				}
+			}
+
+			expect_logs {
+				component = "loki.write.default"
+				entry {
+					line   = "level=info msg=hello"
+					labels = { job = "app", pipeline = "app" }
+				}
+				entry {
+					line                = "level=warn msg=slow"
+					labels              = { job = "app", pipeline = "app" }
+					structured_metadata = { trace_id = "abc" }
+				}
+			}
+		}
+
+		test "drops_go_metrics" {
+			targets {
+				component = "discovery.kubernetes.pods"
+				targets = []
+			}
+
+			samples {
+				component = "prometheus.scrape.app"
+				sample {
+					labels    = { __name__ = "up", job = "app" }
+					value     = 1
+					timestamp = "2024-01-01T00:00:00Z"
+				}
+				sample {
+					labels = { __name__ = "go_goroutines", job = "app" }
+					value  = 10
+				}
+			}
+
+			expect_samples {
+				component = "prometheus.remote_write.default"
+				sample {
+					labels    = { __name__ = "up", job = "app" }
+					value     = 1
+					timestamp = "2024-01-01T00:00:00Z"
+				}
+			}
+		}
+
+		test "fails_on_mismatch" {
+			timeout = "1s"
+
+			targets {
+				component = "discovery.kubernetes.pods"
+				targets = []
+			}
+
+			logs {
+				component = "loki.source.file.app"
+				entry {
+					line   = "level=info msg=hello"
+					labels = { job = "app" }
+				}
+			}
+
+			expect_logs {
+				component = "loki.write.default"
+				entry {
+					line   = "level=info msg=bye"
+					labels = { job = "app", pipeline = "app" }
+				}
+			}
+		}
+	`))
+	require.NoError(t, err)
+
+	results := Run(context.Background(), Options{MinStability: featuregate.StabilityGenerallyAvailable}, "config.alloy", []byte(testConfig), f)
+	require.Len(t, results, 3)
+
+	for _, r := range results[:2] {
+		require.NoError(t, r.Err, r.Test)
+		require.Empty(t, r.Failures, r.Test)
+		require.True(t, r.Passed())
+	}
+
+	require.NoError(t, results[2].Err)
+	require.False(t, results[2].Passed())
+	require.Equal(t, []string{
+		`loki.write.default: missing log entry {job="app", pipeline="app"} "level=info msg=bye"`,
+		`loki.write.default: unexpected log entry {job="app", pipeline="app"} "level=info msg=hello"`,
+	}, results[2].Failures)
+}
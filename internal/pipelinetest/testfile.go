@@ -0,0 +1,229 @@
+package pipelinetest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/alloy/syntax/ast"
+	"github.com/grafana/alloy/syntax/diag"
+	"github.com/grafana/alloy/syntax/parser"
+	"github.com/grafana/alloy/syntax/vm"
+)
+
+// DefaultTimeout is the maximum amount of time a test waits for its expected
+// results when it doesn't set a timeout.
+const DefaultTimeout = 5 * time.Second
+
+// File is a parsed test file.
+type File struct {
+	Name  string
+	Tests []*Test
+}
+
+// Test is a single test case of a test file.
+type Test struct {
+	Name string
+
+	Timeout       time.Duration
+	Logs          []LogsInput
+	Samples       []SamplesInput
+	Targets       []TargetsInput
+	ExpectLogs    []LogsExpectation
+	ExpectSamples []SamplesExpectation
+
+	// fixtures holds the source of the statements of every block of the
+	// test, keyed by the ID of the component the block replaces. The
+	// statements of input blocks are passed as-is to the harness component
+	// standing in for the replaced component.
+	fixtures map[string][]byte
+}
+
+// testBlock is the Alloy representation of a test block.
+type testBlock struct {
+	Name          string               `alloy:",label"`
+	Timeout       time.Duration        `alloy:"timeout,attr,optional"`
+	Logs          []LogsInput          `alloy:"logs,block,optional"`
+	Samples       []SamplesInput       `alloy:"samples,block,optional"`
+	Targets       []TargetsInput       `alloy:"targets,block,optional"`
+	ExpectLogs    []LogsExpectation    `alloy:"expect_logs,block,optional"`
+	ExpectSamples []SamplesExpectation `alloy:"expect_samples,block,optional"`
+}
+
+// LogsInput replaces a component with one sending the fixture log entries to
+// the receivers in the forward_to argument of the replaced component.
+type LogsInput struct {
+	Component string     `alloy:"component,attr"`
+	Entries   []LogEntry `alloy:"entry,block,optional"`
+}
+
+// SamplesInput replaces a component with one sending the fixture samples to
+// the receivers in the forward_to argument of the replaced component.
+type SamplesInput struct {
+	Component string   `alloy:"component,attr"`
+	Samples   []Sample `alloy:"sample,block,optional"`
+}
+
+// TargetsInput replaces a component with one exporting the fixture targets.
+type TargetsInput struct {
+	Component string              `alloy:"component,attr"`
+	Targets   []map[string]string `alloy:"targets,attr"`
+}
+
+// LogsExpectation replaces a component with a sink capturing the log entries
+// sent to its receiver and lists the entries the sink must receive.
+type LogsExpectation struct {
+	Component string     `alloy:"component,attr"`
+	Entries   []LogEntry `alloy:"entry,block,optional"`
+}
+
+// SamplesExpectation replaces a component with a sink capturing the samples
+// sent to its receiver and lists the samples the sink must receive.
+type SamplesExpectation struct {
+	Component string   `alloy:"component,attr"`
+	Samples   []Sample `alloy:"sample,block,optional"`
+}
+
+// ParseFile parses and validates a test file.
+func ParseFile(name string, bb []byte) (*File, error) {
+	node, err := parser.ParseFile(name, bb)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		diags diag.Diagnostics
+		file  = &File{Name: name}
+		names = make(map[string]struct{})
+	)
+
+	for _, stmt := range node.Body {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok || strings.Join(block.Name, ".") != "test" {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				StartPos: ast.StartPos(stmt).Position(),
+				EndPos:   ast.EndPos(stmt).Position(),
+				Message:  "test files may only contain test blocks",
+			})
+			continue
+		}
+		if block.Label == "" {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				StartPos: block.NamePos.Position(),
+				EndPos:   block.LCurlyPos.Position(),
+				Message:  "test blocks must have a label",
+			})
+			continue
+		}
+		if _, exists := names[block.Label]; exists {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				StartPos: block.NamePos.Position(),
+				EndPos:   block.LCurlyPos.Position(),
+				Message:  fmt.Sprintf("test %q already declared", block.Label),
+			})
+			continue
+		}
+		names[block.Label] = struct{}{}
+
+		test, err := parseTest(bb, block)
+		if err != nil {
+			var blockDiags diag.Diagnostics
+			if errors.As(err, &blockDiags) {
+				diags = append(diags, blockDiags...)
+				continue
+			}
+			return nil, err
+		}
+		file.Tests = append(file.Tests, test)
+	}
+
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return file, nil
+}
+
+func parseTest(bb []byte, block *ast.BlockStmt) (*Test, error) {
+	var tb testBlock
+	if err := vm.New(block).Evaluate(nil, &tb); err != nil {
+		return nil, err
+	}
+
+	test := &Test{
+		Name:          block.Label,
+		Timeout:       tb.Timeout,
+		Logs:          tb.Logs,
+		Samples:       tb.Samples,
+		Targets:       tb.Targets,
+		ExpectLogs:    tb.ExpectLogs,
+		ExpectSamples: tb.ExpectSamples,
+		fixtures:      make(map[string][]byte),
+	}
+	if test.Timeout <= 0 {
+		test.Timeout = DefaultTimeout
+	}
+
+	// Blocks of the same kind are decoded in order, which allows matching
+	// each block with the component it replaces.
+	components := make(map[string][]string)
+	for _, in := range test.Logs {
+		components["logs"] = append(components["logs"], in.Component)
+	}
+	for _, in := range test.Samples {
+		components["samples"] = append(components["samples"], in.Component)
+	}
+	for _, in := range test.Targets {
+		components["targets"] = append(components["targets"], in.Component)
+	}
+	for _, expect := range test.ExpectLogs {
+		components["expect_logs"] = append(components["expect_logs"], expect.Component)
+	}
+	for _, expect := range test.ExpectSamples {
+		components["expect_samples"] = append(components["expect_samples"], expect.Component)
+	}
+
+	// A component may only be replaced once per test.
+	var diags diag.Diagnostics
+	for _, stmt := range block.Body {
+		inner, ok := stmt.(*ast.BlockStmt)
+		if !ok {
+			continue
+		}
+		name := strings.Join(inner.Name, ".")
+		id := components[name][0]
+		components[name] = components[name][1:]
+
+		if _, exists := test.fixtures[id]; exists {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				StartPos: inner.NamePos.Position(),
+				EndPos:   inner.LCurlyPos.Position(),
+				Message:  fmt.Sprintf("component %q is replaced more than once", id),
+			})
+			continue
+		}
+		test.fixtures[id] = fixtureBody(bb, inner.Body)
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return test, nil
+}
+
+// fixtureBody returns the source of the statements of body, excluding the
+// component attribute.
+func fixtureBody(bb []byte, body ast.Body) []byte {
+	var fixture []byte
+	for _, stmt := range body {
+		if attr, ok := stmt.(*ast.AttributeStmt); ok && attr.Name.Name == "component" {
+			continue
+		}
+		fixture = append(fixture, "\n\t"...)
+		fixture = append(fixture, bb[ast.StartPos(stmt).Offset():ast.EndPos(stmt).Offset()+1]...)
+	}
+	return fixture
+}
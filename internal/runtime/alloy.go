@@ -54,6 +54,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/atomic"
 
+	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/runtime/internal/controller"
 	"github.com/grafana/alloy/internal/runtime/internal/importsource"
@@ -113,6 +114,20 @@ type Options struct {
 
 	// EnableCommunityComps enables the use of community components.
 	EnableCommunityComps bool
+
+	// DryRun evaluates the loaded configuration without building components,
+	// including the components of modules. Exports of components keep their
+	// zero value, and errors caused by them are reported as warnings.
+	//
+	// A dry run controller must not be run.
+	DryRun bool
+
+	// ExtraComponents are available to the loaded configuration in addition
+	// to the components registered to the global registry, and take
+	// precedence over them. They're used for components which must not be
+	// usable in regular configurations, like the harness components of
+	// pipeline tests.
+	ExtraComponents map[string]component.Registration
 }
 
 // Runtime is the Alloy system.
@@ -134,11 +149,19 @@ type Runtime struct {
 
 // New creates a new, unstarted Alloy controller. Call Run to run the controller.
 func New(o Options) *Runtime {
+	var reg controller.ComponentRegistry
+	if len(o.ExtraComponents) > 0 {
+		reg = controller.NewExtendedComponentRegistry(
+			controller.NewDefaultComponentRegistry(o.MinStability, o.EnableCommunityComps),
+			o.ExtraComponents,
+		)
+	}
 	return newController(controllerOptions{
-		Options:        o,
-		ModuleRegistry: newModuleRegistry(),
-		IsModule:       false, // We are creating a new root controller.
-		WorkerPool:     worker.NewDefaultWorkerPool(),
+		Options:           o,
+		ComponentRegistry: reg,
+		ModuleRegistry:    newModuleRegistry(),
+		IsModule:          false, // We are creating a new root controller.
+		WorkerPool:        worker.NewDefaultWorkerPool(),
 	})
 }
 
@@ -200,6 +223,7 @@ func newController(o controllerOptions) *Runtime {
 			DataPath:             o.DataPath,
 			MinStability:         o.MinStability,
 			EnableCommunityComps: o.EnableCommunityComps,
+			DryRun:               o.DryRun,
 			OnBlockNodeUpdate: func(cn controller.BlockNode) {
 				// Changed node should be queued for reevaluation.
 				f.updateQueue.Enqueue(&controller.QueuedNode{Node: cn, LastUpdatedTime: time.Now()})
@@ -224,6 +248,7 @@ func newController(o controllerOptions) *Runtime {
 					DataPath:             o.DataPath,
 					MinStability:         o.MinStability,
 					EnableCommunityComps: o.EnableCommunityComps,
+					DryRun:               o.DryRun,
 					ID:                   opts.Id,
 					ServiceMap:           serviceMap,
 					WorkerPool:           workerPool,
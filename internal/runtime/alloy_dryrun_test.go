@@ -0,0 +1,162 @@
+package runtime
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/runtime/internal/controller"
+	"github.com/grafana/alloy/internal/runtime/internal/testcomponents"
+	"github.com/grafana/alloy/syntax/diag"
+)
+
+func dryRunOptions(t *testing.T) Options {
+	t.Helper()
+
+	o := testOptions(t)
+	o.DryRun = true
+	return o
+}
+
+func TestController_DryRun(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+	ctrl := New(dryRunOptions(t))
+	defer ctrl.loader.Cleanup(true)
+
+	f, err := ParseSource(t.Name(), []byte(testFile))
+	require.NoError(t, err)
+
+	require.NoError(t, ctrl.LoadSource(f, nil, ""))
+	require.Len(t, ctrl.loader.Components(), 4)
+
+	// Arguments are evaluated, but components are never built and their
+	// exports keep their zero value.
+	in, out := getFields(t, ctrl.loader.Graph(), "testcomponents.passthrough.static")
+	require.Equal(t, "hello, world!", in.(testcomponents.PassthroughConfig).Input)
+	require.Equal(t, testcomponents.PassthroughExports{}, out)
+
+	for _, n := range ctrl.loader.Components() {
+		require.Nil(t, n.(*controller.BuiltinComponentNode).Component(), n.NodeID())
+	}
+}
+
+func TestController_DryRun_Diagnostics(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		expect []diag.Diagnostic
+	}{
+		{
+			name: "all invalid arguments",
+			config: `
+				testcomponents.tick "a" {
+					frequency = "bad"
+				}
+				testcomponents.tick "b" {
+					frequency = 5
+				}
+			`,
+			expect: []diag.Diagnostic{
+				{Severity: diag.SeverityLevelError, Message: `time: invalid duration "bad"`},
+				{Severity: diag.SeverityLevelError, Message: `missing unit in duration "5"`},
+			},
+		},
+		{
+			name: "value depending on exports",
+			config: `
+				testcomponents.passthrough "freq" {
+					input = "1s"
+				}
+				testcomponents.tick "a" {
+					frequency = testcomponents.passthrough.freq.output
+				}
+			`,
+			expect: []diag.Diagnostic{
+				{Severity: diag.SeverityLevelWarn, Message: "the failing value may depend on exports only known at runtime"},
+			},
+		},
+		{
+			name: "literal error next to a value depending on exports",
+			config: `
+				testcomponents.passthrough "in" {
+					input = "hello"
+				}
+				testcomponents.passthrough "a" {
+					input = testcomponents.passthrough.in.output
+					lag   = "notaduration"
+				}
+			`,
+			expect: []diag.Diagnostic{
+				{Severity: diag.SeverityLevelError, Message: `time: invalid duration "notaduration"`},
+			},
+		},
+		{
+			name: "literal error in a module reading an argument",
+			config: `
+				declare "ticker" {
+					argument "input" { }
+
+					testcomponents.passthrough "a" {
+						input = argument.input.value
+						lag   = "notaduration"
+					}
+				}
+				ticker "a" {
+					input = "hello"
+				}
+			`,
+			expect: []diag.Diagnostic{
+				{Severity: diag.SeverityLevelError, Message: `time: invalid duration "notaduration"`},
+			},
+		},
+		{
+			name: "imported module",
+			config: `
+				import.string "mod" {
+					content = "declare \"ticker\" {\n testcomponents.tick \"t\" {\n frequency = \"bad\"\n }\n}"
+				}
+				mod.ticker "a" { }
+			`,
+			expect: []diag.Diagnostic{
+				{Severity: diag.SeverityLevelError, Message: `time: invalid duration "bad"`},
+			},
+		},
+		{
+			name: "invalid imported content",
+			config: `
+				import.string "mod" {
+					content = "declare \"ticker\" {"
+				}
+			`,
+			expect: []diag.Diagnostic{
+				{Severity: diag.SeverityLevelError, Message: "Failed to import module"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := New(dryRunOptions(t))
+			defer ctrl.loader.Cleanup(true)
+
+			f, err := ParseSource(t.Name(), []byte(tc.config))
+			require.NoError(t, err)
+
+			err = ctrl.LoadSource(f, nil, "")
+			require.Error(t, err)
+
+			var diags diag.Diagnostics
+			require.ErrorAs(t, err, &diags)
+			require.Len(t, diags, len(tc.expect), diags.Error())
+			// Nodes are evaluated in parallel, so the diagnostics of independent
+			// nodes may be reported in any order.
+			for _, expect := range tc.expect {
+				require.True(t, slices.ContainsFunc(diags, func(d diag.Diagnostic) bool {
+					return d.Severity == expect.Severity && strings.Contains(d.Message, expect.Message)
+				}), "missing diagnostic %q in %s", expect.Message, diags.Error())
+			}
+		})
+	}
+}
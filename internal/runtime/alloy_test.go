@@ -224,3 +224,28 @@ func verifyNoGoroutineLeaks(t *testing.T) {
 		goleak.IgnoreTopFunction("go.opentelemetry.io/otel/sdk/trace.(*batchSpanProcessor).processQueue"),
 	)
 }
+
+func TestController_LoadSource_ExperimentalStdlib(t *testing.T) {
+	config := `
+		testcomponents.passthrough "static" {
+			input = string.regex_replace("hello, world!", "w[a-z]+", "alloy")
+		}
+	`
+
+	f, err := ParseSource(t.Name(), []byte(config))
+	require.NoError(t, err)
+
+	ctrl := New(testOptions(t))
+	err = ctrl.LoadSource(f, nil, "")
+	require.ErrorContains(t, err, `string.regex_replace is at stability level "experimental"`)
+	cleanUpController(ctrl)
+
+	opts := testOptions(t)
+	opts.MinStability = featuregate.StabilityExperimental
+	ctrl = New(opts)
+	defer cleanUpController(ctrl)
+	require.NoError(t, ctrl.LoadSource(f, nil, ""))
+
+	in, _ := getFields(t, ctrl.loader.Graph(), "testcomponents.passthrough.static")
+	require.Equal(t, "hello, alloy!", in.(testcomponents.PassthroughConfig).Input)
+}
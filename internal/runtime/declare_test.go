@@ -55,6 +55,33 @@ func TestDeclare(t *testing.T) {
 			`,
 			expected: 10,
 		},
+		{
+			name: "TypedArgument",
+			config: `
+			declare "test" {
+				argument "input" {
+					type = "number"
+				}
+
+				export "output" {
+					value = argument.input.value
+				}
+			}
+			testcomponents.count "inc" {
+				frequency = "10ms"
+				max = 10
+			}
+
+			test "myModule" {
+				input = testcomponents.count.inc.count
+			}
+
+			testcomponents.summation "sum" {
+				input = test.myModule.output
+			}
+			`,
+			expected: 10,
+		},
 		{
 			name: "NestedDeclares",
 			config: `
@@ -475,6 +502,62 @@ func TestDeclareError(t *testing.T) {
 			`,
 			expectedError: regexp.MustCompile(`cannot find the definition of component name "b_1"`),
 		},
+		{
+			name: "ArgumentTypeMismatch",
+			config: `
+			declare "a" {
+				argument "targets" {
+					type = "list(map(string))"
+				}
+			}
+			a "example" {
+				targets = "localhost:9090"
+			}
+			`,
+			expectedError: regexp.MustCompile(`:8:5: invalid value for argument "targets" of a: targets should be list\(map\(string\)\), got string`),
+		},
+		{
+			name: "ArgumentTypeNestedMismatch",
+			config: `
+			declare "a" {
+				argument "targets" {
+					type = "list(map(string))"
+				}
+			}
+			a "example" {
+				targets = [{"__address__" = "localhost:9090"}, {"__address__" = 9091}]
+			}
+			`,
+			expectedError: regexp.MustCompile(`targets\[1\]\["__address__"\] should be string, got number`),
+		},
+		{
+			name: "ArgumentInvalidType",
+			config: `
+			declare "a" {
+				argument "input" {
+					type = "list(strng)"
+				}
+			}
+			a "example" {
+				input = []
+			}
+			`,
+			expectedError: regexp.MustCompile(`invalid type "list\(strng\)": unknown type "strng"`),
+		},
+		{
+			name: "ArgumentDefaultTypeMismatch",
+			config: `
+			declare "a" {
+				argument "input" {
+					type     = "number"
+					optional = true
+					default  = "ten"
+				}
+			}
+			a "example" {}
+			`,
+			expectedError: regexp.MustCompile(`invalid default value: default should be number, got string`),
+		},
 		{
 			name: "ForbiddenDeclareLabel",
 			config: `
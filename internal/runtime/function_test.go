@@ -0,0 +1,321 @@
+package runtime_test
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime"
+	"github.com/grafana/alloy/internal/runtime/internal/testcomponents"
+	"github.com/grafana/alloy/internal/runtime/logging"
+	"github.com/grafana/alloy/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunction(t *testing.T) {
+	tt := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name: "BasicFunction",
+			config: `
+			function "greet" {
+				params = ["name"]
+				result = "hello, " + name
+			}
+
+			testcomponents.passthrough "pass" {
+				input = greet("world")
+			}
+			`,
+			expected: "hello, world",
+		},
+		{
+			name: "FunctionCallingFunctions",
+			config: `
+			function "target_label" {
+				params = ["namespace", "pod"]
+				result = string.join([namespace, pod], "/")
+			}
+
+			function "target" {
+				params = ["labels"]
+				result = string.to_upper(target_label(labels.namespace, labels.pod))
+			}
+
+			testcomponents.passthrough "pass" {
+				input = target({"namespace" = "default", "pod" = "alloy-0"})
+			}
+			`,
+			expected: "DEFAULT/ALLOY-0",
+		},
+		{
+			name: "FunctionWithoutParams",
+			config: `
+			function "prefix" {
+				result = "k8s"
+			}
+
+			testcomponents.passthrough "pass" {
+				input = prefix() + "_target"
+			}
+			`,
+			expected: "k8s_target",
+		},
+		{
+			name: "ImportedFunction",
+			config: `
+			import.string "lib" {
+				content = ` + "`" + `
+					function "join" {
+						params = ["a", "b"]
+						result = a + sep()
+					}
+					function "sep" {
+						result = "/"
+					}
+					function "target" {
+						params = ["namespace", "pod"]
+						result = join(namespace, "") + pod
+					}
+				` + "`" + `
+			}
+
+			testcomponents.passthrough "pass" {
+				input = lib.target("default", "alloy-0")
+			}
+			`,
+			expected: "default/alloy-0",
+		},
+		{
+			name: "FunctionInDeclare",
+			config: `
+			function "greet" {
+				params = ["name"]
+				result = "hello, " + name
+			}
+
+			declare "mod" {
+				function "shout" {
+					params = ["s"]
+					result = string.to_upper(s)
+				}
+
+				export "output" {
+					value = shout(greet("module"))
+				}
+			}
+
+			mod "m" {}
+
+			testcomponents.passthrough "pass" {
+				input = mod.m.output
+			}
+			`,
+			expected: "HELLO, MODULE",
+		},
+		{
+			name: "ImportedFunctionInImportedDeclare",
+			config: `
+			import.string "lib" {
+				content = ` + "`" + `
+					function "greet" {
+						params = ["name"]
+						result = "hello, " + name
+					}
+					declare "mod" {
+						export "output" {
+							value = greet("imported module")
+						}
+					}
+				` + "`" + `
+			}
+
+			lib.mod "m" {}
+
+			testcomponents.passthrough "pass" {
+				input = lib.mod.m.output
+			}
+			`,
+			expected: "hello, imported module",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := testOptions(t)
+			opts.MinStability = featuregate.StabilityExperimental
+			ctrl := runtime.New(opts)
+			f, err := runtime.ParseSource(t.Name(), []byte(tc.config))
+			require.NoError(t, err)
+			require.NotNil(t, f)
+
+			err = ctrl.LoadSource(f, nil, "")
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				ctrl.Run(ctx)
+				close(done)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			require.Eventually(t, func() bool {
+				export := getExport[testcomponents.PassthroughExports](t, ctrl, "", "testcomponents.passthrough.pass")
+				return export.Output == tc.expected
+			}, 3*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestFunctionError(t *testing.T) {
+	tt := []errorTestCase{
+		{
+			name: "WrongNumberOfArguments",
+			config: `
+			function "greet" {
+				params = ["name"]
+				result = "hello, " + name
+			}
+
+			testcomponents.passthrough "pass" {
+				input = greet("a", "b")
+			}
+			`,
+			expectedError: regexp.MustCompile(`function "greet" expects 1 argument\(s\), got 2`),
+		},
+		{
+			name: "ReferenceToComponent",
+			config: `
+			testcomponents.passthrough "pass" {
+				input = "a"
+			}
+
+			function "output" {
+				result = testcomponents.passthrough.pass.output
+			}
+			`,
+			expectedError: regexp.MustCompile(`function "output" can only reference its parameters, other functions and the standard library, found "testcomponents.passthrough.pass.output"`),
+		},
+		{
+			name: "RecursiveFunctions",
+			config: `
+			function "a" {
+				result = b()
+			}
+
+			function "b" {
+				result = a()
+			}
+			`,
+			expectedError: regexp.MustCompile(`cycle: (a|b), (a|b)`),
+		},
+		{
+			name: "RecursiveImportedFunctions",
+			config: `
+			import.string "lib" {
+				content = "function \"a\" {\n result = a()\n}"
+			}
+
+			testcomponents.passthrough "pass" {
+				input = lib.a()
+			}
+			`,
+			// The import node reports the cycle in its health and doesn't
+			// expose any function.
+			expectedError: regexp.MustCompile(`field "a" does not exist`),
+		},
+		{
+			name: "InvalidParameter",
+			config: `
+			function "greet" {
+				params = ["first name"]
+				result = "hello"
+			}
+			`,
+			expectedError: regexp.MustCompile(`function "greet": parameter "first name" is not a valid identifier`),
+		},
+		{
+			name: "ConflictWithComponent",
+			config: `
+			function "testcomponents" {
+				result = "a"
+			}
+
+			testcomponents.passthrough "pass" {
+				input = "a"
+			}
+			`,
+			expectedError: regexp.MustCompile(`function "testcomponents" conflicts with testcomponents.passthrough.pass`),
+		},
+		{
+			name: "ConflictWithImport",
+			config: `
+			import.string "lib" {
+				content = "function \"a\" {\n result = \"a\"\n}"
+			}
+
+			function "lib" {
+				result = "a"
+			}
+			`,
+			expectedError: regexp.MustCompile(`function "lib" conflicts with import.string.lib`),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			testFunctionError(t, tc.config, featuregate.StabilityExperimental, tc.expectedError)
+		})
+	}
+}
+
+func TestFunctionStability(t *testing.T) {
+	config := `
+	function "greet" {
+		result = "hello"
+	}
+	`
+	testFunctionError(t, config, featuregate.StabilityPublicPreview, regexp.MustCompile(`config block "function" is at stability level "experimental"`))
+}
+
+func testFunctionError(t *testing.T, config string, minStability featuregate.Stability, expectedError *regexp.Regexp) {
+	defer verifyNoGoroutineLeaks(t)
+	s, err := logging.New(os.Stderr, logging.DefaultOptions)
+	require.NoError(t, err)
+	ctrl := runtime.New(runtime.Options{
+		Logger:       s,
+		DataPath:     t.TempDir(),
+		MinStability: minStability,
+		Reg:          nil,
+		Services:     []service.Service{},
+	})
+	f, err := runtime.ParseSource(t.Name(), []byte(config))
+	require.NoError(t, err)
+	require.NotNil(t, f)
+
+	err = ctrl.LoadSource(f, nil, "")
+	if err == nil {
+		t.Errorf("Expected error to match regex %q, but got: nil", expectedError)
+	} else if !expectedError.MatchString(err.Error()) {
+		t.Errorf("Expected error to match regex %q, but got: %v", expectedError, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+}
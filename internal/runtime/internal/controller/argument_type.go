@@ -0,0 +1,454 @@
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/otelcol"
+	"github.com/grafana/alloy/syntax"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+// argumentType is the type constraint of an argument block, parsed from the
+// value of its type attribute.
+//
+// Supported type expressions are:
+//
+//   - any
+//   - string, number, bool
+//   - secret (a string or a secret value)
+//   - list(T) and map(T), where T is another type expression
+//   - object({ key = T, ... }), where every listed key is required
+//   - capsule (any capsule value)
+//   - receiver, loki_receiver, prometheus_receiver, otelcol_consumer
+type argumentType interface {
+	// String returns the type expression for the type.
+	String() string
+
+	// check returns an error if v doesn't conform to the type. path is the
+	// location of v inside the argument value and is used to build error
+	// messages.
+	check(path string, v any) error
+}
+
+// argumentTypeError is returned when the value of an argument doesn't match
+// the type declared in its argument block.
+type argumentTypeError struct {
+	Path     string // Location of the offending value (e.g., targets[0]).
+	Expected string // Expected type expression.
+	Got      string // Description of the value which was found.
+}
+
+func (e argumentTypeError) Error() string {
+	return fmt.Sprintf("%s should be %s, got %s", e.Path, e.Expected, e.Got)
+}
+
+// checkArgumentType validates the value v for argument name against typ. nil
+// values are always accepted since optional arguments default to null.
+func checkArgumentType(typ argumentType, name string, v any) error {
+	if typ == nil || v == nil {
+		return nil
+	}
+	return typ.check(name, v)
+}
+
+type primitiveKind string
+
+const (
+	kindAny    primitiveKind = "any"
+	kindString primitiveKind = "string"
+	kindNumber primitiveKind = "number"
+	kindBool   primitiveKind = "bool"
+	kindSecret primitiveKind = "secret"
+)
+
+type primitiveType struct{ kind primitiveKind }
+
+func (t primitiveType) String() string { return string(t.kind) }
+
+func (t primitiveType) check(path string, v any) error {
+	if v == nil {
+		return nil
+	}
+
+	var ok bool
+	switch t.kind {
+	case kindAny:
+		ok = true
+	case kindString:
+		var s string
+		ok = (!isCapsule(v) && reflect.TypeOf(v).Kind() == reflect.String) || convertInto(v, &s)
+	case kindNumber:
+		switch reflect.TypeOf(v).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			ok = true
+		}
+	case kindBool:
+		ok = reflect.TypeOf(v).Kind() == reflect.Bool
+	case kindSecret:
+		var s alloytypes.Secret
+		ok = reflect.TypeOf(v).Kind() == reflect.String || convertInto(v, &s)
+		if !ok {
+			_, ok = v.(alloytypes.Secret)
+		}
+	}
+
+	if !ok {
+		return argumentTypeError{Path: path, Expected: t.String(), Got: describeValue(v)}
+	}
+	return nil
+}
+
+type listType struct{ elem argumentType }
+
+func (t listType) String() string { return fmt.Sprintf("list(%s)", t.elem) }
+
+func (t listType) check(path string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return argumentTypeError{Path: path, Expected: t.String(), Got: describeValue(v)}
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := checkElem(t.elem, fmt.Sprintf("%s[%d]", path, i), rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type mapType struct{ elem argumentType }
+
+func (t mapType) String() string { return fmt.Sprintf("map(%s)", t.elem) }
+
+func (t mapType) check(path string, v any) error {
+	fields, ok := objectFields(v)
+	if !ok {
+		return argumentTypeError{Path: path, Expected: t.String(), Got: describeValue(v)}
+	}
+	for _, key := range sortedKeys(fields) {
+		if err := checkElem(t.elem, fmt.Sprintf("%s[%q]", path, key), fields[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type objectType struct{ fields map[string]argumentType }
+
+func (t objectType) String() string {
+	parts := make([]string, 0, len(t.fields))
+	for _, key := range sortedKeys(t.fields) {
+		parts = append(parts, fmt.Sprintf("%s = %s", key, t.fields[key]))
+	}
+	return fmt.Sprintf("object({ %s })", strings.Join(parts, ", "))
+}
+
+func (t objectType) check(path string, v any) error {
+	fields, ok := objectFields(v)
+	if !ok {
+		return argumentTypeError{Path: path, Expected: t.String(), Got: describeValue(v)}
+	}
+	for _, key := range sortedKeys(t.fields) {
+		fieldPath := fmt.Sprintf("%s.%s", path, key)
+		field, found := fields[key]
+		if !found {
+			return argumentTypeError{Path: fieldPath, Expected: t.fields[key].String(), Got: "nothing"}
+		}
+		if err := checkElem(t.fields[key], fieldPath, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// capsuleType matches capsule values. If match is nil, any capsule value is
+// accepted.
+type capsuleType struct {
+	name  string
+	match func(v any) bool
+}
+
+func (t capsuleType) String() string { return t.name }
+
+func (t capsuleType) check(path string, v any) error {
+	ok := isCapsule(v)
+	if ok && t.match != nil {
+		ok = t.match(v)
+	}
+	if !ok {
+		return argumentTypeError{Path: path, Expected: t.String(), Got: describeValue(v)}
+	}
+	return nil
+}
+
+func isLokiReceiver(v any) bool {
+	_, ok := v.(loki.LogsReceiver)
+	return ok
+}
+
+func isPrometheusReceiver(v any) bool {
+	_, ok := v.(storage.Appendable)
+	return ok
+}
+
+func isOtelcolConsumer(v any) bool {
+	_, ok := v.(otelcol.Consumer)
+	return ok
+}
+
+// capsuleKinds are the named capsule types which can be used in type
+// expressions.
+var capsuleKinds = map[string]capsuleType{
+	"capsule":             {name: "capsule"},
+	"loki_receiver":       {name: "loki_receiver", match: isLokiReceiver},
+	"prometheus_receiver": {name: "prometheus_receiver", match: isPrometheusReceiver},
+	"otelcol_consumer":    {name: "otelcol_consumer", match: isOtelcolConsumer},
+	"receiver": {name: "receiver", match: func(v any) bool {
+		return isLokiReceiver(v) || isPrometheusReceiver(v) || isOtelcolConsumer(v)
+	}},
+}
+
+// checkElem checks a nested value, unwrapping interfaces first so that
+// elements of []any and map[string]any are inspected by their dynamic type.
+func checkElem(typ argumentType, path string, rv reflect.Value) error {
+	for rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || !rv.CanInterface() {
+		return nil
+	}
+	return typ.check(path, rv.Interface())
+}
+
+// objectFields returns the fields of v if v can be used as an object. Capsule
+// values which can be converted into objects (such as discovery targets) are
+// converted first.
+func objectFields(v any) (map[string]reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		fields := make(map[string]reflect.Value, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			fields[iter.Key().String()] = iter.Value()
+		}
+		return fields, true
+	}
+
+	var converted map[string]syntax.Value
+	if convertInto(v, &converted) {
+		fields := make(map[string]reflect.Value, len(converted))
+		for key, val := range converted {
+			fields[key] = reflect.ValueOf(val.Interface())
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// convertInto tries to convert a capsule value into dst using its custom
+// conversion rules.
+func convertInto(v any, dst any) bool {
+	conv, ok := v.(syntax.ConvertibleIntoCapsule)
+	if !ok {
+		return false
+	}
+	return conv.ConvertInto(dst) == nil
+}
+
+// isCapsule reports whether v would be represented as a capsule in Alloy
+// syntax.
+func isCapsule(v any) bool {
+	if _, ok := v.(syntax.Capsule); ok {
+		return true
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.Slice, reflect.Array, reflect.Func:
+		return false
+	case reflect.Map:
+		return reflect.TypeOf(v).Key().Kind() != reflect.String
+	default:
+		return true
+	}
+}
+
+// describeValue returns a short, user-facing description of the type of v.
+func describeValue(v any) string {
+	if v == nil {
+		return "null"
+	}
+	if isCapsule(v) {
+		return fmt.Sprintf("capsule(%q)", reflect.TypeOf(v))
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map:
+		return "object"
+	case reflect.Func:
+		return "function"
+	default:
+		return "number"
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseArgumentType parses a type expression such as "list(map(string))".
+func parseArgumentType(expr string) (argumentType, error) {
+	p := &typeParser{input: expr}
+	typ, err := p.parseType()
+	if err != nil {
+		return nil, fmt.Errorf("invalid type %q: %w", expr, err)
+	}
+	if tok := p.next(); tok != "" {
+		return nil, fmt.Errorf("invalid type %q: unexpected %q after type", expr, tok)
+	}
+	return typ, nil
+}
+
+type typeParser struct {
+	input string
+	pos   int
+}
+
+// next returns the next token in the input: either an identifier or a single
+// punctuation character. An empty string is returned at the end of input.
+func (p *typeParser) next() string {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		return ""
+	}
+
+	start := p.pos
+	if isTypeIdentChar(p.input[p.pos]) {
+		for p.pos < len(p.input) && isTypeIdentChar(p.input[p.pos]) {
+			p.pos++
+		}
+		return p.input[start:p.pos]
+	}
+	p.pos++
+	return p.input[start:p.pos]
+}
+
+// peek returns the next token without consuming it.
+func (p *typeParser) peek() string {
+	pos := p.pos
+	tok := p.next()
+	p.pos = pos
+	return tok
+}
+
+func (p *typeParser) expect(want string) error {
+	if got := p.next(); got != want {
+		if got == "" {
+			return fmt.Errorf("expected %q, got end of input", want)
+		}
+		return fmt.Errorf("expected %q, got %q", want, got)
+	}
+	return nil
+}
+
+func (p *typeParser) parseType() (argumentType, error) {
+	name := p.next()
+	switch name {
+	case "":
+		return nil, fmt.Errorf("expected type, got end of input")
+	case string(kindAny), string(kindString), string(kindNumber), string(kindBool), string(kindSecret):
+		return primitiveType{kind: primitiveKind(name)}, nil
+	case "list", "map":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if name == "list" {
+			return listType{elem: elem}, nil
+		}
+		return mapType{elem: elem}, nil
+	case "object":
+		return p.parseObject()
+	}
+
+	if kind, ok := capsuleKinds[name]; ok {
+		return kind, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", name)
+}
+
+func (p *typeParser) parseObject() (argumentType, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]argumentType)
+	for p.peek() != "}" {
+		key := p.next()
+		if key == "" || !isTypeIdentChar(key[0]) {
+			return nil, fmt.Errorf("expected object key, got %q", key)
+		}
+		if _, exists := fields[key]; exists {
+			return nil, fmt.Errorf("duplicate object key %q", key)
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		typ, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = typ
+
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return objectType{fields: fields}, nil
+}
+
+func isTypeIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/syntax"
+	"github.com/grafana/alloy/syntax/alloytypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArgumentType(t *testing.T) {
+	tt := []struct {
+		expr      string
+		expected  string
+		expectErr string
+	}{
+		{expr: "string", expected: "string"},
+		{expr: " list( map( string ) ) ", expected: "list(map(string))"},
+		{expr: "object({ port = number, host = string, })", expected: "object({ host = string, port = number })"},
+		{expr: "object({})", expected: "object({  })"},
+		{expr: "list(receiver)", expected: "list(receiver)"},
+		{expr: "strng", expectErr: `invalid type "strng": unknown type "strng"`},
+		{expr: "list(string", expectErr: `invalid type "list(string": expected ")", got end of input`},
+		{expr: "string number", expectErr: `invalid type "string number": unexpected "number" after type`},
+		{expr: "object({ a = string, a = number })", expectErr: `duplicate object key "a"`},
+		{expr: "", expectErr: "expected type, got end of input"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.expr, func(t *testing.T) {
+			typ, err := parseArgumentType(tc.expr)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, typ.String())
+		})
+	}
+}
+
+// testTarget mimics discovery.Target, which is a capsule that can be
+// converted into an object.
+type testTarget map[string]string
+
+func (t testTarget) AlloyCapsule() {}
+
+func (t testTarget) ConvertInto(dst interface{}) error {
+	if dst, ok := dst.(*map[string]syntax.Value); ok {
+		*dst = make(map[string]syntax.Value, len(t))
+		for k, v := range t {
+			(*dst)[k] = syntax.ValueFromString(v)
+		}
+		return nil
+	}
+	return syntax.ErrNoConversion
+}
+
+func TestCheckArgumentType(t *testing.T) {
+	target := testTarget{"__address__": "localhost:9090"}
+
+	tt := []struct {
+		name      string
+		typ       string
+		value     any
+		expectErr string
+	}{
+		{name: "null is always valid", typ: "number", value: nil},
+		{name: "any", typ: "any", value: []any{1, "a"}},
+		{name: "number", typ: "number", value: 5},
+		{name: "float", typ: "number", value: 1.5},
+		{name: "string mismatch", typ: "number", value: "5", expectErr: "arg should be number, got string"},
+		{name: "bool", typ: "bool", value: true},
+		{name: "secret from string", typ: "secret", value: "hunter2"},
+		{name: "secret", typ: "secret", value: alloytypes.Secret("hunter2")},
+		{name: "string from optional secret", typ: "string", value: alloytypes.OptionalSecret{Value: "a"}},
+		{name: "string from secret", typ: "string", value: alloytypes.Secret("a"), expectErr: `arg should be string, got capsule("alloytypes.Secret")`},
+		{name: "list", typ: "list(string)", value: []any{"a", "b"}},
+		{name: "list element mismatch", typ: "list(string)", value: []any{"a", 1}, expectErr: "arg[1] should be string, got number"},
+		{name: "map", typ: "map(number)", value: map[string]any{"a": 1}},
+		{name: "map of targets", typ: "list(map(string))", value: []testTarget{target}},
+		{name: "map mismatch", typ: "map(number)", value: map[string]any{"a": "b"}, expectErr: `arg["a"] should be number, got string`},
+		{name: "object", typ: "object({ host = string, port = number })", value: map[string]any{"host": "a", "port": 80, "extra": true}},
+		{name: "object missing key", typ: "object({ host = string, port = number })", value: map[string]any{"host": "a"}, expectErr: "arg.port should be number, got nothing"},
+		{name: "capsule", typ: "capsule", value: target},
+		{name: "capsule mismatch", typ: "capsule", value: "a", expectErr: "arg should be capsule, got string"},
+		{name: "receiver", typ: "list(receiver)", value: []any{loki.NewLogsReceiver()}},
+		{name: "loki receiver", typ: "loki_receiver", value: loki.NewLogsReceiver()},
+		{name: "receiver mismatch", typ: "prometheus_receiver", value: loki.NewLogsReceiver(), expectErr: "arg should be prometheus_receiver, got capsule"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			typ, err := parseArgumentType(tc.typ)
+			require.NoError(t, err)
+
+			err = checkArgumentType(typ, "arg", tc.value)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -90,3 +90,26 @@ func (m registryMap) Get(name string) (component.Registration, error) {
 	}
 	return reg, nil
 }
+
+type extendedComponentRegistry struct {
+	parent ComponentRegistry
+	extra  map[string]component.Registration
+}
+
+// NewExtendedComponentRegistry creates a new [ComponentRegistry] which gets
+// the components in extra, and the other components from parent. The
+// components in extra aren't restricted by their stability.
+func NewExtendedComponentRegistry(parent ComponentRegistry, extra map[string]component.Registration) ComponentRegistry {
+	return extendedComponentRegistry{
+		parent: parent,
+		extra:  extra,
+	}
+}
+
+// Get retrieves a component from extra, or from the parent registry.
+func (reg extendedComponentRegistry) Get(name string) (component.Registration, error) {
+	if cr, ok := reg.extra[name]; ok {
+		return cr, nil
+	}
+	return reg.parent.Get(name)
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"fmt"
+	"maps"
 	"sync"
 
 	"github.com/grafana/alloy/syntax/ast"
@@ -14,20 +15,22 @@ import (
 type CustomComponentRegistry struct {
 	parent *CustomComponentRegistry // nil if root config
 
-	mut      sync.RWMutex
-	scope    *vm.Scope
-	imports  map[string]*CustomComponentRegistry // importNamespace: importScope
-	declares map[string]ast.Body                 // customComponentName: template
+	mut       sync.RWMutex
+	scope     *vm.Scope
+	imports   map[string]*CustomComponentRegistry // importNamespace: importScope
+	declares  map[string]ast.Body                 // customComponentName: template
+	functions map[string]any                      // functionName or importNamespace: function or imported functions
 }
 
 // NewCustomComponentRegistry creates a new CustomComponentRegistry with a parent.
 // parent can be nil.
 func NewCustomComponentRegistry(parent *CustomComponentRegistry, scope *vm.Scope) *CustomComponentRegistry {
 	return &CustomComponentRegistry{
-		parent:   parent,
-		scope:    scope,
-		declares: make(map[string]ast.Body),
-		imports:  make(map[string]*CustomComponentRegistry),
+		parent:    parent,
+		scope:     scope,
+		declares:  make(map[string]ast.Body),
+		imports:   make(map[string]*CustomComponentRegistry),
+		functions: make(map[string]any),
 	}
 }
 
@@ -45,10 +48,21 @@ func (s *CustomComponentRegistry) getImport(name string) (*CustomComponentRegist
 	return im, ok
 }
 
+// Scope returns the scope used to evaluate the custom components of the
+// registry. It includes the functions available in the registry.
 func (s *CustomComponentRegistry) Scope() *vm.Scope {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
-	return s.scope
+	if len(s.functions) == 0 {
+		return s.scope
+	}
+
+	vars := make(map[string]any, len(s.functions))
+	if s.scope != nil {
+		maps.Copy(vars, s.scope.Variables)
+	}
+	maps.Copy(vars, s.functions)
+	return vm.NewScope(vars)
 }
 
 // registerDeclare stores a local declare block.
@@ -58,6 +72,14 @@ func (s *CustomComponentRegistry) registerDeclare(declare *ast.BlockStmt) {
 	s.declares[declare.Label] = declare.Body
 }
 
+// registerFunction stores a function, or the functions imported in a
+// namespace, so that the custom components of the registry can use them.
+func (s *CustomComponentRegistry) registerFunction(name string, fn any) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.functions[name] = fn
+}
+
 // registerImport stores the import namespace.
 // The content will be added later during evaluation.
 // It's important to register it before populating the component nodes
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/runtime/internal/dag"
 	"github.com/grafana/alloy/internal/runtime/internal/worker"
@@ -47,6 +49,7 @@ type Loader struct {
 	declareNodes         map[string]*DeclareNode
 	importConfigNodes    map[string]*ImportConfigNode
 	forEachNodes         map[string]*ForeachConfigNode
+	functionNodes        map[string]*FunctionConfigNode
 	serviceNodes         []*ServiceNode
 	cache                *valueCache
 	blocks               []*ast.BlockStmt // Most recently loaded blocks, used for writing
@@ -168,6 +171,7 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 	if diags.HasErrors() {
 		return diags
 	}
+	l.syncFunctions()
 
 	var (
 		components   = make([]ComponentNode, 0)
@@ -200,7 +204,10 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 			level.Info(logger).Log("msg", "finished node evaluation", "node_id", n.NodeID(), "duration", time.Since(start))
 		}()
 
-		var err error
+		var (
+			err       error
+			diagsSize = len(diags)
+		)
 
 		switch n := n.(type) {
 		case ComponentNode:
@@ -250,6 +257,29 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 			if exp, ok := n.(*ExportConfigNode); ok {
 				l.cache.CacheModuleExportValue(exp.Label(), exp.Value())
 			}
+			if imp, ok := n.(*ImportConfigNode); ok && err == nil && l.globals.DryRun {
+				// Errors in the imported content are only reported through the
+				// health of the node.
+				if health := imp.CurrentHealth(); health.Health == component.HealthTypeUnhealthy {
+					diags.Add(diag.Diagnostic{
+						Severity: diag.SeverityLevelError,
+						Message:  fmt.Sprintf("Failed to import module: %s", health.Message),
+						StartPos: ast.StartPos(n.Block()).Position(),
+						EndPos:   ast.EndPos(n.Block()).Position(),
+					})
+				}
+			}
+		}
+
+		if l.globals.DryRun {
+			// Exports of components aren't known during a dry run, so errors of
+			// expressions using them may be caused by their placeholder value.
+			for i := diagsSize; i < len(diags); i++ {
+				if usesRuntimeValue(&newGraph, n, diags[i]) {
+					diags[i].Severity = diag.SeverityLevelWarn
+					diags[i].Message += " (the failing value may depend on exports only known at runtime)"
+				}
+			}
 		}
 
 		// We only use the error for updating the span status; we don't return the
@@ -282,6 +312,43 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 	return diags
 }
 
+// usesRuntimeValue returns true if the expression which failed with d
+// references exports of builtin or custom components, whose values are
+// placeholders during a dry run. Diagnostics which aren't reported for an
+// expression of the block of n, like the validation errors of a whole block,
+// never use runtime values.
+func usesRuntimeValue(g *dag.Graph, n dag.Node, d diag.Diagnostic) bool {
+	bn, ok := n.(BlockNode)
+	if !ok || bn.Block() == nil {
+		return false
+	}
+	block := bn.Block()
+	if d.StartPos.Filename != ast.StartPos(block).Position().Filename ||
+		(d.StartPos.Offset == ast.StartPos(block).Position().Offset && d.EndPos.Offset == ast.EndPos(block).Position().Offset) {
+		return false
+	}
+
+	for _, t := range expressionsFromBody(block.Body) {
+		pos := ast.StartPos(t[0]).Position()
+		if pos.Offset < d.StartPos.Offset || pos.Offset > d.EndPos.Offset {
+			continue
+		}
+		ref, diags := resolveTraversal(t, g)
+		if diags.HasErrors() {
+			continue
+		}
+		switch target := ref.Target.(type) {
+		case *BuiltinComponentNode:
+			if target.exportsType != nil {
+				return true
+			}
+		case *CustomComponentNode:
+			return true
+		}
+	}
+	return false
+}
+
 // Cleanup unregisters any existing metrics and optionally stops the worker pool.
 func (l *Loader) Cleanup(stopWorkerPool bool) {
 	if stopWorkerPool {
@@ -317,6 +384,10 @@ func (l *Loader) loadNewGraph(args map[string]any, componentBlocks []*ast.BlockS
 	componentNodeDiags := l.populateComponentNodes(&g, componentBlocks)
 	diags = append(diags, componentNodeDiags...)
 
+	// Functions share the scope of components and imports.
+	functionDiags := l.validateFunctionLabels(&g)
+	diags = append(diags, functionDiags...)
+
 	// Write up the edges of the graph
 	wireDiags := l.wireGraphEdges(&g)
 	diags = append(diags, wireDiags...)
@@ -502,14 +573,20 @@ func (l *Loader) populateConfigBlockNodes(args map[string]any, g *dag.Graph, con
 			newConfigNodeDiags diag.Diagnostics
 		)
 		id := BlockComponentID(block).String()
+		if block.GetBlockName() == functionBlockID {
+			// Functions are referenced by their label only.
+			id = block.Label
+		}
 		if diag, defined := blockAlreadyDefined(blockMap, id, block); defined {
 			diags = append(diags, diag)
 			continue
 		}
 		// Check the graph from the previous call to Load to see we can copy an
-		// existing instance of BlockNode.
-		if exist := l.graph.GetByID(id); exist != nil {
-			node = exist.(BlockNode)
+		// existing instance of BlockNode. The label of a function may match the
+		// ID of another kind of node, which is reported once the graph is
+		// populated.
+		if exist, ok := l.graph.GetByID(id).(BlockNode); ok && sameBlockType(exist, block) {
+			node = exist
 			node.UpdateBlock(block)
 		} else {
 			node, newConfigNodeDiags = NewConfigNode(block, l.globals, l.componentNodeManager.customComponentReg)
@@ -527,6 +604,11 @@ func (l *Loader) populateConfigBlockNodes(args map[string]any, g *dag.Graph, con
 
 		if importNode, ok := node.(*ImportConfigNode); ok {
 			l.componentNodeManager.customComponentReg.registerImport(importNode.label)
+			// Register the namespace of the imported functions, which are only
+			// known once the import node is evaluated.
+			if !l.cache.HasFunctions(importNode.label) {
+				l.cache.CacheImportedFunctions(importNode.label, map[string]any{})
+			}
 		}
 
 		g.Add(node)
@@ -549,6 +631,7 @@ func (l *Loader) populateConfigBlockNodes(args map[string]any, g *dag.Graph, con
 
 	l.importConfigNodes = nodeMap.importMap
 	l.forEachNodes = nodeMap.foreachMap
+	l.functionNodes = nodeMap.functionMap
 
 	return diags
 }
@@ -627,8 +710,16 @@ func (l *Loader) wireGraphEdges(g *dag.Graph) diag.Diagnostics {
 			l.wireCustomComponentNode(g, n)
 		case *ForeachConfigNode:
 			l.wireForEachNode(g, n)
+		case *FunctionConfigNode:
+			// Functions only reference their parameters, so they are wired
+			// separately from other nodes.
+			diags = append(diags, l.wireFunctionNode(g, n)...)
+			continue
 		}
 
+		// Calls to imported functions depend on the import node.
+		l.wireImportedFunctionCalls(g, n)
+
 		// Finally, wire component references.
 		l.cache.mut.RLock()
 		refs, nodeDiags := ComponentReferences(n, g, l.log, l.cache.GetContext(), l.globals.MinStability)
@@ -655,9 +746,140 @@ func (l *Loader) wireCustomComponentNode(g *dag.Graph, cc *CustomComponentNode)
 			// add edges between the custom component and declare/import nodes.
 			g.AddEdge(dag.Edge{From: cc, To: ref})
 		}
+		// add edges between the custom component and the functions used in the declare block.
+		for _, t := range expressionsFromBody(declare.Block().Body) {
+			if fn, ok := l.functionNodes[t[0].Name]; ok {
+				g.AddEdge(dag.Edge{From: cc, To: fn})
+			} else if importNode, ok := l.importConfigNodes[t[0].Name]; ok && len(t) > 1 {
+				g.AddEdge(dag.Edge{From: cc, To: importNode})
+			}
+		}
 	}
 }
 
+// wireFunctionNode wires a function to the functions and import nodes that it
+// calls. Functions can't reference anything else than their parameters, other
+// functions and the standard library.
+func (l *Loader) wireFunctionNode(g *dag.Graph, fn *FunctionConfigNode) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	def, err := parseFunctionBlock(fn.Block())
+	if err != nil {
+		diags.Add(diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			Message:  err.Error(),
+			StartPos: ast.StartPos(fn.Block()).Position(),
+			EndPos:   ast.EndPos(fn.Block()).Position(),
+		})
+		return diags
+	}
+
+	scope := l.cache.GetContext()
+	for _, t := range def.references() {
+		name := t[0].Name
+		if dep, ok := g.GetByID(name).(*FunctionConfigNode); ok {
+			g.AddEdge(dag.Edge{From: fn, To: dep})
+			continue
+		}
+		if importNode, ok := l.importConfigNodes[name]; ok && len(t) > 1 {
+			g.AddEdge(dag.Edge{From: fn, To: importNode})
+			continue
+		}
+		if scope.IsStdlibIdentifiers(name) || isFunctionInScope(scope, t) {
+			if funcName := t.String(); scope.IsStdlibExperimental(funcName) {
+				if err := featuregate.CheckAllowed(featuregate.StabilityExperimental, l.globals.MinStability, funcName); err != nil {
+					diags.Add(diag.Diagnostic{
+						Severity: diag.SeverityLevelError,
+						Message:  err.Error(),
+						StartPos: ast.StartPos(t[0]).Position(),
+						EndPos:   ast.StartPos(t[len(t)-1]).Position(),
+					})
+				}
+			}
+			continue
+		}
+		diags.Add(diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			Message:  fmt.Sprintf("function %q can only reference its parameters, other functions and the standard library, found %q", fn.Label(), t.String()),
+			StartPos: ast.StartPos(t[0]).Position(),
+			EndPos:   ast.StartPos(t[len(t)-1]).Position(),
+		})
+	}
+	return diags
+}
+
+// isFunctionInScope returns true if t refers to a function of scope, such as
+// the functions of a parent module.
+func isFunctionInScope(scope *vm.Scope, t Traversal) bool {
+	v, ok := scope.Lookup(t[0].Name)
+	for _, ident := range t[1:] {
+		if !ok {
+			return false
+		}
+		ns, isNamespace := v.(map[string]any)
+		if !isNamespace {
+			return false
+		}
+		v, ok = ns[ident.Name]
+	}
+	return ok && reflect.ValueOf(v).Kind() == reflect.Func
+}
+
+// wireImportedFunctionCalls adds edges between n and the import nodes
+// providing the functions that it calls.
+func (l *Loader) wireImportedFunctionCalls(g *dag.Graph, n dag.Node) {
+	bn, ok := n.(BlockNode)
+	if !ok || bn.Block() == nil {
+		return
+	}
+	for _, t := range expressionsFromBody(bn.Block().Body) {
+		if importNode, ok := l.importConfigNodes[t[0].Name]; ok && len(t) > 1 && importNode != n {
+			g.AddEdge(dag.Edge{From: n, To: importNode})
+		}
+	}
+}
+
+// validateFunctionLabels returns an error for every function whose label is
+// already used by other nodes or by an import namespace, since they would
+// share the same identifier in expressions.
+func (l *Loader) validateFunctionLabels(g *dag.Graph) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if len(l.functionNodes) == 0 {
+		return diags
+	}
+
+	used := make(map[string]string)
+	for _, n := range g.Nodes() {
+		if _, ok := n.(*FunctionConfigNode); !ok {
+			used[strings.SplitN(n.NodeID(), ".", 2)[0]] = n.NodeID()
+		}
+	}
+	for label, importNode := range l.importConfigNodes {
+		used[label] = importNode.NodeID()
+	}
+
+	for label, fn := range l.functionNodes {
+		if nodeID, ok := used[label]; ok {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				Message:  fmt.Sprintf("function %q conflicts with %s", label, nodeID),
+				StartPos: ast.StartPos(fn.Block()).Position(),
+				EndPos:   ast.EndPos(fn.Block()).Position(),
+			})
+		}
+	}
+	return diags
+}
+
+// sameBlockType returns true if block can update the existing node n.
+func sameBlockType(n BlockNode, block *ast.BlockStmt) bool {
+	if n.Block() == nil {
+		// Default nodes don't have a block.
+		return true
+	}
+	return n.Block().GetBlockName() == block.GetBlockName()
+}
+
 // wireForEachNode add edges between a foreach node and declare/import nodes that are used in the foreach pipeline.
 func (l *Loader) wireForEachNode(g *dag.Graph, fn *ForeachConfigNode) {
 	refs := l.findCustomComponentReferences(fn.Block())
@@ -741,6 +963,7 @@ func (l *Loader) EvaluateDependants(ctx context.Context, updatedNodes []*QueuedN
 		case *ImportConfigNode:
 			// Update the scope with the imported content.
 			l.componentNodeManager.customComponentReg.updateImportContent(parentNode)
+			l.cacheImportedFunctions(parentNode)
 		}
 		// We collect all nodes directly incoming to parent.
 		_ = dag.WalkIncomingNodes(l.graph, parent.Node, func(n dag.Node) error {
@@ -834,6 +1057,12 @@ func (l *Loader) concurrentEvalFn(n dag.Node, spanCtx context.Context, tracer tr
 
 		err = l.postEvaluate(l.log, n, evalErr)
 
+		// Functions don't have exports: dependants must be notified when they
+		// are rebuilt, for example after an update of the functions they import.
+		if fn, ok := n.(*FunctionConfigNode); ok && err == nil {
+			l.globals.OnBlockNodeUpdate(fn)
+		}
+
 		// Additional post-evaluation steps necessary for module exports.
 		if exp, ok := n.(*ExportConfigNode); ok {
 			l.cache.CacheModuleExportValue(exp.Label(), exp.Value())
@@ -891,7 +1120,7 @@ func (l *Loader) postEvaluate(logger log.Logger, bn BlockNode, err error) error
 			}
 		}
 	case *ArgumentConfigNode:
-		if _, found := l.cache.GetModuleArgument(c.Label()); !found {
+		if value, found := l.cache.GetModuleArgument(c.Label()); !found {
 			if c.Optional() {
 				l.cache.CacheModuleArgument(c.Label(), c.Default())
 			} else {
@@ -899,9 +1128,20 @@ func (l *Loader) postEvaluate(logger log.Logger, bn BlockNode, err error) error
 				// a more important error to address.
 				err = fmt.Errorf("missing required argument %q to module", c.Label())
 			}
+		} else if err == nil {
+			// Cached module arguments are wrapped into an object with a single "value" key.
+			if typeErr := checkArgumentType(c.Type(), c.Label(), value.(map[string]any)["value"]); typeErr != nil {
+				err = fmt.Errorf("invalid value for argument %q: %w", c.Label(), typeErr)
+			}
 		}
 	case *ImportConfigNode:
 		l.componentNodeManager.customComponentReg.updateImportContent(c)
+		l.cacheImportedFunctions(c)
+	case *FunctionConfigNode:
+		if err == nil {
+			l.cache.CacheFunction(c.Label(), c.Function())
+			l.componentNodeManager.customComponentReg.registerFunction(c.Label(), c.Function())
+		}
 	}
 
 	if err != nil {
@@ -911,6 +1151,27 @@ func (l *Loader) postEvaluate(logger log.Logger, bn BlockNode, err error) error
 	return nil
 }
 
+// syncFunctions removes the cached functions which are no longer defined or
+// imported.
+func (l *Loader) syncFunctions() {
+	names := make(map[string]struct{}, len(l.functionNodes)+len(l.importConfigNodes))
+	for label := range l.functionNodes {
+		names[label] = struct{}{}
+	}
+	for label := range l.importConfigNodes {
+		names[label] = struct{}{}
+	}
+	l.cache.SyncFunctions(names)
+}
+
+// cacheImportedFunctions exposes the functions imported by importNode under
+// its namespace.
+func (l *Loader) cacheImportedFunctions(importNode *ImportConfigNode) {
+	functions := importNode.ImportedFunctions()
+	l.cache.CacheImportedFunctions(importNode.Label(), functions)
+	l.componentNodeManager.customComponentReg.registerFunction(importNode.Label(), functions)
+}
+
 func multierrToDiags(errors error) diag.Diagnostics {
 	var diags diag.Diagnostics
 	for _, err := range errors.(*multierror.Error).Errors {
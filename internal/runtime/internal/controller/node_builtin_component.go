@@ -84,6 +84,7 @@ type ComponentGlobals struct {
 	NewModuleController  func(opts ModuleControllerOpts) ModuleController // Func to generate a module controller.
 	GetServiceData       func(name string) (interface{}, error)           // Get data for a service.
 	EnableCommunityComps bool                                             // Enables the use of community components.
+	DryRun               bool                                             // Evaluate arguments without building components.
 }
 
 // BuiltinComponentNode is a controller node which manages a builtin component.
@@ -102,6 +103,7 @@ type BuiltinComponentNode struct {
 	registry          *prometheus.Registry
 	exportsType       reflect.Type
 	moduleController  ModuleController
+	dryRun            bool               // Only evaluate arguments; the managed component is never built.
 	OnBlockNodeUpdate func(cn BlockNode) // Informs controller that we need to reevaluate
 
 	mut     sync.RWMutex
@@ -157,6 +159,7 @@ func NewBuiltinComponentNode(globals ComponentGlobals, reg component.Registratio
 		reg:               reg,
 		exportsType:       getExportsType(reg),
 		moduleController:  globals.NewModuleController(ModuleControllerOpts{Id: globalID}),
+		dryRun:            globals.DryRun,
 		OnBlockNodeUpdate: globals.OnBlockNodeUpdate,
 
 		block: b,
@@ -280,6 +283,13 @@ func (cn *BuiltinComponentNode) evaluate(scope *vm.Scope) error {
 	// components expect a non-pointer.
 	argsCopyValue := reflect.ValueOf(argsPointer).Elem().Interface()
 
+	if cn.dryRun {
+		// The arguments are valid. Exports keep their zero value since they're
+		// only known once the component runs.
+		cn.args = argsCopyValue
+		return nil
+	}
+
 	if cn.managed == nil {
 		// We haven't built the managed component successfully yet.
 		managed, err := cn.reg.Build(cn.managedOpts, argsCopyValue)
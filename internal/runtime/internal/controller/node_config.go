@@ -15,11 +15,13 @@ const (
 	loggingBlockID  = "logging"
 	tracingBlockID  = "tracing"
 	foreachID       = "foreach"
+	functionBlockID = "function"
 )
 
 // Add config blocks that are not GA. Config blocks that are not specified here are considered GA.
 var configBlocksUnstable = map[string]featuregate.Stability{
-	foreachID: featuregate.StabilityExperimental,
+	foreachID:       featuregate.StabilityExperimental,
+	functionBlockID: featuregate.StabilityExperimental,
 }
 
 // NewConfigNode creates a new ConfigNode from an initial ast.BlockStmt.
@@ -50,6 +52,17 @@ func NewConfigNode(block *ast.BlockStmt, globals ComponentGlobals, customReg *Cu
 		return NewImportConfigNode(block, globals, importsource.GetSourceType(block.GetBlockName())), nil
 	case foreachID:
 		return NewForeachConfigNode(block, globals, customReg), nil
+	case functionBlockID:
+		if block.Label == "" {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				Message:  "function blocks must have a label",
+				StartPos: ast.StartPos(block).Position(),
+				EndPos:   ast.EndPos(block).Position(),
+			})
+			return nil, diags
+		}
+		return NewFunctionConfigNode(block, globals), nil
 	default:
 		diags.Add(diag.Diagnostic{
 			Severity: diag.SeverityLevelError,
@@ -79,6 +92,7 @@ type ConfigNodeMap struct {
 	exportMap   map[string]*ExportConfigNode
 	importMap   map[string]*ImportConfigNode
 	foreachMap  map[string]*ForeachConfigNode
+	functionMap map[string]*FunctionConfigNode
 }
 
 // NewConfigNodeMap will create an initial ConfigNodeMap. Append must be called
@@ -91,6 +105,7 @@ func NewConfigNodeMap() *ConfigNodeMap {
 		exportMap:   map[string]*ExportConfigNode{},
 		importMap:   map[string]*ImportConfigNode{},
 		foreachMap:  map[string]*ForeachConfigNode{},
+		functionMap: map[string]*FunctionConfigNode{},
 	}
 }
 
@@ -112,6 +127,8 @@ func (nodeMap *ConfigNodeMap) Append(configNode BlockNode) diag.Diagnostics {
 		nodeMap.importMap[n.Label()] = n
 	case *ForeachConfigNode:
 		nodeMap.foreachMap[n.Label()] = n
+	case *FunctionConfigNode:
+		nodeMap.functionMap[n.Label()] = n
 	default:
 		diags.Add(diag.Diagnostic{
 			Severity: diag.SeverityLevelError,
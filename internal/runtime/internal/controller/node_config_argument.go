@@ -19,6 +19,7 @@ type ArgumentConfigNode struct {
 	eval         *vm.Evaluator
 	defaultValue any
 	optional     bool
+	typ          argumentType
 }
 
 var _ BlockNode = (*ArgumentConfigNode)(nil)
@@ -40,6 +41,7 @@ type argumentBlock struct {
 	Optional bool   `alloy:"optional,attr,optional"`
 	Default  any    `alloy:"default,attr,optional"`
 	Comment  string `alloy:"comment,attr,optional"`
+	Type     string `alloy:"type,attr,optional"`
 }
 
 // Evaluate implements BlockNode and updates the arguments for the managed config block
@@ -59,6 +61,18 @@ func (cn *ArgumentConfigNode) Evaluate(scope *vm.Scope) error {
 
 	cn.defaultValue = argument.Default
 	cn.optional = argument.Optional
+	cn.typ = nil
+
+	if argument.Type != "" {
+		typ, err := parseArgumentType(argument.Type)
+		if err != nil {
+			return err
+		}
+		if err := checkArgumentType(typ, "default", argument.Default); err != nil {
+			return fmt.Errorf("invalid default value: %w", err)
+		}
+		cn.typ = typ
+	}
 
 	return nil
 }
@@ -75,6 +89,14 @@ func (cn *ArgumentConfigNode) Default() any {
 	return cn.defaultValue
 }
 
+// Type returns the type declared by the argument block, or nil if the block
+// doesn't declare a type.
+func (cn *ArgumentConfigNode) Type() argumentType {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.typ
+}
+
 func (cn *ArgumentConfigNode) Label() string { return cn.label }
 
 // Block implements BlockNode and returns the current block of the managed config node.
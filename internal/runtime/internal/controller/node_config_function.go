@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/grafana/alloy/syntax/ast"
+	"github.com/grafana/alloy/syntax/scanner"
+	"github.com/grafana/alloy/syntax/vm"
+)
+
+const (
+	functionParamsAttr = "params"
+	functionResultAttr = "result"
+)
+
+// Function is the Go representation of user-defined functions. Alloy
+// expressions call it like any other function of the standard library.
+type Function func(args ...any) (any, error)
+
+// FunctionConfigNode represents a function block in the DAG. Its ID is the
+// label of the block so that references to the function can be resolved like
+// references to components.
+type FunctionConfigNode struct {
+	label         string
+	nodeID        string
+	componentName string
+
+	mut   sync.RWMutex
+	block *ast.BlockStmt // Current Alloy blocks to derive config from
+	fn    Function
+}
+
+var _ BlockNode = (*FunctionConfigNode)(nil)
+
+// NewFunctionConfigNode creates a new FunctionConfigNode from an initial
+// ast.BlockStmt. The function isn't built until Evaluate is called.
+func NewFunctionConfigNode(block *ast.BlockStmt, globals ComponentGlobals) *FunctionConfigNode {
+	return &FunctionConfigNode{
+		label:         block.Label,
+		nodeID:        block.Label,
+		componentName: block.GetBlockName(),
+
+		block: block,
+	}
+}
+
+// Evaluate implements BlockNode and builds the function. The result of the
+// function can only use its parameters, the standard library, and other
+// functions found in scope.
+func (cn *FunctionConfigNode) Evaluate(scope *vm.Scope) error {
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+
+	def, err := parseFunctionBlock(cn.block)
+	if err != nil {
+		return err
+	}
+	cn.fn = def.build(scope.Variables)
+	return nil
+}
+
+// Function returns the function built by the last successful call to
+// Evaluate.
+func (cn *FunctionConfigNode) Function() Function {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.fn
+}
+
+// Label returns the label of the block.
+func (cn *FunctionConfigNode) Label() string { return cn.label }
+
+// Block implements BlockNode and returns the current block of the managed config node.
+func (cn *FunctionConfigNode) Block() *ast.BlockStmt {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.block
+}
+
+// NodeID implements dag.Node and returns the unique ID for the config node.
+func (cn *FunctionConfigNode) NodeID() string { return cn.nodeID }
+
+// UpdateBlock updates the Alloy block used to construct the function.
+//
+// UpdateBlock will panic if the block does not match the label of the
+// FunctionConfigNode.
+func (cn *FunctionConfigNode) UpdateBlock(b *ast.BlockStmt) {
+	if b.GetBlockName() != functionBlockID || b.Label != cn.label {
+		panic("UpdateBlock called with an Alloy block with a different ID")
+	}
+
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+	cn.block = b
+}
+
+// functionDef is the definition of a function block.
+type functionDef struct {
+	name   string
+	params []string
+	result ast.Expr
+}
+
+// parseFunctionBlock validates a function block and returns its definition.
+// The params attribute must be a list of string literals so that the
+// parameters are known without evaluating anything.
+func parseFunctionBlock(block *ast.BlockStmt) (*functionDef, error) {
+	if block.Label == "" {
+		return nil, fmt.Errorf("function blocks must have a label")
+	}
+
+	def := &functionDef{name: block.Label}
+	for _, stmt := range block.Body {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if !ok {
+			return nil, fmt.Errorf("function %q: only the %s and %s attributes are allowed", def.name, functionParamsAttr, functionResultAttr)
+		}
+
+		switch attr.Name.Name {
+		case functionParamsAttr:
+			var params []string
+			if err := vm.New(attr.Value).Evaluate(nil, &params); err != nil {
+				return nil, fmt.Errorf("function %q: decoding params: %w", def.name, err)
+			}
+			for _, param := range params {
+				if !scanner.IsValidIdentifier(param) {
+					return nil, fmt.Errorf("function %q: parameter %q is not a valid identifier", def.name, param)
+				}
+				if slices.Contains(def.params, param) {
+					return nil, fmt.Errorf("function %q: parameter %q is declared more than once", def.name, param)
+				}
+				def.params = append(def.params, param)
+			}
+		case functionResultAttr:
+			def.result = attr.Value
+		default:
+			return nil, fmt.Errorf("function %q: unrecognized attribute %q", def.name, attr.Name.Name)
+		}
+	}
+
+	if def.result == nil {
+		return nil, fmt.Errorf("function %q: missing required attribute %q", def.name, functionResultAttr)
+	}
+	return def, nil
+}
+
+// references returns the traversals of the result expression which don't
+// refer to the parameters of the function.
+func (def *functionDef) references() []Traversal {
+	var w traversalWalker
+	ast.Walk(&w, def.result)
+	w.flush()
+
+	refs := make([]Traversal, 0, len(w.traversals))
+	for _, t := range w.traversals {
+		if !slices.Contains(def.params, t[0].Name) {
+			refs = append(refs, t)
+		}
+	}
+	return refs
+}
+
+// build returns the function evaluating the result expression. Identifiers
+// which aren't parameters are looked up in variables, which must only hold
+// other functions and namespaces of imported functions.
+func (def *functionDef) build(variables map[string]any) Function {
+	// Only keep the variables used by the function so that it can't depend
+	// on anything else, such as the exports of components.
+	captured := make(map[string]any)
+	for _, t := range def.references() {
+		if v, ok := variables[t[0].Name]; ok {
+			captured[t[0].Name] = v
+		}
+	}
+
+	eval := vm.New(def.result)
+	return func(args ...any) (any, error) {
+		if len(args) != len(def.params) {
+			return nil, fmt.Errorf("function %q expects %d argument(s), got %d", def.name, len(def.params), len(args))
+		}
+
+		vars := make(map[string]any, len(captured)+len(args))
+		for name, v := range captured {
+			vars[name] = v
+		}
+		for i, param := range def.params {
+			vars[param] = args[i]
+		}
+
+		var res any
+		if err := eval.Evaluate(vm.NewScope(vars), &res); err != nil {
+			return nil, fmt.Errorf("function %q: %w", def.name, err)
+		}
+		return res, nil
+	}
+}
+
+// buildImportedFunctions builds the functions of an imported module. The
+// functions can call each other and the functions of the nested imports
+// found in namespaces. defs must have been checked with checkFunctionCycles.
+func buildImportedFunctions(defs map[string]*functionDef, namespaces map[string]any) map[string]any {
+	// The functions are built lazily since they may use each other: variables
+	// is only complete once all of them are created.
+	variables := make(map[string]any, len(defs)+len(namespaces))
+	for name, ns := range namespaces {
+		variables[name] = ns
+	}
+
+	functions := make(map[string]any, len(defs))
+	for name, def := range defs {
+		functions[name] = lazyFunction(def, variables)
+	}
+	for name, fn := range functions {
+		variables[name] = fn
+	}
+	return functions
+}
+
+// lazyFunction builds the function on its first call, once variables has
+// been filled.
+func lazyFunction(def *functionDef, variables map[string]any) Function {
+	var (
+		once sync.Once
+		fn   Function
+	)
+	return func(args ...any) (any, error) {
+		once.Do(func() { fn = def.build(variables) })
+		return fn(args...)
+	}
+}
+
+// checkFunctionCycles returns an error if functions of defs call each other
+// recursively, since they would never return.
+func checkFunctionCycles(defs map[string]*functionDef) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(defs))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("cycle between functions: %s", strings.Join(append(path, name), " -> "))
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, t := range defs[name].references() {
+			if _, ok := defs[t[0].Name]; ok {
+				if err := visit(t[0].Name, append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = done
+		return nil
+	}
+
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
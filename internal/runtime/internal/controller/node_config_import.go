@@ -26,8 +26,8 @@ import (
 	"github.com/grafana/alloy/syntax/vm"
 )
 
-// ImportConfigNode imports declare and import blocks via a managed import source.
-// The imported declare are stored in importedDeclares.
+// ImportConfigNode imports declare, function and import blocks via a managed import source.
+// The imported declare are stored in importedDeclares and the imported functions in importedFunctions.
 // For every imported import block, the ImportConfigNode will create ImportConfigNode children.
 // The children are evaluated and ran by the parent.
 // When an ImportConfigNode receives new content from its source, it updates its importedDeclares and recreates its children.
@@ -53,6 +53,7 @@ type ImportConfigNode struct {
 	importConfigNodesChildren map[string]*ImportConfigNode
 	importChildrenRunning     bool
 	importedDeclares          map[string]ast.Body
+	importedFunctions         map[string]*functionDef
 
 	// NOTE: To avoid deadlocks, whenever we need both locks we must always first lock the mut, then healthMut.
 	healthMut     sync.RWMutex
@@ -212,6 +213,7 @@ func (cn *ImportConfigNode) onContentUpdate(importedContent map[string]string) {
 		cn.importedContent[k] = v
 	}
 	cn.importedDeclares = make(map[string]ast.Body)
+	cn.importedFunctions = make(map[string]*functionDef)
 	cn.importConfigNodesChildren = make(map[string]*ImportConfigNode)
 
 	for f, ic := range importedContent {
@@ -231,8 +233,17 @@ func (cn *ImportConfigNode) onContentUpdate(importedContent map[string]string) {
 		}
 	}
 
+	// functions calling each other recursively would never return
+	err := checkFunctionCycles(cn.importedFunctions)
+	if err != nil {
+		cn.importedFunctions = nil
+		level.Error(cn.logger).Log("msg", "failed to process imported functions", "err", err)
+		cn.setContentHealth(component.HealthTypeUnhealthy, fmt.Sprintf("imported content is invalid: %s", err))
+		return
+	}
+
 	// evaluate the importConfigNodesChildren that have been created
-	err := cn.evaluateChildren()
+	err = cn.evaluateChildren()
 	if err != nil {
 		level.Error(cn.logger).Log("msg", "failed to evaluate nested import", "err", err)
 		cn.setContentHealth(component.HealthTypeUnhealthy, fmt.Sprintf("nested import block failed to evaluate: %s", err))
@@ -251,25 +262,30 @@ func (cn *ImportConfigNode) onContentUpdate(importedContent map[string]string) {
 	cn.OnBlockNodeUpdate(cn)
 }
 
-// processImportedContent processes declare and import blocks of the provided ast content.
+// processImportedContent processes declare, function and import blocks of the provided ast content.
 func (cn *ImportConfigNode) processImportedContent(content *ast.File) error {
 	for _, stmt := range content.Body {
 		blockStmt, ok := stmt.(*ast.BlockStmt)
 		if !ok {
-			return fmt.Errorf("only declare and import blocks are allowed in a module")
+			return fmt.Errorf("only declare, function and import blocks are allowed in a module")
 		}
 
 		componentName := strings.Join(blockStmt.Name, ".")
 		switch componentName {
 		case declareType:
 			cn.processDeclareBlock(blockStmt)
+		case functionBlockID:
+			err := cn.processFunctionBlock(blockStmt)
+			if err != nil {
+				return err
+			}
 		case importsource.BlockImportFile, importsource.BlockImportString, importsource.BlockImportHTTP, importsource.BlockImportGit:
 			err := cn.processImportBlock(blockStmt, componentName)
 			if err != nil {
 				return err
 			}
 		default:
-			return fmt.Errorf("only declare and import blocks are allowed in a module, got %s", componentName)
+			return fmt.Errorf("only declare, function and import blocks are allowed in a module, got %s", componentName)
 		}
 	}
 	return nil
@@ -284,6 +300,22 @@ func (cn *ImportConfigNode) processDeclareBlock(stmt *ast.BlockStmt) {
 	cn.importedDeclares[stmt.Label] = stmt.Body
 }
 
+// processFunctionBlock stores the function definition in the importedFunctions.
+func (cn *ImportConfigNode) processFunctionBlock(stmt *ast.BlockStmt) error {
+	if err := checkFeatureStability(functionBlockID, cn.globals.MinStability); err != nil {
+		return err
+	}
+	if _, ok := cn.importedFunctions[stmt.Label]; ok {
+		return fmt.Errorf("function block redefined %s", stmt.Label)
+	}
+	def, err := parseFunctionBlock(stmt)
+	if err != nil {
+		return err
+	}
+	cn.importedFunctions[stmt.Label] = def
+	return nil
+}
+
 // processDeclareBlock creates an ImportConfigNode child from the provided import block.
 func (cn *ImportConfigNode) processImportBlock(stmt *ast.BlockStmt, fullName string) error {
 	sourceType := importsource.GetSourceType(fullName)
@@ -434,11 +466,37 @@ func (cn *ImportConfigNode) ImportedDeclares() map[string]ast.Body {
 	return cn.importedDeclares
 }
 
-// Scope returns the scope associated with the import source.
+// ImportedFunctions returns all functions that it imported. The functions
+// can use the functions imported by nested import blocks.
+func (cn *ImportConfigNode) ImportedFunctions() map[string]any {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return buildImportedFunctions(cn.importedFunctions, cn.nestedFunctions())
+}
+
+// nestedFunctions returns the functions imported by the children, keyed by
+// the label of the child. mut must be held when calling nestedFunctions.
+func (cn *ImportConfigNode) nestedFunctions() map[string]any {
+	namespaces := make(map[string]any, len(cn.importConfigNodesChildren))
+	for label, child := range cn.importConfigNodesChildren {
+		namespaces[label] = child.ImportedFunctions()
+	}
+	return namespaces
+}
+
+// Scope returns the scope associated with the import source. It includes the
+// imported functions so that imported declare blocks can use them.
 func (cn *ImportConfigNode) Scope() *vm.Scope {
-	return vm.NewScope(map[string]interface{}{
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+
+	namespaces := cn.nestedFunctions()
+	vars := map[string]interface{}{
 		importsource.ModulePath: cn.source.ModulePath(),
-	})
+	}
+	maps.Copy(vars, namespaces)
+	maps.Copy(vars, buildImportedFunctions(cn.importedFunctions, namespaces))
+	return vm.NewScope(vars)
 }
 
 // ImportConfigNodesChildren returns the ImportConfigNodesChildren of this ImportConfigNode.
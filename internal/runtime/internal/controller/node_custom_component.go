@@ -13,6 +13,7 @@ import (
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/runtime/equality"
 	"github.com/grafana/alloy/syntax/ast"
+	"github.com/grafana/alloy/syntax/diag"
 	"github.com/grafana/alloy/syntax/vm"
 )
 
@@ -201,6 +202,13 @@ func (cn *CustomComponentNode) evaluate(evalScope *vm.Scope) error {
 		return fmt.Errorf("loading custom component controller: %w", err)
 	}
 
+	// Validate the arguments against the declared types before loading the
+	// template so that mismatches are reported against this block rather than
+	// against the internals of the custom component.
+	if diags := cn.checkArgumentTypes(template, args); diags.HasErrors() {
+		return diags
+	}
+
 	// Reload the custom component with new config
 	if err := cn.managed.LoadBody(template, args, customComponentRegistry); err != nil {
 		return fmt.Errorf("updating custom component: %w", err)
@@ -208,6 +216,70 @@ func (cn *CustomComponentNode) evaluate(evalScope *vm.Scope) error {
 	return nil
 }
 
+// checkArgumentTypes validates args against the type attributes of the
+// argument blocks found in template. Argument blocks with a type which can't
+// be evaluated statically are skipped; the module reports those itself.
+func (cn *CustomComponentNode) checkArgumentTypes(template ast.Body, args map[string]any) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for _, stmt := range template {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok || block.GetBlockName() != argumentBlockID {
+			continue
+		}
+		value, provided := args[block.Label]
+		if !provided {
+			continue
+		}
+
+		typ, ok := staticArgumentType(block)
+		if !ok {
+			continue
+		}
+		if err := checkArgumentType(typ, block.Label, value); err != nil {
+			var node ast.Node = cn.block
+			if attr := findAttribute(cn.block.Body, block.Label); attr != nil {
+				node = attr
+			}
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				Message:  fmt.Sprintf("invalid value for argument %q of %s: %s", block.Label, cn.componentName, err),
+				StartPos: ast.StartPos(node).Position(),
+				EndPos:   ast.EndPos(node).Position(),
+			})
+		}
+	}
+
+	return diags
+}
+
+// staticArgumentType returns the type declared by an argument block if it
+// can be evaluated without any scope.
+func staticArgumentType(block *ast.BlockStmt) (argumentType, bool) {
+	attr := findAttribute(block.Body, "type")
+	if attr == nil {
+		return nil, false
+	}
+	var expr string
+	if err := vm.New(attr.Value).Evaluate(nil, &expr); err != nil {
+		return nil, false
+	}
+	typ, err := parseArgumentType(expr)
+	if err != nil {
+		return nil, false
+	}
+	return typ, true
+}
+
+func findAttribute(body ast.Body, name string) *ast.AttributeStmt {
+	for _, stmt := range body {
+		if attr, ok := stmt.(*ast.AttributeStmt); ok && attr.Name.Name == name {
+			return attr
+		}
+	}
+	return nil
+}
+
 func (cn *CustomComponentNode) Run(ctx context.Context) error {
 	cn.mut.RLock()
 	managed := cn.managed
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"fmt"
+	"maps"
 	"sync"
 
 	"github.com/grafana/alloy/internal/component"
@@ -21,6 +22,7 @@ type valueCache struct {
 	moduleExports      map[string]any         // Export label -> Export value
 	moduleArguments    map[string]any         // Argument label -> Map with the key "value" that points to the Argument value
 	moduleChangedIndex int                    // Everytime a change occurs this is incremented
+	functions          map[string]any         // Function name or import namespace -> Function or map of imported functions
 	scope              *vm.Scope              // scope provides additional context for the nodes in the module
 }
 
@@ -30,6 +32,7 @@ func newValueCache() *valueCache {
 		componentIds:    make(map[string]ComponentID, 0),
 		moduleExports:   make(map[string]any),
 		moduleArguments: make(map[string]any),
+		functions:       make(map[string]any),
 		scope:           vm.NewScope(make(map[string]any)),
 	}
 }
@@ -205,6 +208,43 @@ func (vc *valueCache) SyncModuleArgs(args map[string]any) {
 	}
 }
 
+// CacheFunction caches a function defined by a function block.
+func (vc *valueCache) CacheFunction(name string, fn Function) {
+	vc.mut.Lock()
+	defer vc.mut.Unlock()
+	vc.functions[name] = fn
+}
+
+// CacheImportedFunctions caches the functions imported under the given
+// namespace. functions may be empty to only register the namespace.
+func (vc *valueCache) CacheImportedFunctions(namespace string, functions map[string]any) {
+	vc.mut.Lock()
+	defer vc.mut.Unlock()
+	vc.functions[namespace] = functions
+}
+
+// HasFunctions returns true if a function or a namespace of imported
+// functions is cached with the given name.
+func (vc *valueCache) HasFunctions(name string) bool {
+	vc.mut.RLock()
+	defer vc.mut.RUnlock()
+	_, ok := vc.functions[name]
+	return ok
+}
+
+// SyncFunctions will remove any cached function or namespace of imported
+// functions which is not in names.
+func (vc *valueCache) SyncFunctions(names map[string]struct{}) {
+	vc.mut.Lock()
+	defer vc.mut.Unlock()
+
+	for name := range vc.functions {
+		if _, ok := names[name]; !ok {
+			delete(vc.functions, name)
+		}
+	}
+}
+
 // GetContext returns a scope that can be used for evaluation.
 func (vc *valueCache) GetContext() *vm.Scope {
 	vc.mut.RLock()
@@ -216,6 +256,22 @@ func (vc *valueCache) GetContext() *vm.Scope {
 		vars[argumentLabel] = deepCopyMap(vc.moduleArguments)
 	}
 
+	// Add functions. Imported functions share their namespace with the
+	// exports of the imported custom components.
+	for name, fn := range vc.functions {
+		imported, isNamespace := fn.(map[string]any)
+		if !isNamespace {
+			vars[name] = fn
+			continue
+		}
+		ns, ok := vars[name].(map[string]any)
+		if !ok {
+			ns = make(map[string]any, len(imported))
+			vars[name] = ns
+		}
+		maps.Copy(ns, imported)
+	}
+
 	return vm.NewScope(vars)
 }
 
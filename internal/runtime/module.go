@@ -143,6 +143,7 @@ func newModule(o *moduleOptions) *module {
 				DataPath:             o.DataPath,
 				MinStability:         o.MinStability,
 				EnableCommunityComps: o.EnableCommunityComps,
+				DryRun:               o.DryRun,
 				OnExportsChange: func(exports map[string]any) {
 					if o.export != nil {
 						o.export(exports)
@@ -230,4 +231,7 @@ type moduleControllerOptions struct {
 
 	// EnableCommunityComps enables the use of community components.
 	EnableCommunityComps bool
+
+	// DryRun evaluates modules without building their components.
+	DryRun bool
 }
@@ -0,0 +1,89 @@
+package livedebugging
+
+import (
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// DataType is the kind of telemetry signal described by a Data record.
+type DataType string
+
+const (
+	PrometheusMetric DataType = "prometheus_metric"
+	LokiLog          DataType = "loki_log"
+	Target           DataType = "target"
+	OtelMetric       DataType = "otel_metric"
+	OtelLog          DataType = "otel_log"
+	OtelTrace        DataType = "otel_trace"
+)
+
+// Data is a structured debugging record published by a component.
+type Data struct {
+	// Type is the kind of signal the record is about.
+	Type DataType
+	// Timestamp is the time at which the record was published.
+	Timestamp time.Time
+	// Count is the number of signal items (samples, log lines, targets,
+	// spans...) summarized by the record.
+	Count uint64
+	// Labels are the labels or attributes associated with the record. They are
+	// used to filter records before they are sent to consumers.
+	Labels labels.Labels
+	// DataFunc renders the human-readable payload of the record. It is only
+	// invoked for records which are sent to at least one consumer, so the
+	// formatting cost is not paid for filtered out records.
+	//
+	// DataFunc must only capture values which are not modified after the
+	// record is published.
+	DataFunc func() string
+}
+
+// NewData creates a new Data record timestamped with the current time.
+func NewData(dataType DataType, count uint64, lbls labels.Labels, dataFunc func() string) Data {
+	return Data{
+		Type:      dataType,
+		Timestamp: time.Now(),
+		Count:     count,
+		Labels:    lbls,
+		DataFunc:  dataFunc,
+	}
+}
+
+// LabelsFromLabelSet converts a label set, as used by Loki entries, into
+// labels which can be attached to a Data record.
+func LabelsFromLabelSet(ls model.LabelSet) labels.Labels {
+	b := labels.NewScratchBuilder(len(ls))
+	for name, value := range ls {
+		b.Add(string(name), string(value))
+	}
+	b.Sort()
+	return b.Labels()
+}
+
+// Record is the serialized form of a Data record sent to live debugging
+// consumers.
+type Record struct {
+	Type      DataType          `json:"type"`
+	Timestamp time.Time         `json:"timestamp"`
+	Count     uint64            `json:"count"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Data      string            `json:"data"`
+}
+
+// Record renders d into a Record, invoking its DataFunc.
+func (d Data) Record() Record {
+	r := Record{
+		Type:      d.Type,
+		Timestamp: d.Timestamp,
+		Count:     d.Count,
+	}
+	if !d.Labels.IsEmpty() {
+		r.Labels = d.Labels.Map()
+	}
+	if d.DataFunc != nil {
+		r.Data = d.DataFunc()
+	}
+	return r
+}
@@ -0,0 +1,121 @@
+package livedebugging
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"golang.org/x/time/rate"
+)
+
+// Filter is evaluated against every Data record before it is serialized and
+// sent to a live debugging consumer.
+type Filter struct {
+	// Types restricts the records to the given data types. All types are
+	// allowed when empty.
+	Types []DataType
+	// Matchers must all match the labels of a record for it to be kept.
+	Matchers []*labels.Matcher
+	// SampleProb is the probability for a matching record to be kept.
+	SampleProb float64
+	// MaxRate is the maximum number of records per second sent to the
+	// consumer. 0 means no limit.
+	MaxRate float64
+
+	limiter *rate.Limiter
+}
+
+// NewFilter creates a Filter which keeps every record.
+func NewFilter() *Filter {
+	return &Filter{SampleProb: 1}
+}
+
+// ParseFilter builds a Filter from URL query parameters:
+//
+//   - types: comma-separated list of data types to keep.
+//   - selector: label matchers using the Prometheus selector syntax, e.g.
+//     {job="api", level=~"warn|error"}.
+//   - sampleProb: probability between 0 and 1 to keep a matching record.
+//   - maxRate: maximum number of records per second.
+func ParseFilter(query url.Values) (*Filter, error) {
+	f := NewFilter()
+
+	if types := query.Get("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			f.Types = append(f.Types, DataType(strings.TrimSpace(t)))
+		}
+	}
+
+	if selector := query.Get("selector"); selector != "" {
+		matchers, err := parser.ParseMetricSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+		f.Matchers = matchers
+	}
+
+	if sampleProb := query.Get("sampleProb"); sampleProb != "" {
+		prob, err := strconv.ParseFloat(sampleProb, 64)
+		if err != nil || prob < 0 || prob > 1 {
+			return nil, fmt.Errorf("invalid sample probability %q", sampleProb)
+		}
+		f.SampleProb = prob
+	}
+
+	if maxRate := query.Get("maxRate"); maxRate != "" {
+		r, err := strconv.ParseFloat(maxRate, 64)
+		if err != nil || r < 0 || math.IsInf(r, 0) {
+			return nil, fmt.Errorf("invalid max rate %q", maxRate)
+		}
+		f.MaxRate = r
+	}
+
+	if f.MaxRate > 0 {
+		f.limiter = rate.NewLimiter(rate.Limit(f.MaxRate), int(math.Ceil(f.MaxRate)))
+	}
+	return f, nil
+}
+
+// Allow returns true if the record should be sent to the consumer. Allow
+// consumes the rate limit budget of the filter for the records it keeps.
+func (f *Filter) Allow(d Data) bool {
+	if !f.Match(d) {
+		return false
+	}
+	if f.SampleProb < 1 && rand.Float64() >= f.SampleProb {
+		return false
+	}
+	if f.limiter != nil && !f.limiter.Allow() {
+		return false
+	}
+	return true
+}
+
+// Match returns true if the type and labels of the record match the filter.
+// Sampling and rate limiting are not taken into account.
+func (f *Filter) Match(d Data) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == d.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, m := range f.Matchers {
+		if !m.Matches(d.Labels.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package livedebugging
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	tt := []struct {
+		name      string
+		query     string
+		expectErr string
+	}{
+		{name: "empty", query: ""},
+		{name: "all options", query: `types=loki_log,prometheus_metric&selector={job="api"}&sampleProb=0.5&maxRate=10`},
+		{name: "invalid selector", query: `selector={job=api}`, expectErr: "invalid selector"},
+		{name: "invalid sample probability", query: `sampleProb=2`, expectErr: `invalid sample probability "2"`},
+		{name: "invalid max rate", query: `maxRate=-1`, expectErr: `invalid max rate "-1"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+
+			_, err = ParseFilter(query)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	query, err := url.ParseQuery(`types=loki_log&selector={job="api", level=~"warn|error"}`)
+	require.NoError(t, err)
+	filter, err := ParseFilter(query)
+	require.NoError(t, err)
+
+	newLog := func(lbls ...string) Data {
+		return NewData(LokiLog, 1, labels.FromStrings(lbls...), func() string { return "line" })
+	}
+
+	require.True(t, filter.Allow(newLog("job", "api", "level", "error")))
+	require.False(t, filter.Allow(newLog("job", "api", "level", "info")))
+	require.False(t, filter.Allow(newLog("job", "web", "level", "error")))
+	require.False(t, filter.Allow(newLog("job", "api")))
+	require.False(t, filter.Allow(NewData(PrometheusMetric, 1, labels.FromStrings("job", "api", "level", "error"), nil)))
+}
+
+func TestFilterSampling(t *testing.T) {
+	query, err := url.ParseQuery(`sampleProb=0`)
+	require.NoError(t, err)
+	filter, err := ParseFilter(query)
+	require.NoError(t, err)
+
+	data := NewData(PrometheusMetric, 1, labels.EmptyLabels(), nil)
+	for i := 0; i < 100; i++ {
+		require.False(t, filter.Allow(data))
+	}
+	// Sampling and rate limiting don't affect matching.
+	require.True(t, filter.Match(data))
+}
+
+func TestFilterMaxRate(t *testing.T) {
+	query, err := url.ParseQuery(`maxRate=5`)
+	require.NoError(t, err)
+	filter, err := ParseFilter(query)
+	require.NoError(t, err)
+
+	data := NewData(PrometheusMetric, 1, labels.EmptyLabels(), nil)
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if filter.Allow(data) {
+			allowed++
+		}
+	}
+	// The burst allows up to one second worth of records at once.
+	require.Equal(t, 5, allowed)
+}
+
+func TestDataRecord(t *testing.T) {
+	data := NewData(LokiLog, 3, labels.FromStrings("job", "api"), func() string { return "payload" })
+
+	record := data.Record()
+	require.Equal(t, LokiLog, record.Type)
+	require.Equal(t, uint64(3), record.Count)
+	require.Equal(t, map[string]string{"job": "api"}, record.Labels)
+	require.Equal(t, "payload", record.Data)
+	require.Equal(t, data.Timestamp, record.Timestamp)
+}
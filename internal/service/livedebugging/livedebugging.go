@@ -15,7 +15,7 @@ type CallbackID string
 type CallbackManager interface {
 	// AddCallback sets a callback for a given componentID.
 	// The callback is used to send debugging data to live debugging consumers.
-	AddCallback(callbackID CallbackID, componentID ComponentID, callback func(string)) error
+	AddCallback(callbackID CallbackID, componentID ComponentID, callback func(Data)) error
 	// DeleteCallback deletes a callback for a given componentID.
 	DeleteCallback(callbackID CallbackID, componentID ComponentID)
 }
@@ -23,14 +23,14 @@ type CallbackManager interface {
 // DebugDataPublisher is used by components to push information to live debugging consumers.
 type DebugDataPublisher interface {
 	// Publish sends debugging data for a given componentID.
-	Publish(componentID ComponentID, data string)
+	Publish(componentID ComponentID, data Data)
 	// IsActive returns true when at least one consumer is listening for debugging data for the given componentID.
 	IsActive(componentID ComponentID) bool
 }
 
 type liveDebugging struct {
 	loadMut   sync.RWMutex
-	callbacks map[ComponentID]map[CallbackID]func(string)
+	callbacks map[ComponentID]map[CallbackID]func(Data)
 	host      service.Host
 	enabled   bool
 }
@@ -41,11 +41,11 @@ var _ DebugDataPublisher = &liveDebugging{}
 // NewLiveDebugging creates a new instance of liveDebugging.
 func NewLiveDebugging() *liveDebugging {
 	return &liveDebugging{
-		callbacks: make(map[ComponentID]map[CallbackID]func(string)),
+		callbacks: make(map[ComponentID]map[CallbackID]func(Data)),
 	}
 }
 
-func (s *liveDebugging) Publish(componentID ComponentID, data string) {
+func (s *liveDebugging) Publish(componentID ComponentID, data Data) {
 	s.loadMut.RLock()
 	defer s.loadMut.RUnlock()
 	if s.enabled {
@@ -62,7 +62,7 @@ func (s *liveDebugging) IsActive(componentID ComponentID) bool {
 	return exist && len(callbacks) > 0
 }
 
-func (s *liveDebugging) AddCallback(callbackID CallbackID, componentID ComponentID, callback func(string)) error {
+func (s *liveDebugging) AddCallback(callbackID CallbackID, componentID ComponentID, callback func(Data)) error {
 	err := s.addCallback(callbackID, componentID, callback)
 	if err != nil {
 		return err
@@ -78,7 +78,7 @@ func (s *liveDebugging) DeleteCallback(callbackID CallbackID, componentID Compon
 	delete(s.callbacks[componentID], callbackID)
 }
 
-func (s *liveDebugging) addCallback(callbackID CallbackID, componentID ComponentID, callback func(string)) error {
+func (s *liveDebugging) addCallback(callbackID CallbackID, componentID ComponentID, callback func(Data)) error {
 	s.loadMut.Lock()
 	defer s.loadMut.Unlock()
 
@@ -100,7 +100,7 @@ func (s *liveDebugging) addCallback(callbackID CallbackID, componentID Component
 	}
 
 	if _, ok := s.callbacks[componentID]; !ok {
-		s.callbacks[componentID] = make(map[CallbackID]func(string))
+		s.callbacks[componentID] = make(map[CallbackID]func(Data))
 	}
 	s.callbacks[componentID][callbackID] = callback
 	return nil
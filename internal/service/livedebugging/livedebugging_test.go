@@ -5,13 +5,14 @@ import (
 
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/util/testlivedebugging"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/require"
 )
 
 func TestAddCallback(t *testing.T) {
 	livedebugging := NewLiveDebugging()
 	callbackID := CallbackID("callback1")
-	callback := func(data string) {}
+	callback := func(data Data) {}
 
 	err := livedebugging.AddCallback(callbackID, "fake.liveDebugging", callback)
 	require.ErrorContains(t, err, "the live debugging service is disabled. Check the documentation to find out how to enable it")
@@ -47,19 +48,19 @@ func TestStream(t *testing.T) {
 	callbackID := CallbackID("callback1")
 
 	var receivedData string
-	callback := func(data string) {
-		receivedData = data
+	callback := func(data Data) {
+		receivedData = data.DataFunc()
 	}
 	require.False(t, livedebugging.IsActive(componentID))
 	livedebugging.AddCallback(callbackID, componentID, callback)
 	require.True(t, livedebugging.IsActive(componentID))
 	require.Len(t, livedebugging.callbacks[componentID], 1)
 
-	livedebugging.Publish(componentID, "test data")
+	livedebugging.Publish(componentID, testData("test data"))
 	require.Equal(t, "test data", receivedData)
 
 	livedebugging.SetEnabled(false)
-	livedebugging.Publish(componentID, "new test data")
+	livedebugging.Publish(componentID, testData("new test data"))
 	require.Equal(t, "test data", receivedData) // not updated because the feature is disabled
 }
 
@@ -67,7 +68,7 @@ func TestStreamEmpty(t *testing.T) {
 	livedebugging := NewLiveDebugging()
 	setupServiceHost(livedebugging)
 	componentID := ComponentID("fake.liveDebugging")
-	require.NotPanics(t, func() { livedebugging.Publish(componentID, "test data") })
+	require.NotPanics(t, func() { livedebugging.Publish(componentID, testData("test data")) })
 }
 
 func TestMultipleStreams(t *testing.T) {
@@ -78,20 +79,20 @@ func TestMultipleStreams(t *testing.T) {
 	callbackID2 := CallbackID("callback2")
 
 	var receivedData1 string
-	callback1 := func(data string) {
-		receivedData1 = data
+	callback1 := func(data Data) {
+		receivedData1 = data.DataFunc()
 	}
 
 	var receivedData2 string
-	callback2 := func(data string) {
-		receivedData2 = data
+	callback2 := func(data Data) {
+		receivedData2 = data.DataFunc()
 	}
 
 	require.NoError(t, livedebugging.AddCallback(callbackID1, componentID, callback1))
 	require.NoError(t, livedebugging.AddCallback(callbackID2, componentID, callback2))
 	require.Len(t, livedebugging.callbacks[componentID], 2)
 
-	livedebugging.Publish(componentID, "test data")
+	livedebugging.Publish(componentID, testData("test data"))
 	require.Equal(t, "test data", receivedData1)
 	require.Equal(t, "test data", receivedData2)
 }
@@ -103,8 +104,8 @@ func TestDeleteCallback(t *testing.T) {
 	callbackID1 := CallbackID("callback1")
 	callbackID2 := CallbackID("callback2")
 
-	callback1 := func(data string) {}
-	callback2 := func(data string) {}
+	callback1 := func(data Data) {}
+	callback2 := func(data Data) {}
 
 	component, _ := livedebugging.host.GetComponent(component.ParseID("fake.liveDebugging"), component.InfoOptions{})
 
@@ -141,3 +142,7 @@ func setupServiceHost(liveDebugging *liveDebugging) {
 	liveDebugging.SetServiceHost(host)
 	liveDebugging.SetEnabled(true)
 }
+
+func testData(data string) Data {
+	return NewData(PrometheusMetric, 1, labels.EmptyLabels(), func() string { return data })
+}
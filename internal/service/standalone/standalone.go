@@ -0,0 +1,73 @@
+// Package standalone builds the services of controllers which load
+// configurations outside of a regular Alloy process, like the ones of the
+// validate and test commands.
+package standalone
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/runtime/logging"
+	"github.com/grafana/alloy/internal/service"
+	cluster_service "github.com/grafana/alloy/internal/service/cluster"
+	http_service "github.com/grafana/alloy/internal/service/http"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/service/livedebugging"
+	otel_service "github.com/grafana/alloy/internal/service/otel"
+	remotecfg_service "github.com/grafana/alloy/internal/service/remotecfg"
+	uiservice "github.com/grafana/alloy/internal/service/ui"
+)
+
+// Options configures the services.
+type Options struct {
+	Logger *logging.Logger
+
+	// DataPath is where the services store their data.
+	DataPath string
+
+	// NodeName is the name of the cluster node, which never joins a cluster.
+	NodeName string
+}
+
+// Services returns the services required by components. The HTTP service
+// listens on a random local port, clustering is disabled, and their metrics
+// aren't exposed.
+func Services(opts Options) ([]service.Service, error) {
+	clusterService, err := cluster_service.New(cluster_service.Options{
+		Log:              opts.Logger,
+		EnableClustering: false,
+		NodeName:         opts.NodeName,
+		AdvertiseAddress: "127.0.0.1:80",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating cluster service: %w", err)
+	}
+
+	// remotecfg is never configured, but the HTTP service depends on it.
+	remotecfgService, err := remotecfg_service.New(remotecfg_service.Options{
+		Logger:      opts.Logger,
+		StoragePath: opts.DataPath,
+		Metrics:     prometheus.NewRegistry(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating remotecfg service: %w", err)
+	}
+
+	liveDebuggingService := livedebugging.New()
+
+	return []service.Service{
+		http_service.New(http_service.Options{
+			Logger:         opts.Logger,
+			HTTPListenAddr: "127.0.0.1:0",
+		}),
+		clusterService,
+		labelstore.New(opts.Logger, prometheus.NewRegistry()),
+		liveDebuggingService,
+		otel_service.New(opts.Logger),
+		remotecfgService,
+		uiservice.New(uiservice.Options{
+			CallbackManager: liveDebuggingService.Data().(livedebugging.CallbackManager),
+		}),
+	}, nil
+}
@@ -0,0 +1,125 @@
+package testappender
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Collector is a storage.Appendable which keeps the last sample, histogram
+// and metadata appended for each series, keyed by the string representation
+// of the series labels. Appended data is collected right away, whether or not
+// the appender is committed.
+//
+// Collector is safe for concurrent use. It's intended for components tests
+// which need to check what a component forwards to its receivers.
+type Collector struct {
+	mut             sync.Mutex
+	samples         map[string]float64
+	histograms      map[string]*histogram.Histogram
+	floatHistograms map[string]*histogram.FloatHistogram
+	metadata        map[string]metadata.Metadata
+}
+
+var _ storage.Appendable = (*Collector)(nil)
+
+// NewCollector creates an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{
+		samples:         make(map[string]float64),
+		histograms:      make(map[string]*histogram.Histogram),
+		floatHistograms: make(map[string]*histogram.FloatHistogram),
+		metadata:        make(map[string]metadata.Metadata),
+	}
+}
+
+// Appender implements storage.Appendable.
+func (c *Collector) Appender(_ context.Context) storage.Appender {
+	return collectorAppender{c}
+}
+
+// TakeSamples returns the samples collected since the last call.
+func (c *Collector) TakeSamples() map[string]float64 {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	samples := c.samples
+	c.samples = make(map[string]float64)
+	return samples
+}
+
+// TakeHistograms returns the integer histograms collected since the last
+// call.
+func (c *Collector) TakeHistograms() map[string]*histogram.Histogram {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	histograms := c.histograms
+	c.histograms = make(map[string]*histogram.Histogram)
+	return histograms
+}
+
+// TakeFloatHistograms returns the float histograms collected since the last
+// call.
+func (c *Collector) TakeFloatHistograms() map[string]*histogram.FloatHistogram {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	histograms := c.floatHistograms
+	c.floatHistograms = make(map[string]*histogram.FloatHistogram)
+	return histograms
+}
+
+// MetadataOf returns the last metadata collected for a series.
+func (c *Collector) MetadataOf(series string) metadata.Metadata {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.metadata[series]
+}
+
+type collectorAppender struct {
+	c *Collector
+}
+
+func (app collectorAppender) Append(ref storage.SeriesRef, l labels.Labels, _ int64, v float64) (storage.SeriesRef, error) {
+	app.c.mut.Lock()
+	defer app.c.mut.Unlock()
+	app.c.samples[l.String()] = v
+	return ref, nil
+}
+
+func (app collectorAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, _ int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	app.c.mut.Lock()
+	defer app.c.mut.Unlock()
+	if h != nil {
+		app.c.histograms[l.String()] = h
+	} else {
+		app.c.floatHistograms[l.String()] = fh
+	}
+	return ref, nil
+}
+
+func (app collectorAppender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	app.c.mut.Lock()
+	defer app.c.mut.Unlock()
+	app.c.metadata[l.String()] = m
+	return ref, nil
+}
+
+func (app collectorAppender) AppendExemplar(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (app collectorAppender) AppendCTZeroSample(ref storage.SeriesRef, _ labels.Labels, _, _ int64) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (app collectorAppender) Commit() error {
+	return nil
+}
+
+func (app collectorAppender) Rollback() error {
+	return nil
+}
@@ -6,10 +6,8 @@ package api
 
 import (
 	"encoding/json"
-	"math/rand"
 	"net/http"
 	"path"
-	"strconv"
 	"strings"
 	"time"
 
@@ -171,19 +169,25 @@ func liveDebugging(_ service.Host, callbackManager livedebugging.CallbackManager
 		vars := mux.Vars(r)
 		componentID := livedebugging.ComponentID(vars["id"])
 
-		dataCh := make(chan string, 1000)
-		ctx := r.Context()
+		// The filter is evaluated before the records are rendered and serialized
+		// so that busy components don't flood the consumer.
+		filter, err := livedebugging.ParseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		sampleProb := setSampleProb(w, r.URL.Query().Get("sampleProb"))
+		dataCh := make(chan livedebugging.Data, 1000)
+		ctx := r.Context()
 
 		id := livedebugging.CallbackID(uuid.New().String())
 
-		err := callbackManager.AddCallback(id, componentID, func(data string) {
+		err = callbackManager.AddCallback(id, componentID, func(data livedebugging.Data) {
 			select {
 			case <-ctx.Done():
 				return
 			default:
-				if sampleProb < 1 && rand.Float64() > sampleProb {
+				if !filter.Allow(data) {
 					return
 				}
 				// Avoid blocking the channel when the channel is full
@@ -210,8 +214,12 @@ func liveDebugging(_ service.Host, callbackManager livedebugging.CallbackManager
 		for {
 			select {
 			case data := <-dataCh:
+				record, marshalErr := json.Marshal(data.Record())
+				if marshalErr != nil {
+					continue
+				}
 				var builder strings.Builder
-				builder.WriteString(string(data))
+				builder.Write(record)
 				// |;| delimiter is added at the end of every chunk
 				builder.WriteString("|;|")
 				_, writeErr := w.Write([]byte(builder.String()))
@@ -226,16 +234,3 @@ func liveDebugging(_ service.Host, callbackManager livedebugging.CallbackManager
 		}
 	}
 }
-
-func setSampleProb(w http.ResponseWriter, sampleProbParam string) (sampleProb float64) {
-	sampleProb = 1.0
-	if sampleProbParam != "" {
-		var err error
-		sampleProb, err = strconv.ParseFloat(sampleProbParam, 64)
-		if err != nil || sampleProb < 0 || sampleProb > 1 {
-			http.Error(w, "Invalid sample probability", http.StatusBadRequest)
-			return 1.0
-		}
-	}
-	return sampleProb
-}
@@ -1,5 +1,27 @@
 import { useEffect, useState } from 'react';
 
+/**
+ * DebugRecord is a structured live debugging record sent by the API.
+ */
+interface DebugRecord {
+  type: string;
+  timestamp: string;
+  count: number;
+  labels?: Record<string, string>;
+  data: string;
+}
+
+// parseRecord returns the human-readable payload of a serialized DebugRecord.
+// Chunks which aren't valid records are returned as is.
+const parseRecord = (chunk: string): string => {
+  try {
+    const record = JSON.parse(chunk) as DebugRecord;
+    return record.data;
+  } catch {
+    return chunk;
+  }
+};
+
 export const useLiveDebugging = (
   componentID: string,
   enabled: boolean,
@@ -45,8 +67,9 @@ export const useLiveDebugging = (
           const decodedChunk = decoder.decode(value, { stream: true });
 
           setData((prevValue) => {
-            const newValue = decodedChunk.split('|;|');
-            newValue.pop(); // last element is empty because of the split, we discard it
+            const chunks = decodedChunk.split('|;|');
+            chunks.pop(); // last element is empty because of the split, we discard it
+            const newValue = chunks.map(parseRecord);
 
             if (newValue.length > maxLines) {
               console.warn(
@@ -0,0 +1,267 @@
+package stdlib
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/grafana/alloy/syntax/internal/value"
+)
+
+var mapFuncs = map[string]interface{}{
+	"keys":   mapKeys,
+	"values": mapValues,
+	"merge":  mapMerge,
+}
+
+// checkType returns an ArgError if the argument at index i isn't of type t.
+func checkType(funcValue value.Value, args []value.Value, i int, t value.Type) error {
+	if args[i].Type() != t {
+		return value.ArgError{
+			Function: funcValue,
+			Argument: args[i],
+			Index:    i,
+			Inner: value.TypeError{
+				Value:    args[i],
+				Expected: t,
+			},
+		}
+	}
+	return nil
+}
+
+// checkArgs returns an error if args doesn't match types.
+func checkArgs(funcValue value.Value, args []value.Value, types ...value.Type) error {
+	if len(args) != len(types) {
+		return value.Error{
+			Value: funcValue,
+			Inner: fmt.Errorf("expected %d args, got %d", len(types), len(args)),
+		}
+	}
+	for i, t := range types {
+		if err := checkType(funcValue, args, i, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inputs:
+// args[0]: array: elements to search
+// args[1]: any:   element to find
+var contains = value.RawFunction(func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+	if len(args) != 2 {
+		return value.Null, value.Error{
+			Value: funcValue,
+			Inner: fmt.Errorf("expected 2 args, got %d", len(args)),
+		}
+	}
+	if err := checkType(funcValue, args, 0, value.TypeArray); err != nil {
+		return value.Null, err
+	}
+
+	for i := 0; i < args[0].Len(); i++ {
+		if valuesEqual(args[0].Index(i), args[1]) {
+			return value.Bool(true), nil
+		}
+	}
+	return value.Bool(false), nil
+})
+
+// distinct returns the elements of an array without duplicates, keeping the
+// first occurrence of each element.
+var distinct = value.RawFunction(func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+	if err := checkArgs(funcValue, args, value.TypeArray); err != nil {
+		return value.Null, err
+	}
+
+	res := make([]value.Value, 0, args[0].Len())
+	for i := 0; i < args[0].Len(); i++ {
+		elem := args[0].Index(i)
+		if !slices.ContainsFunc(res, func(v value.Value) bool { return valuesEqual(v, elem) }) {
+			res = append(res, elem)
+		}
+	}
+	return value.Array(res...), nil
+})
+
+// flatten replaces the nested arrays of an array with their elements,
+// recursively.
+var flatten = value.RawFunction(func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+	if err := checkArgs(funcValue, args, value.TypeArray); err != nil {
+		return value.Null, err
+	}
+	return value.Array(flattenArray(nil, args[0])...), nil
+})
+
+func flattenArray(res []value.Value, arr value.Value) []value.Value {
+	for i := 0; i < arr.Len(); i++ {
+		if elem := arr.Index(i); elem.Type() == value.TypeArray {
+			res = flattenArray(res, elem)
+		} else {
+			res = append(res, elem)
+		}
+	}
+	return res
+}
+
+// Inputs:
+// args[0]: array:    elements to filter
+// args[1]: function: predicate called with each element, returning a bool
+var filter = value.RawFunction(func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+	if err := checkArgs(funcValue, args, value.TypeArray, value.TypeFunction); err != nil {
+		return value.Null, err
+	}
+
+	res := []value.Value{}
+	for i := 0; i < args[0].Len(); i++ {
+		elem := args[0].Index(i)
+		keep, err := args[1].Call(elem)
+		if err != nil {
+			return value.Null, err
+		}
+		if keep.Type() != value.TypeBool {
+			return value.Null, value.ArgError{
+				Function: funcValue,
+				Argument: args[1],
+				Index:    1,
+				Inner:    fmt.Errorf("filter function must return a bool, got %s", keep.Type()),
+			}
+		}
+		if keep.Bool() {
+			res = append(res, elem)
+		}
+	}
+	return value.Array(res...), nil
+})
+
+// Inputs:
+// args[0]: array:    elements to transform
+// args[1]: function: function called with each element
+var mapArray = value.RawFunction(func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+	if err := checkArgs(funcValue, args, value.TypeArray, value.TypeFunction); err != nil {
+		return value.Null, err
+	}
+
+	res := make([]value.Value, 0, args[0].Len())
+	for i := 0; i < args[0].Len(); i++ {
+		v, err := args[1].Call(args[0].Index(i))
+		if err != nil {
+			return value.Null, err
+		}
+		res = append(res, v)
+	}
+	return value.Array(res...), nil
+})
+
+// mapKeys returns the keys of an object in sorted order.
+var mapKeys = value.RawFunction(func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+	if err := checkArgs(funcValue, args, value.TypeObject); err != nil {
+		return value.Null, err
+	}
+
+	keys := sortedKeys(args[0])
+	res := make([]value.Value, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, value.String(key))
+	}
+	return value.Array(res...), nil
+})
+
+// mapValues returns the values of an object, sorted by their key.
+var mapValues = value.RawFunction(func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+	if err := checkArgs(funcValue, args, value.TypeObject); err != nil {
+		return value.Null, err
+	}
+
+	keys := sortedKeys(args[0])
+	res := make([]value.Value, 0, len(keys))
+	for _, key := range keys {
+		v, _ := args[0].Key(key)
+		res = append(res, v)
+	}
+	return value.Array(res...), nil
+})
+
+// mapMerge merges objects. If a key exists in several objects, the value
+// from the last one is used.
+var mapMerge = value.RawFunction(func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+	res := value.Object(map[string]value.Value{})
+	for i := range args {
+		if err := checkType(funcValue, args, i, value.TypeObject); err != nil {
+			return value.Null, err
+		}
+
+		var err error
+		if res, err = concatMaps(res, args[i]); err != nil {
+			return value.Null, err
+		}
+	}
+	return res, nil
+})
+
+func sortedKeys(obj value.Value) []string {
+	keys := obj.Keys()
+	slices.Sort(keys)
+	return keys
+}
+
+// valuesEqual returns true if two Values are equal, using the same rules as
+// the == operator.
+func valuesEqual(lhs value.Value, rhs value.Value) bool {
+	if lhs.Type() != rhs.Type() {
+		return false
+	}
+
+	switch lhs.Type() {
+	case value.TypeNull:
+		return true
+	case value.TypeNumber:
+		return compareNumbers(lhs.Number(), rhs.Number()) == 0
+	case value.TypeString:
+		return lhs.Text() == rhs.Text()
+	case value.TypeBool:
+		return lhs.Bool() == rhs.Bool()
+	case value.TypeArray:
+		if lhs.Len() != rhs.Len() {
+			return false
+		}
+		for i := 0; i < lhs.Len(); i++ {
+			if !valuesEqual(lhs.Index(i), rhs.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case value.TypeObject:
+		if lhs.Len() != rhs.Len() {
+			return false
+		}
+		for _, key := range lhs.Keys() {
+			lhsElement, _ := lhs.Key(key)
+			rhsElement, inRHS := rhs.Key(key)
+			if !inRHS || !valuesEqual(lhsElement, rhsElement) {
+				return false
+			}
+		}
+		return true
+	case value.TypeCapsule:
+		return reflect.DeepEqual(lhs.Interface(), rhs.Interface())
+	default:
+		// Functions can't be compared.
+		return false
+	}
+}
+
+// compareNumbers compares two numbers, converting them to the most precise
+// kind which can hold both of them.
+func compareNumbers(lhs, rhs value.Number) int {
+	switch {
+	case lhs.Kind() == value.NumberKindFloat || rhs.Kind() == value.NumberKindFloat:
+		return cmp.Compare(lhs.Float(), rhs.Float())
+	case lhs.Kind() == value.NumberKindInt || rhs.Kind() == value.NumberKindInt:
+		return cmp.Compare(lhs.Int(), rhs.Int())
+	default:
+		return cmp.Compare(lhs.Uint(), rhs.Uint())
+	}
+}
@@ -0,0 +1,38 @@
+package stdlib
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+)
+
+var hashFuncs = map[string]interface{}{
+	"md5":    hashMD5,
+	"sha1":   hashSHA1,
+	"sha256": hashSHA256,
+	"sha512": hashSHA512,
+}
+
+// The hash functions return the hex-encoded digest of a string.
+
+func hashMD5(in string) string {
+	sum := md5.Sum([]byte(in))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashSHA1(in string) string {
+	sum := sha1.Sum([]byte(in))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashSHA256(in string) string {
+	sum := sha256.Sum256([]byte(in))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashSHA512(in string) string {
+	sum := sha512.Sum512([]byte(in))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,42 @@
+package stdlib
+
+import (
+	"fmt"
+
+	"github.com/grafana/alloy/syntax/internal/value"
+)
+
+var mathFuncs = map[string]interface{}{
+	"max": mathMax,
+	"min": mathMin,
+}
+
+var (
+	mathMin = extremum(-1)
+	mathMax = extremum(1)
+)
+
+// extremum returns a function returning its smallest number argument if sign
+// is negative, or its largest number argument if sign is positive. The
+// argument is returned as is so that its number kind is preserved.
+func extremum(sign int) value.RawFunction {
+	return func(funcValue value.Value, args ...value.Value) (value.Value, error) {
+		if len(args) == 0 {
+			return value.Null, value.Error{
+				Value: funcValue,
+				Inner: fmt.Errorf("expected at least 1 arg, got 0"),
+			}
+		}
+
+		var res value.Value
+		for i := range args {
+			if err := checkType(funcValue, args, i, value.TypeNumber); err != nil {
+				return value.Null, err
+			}
+			if i == 0 || compareNumbers(args[i].Number(), res.Number())*sign > 0 {
+				res = args[i]
+			}
+		}
+		return res, nil
+	}
+}
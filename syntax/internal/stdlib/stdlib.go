@@ -8,6 +8,7 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/grafana/alloy/syntax/alloytypes"
@@ -22,7 +23,28 @@ import (
 // ExperimentalIdentifiers contains the full name (namespace + identifier's name) of stdlib
 // identifiers that are considered "experimental".
 var ExperimentalIdentifiers = map[string]bool{
-	"array.combine_maps": true,
+	"array.combine_maps":   true,
+	"array.contains":       true,
+	"array.distinct":       true,
+	"array.filter":         true,
+	"array.flatten":        true,
+	"array.map":            true,
+	"encoding.to_json":     true,
+	"encoding.to_yaml":     true,
+	"hash.md5":             true,
+	"hash.sha1":            true,
+	"hash.sha256":          true,
+	"hash.sha512":          true,
+	"map.keys":             true,
+	"map.merge":            true,
+	"map.values":           true,
+	"math.max":             true,
+	"math.min":             true,
+	"string.regex_match":   true,
+	"string.regex_replace": true,
+	"time.now":             true,
+	"time.parse_duration":  true,
+	"time.unix":            true,
 }
 
 // These identifiers are deprecated in favour of the namespaced ones.
@@ -63,6 +85,10 @@ var Identifiers = map[string]interface{}{
 	"encoding": encoding,
 	"string":   str,
 	"file":     file,
+	"hash":     hashFuncs,
+	"map":      mapFuncs,
+	"math":     mathFuncs,
+	"time":     timeFuncs,
 }
 
 func init() {
@@ -81,6 +107,8 @@ var encoding = map[string]interface{}{
 	"from_URLbase64": base64URLDecode,
 	"to_base64":      base64Encode,
 	"to_URLbase64":   base64URLEncode,
+	"to_json":        jsonEncode,
+	"to_yaml":        yamlEncode,
 }
 
 var str = map[string]interface{}{
@@ -94,11 +122,19 @@ var str = map[string]interface{}{
 	"trim_prefix": strings.TrimPrefix,
 	"trim_suffix": strings.TrimSuffix,
 	"trim_space":  strings.TrimSpace,
+
+	"regex_match":   regexMatch,
+	"regex_replace": regexReplace,
 }
 
 var array = map[string]interface{}{
 	"concat":       concat,
 	"combine_maps": combineMaps,
+	"contains":     contains,
+	"distinct":     distinct,
+	"filter":       filter,
+	"flatten":      flatten,
+	"map":          mapArray,
 }
 
 var convert = map[string]interface{}{
@@ -308,6 +344,71 @@ func yamlDecode(in string) (interface{}, error) {
 	return res, nil
 }
 
+func jsonEncode(in interface{}) (string, error) {
+	if err := checkNoSecrets(in); err != nil {
+		return "", err
+	}
+	bb, err := json.Marshal(in)
+	if err != nil {
+		return "", err
+	}
+	return string(bb), nil
+}
+
+func yamlEncode(in interface{}) (string, error) {
+	if err := checkNoSecrets(in); err != nil {
+		return "", err
+	}
+	bb, err := yaml.Marshal(in)
+	if err != nil {
+		return "", err
+	}
+	return string(bb), nil
+}
+
+// checkNoSecrets returns an error if in contains a secret, so that encoding
+// functions can't be used to reveal secrets. Secrets must be converted with
+// convert.nonsensitive first.
+func checkNoSecrets(in interface{}) error {
+	switch in := in.(type) {
+	case alloytypes.Secret:
+		return fmt.Errorf("secrets can't be encoded; use convert.nonsensitive to encode them as strings")
+	case alloytypes.OptionalSecret:
+		if in.IsSecret {
+			return fmt.Errorf("secrets can't be encoded; use convert.nonsensitive to encode them as strings")
+		}
+	case map[string]interface{}:
+		for _, v := range in {
+			if err := checkNoSecrets(v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, v := range in {
+			if err := checkNoSecrets(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func regexMatch(in string, pattern string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(in), nil
+}
+
+func regexReplace(in string, pattern string, replacement string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(in, replacement), nil
+}
+
 func base64Decode(in string) (interface{}, error) {
 	decoded, err := base64.StdEncoding.DecodeString(in)
 	if err != nil {
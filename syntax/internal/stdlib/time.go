@@ -0,0 +1,33 @@
+package stdlib
+
+import (
+	"time"
+)
+
+var timeFuncs = map[string]interface{}{
+	"now":            timeNow,
+	"parse_duration": parseDuration,
+	"unix":           timeUnix,
+}
+
+// timeNow returns the current time in RFC 3339 format.
+//
+// Unlike other stdlib functions, timeNow isn't pure: expressions using it are
+// only reevaluated when their component is.
+func timeNow() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// timeUnix returns the current time as a Unix timestamp in seconds.
+func timeUnix() int64 {
+	return time.Now().Unix()
+}
+
+// parseDuration returns the number of seconds in a Go duration string.
+func parseDuration(in string) (float64, error) {
+	d, err := time.ParseDuration(in)
+	if err != nil {
+		return 0, err
+	}
+	return d.Seconds(), nil
+}
@@ -3,9 +3,12 @@ package vm_test
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/grafana/alloy/syntax/alloytypes"
+	"github.com/grafana/alloy/syntax/internal/stdlib"
 	"github.com/grafana/alloy/syntax/internal/value"
 	"github.com/grafana/alloy/syntax/parser"
 	"github.com/grafana/alloy/syntax/vm"
@@ -42,6 +45,10 @@ func TestVM_Stdlib(t *testing.T) {
 		{"encoding.from_URLbase64", `encoding.from_URLbase64("c3RyaW5nMTIzIT8kKiYoKSctPUB-")`, string(`string123!?$*&()'-=@~`)},
 		{"encoding.to_base64", `encoding.to_base64("string123!?$*&()'-=@~")`, string(`c3RyaW5nMTIzIT8kKiYoKSctPUB+`)},
 		{"encoding.to_URLbase64", `encoding.to_URLbase64("string123!?$*&()'-=@~")`, string(`c3RyaW5nMTIzIT8kKiYoKSctPUB-`)},
+		{"encoding.to_json object", `encoding.to_json({"foo" = ["bar", 1, true, null]})`, string(`{"foo":["bar",1,true,null]}`)},
+		{"encoding.to_json round trip", `encoding.from_json(encoding.to_json({"foo" = "bar"}))`, map[string]interface{}{"foo": "bar"}},
+		{"encoding.to_json nonsensitive", `encoding.to_json([convert.nonsensitive(secret)])`, string(`["s3cr3t"]`)},
+		{"encoding.to_yaml object", `encoding.to_yaml({"foo" = ["bar", 1]})`, string("foo:\n    - bar\n    - 1\n")},
 
 		// Map tests
 		{
@@ -137,6 +144,10 @@ func TestVM_Stdlib(t *testing.T) {
 		},
 	}
 
+	scope := vm.NewScope(map[string]interface{}{
+		"secret": alloytypes.Secret("s3cr3t"),
+	})
+
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			expr, err := parser.ParseExpression(tc.input)
@@ -145,7 +156,7 @@ func TestVM_Stdlib(t *testing.T) {
 			eval := vm.New(expr)
 
 			rv := reflect.New(reflect.TypeOf(tc.expect))
-			require.NoError(t, eval.Evaluate(nil, rv.Interface()))
+			require.NoError(t, eval.Evaluate(scope, rv.Interface()))
 			require.Equal(t, tc.expect, rv.Elem().Interface())
 		})
 	}
@@ -175,8 +186,57 @@ func TestVM_Stdlib_Errors(t *testing.T) {
 			`array.combine_maps([{"a" = "a1", "b" = "b1"}], [{"a" = "a1", "c" = "b1"}], [])`,
 			`combine_maps: merge conditions must not be empty`,
 		},
+		{
+			"array.filter",
+			`array.filter(["a"], "a")`,
+			`"a" should be function, got string`,
+		},
+		{
+			"array.filter",
+			`array.filter(["a"], string.to_upper)`,
+			`filter function must return a bool, got string`,
+		},
+		{
+			"array.flatten",
+			`array.flatten({"a" = 1})`,
+			`should be array, got object`,
+		},
+		{
+			"map.merge",
+			`map.merge({"a" = 1}, [1])`,
+			`[1] should be object, got array`,
+		},
+		{
+			"math.min",
+			`math.min()`,
+			`expected at least 1 arg, got 0`,
+		},
+		{
+			"math.max",
+			`math.max(1, "2")`,
+			`"2" should be number, got string`,
+		},
+		{
+			"string.regex_match",
+			`string.regex_match("a", "(")`,
+			`error parsing regexp`,
+		},
+		{
+			"time.parse_duration",
+			`time.parse_duration("1 minute")`,
+			`time: unknown unit`,
+		},
+		{
+			"encoding.to_json",
+			`encoding.to_json({"password" = secret})`,
+			`secrets can't be encoded`,
+		},
 	}
 
+	scope := vm.NewScope(map[string]interface{}{
+		"secret": alloytypes.Secret("s3cr3t"),
+	})
+
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			expr, err := parser.ParseExpression(tc.input)
@@ -185,7 +245,7 @@ func TestVM_Stdlib_Errors(t *testing.T) {
 			eval := vm.New(expr)
 
 			rv := reflect.New(reflect.TypeOf([]map[string]interface{}{}))
-			err = eval.Evaluate(nil, rv.Interface())
+			err = eval.Evaluate(scope, rv.Interface())
 			require.ErrorContains(t, err, tc.expectedErr)
 		})
 	}
@@ -331,6 +391,10 @@ func TestStdlib_StringFunc(t *testing.T) {
 		{"string.trim2", `string.trim("   hello! world.!  ", "! ")`, "hello! world."},
 		{"string.trim_prefix", `string.trim_prefix("helloworld", "hello")`, "world"},
 		{"string.trim_suffix", `string.trim_suffix("helloworld", "world")`, "hello"},
+		{"string.regex_match", `string.regex_match("level=error", "level=(error|warn)")`, true},
+		{"string.regex_match no match", `string.regex_match("level=info", "^level=(error|warn)$")`, false},
+		{"string.regex_replace", `string.regex_replace("pod-1234-abcd", "-[0-9]+-", "-")`, "pod-abcd"},
+		{"string.regex_replace groups", `string.regex_replace("user=bob", "user=(\\w+)", "name=$1")`, "name=bob"},
 	}
 
 	for _, tc := range tt {
@@ -371,6 +435,110 @@ func TestStdlibFileFunc(t *testing.T) {
 	}
 }
 
+func TestStdlib_Collections(t *testing.T) {
+	scope := vm.NewScope(map[string]interface{}{
+		"is_even": func(n int) bool { return n%2 == 0 },
+	})
+
+	tt := []struct {
+		name   string
+		input  string
+		expect interface{}
+	}{
+		{"array.contains", `array.contains(["a", "b"], "b")`, true},
+		{"array.contains missing", `array.contains(["a", "b"], "c")`, false},
+		{"array.contains number kinds", `array.contains([1, 2], 2.0)`, true},
+		{"array.contains object", `array.contains([{"a" = 1}], {"a" = 1})`, true},
+		{"array.distinct", `array.distinct(["a", "b", "a", "c", "b"])`, []string{"a", "b", "c"}},
+		{"array.distinct objects", `array.distinct([{"a" = 1}, {"a" = 1}, {"a" = 2}])`, []map[string]int{{"a": 1}, {"a": 2}}},
+		{"array.distinct empty", `array.distinct([])`, []interface{}{}},
+		{"array.flatten", `array.flatten([1, [2, [3, [4]]], [], 5])`, []int{1, 2, 3, 4, 5}},
+		{"array.filter", `array.filter([1, 2, 3, 4], is_even)`, []int{2, 4}},
+		{"array.filter none", `array.filter([1, 3], is_even)`, []int{}},
+		{"array.map", `array.map(["a", "b"], string.to_upper)`, []string{"A", "B"}},
+		{"array.map+distinct", `array.distinct(array.map(["a", "A"], string.to_lower))`, []string{"a"}},
+		{"map.keys", `map.keys({"b" = 1, "a" = 2})`, []string{"a", "b"}},
+		{"map.values", `map.values({"b" = 1, "a" = 2})`, []int{2, 1}},
+		{"map.merge", `map.merge({"a" = 1, "b" = 1}, {"b" = 2}, {"c" = 3})`, map[string]int{"a": 1, "b": 2, "c": 3}},
+		{"map.merge empty", `map.merge()`, map[string]int{}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := parser.ParseExpression(tc.input)
+			require.NoError(t, err)
+
+			eval := vm.New(expr)
+
+			rv := reflect.New(reflect.TypeOf(tc.expect))
+			require.NoError(t, eval.Evaluate(scope, rv.Interface()))
+			require.Equal(t, tc.expect, rv.Elem().Interface())
+		})
+	}
+}
+
+func TestStdlib_MathHashTime(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  string
+		expect interface{}
+	}{
+		{"math.min", `math.min(3, 1, 2)`, 1},
+		{"math.min float", `math.min(3, -1.5, 2)`, -1.5},
+		{"math.max", `math.max(3, 1, 7)`, 7},
+		{"math.max single", `math.max(42)`, 42},
+		{"hash.md5", `hash.md5("hello")`, "5d41402abc4b2a76b9719d911017c592"},
+		{"hash.sha1", `hash.sha1("hello")`, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{"hash.sha256", `hash.sha256("hello")`, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{"hash.sha512", `hash.sha512("")`, "cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e"},
+		{"time.parse_duration", `time.parse_duration("1m30s")`, 90.0},
+		{"time.parse_duration fraction", `time.parse_duration("250ms")`, 0.25},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := parser.ParseExpression(tc.input)
+			require.NoError(t, err)
+
+			eval := vm.New(expr)
+
+			rv := reflect.New(reflect.TypeOf(tc.expect))
+			require.NoError(t, eval.Evaluate(nil, rv.Interface()))
+			require.Equal(t, tc.expect, rv.Elem().Interface())
+		})
+	}
+}
+
+func TestStdlib_TimeNow(t *testing.T) {
+	before := time.Now().Truncate(time.Second)
+
+	var now string
+	expr, err := parser.ParseExpression(`time.now()`)
+	require.NoError(t, err)
+	require.NoError(t, vm.New(expr).Evaluate(nil, &now))
+
+	var unix int64
+	expr, err = parser.ParseExpression(`time.unix()`)
+	require.NoError(t, err)
+	require.NoError(t, vm.New(expr).Evaluate(nil, &unix))
+
+	parsed, err := time.Parse(time.RFC3339Nano, now)
+	require.NoError(t, err)
+	require.False(t, parsed.Before(before))
+	require.GreaterOrEqual(t, unix, before.Unix())
+}
+
+func TestStdlib_ExperimentalIdentifiers(t *testing.T) {
+	for fullName := range stdlib.ExperimentalIdentifiers {
+		namespace, name, ok := strings.Cut(fullName, ".")
+		require.True(t, ok, fullName)
+
+		ns, ok := stdlib.Identifiers[namespace].(map[string]interface{})
+		require.True(t, ok, "namespace of %s not found", fullName)
+		require.Contains(t, ns, name)
+	}
+}
+
 func BenchmarkConcat(b *testing.B) {
 	// There's a bit of setup work to do here: we want to create a scope holding
 	// a slice of the Person type, which has a fair amount of data in it.